@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const defaultAIModel = "gpt-4o-mini"
+
+// maxAIDiffBytes bounds the diff sent for a summary; larger ones are cut.
+const maxAIDiffBytes = 32 * 1024
+
+// aiClient waits less than httpClient, since summaries hold up the hook.
+var aiClient = &http.Client{Timeout: 20 * time.Second}
+
+const aiSystemPrompt = "You summarize changes to AI prompt and skill files for the people reviewing them. " +
+	"Reply with a single paragraph of at most 80 words saying what changed in the skill and why it matters. " +
+	"No preamble, headings or lists."
+
+// getAIEndpoint returns the OpenAI-compatible API summaries are requested
+// from, e.g. https://api.openai.com/v1, when prrompt.ai.summary opts in;
+// "" otherwise.
+func getAIEndpoint() string {
+	if !getBoolConfig("prrompt.ai.summary", false) {
+		return ""
+	}
+	value, _ := gitConfig("--get", "prrompt.ai.endpoint")
+	if value = strings.TrimRight(strings.TrimSpace(value), "/"); value == "" {
+		warnf("prrompt.ai.summary is set but prrompt.ai.endpoint is not, not summarizing")
+	}
+	return value
+}
+
+func getAIModel() string {
+	value, err := gitConfig("--get", "prrompt.ai.model")
+	if err != nil || strings.TrimSpace(value) == "" {
+		return defaultAIModel
+	}
+	return strings.TrimSpace(value)
+}
+
+// getAIToken returns the API key sent to endpoint: prrompt.ai.token
+// (PRROMPT_AI_TOKEN), else OPENAI_API_KEY when endpoint may have it.
+func getAIToken(endpoint string) string {
+	if value, err := gitConfig("--get", "prrompt.ai.token"); err == nil && value != "" {
+		return value
+	}
+	if !trustedAIEndpoint(endpoint) {
+		return ""
+	}
+	return os.Getenv("OPENAI_API_KEY")
+}
+
+// trustedAIEndpoint reports whether OPENAI_API_KEY may go to endpoint: the
+// OpenAI API itself, or an endpoint the user set in the environment or
+// their global or system git config, rather than one a repository's own
+// config names.
+func trustedAIEndpoint(endpoint string) bool {
+	if u, err := url.Parse(endpoint); err == nil && u.Scheme == "https" && strings.EqualFold(u.Hostname(), "api.openai.com") {
+		return true
+	}
+	source := configSource("prrompt.ai.endpoint")
+	return strings.HasPrefix(source, "env ") || strings.HasPrefix(source, "global ") || strings.HasPrefix(source, "system ")
+}
+
+// summarizePromptChanges asks the configured model what the prompt changes
+// of info do. The commit message and diff go through the redaction
+// patterns before leaving the machine, and so does the answer. It returns
+// "" when summaries are off or the request fails.
+func summarizePromptChanges(info *CommitInfo) string {
+	endpoint := getAIEndpoint()
+	if endpoint == "" || len(info.PromptFiles) == 0 {
+		return ""
+	}
+	patterns := getRedactPatterns()
+	diff, err := runGit(append([]string{"diff", "-M", info.parent(), info.SHA, "--"}, info.PromptFiles...)...)
+	if err != nil || diff == "" {
+		return ""
+	}
+	if len(diff) > maxAIDiffBytes {
+		// Cut before the rune straddling the limit, not through it
+		cut := maxAIDiffBytes
+		for cut > 0 && !utf8.RuneStart(diff[cut]) {
+			cut--
+		}
+		diff = diff[:cut] + "\n[diff truncated]"
+	}
+	content := fmt.Sprintf("Commit message:\n%s\n\nDiff:\n%s", redactWith(extractionMessage(info), patterns), redactWith(diff, patterns))
+
+	model := getAIModel()
+	summary, err := requestCompletion(endpoint, model, aiSystemPrompt, content)
+	if err != nil {
+		warnf("not summarizing %s: %v", shortSHA(info.SHA), err)
+		return ""
+	}
+	summary = strings.Join(strings.Fields(redactWith(summary, patterns)), " ")
+	if summary == "" {
+		return ""
+	}
+	verbosef("✓ Summarized %s with %s", shortSHA(info.SHA), model)
+	return fmt.Sprintf("Summary (generated by %s): %s", model, summary)
+}
+
+// chatRequest and chatResponse are the parts of the OpenAI chat
+// completions API prrompt uses.
+type chatRequest struct {
+	Model    string        `json:"model"`
+	Messages []chatMessage `json:"messages"`
+}
+
+type chatMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type chatResponse struct {
+	Choices []struct {
+		Message chatMessage `json:"message"`
+	} `json:"choices"`
+}
+
+// requestCompletion posts one chat completion to endpoint and returns the
+// answer.
+func requestCompletion(endpoint, model, system, content string) (string, error) {
+	data, err := json.Marshal(chatRequest{Model: model, Messages: []chatMessage{{"system", system}, {"user", content}}})
+	if err != nil {
+		return "", err
+	}
+	target := endpoint
+	if !strings.HasSuffix(target, "/chat/completions") {
+		target += "/chat/completions"
+	}
+	req, err := http.NewRequest(http.MethodPost, target, bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token := getAIToken(endpoint); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := aiClient.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return "", fmt.Errorf("%s from %s", resp.Status, withoutCredentials(endpoint))
+	}
+	var answer chatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&answer); err != nil {
+		return "", fmt.Errorf("unexpected answer from %s: %w", withoutCredentials(endpoint), err)
+	}
+	if len(answer.Choices) == 0 {
+		return "", fmt.Errorf("no answer from %s", withoutCredentials(endpoint))
+	}
+	return answer.Choices[0].Message.Content, nil
+}
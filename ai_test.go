@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func Test_AISummary(t *testing.T) {
+	repo := setupTestRepo(t)
+	sha := commitFiles(t, repo.Dir, "Tell the reviewer about the deploy key", map[string]string{
+		"prompts/review.md": "# Review\n\nUse key sk-live-123 to deploy.",
+	})
+
+	var requests []chatRequest
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" {
+			http.NotFound(w, r)
+			return
+		}
+		var request chatRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		requests = append(requests, request)
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "The review skill now\n\nexplains deploys with sk-live-123."}}]}`))
+	}))
+	defer server.Close()
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	// Nothing is sent without opting in, even with an endpoint
+	runGitInDir(repo.Dir, "config", "prrompt.ai.endpoint", server.URL+"/v1")
+	runGitInDir(repo.Dir, "config", "prrompt.redactPattern", `sk-live-[0-9]+`)
+	info, _ := analyzeCommit(sha)
+	if summary := summarizePromptChanges(info); summary != "" || len(requests) != 0 {
+		t.Fatalf("Expected no summary without prrompt.ai.summary, got %q after %d requests", summary, len(requests))
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.ai.summary", "true")
+	t.Setenv("PRROMPT_AI_TOKEN", "test-token")
+	result, err := processCommit(sha)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", result, err)
+	}
+	if len(requests) != 1 || authorization != "Bearer test-token" || requests[0].Model != defaultAIModel {
+		t.Fatalf("Expected one authorized request, got %+v (%q)", requests, authorization)
+	}
+	sent := requests[0].Messages[1].Content
+	if strings.Contains(sent, "sk-live-123") || !strings.Contains(sent, redactedText) || !strings.Contains(sent, "prompts/review.md") {
+		t.Errorf("Expected the redacted diff to be sent, got:\n%s", sent)
+	}
+	message, _ := runGit("log", "-1", "--format=%B", result.Branch)
+	want := "Summary (generated by " + defaultAIModel + "): The review skill now explains deploys with " + redactedText + "."
+	if !strings.Contains(message, want) {
+		t.Errorf("Expected %q in the commit message, got:\n%s", want, message)
+	}
+}
+
+func Test_AITokenAndTruncation(t *testing.T) {
+	repo := setupTestRepo(t)
+	sha := commitFiles(t, repo.Dir, "Translate the greeting", map[string]string{
+		"prompts/greet.md": strings.Repeat("é", maxAIDiffBytes),
+	})
+
+	var sent, authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request chatRequest
+		json.NewDecoder(r.Body).Decode(&request)
+		sent = request.Messages[1].Content
+		authorization = r.Header.Get("Authorization")
+		w.Write([]byte(`{"choices": [{"message": {"role": "assistant", "content": "Translated."}}]}`))
+	}))
+	defer server.Close()
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+	t.Setenv("OPENAI_API_KEY", "sk-user")
+	runGitInDir(repo.Dir, "config", "prrompt.ai.summary", "true")
+	runGitInDir(repo.Dir, "config", "prrompt.ai.endpoint", server.URL)
+
+	info, _ := analyzeCommit(sha)
+	if summary := summarizePromptChanges(info); summary == "" {
+		t.Fatal("Expected a summary")
+	}
+	if authorization != "" {
+		t.Errorf("Expected OPENAI_API_KEY kept from an endpoint in the repository's config, got %q", authorization)
+	}
+	if !strings.Contains(sent, "[diff truncated]") || strings.ContainsRune(sent, utf8.RuneError) {
+		t.Errorf("Expected the diff cut at a rune boundary, got ...%q", sent[len(sent)-40:])
+	}
+
+	runGitInDir(repo.Dir, "config", "--unset", "prrompt.ai.endpoint")
+	t.Setenv("PRROMPT_AI_ENDPOINT", server.URL)
+	summarizePromptChanges(info)
+	if authorization != "Bearer sk-user" {
+		t.Errorf("Expected OPENAI_API_KEY sent to an endpoint from the environment, got %q", authorization)
+	}
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const defaultArchiveDir = "archive"
+
+// getArchivePatterns returns the prompt patterns whose changed files are
+// also snapshotted into the archive directory; none disables archiving.
+func getArchivePatterns() []string {
+	return getListConfig("prrompt.archivePatterns")
+}
+
+func getArchiveDir() string {
+	value, err := gitConfig("--get", "prrompt.archiveDir")
+	if err != nil || strings.Trim(value, "/") == "" {
+		return defaultArchiveDir
+	}
+	return strings.Trim(value, "/")
+}
+
+// archivePath returns where file is snapshotted for a commit dated date,
+// <archiveDir>/<date>/<path below the pattern>, or "" when no archive
+// pattern matches it.
+func archivePath(file, date string) string {
+	for _, pattern := range getArchivePatterns() {
+		if rel, ok := strings.CutPrefix(file, pattern); ok && rel != "" {
+			return filepath.ToSlash(filepath.Join(getArchiveDir(), date, strings.TrimPrefix(rel, "/")))
+		}
+	}
+	return ""
+}
+
+// archivePrompts writes dated copies of the commit's changed prompt files
+// that match prrompt.archivePatterns into the work tree and stages them, so
+// the extraction commit keeps every version even after squash merges.
+func archivePrompts(info *CommitInfo) error {
+	patterns := getArchivePatterns()
+	if len(patterns) == 0 {
+		return nil
+	}
+	date, err := runGit("show", "-s", "--format=%as", info.SHA)
+	if err != nil {
+		return fmt.Errorf("failed to read commit date: %w", err)
+	}
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	var archived []string
+	for _, file := range info.PromptFiles {
+		target := archivePath(file, date)
+		if target == "" || blobAt(info.SHA, file) == "" {
+			continue
+		}
+		path := filepath.Join(toplevel, target)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+		}
+		if err := os.WriteFile(path, []byte(showFile(info.SHA, file)), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+		archived = append(archived, target)
+	}
+	if len(archived) == 0 {
+		return nil
+	}
+	if _, err := runGit(append([]string{"add", "--"}, archived...)...); err != nil {
+		return fmt.Errorf("failed to stage archived prompts: %w", err)
+	}
+	verbosef("Archived %d prompt files under %s/%s", len(archived), getArchiveDir(), date)
+	return nil
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ArchivePrompts(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.archivePatterns", ".claude/skills/")
+
+	skillFile := filepath.Join(repo.Dir, ".claude/skills/review/SKILL.md")
+	os.MkdirAll(filepath.Dir(skillFile), 0755)
+	os.WriteFile(skillFile, []byte("---\nname: review\ndescription: Reviews code\n---\n"), 0644)
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", ".")
+	runGitInDir(repo.Dir, "commit", "-m", "Add review skill")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	date, _ := runGitInDir(repo.Dir, "show", "-s", "--format=%as", commitSHA)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", result, err)
+	}
+	files, _ := runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", result.Branch)
+	if !strings.Contains(files, "archive/"+date+"/review/SKILL.md") {
+		t.Errorf("Expected a dated snapshot of the skill on %s, got:\n%s", result.Branch, files)
+	}
+	if strings.Contains(files, "archive/"+date+"/test.md") {
+		t.Errorf("Expected prompts outside archivePatterns not to be archived, got:\n%s", files)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Dir, "archive")); !os.IsNotExist(err) {
+		t.Error("Expected the archive to stay off the source branch")
+	}
+}
@@ -0,0 +1,140 @@
+package main
+
+import "strings"
+
+// baseBranchCandidates are tried, in order, when the remote has no HEAD.
+var baseBranchCandidates = []string{"main", "master", "trunk", "develop"}
+
+// detectedBaseBranches caches the detected base branch per repository
+// (keyed by the common git dir) for the rest of the run.
+var detectedBaseBranches = map[string]detectedBase{}
+
+type detectedBase struct {
+	Branch string
+	Source string
+}
+
+// detectBaseBranch returns the base branch to use when prrompt.baseBranch is
+// not set, and how it was found: the remote's HEAD (as set by clone or
+// `git remote set-head`), else init.defaultBranch or the first of the common
+// names that exists locally or on the remote, else defaultBaseBranch.
+func detectBaseBranch() (branch, source string) {
+	commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return defaultBaseBranch, "default"
+	}
+	if cached, ok := detectedBaseBranches[commonDir]; ok {
+		return cached.Branch, cached.Source
+	}
+
+	remote := getRemote()
+	detected := detectedBase{defaultBaseBranch, "default"}
+	if ref, err := runGit("symbolic-ref", "--quiet", "refs/remotes/"+remote+"/HEAD"); err == nil {
+		if name, ok := strings.CutPrefix(ref, "refs/remotes/"+remote+"/"); ok && name != "" {
+			detected = detectedBase{name, remote + "/HEAD"}
+		}
+	} else {
+		candidates := baseBranchCandidates
+		if initDefault, err := runGit("config", "--get", "init.defaultBranch"); err == nil && initDefault != "" {
+			candidates = append([]string{initDefault}, candidates...)
+		}
+		for _, name := range candidates {
+			if branchExists(name) || branchExists(remote+"/"+name) {
+				detected = detectedBase{name, "existing branch"}
+				break
+			}
+		}
+	}
+
+	debugf("Detected base branch %s (%s)", detected.Branch, detected.Source)
+	detectedBaseBranches[commonDir] = detected
+	return detected.Branch, detected.Source
+}
+
+// branchExists reports whether name is a local branch, or a remote-tracking
+// branch when given as <remote>/<branch>.
+func branchExists(name string) bool {
+	for _, ref := range []string{"refs/heads/" + name, "refs/remotes/" + name} {
+		if _, err := runGit("rev-parse", "--verify", "--quiet", ref); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// baseStartPoints caches the resolved start point per repository and base
+// so the base is fetched at most once per run.
+var baseStartPoints = map[string]string{}
+
+// baseStartPoint returns the revision prompt branches are created from:
+// the start point of the target branch, or of the base branch while
+// prrompt.stagingBranch doesn't exist yet.
+func baseStartPoint() string {
+	return targetStartPoint(startPointOf)
+}
+
+// localBaseStartPoint is baseStartPoint without fetching, for paths that
+// only look, see localStartPointOf.
+func localBaseStartPoint() string {
+	return targetStartPoint(localStartPointOf)
+}
+
+func targetStartPoint(startOf func(string) string) string {
+	target := getTargetBranch()
+	ref := startOf(target)
+	if ref == target && target != getBaseBranch() && !branchExists(target) {
+		debugf("%s does not exist yet, creating prompt branches from %s", target, getBaseBranch())
+		return startOf(getBaseBranch())
+	}
+	return ref
+}
+
+// localStartPointOf returns the revision startPointOf would branch from
+// base without fetching: what this run fetched, else with
+// prrompt.fetchBase <remote>/<base> as last fetched, else the local base
+// branch. Paths that don't create a branch, such as previews, dedupe and
+// checks of existing prompt branches, use it to stay off the network.
+func localStartPointOf(base string) string {
+	remote := getRemote()
+	if !getBoolConfig("prrompt.fetchBase", true) || !hasRemote(remote) {
+		return base
+	}
+	if commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir"); err == nil {
+		if ref, ok := baseStartPoints[commonDir+"\x00"+remote+"/"+base]; ok {
+			return ref
+		}
+	}
+	if branchExists(remote + "/" + base) {
+		return remote + "/" + base
+	}
+	return base
+}
+
+// startPointOf returns the revision to branch from base: with
+// prrompt.fetchBase, <remote>/<base> freshly fetched so the branch isn't
+// behind a stale local base, else (or when offline) the local base branch.
+// Only paths creating a branch or commit call it.
+func startPointOf(base string) string {
+	remote := getRemote()
+	if !getBoolConfig("prrompt.fetchBase", true) || !hasRemote(remote) || !remoteReachable(remote) {
+		return base
+	}
+	commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return base
+	}
+	key := commonDir + "\x00" + remote + "/" + base
+	if ref, ok := baseStartPoints[key]; ok {
+		return ref
+	}
+
+	ref := base
+	if output, err := runGit("fetch", "--quiet", remote, base); err != nil {
+		verbosef("Could not fetch %s from %s, using the local branch: %s", base, remote, truncate(output, 200))
+	} else if branchExists(remote + "/" + base) {
+		ref = remote + "/" + base
+	}
+	debugf("Creating prompt branches from %s", ref)
+	baseStartPoints[key] = ref
+	return ref
+}
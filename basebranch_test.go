@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_DetectBaseBranch(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "branch", "-m", "main", "trunk")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if base := getBaseBranch(); base != "trunk" {
+		t.Errorf("Expected trunk to be detected, got %s", base)
+	}
+
+	// The remote's HEAD wins over the heuristics
+	other := setupTestRepo(t)
+	os.Chdir(other.Dir)
+	runGitInDir(other.Dir, "update-ref", "refs/remotes/origin/develop", "HEAD")
+	runGitInDir(other.Dir, "symbolic-ref", "refs/remotes/origin/HEAD", "refs/remotes/origin/develop")
+	if branch, source := detectBaseBranch(); branch != "develop" || source != "origin/HEAD" {
+		t.Errorf("Expected develop from origin/HEAD, got %s (%s)", branch, source)
+	}
+
+	runGitInDir(other.Dir, "config", "prrompt.baseBranch", "release")
+	if base := getBaseBranch(); base != "release" {
+		t.Errorf("Expected prrompt.baseBranch to override detection, got %s", base)
+	}
+}
+
+func Test_FetchBase(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.push", "false")
+	runGitInDir(repo.Dir, "push", "-q", "origin", "main")
+
+	// Someone else moves main on the remote; the local main is now stale
+	tree, _ := runGitInDir(repo.Dir, "rev-parse", "main^{tree}")
+	upstream, _ := runGitInDir(repo.Dir, "commit-tree", tree, "-p", "main", "-m", "Upstream change")
+	runGitInDir(repo.Dir, "push", "-q", "origin", upstream+":refs/heads/main")
+	runGitInDir(repo.Dir, "update-ref", "-d", "refs/remotes/origin/main")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	// Previews and checks of existing branches don't fetch
+	var preview bytes.Buffer
+	if err := runDiff(&preview, []string{commitSHA}); err != nil || !strings.Contains(preview.String(), "(from main)") {
+		t.Errorf("Expected a preview from the local main, got %v:\n%s", err, preview.String())
+	}
+	if _, err := runGitInDir(repo.Dir, "rev-parse", "--verify", "--quiet", "refs/remotes/origin/main"); err == nil {
+		t.Error("Expected prrompt diff not to fetch the base")
+	}
+
+	result, err := processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", result, err)
+	}
+	if parent, _ := runGitInDir(repo.Dir, "rev-parse", result.Branch+"^"); parent != upstream {
+		t.Errorf("Expected %s to start at origin/main %s, got %s", result.Branch, upstream, parent)
+	}
+	if upstreamRef, err := runGitInDir(repo.Dir, "rev-parse", "--abbrev-ref", result.Branch+"@{upstream}"); err == nil {
+		t.Errorf("Expected %s not to track %s", result.Branch, upstreamRef)
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+const (
+	rangeModePerCommit = "per-commit"
+	rangeModeCombined  = "combined"
+	rangeModeSkill     = "skill"
+)
+
+const defaultRangeMode = rangeModePerCommit
+
+// Batch squash modes, selected with prrompt.batchSquash.
+const (
+	batchSquashPerCommit = "per-commit" // an extraction commit per source commit
+	batchSquashNet       = "net"        // one commit with the net change
+)
+
+// getRangeMode returns how several commits given in one invocation are
+// extracted: to a branch each, to one combined branch, or to a branch per
+// skill.
+func getRangeMode() string {
+	value, err := gitConfig("--get", "prrompt.rangeMode")
+	if err != nil {
+		return defaultRangeMode
+	}
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case rangeModeCombined, rangeModeSkill:
+		return mode
+	}
+	return defaultRangeMode
+}
+
+// getBatchSquash returns how a prompt branch extracting several commits at
+// once records them: a faithful commit each, or one commit of their net
+// change listing them all.
+func getBatchSquash() string {
+	value, err := gitConfig("--get", "prrompt.batchSquash")
+	if err == nil && strings.ToLower(strings.TrimSpace(value)) == batchSquashNet {
+		return batchSquashNet
+	}
+	return batchSquashPerCommit
+}
+
+// squashBatch replaces the extraction commits of infos at the tip of
+// promptBranch, one per source commit, with a commit of their net change.
+// It carries the final content of every file however often the commits
+// edited it, and the provenance of each of them.
+func squashBatch(promptBranch string, infos []*CommitInfo) error {
+	tip, err := runGit("rev-parse", "--verify", "-q", "refs/heads/"+promptBranch)
+	if err != nil {
+		return fmt.Errorf("no branch %s", promptBranch)
+	}
+	args := []string{"commit-tree", tip + "^{tree}", "-m", signoff(netCommitMessage(infos))}
+	// A new history has no commit before the batch
+	if base, err := runGit("rev-parse", "--verify", "-q", fmt.Sprintf("%s~%d", tip, len(infos))); err == nil {
+		args = append(args, "-p", base)
+	}
+	// The latest author wrote the content that is left
+	authorEnv, err := commitAuthorEnv(infos[len(infos)-1].SHA)
+	if err != nil {
+		return err
+	}
+	commit, err := retryGit(func() (string, error) {
+		return runGitWithEnv(append(authorEnv, committerEnv()...), append(args, commitSigningArgs()...)...)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to commit: %w: %s", err, truncate(commit, 200))
+	}
+	if output, err := runGitRetry("update-ref", "-m", "prrompt: squash "+strconv.Itoa(len(infos))+" extractions", "refs/heads/"+promptBranch, commit, tip); err != nil {
+		return fmt.Errorf("failed to update %s: %s", promptBranch, output)
+	}
+	verbosef("Squashed %d extraction commits on %s into their net change (prrompt.batchSquash=%s)", len(infos), promptBranch, batchSquashNet)
+	return nil
+}
+
+// netCommitMessage returns the message of a batch's net commit: a summary
+// subject, the source commits oldest first, and a provenance trailer for
+// each.
+func netCommitMessage(infos []*CommitInfo) string {
+	subject := fmt.Sprintf("Prompt changes from %d commits", len(infos))
+	intro := "The net change of these commits:"
+	if group := infos[0].Group; group != "" {
+		subject = fmt.Sprintf("%s: changes from %d commits", group, len(infos))
+		intro = fmt.Sprintf("The net change to %s, from these commits:", group)
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n%s\n\n", prefixSubject(subject), intro)
+	trailers := prefixTrailers()
+	for _, info := range infos {
+		first, _, _ := strings.Cut(extractionMessage(info), "\n")
+		fmt.Fprintf(&b, "- %s %s\n", shortSHA(info.SHA), first)
+		trailers = append(trailers, trailer{trailerSourceCommit, info.SHA})
+	}
+	for _, info := range infos {
+		if info.AISummary != "" {
+			fmt.Fprintf(&b, "\n%s: %s\n", shortSHA(info.SHA), info.AISummary)
+		}
+	}
+	if bumps := netVersionBumps(infos); len(bumps) > 0 {
+		fmt.Fprintf(&b, "\n%s\n", formatVersionBumps(bumps))
+	}
+	seen := make(map[string]bool)
+	for _, info := range infos {
+		if !seen[info.SourceBranch] {
+			seen[info.SourceBranch] = true
+			trailers = append(trailers, trailer{trailerSourceBranch, info.SourceBranch})
+		}
+	}
+	trailers = append(trailers, trailer{trailerToolVersion, toolVersion()})
+	for _, info := range infos {
+		for _, experiment := range info.Experiments {
+			if !seen[trailerExperiment+experiment] {
+				seen[trailerExperiment+experiment] = true
+				trailers = append(trailers, trailer{trailerExperiment, experiment})
+			}
+		}
+	}
+	return appendTrailers(b.String(), trailers)
+}
+
+// expandCommits resolves commit arguments, expanding A..B ranges to their
+// commits oldest first.
+func expandCommits(args []string) ([]string, error) {
+	var commits []string
+	for _, arg := range args {
+		if !strings.Contains(arg, "..") {
+			commits = append(commits, arg)
+			continue
+		}
+		output, err := runGit("rev-list", "--reverse", arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range %s: %s", arg, output)
+		}
+		if output != "" {
+			commits = append(commits, strings.Split(output, "\n")...)
+		}
+	}
+	return commits, nil
+}
+
+// processCommits processes several commits in one invocation, as mode
+// says. Per commit, each gets its own prompt branch and a failure does not
+// stop the others; combined, the prompt changes of all of them go to a
+// single branch with one extraction commit each; by skill, to a branch per
+// skill, see processBySkill.
+func processCommits(args []string, mode string) ([]*Result, error) {
+	commits, err := expandCommits(args)
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		infof("No commits to process")
+		return nil, nil
+	}
+	switch mode {
+	case rangeModeCombined:
+		return processCombined(commits, false)
+	case rangeModeSkill:
+		return processCombined(commits, true)
+	}
+
+	if len(commits) > 1 {
+		disableStay("as there are several commits")
+	}
+	var results []*Result
+	failed := 0
+	for _, sha := range commits {
+		result, err := processCommit(sha)
+		results = append(results, result)
+		if err != nil {
+			failed++
+			fmt.Printf("%s: %v\n", shortSHA(sha), err)
+		}
+	}
+	if failed > 0 {
+		return results, fmt.Errorf("%d of %d commits failed", failed, len(commits))
+	}
+	return results, nil
+}
+
+// processCombined extracts the prompt changes of commits to one branch,
+// named after the first and last commit with prompt changes, checking it
+// out only once. With bySkill they go to a branch per skill instead.
+func processCombined(commits []string, bySkill bool) (results []*Result, err error) {
+	for _, sha := range commits {
+		results = append(results, &Result{Status: statusSkipped, Commit: sha})
+	}
+	// pending are the results an error applies to
+	pending := results
+	defer func() {
+		if err != nil {
+			for _, result := range pending {
+				result.Status = statusError
+				result.Error = err.Error()
+				result.ExitCode = exitCodeOf(err)
+			}
+		}
+		for _, result := range results {
+			appMetrics.observeResult(result)
+		}
+	}()
+
+	if os.Getenv("PRROMPT_SKIP") == "1" {
+		for _, result := range results {
+			result.Reason = reasonSkipEnv
+		}
+		return results, nil
+	}
+
+	release, err := acquireLock()
+	if err != nil {
+		return results, err
+	}
+	defer release()
+
+	if _, err := recoverInterrupted(); err != nil {
+		return results, fmt.Errorf("error recovering interrupted extraction: %w", err)
+	}
+
+	var infos []*CommitInfo
+	var extracted []*Result
+	for i, sha := range commits {
+		info, err := prepareCommit(sha, results[i])
+		if err != nil {
+			pending = results[i : i+1]
+			return results, fmt.Errorf("%s: %w", shortSHA(sha), err)
+		}
+		if info != nil {
+			infos = append(infos, info)
+			extracted = append(extracted, results[i])
+		}
+	}
+	if len(infos) == 0 {
+		infof("No prompt changes in %d commits", len(commits))
+		return results, nil
+	}
+
+	pending = extracted
+	if bySkill {
+		if err := extractBySkill(infos, extracted); err != nil {
+			return results, err
+		}
+	} else {
+		promptBranch := promptBranchName(infos[0])
+		if len(infos) > 1 {
+			promptBranch = fmt.Sprintf("%s/%s-%s", getBranchPrefix(), abbrevSHA(infos[0].SHA), abbrevSHA(infos[len(infos)-1].SHA))
+		}
+		if err := extractAndMirror(promptBranch, infos); err != nil {
+			return results, err
+		}
+		for i, result := range extracted {
+			result.setExtracted(infos[i])
+		}
+	}
+	if err := recordProcessed(configHash(), infos...); err != nil {
+		warnf("failed to record processed commits: %v", err)
+	}
+	return results, nil
+}
+
+// skillPath returns the skill a prompt file belongs to: the directory of a
+// skill under .claude/skills/ or of a SKILL.md, else the file itself.
+func skillPath(file string) string {
+	if i := strings.Index("/"+file, "/.claude/skills/"); i >= 0 {
+		start := i + len(".claude/skills/")
+		if name, _, found := strings.Cut(file[start:], "/"); found {
+			return file[:start] + name
+		}
+		return file
+	}
+	if path.Base(file) == "SKILL.md" {
+		return path.Dir(file)
+	}
+	return file
+}
+
+// skillBranchName returns the prompt branch of a skill group:
+// <prefix>/<skill>-<first-sha>[-<last-sha>]. A skill whose name is already
+// taken in this run is named after its whole path.
+func skillBranchName(group *fileGroup, taken map[string]bool) string {
+	name := slugify(strings.TrimSuffix(path.Base(group.path), ".md"), getSlugStyle())
+	if taken[name] || name == "" {
+		name = slugify(group.path, getSlugStyle())
+	}
+	taken[name] = true
+	first, last := group.infos[0], group.infos[len(group.infos)-1]
+	if first == last {
+		return fmt.Sprintf("%s/%s-%s", getBranchPrefix(), name, abbrevSHA(first.SHA))
+	}
+	return fmt.Sprintf("%s/%s-%s-%s", getBranchPrefix(), name, abbrevSHA(first.SHA), abbrevSHA(last.SHA))
+}
+
+// extractBySkill extracts infos to a branch per skill, so that a long
+// history becomes one PR per skill with its net change and the commits it
+// came from, rather than a PR per commit. A commit touching several skills
+// contributes to each of their branches; its result names them all.
+func extractBySkill(infos []*CommitInfo, results []*Result) error {
+	groups := groupFiles(infos, skillPath)
+	infof("Extracting %d commits to %d skill branches", len(infos), len(groups))
+	taken := make(map[string]bool)
+	return extractGroups(infos, results, groups, func(group *fileGroup) string {
+		return skillBranchName(group, taken)
+	})
+}
+
+func shortSHA(sha string) string {
+	if len(sha) > 7 {
+		return sha[:7]
+	}
+	return sha
+}
@@ -0,0 +1,223 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func commitFiles(t *testing.T, dir, message string, files map[string]string) string {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		runGitInDir(dir, "add", name)
+	}
+	runGitInDir(dir, "commit", "-m", message)
+	sha, _ := runGitInDir(dir, "rev-parse", "HEAD")
+	return sha
+}
+
+func Test_ProcessCommitRange(t *testing.T) {
+	repo := setupTestRepo(t)
+	start, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	first := commitFiles(t, repo.Dir, "Add first prompt", map[string]string{"prompts/one.md": "# One"})
+	commitFiles(t, repo.Dir, "Change code", map[string]string{"main.go": "package main"})
+	last := commitFiles(t, repo.Dir, "Add second prompt with code", map[string]string{
+		"prompts/two.md": "# Two",
+		"util.go":        "package main",
+	})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	results, err := processCommits([]string{start + "..HEAD"}, rangeModePerCommit)
+	if err != nil {
+		t.Fatalf("processCommits failed: %v", err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Expected 3 results, got %d", len(results))
+	}
+	if results[0].Status != statusExtracted || results[1].Reason != reasonNoPromptFiles || results[2].Status != statusExtracted {
+		t.Errorf("Unexpected results: %+v %+v %+v", results[0], results[1], results[2])
+	}
+	if results[0].Branch == results[2].Branch {
+		t.Errorf("Expected a branch per commit, got %s twice", results[0].Branch)
+	}
+	current, _ := runGitInDir(repo.Dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if current != "feature-branch" {
+		t.Errorf("Expected to be back on feature-branch, got %s", current)
+	}
+
+	for _, result := range results {
+		if result.Branch != "" {
+			runGitInDir(repo.Dir, "branch", "-D", result.Branch)
+		}
+	}
+	results, err = processCommits([]string{first, last}, rangeModeCombined)
+	if err != nil {
+		t.Fatalf("processCommits --combine failed: %v", err)
+	}
+	wantBranch := getBranchPrefix() + "/" + first[:7] + "-" + last[:7]
+	for _, result := range results {
+		if result.Status != statusExtracted || result.Branch != wantBranch {
+			t.Errorf("Expected extraction to %s, got %+v", wantBranch, result)
+		}
+	}
+	count, _ := runGitInDir(repo.Dir, "rev-list", "--count", "main.."+wantBranch)
+	if count != "2" {
+		t.Errorf("Expected 2 extraction commits on %s, got %s", wantBranch, count)
+	}
+	files, _ := runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", wantBranch)
+	if !strings.Contains(files, "prompts/one.md") || !strings.Contains(files, "prompts/two.md") || strings.Contains(files, "util.go") {
+		t.Errorf("Unexpected files on %s: %s", wantBranch, files)
+	}
+	current, _ = runGitInDir(repo.Dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if current != "feature-branch" {
+		t.Errorf("Expected to be back on feature-branch, got %s", current)
+	}
+}
+
+func Test_BatchSquash(t *testing.T) {
+	repo := setupTestRepo(t)
+	start, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	var commits []string
+	for i, content := range []string{"# Review v1", "# Review v2", "# Review v3"} {
+		files := map[string]string{"prompts/review.md": content}
+		if i == 1 {
+			files["prompts/other.md"] = "# Other"
+		}
+		commits = append(commits, commitFiles(t, repo.Dir, fmt.Sprintf("Review prompt, take %d", i+1), files))
+	}
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	branch := getBranchPrefix() + "/" + commits[0][:7] + "-" + commits[2][:7]
+	if _, err := processCommits([]string{start + "..HEAD"}, rangeModeCombined); err != nil {
+		t.Fatalf("processCommits failed: %v", err)
+	}
+	// A commit per source commit, each with the file as it committed it
+	log, _ := runGit("log", "--reverse", "--format=%(trailers:key="+trailerSourceCommit+",valueonly,separator=)", "main.."+branch)
+	if log != strings.Join(commits, "\n") {
+		t.Fatalf("Expected the commits oldest first, got:\n%s", log)
+	}
+	for i, want := range []string{"# Review v1", "# Review v2", "# Review v3"} {
+		if got, _ := runGit("show", fmt.Sprintf("%s~%d:prompts/review.md", branch, 2-i)); got != want {
+			t.Errorf("Expected commit %d to hold %q, got %q", i+1, want, got)
+		}
+	}
+
+	runGit("branch", "-D", branch)
+	path, _ := processedPath()
+	os.Remove(path)
+	runGitInDir(repo.Dir, "config", "prrompt.batchSquash", "net")
+	if _, err := processCommits([]string{start + "..HEAD"}, rangeModeCombined); err != nil {
+		t.Fatalf("processCommits failed: %v", err)
+	}
+	if count, _ := runGit("rev-list", "--count", "main.."+branch); count != "1" {
+		t.Fatalf("Expected one net commit, got %s", count)
+	}
+	if got, _ := runGit("show", branch+":prompts/review.md"); got != "# Review v3" {
+		t.Errorf("Expected the final content, got %q", got)
+	}
+	if files, _ := runGit("diff", "--name-only", "main", branch); files != "prompts/other.md\nprompts/review.md" {
+		t.Errorf("Expected both files in the net change, got %q", files)
+	}
+	message, _ := runGit("log", "-1", "--format=%B", branch)
+	for _, sha := range commits {
+		if !strings.Contains(message, trailerSourceCommit+": "+sha) || !strings.Contains(message, "- "+sha[:7]+" Review prompt") {
+			t.Errorf("Expected %s listed with its provenance, got:\n%s", sha[:7], message)
+		}
+	}
+	if !branchCarries("refs/heads/"+branch, commits[1]) {
+		t.Error("Expected every source commit to count as extracted")
+	}
+	if status, _ := runGit("status", "--porcelain", "--untracked-files=no"); status != "" {
+		t.Errorf("Expected a clean work tree, got:\n%s", status)
+	}
+}
+
+func Test_ProcessCommitsBySkill(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.promptPatterns", "prompts/,.claude/skills/")
+	start, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	first := commitFiles(t, repo.Dir, "Add greeter and reviewer", map[string]string{
+		".claude/skills/greeter/SKILL.md":  "# Greeter",
+		".claude/skills/reviewer/SKILL.md": "# Reviewer",
+		"main.go":                          "package main",
+	})
+	second := commitFiles(t, repo.Dir, "Tune greeter", map[string]string{
+		".claude/skills/greeter/SKILL.md":    "# Greeter v2",
+		".claude/skills/greeter/examples.md": "hello",
+	})
+	third := commitFiles(t, repo.Dir, "Add a prompt", map[string]string{"prompts/one.md": "# One"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	results, err := processCommits([]string{start + "..HEAD"}, rangeModeSkill)
+	if err != nil {
+		t.Fatalf("processCommits by skill failed: %v", err)
+	}
+	greeter := getBranchPrefix() + "/greeter-" + first[:7] + "-" + second[:7]
+	reviewer := getBranchPrefix() + "/reviewer-" + first[:7]
+	one := getBranchPrefix() + "/one-" + third[:7]
+	if len(results) != 3 || strings.Join(results[0].Branches, " ") != greeter+" "+reviewer || results[1].Branch != greeter || results[2].Branch != one {
+		t.Fatalf("Unexpected results: %+v", results)
+	}
+	for branch, want := range map[string]string{
+		greeter:  ".claude/skills/greeter/SKILL.md\n.claude/skills/greeter/examples.md",
+		reviewer: ".claude/skills/reviewer/SKILL.md",
+		one:      "prompts/one.md",
+	} {
+		files, _ := runGit("ls-tree", "-r", "--name-only", branch)
+		if files != want {
+			t.Errorf("Expected %s on %s, got %s", want, branch, files)
+		}
+	}
+	if count, _ := runGit("rev-list", "--count", "main.."+greeter); count != "2" {
+		t.Errorf("Expected 2 extraction commits on %s, got %s", greeter, count)
+	}
+	if current, _ := runGit("rev-parse", "--abbrev-ref", "HEAD"); current != repo.BranchName {
+		t.Errorf("Expected to be back on %s, got %s", repo.BranchName, current)
+	}
+
+	groups := groupFiles([]*CommitInfo{
+		{SHA: first, Message: "Add greeter and reviewer", PromptFiles: []string{".claude/skills/greeter/SKILL.md", ".claude/skills/reviewer/SKILL.md"}},
+		{SHA: second, Message: "Tune greeter", PromptFiles: []string{".claude/skills/greeter/SKILL.md"}},
+	}, skillPath)
+	if len(groups) != 2 || groups[0].path != ".claude/skills/greeter" || len(groups[0].infos) != 2 {
+		t.Fatalf("Unexpected groups: %+v", groups)
+	}
+	title, body := prTitleAndBody(groups[0].infos)
+	if !strings.Contains(title, ".claude/skills/greeter: changes from 2 commits") {
+		t.Errorf("Unexpected title: %s", title)
+	}
+	if !strings.Contains(body, first[:7]+" Add greeter and reviewer") || !strings.Contains(body, second[:7]+" Tune greeter") {
+		t.Errorf("Expected the contributing commits in the body, got %s", body)
+	}
+}
+
+func Test_SkillPath(t *testing.T) {
+	for file, want := range map[string]string{
+		".claude/skills/greeter/SKILL.md":     ".claude/skills/greeter",
+		".claude/skills/greeter/docs/ref.md":  ".claude/skills/greeter",
+		"pkg/.claude/skills/greeter/SKILL.md": "pkg/.claude/skills/greeter",
+		".claude/skills/quick.md":             ".claude/skills/quick.md",
+		"agents/triage/SKILL.md":              "agents/triage",
+		"prompts/one.md":                      "prompts/one.md",
+	} {
+		if got := skillPath(file); got != want {
+			t.Errorf("skillPath(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
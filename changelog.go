@@ -0,0 +1,444 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// prrompt.changelog modes: what `prrompt ci` does on the base branch once
+// extraction PRs are merged, or whether extraction adds the entries itself.
+const (
+	changelogOff     = "off"
+	changelogCommit  = "commit"  // commit the entries to the base branch
+	changelogPR      = "pr"      // open a PR with them
+	changelogExtract = "extract" // commit them on the prompt branch
+)
+
+// changelogFile is the per-skill changelog, next to SKILL.md, or next to a
+// prompt file that is not part of a skill.
+const changelogFile = "CHANGELOG.md"
+
+func getChangelogMode() string {
+	value, _ := gitConfig("--get", "prrompt.changelog")
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case changelogCommit, changelogPR, changelogExtract:
+		return mode
+	}
+	return changelogOff
+}
+
+// getChangelogDir returns prrompt.changelogDir, the directory of one
+// changelog for all prompts, or "" for one per skill.
+func getChangelogDir() string {
+	value, _ := gitConfig("--get", "prrompt.changelogDir")
+	return strings.Trim(strings.TrimSpace(value), "/")
+}
+
+// changelogEntry is one merged extraction of one skill.
+type changelogEntry struct {
+	Dir     string // where the changelog goes
+	Date    string
+	Version string // from the frontmatter, if any
+	Summary string
+	PR      string // the PR number, if known
+	Source  string // the source commit
+	Author  string // its author, for entries added on extraction
+}
+
+// String formats the entry; the comment lets a rerun tell it is there.
+func (e changelogEntry) String() string {
+	heading := "## " + e.Date
+	if e.Version != "" {
+		heading += " (version " + e.Version + ")"
+	}
+	line := "- " + e.Summary
+	if e.Author != "" {
+		line += fmt.Sprintf(" (%s by %s)", commitLink(e.Source), e.Author)
+	}
+	if e.PR != "" {
+		if repoPath := getGitHubRepoPath(); repoPath != "" {
+			line += fmt.Sprintf(" ([#%s](%s/%s/pull/%s))", e.PR, forgeWebURL(), repoPath, e.PR)
+		} else {
+			line += " (#" + e.PR + ")"
+		}
+	}
+	return heading + "\n\n" + line + " " + e.marker() + "\n"
+}
+
+func (e changelogEntry) marker() string {
+	return "<!-- prrompt: " + shortSHA(e.Source) + " -->"
+}
+
+var (
+	// mergeSubject matches a GitHub merge commit, squashSubject the
+	// "(#12)" a squash merge appends to the PR title.
+	mergeSubject  = regexp.MustCompile(`^Merge pull request #(\d+) `)
+	squashSubject = regexp.MustCompile(` \(#(\d+)\)$`)
+	// bracketPrefix matches the "[prompt] " marking extraction commits.
+	bracketPrefix = regexp.MustCompile(`^\[[^\]]+\] `)
+)
+
+// changelogRange returns the revisions whose merged extractions rev stands
+// for: a range as given, a single commit with what it merged.
+func changelogRange(rev string) []string {
+	if strings.Contains(rev, "..") {
+		return []string{rev}
+	}
+	if _, err := runGit("rev-parse", "--verify", "-q", rev+"^"); err != nil {
+		return []string{rev}
+	}
+	return []string{rev + "^.." + rev}
+}
+
+// mergedExtractions returns the changelog entries of the extraction
+// commits in revs, found by their Prrompt-Source-Commit trailer, oldest
+// first.
+func mergedExtractions(revs []string) ([]changelogEntry, error) {
+	var entries []changelogEntry
+	for _, rev := range revs {
+		args := []string{"log", "--reverse", "--format=%H%x1f%P%x1f%cs%x1f%s%x1f%(trailers:key=" + trailerSourceCommit + ",valueonly,separator=%x20)%x1e"}
+		log, err := runGit(append(args, changelogRange(rev)...)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the history of %s: %s", rev, log)
+		}
+		// The PRs that merged commits, by merge commit
+		prs := make(map[string]string)
+		dates := make(map[string]string)
+		var commits [][]string
+		for _, record := range strings.Split(log, "\x1e") {
+			fields := strings.Split(strings.TrimSpace(record), "\x1f")
+			if len(fields) != 5 {
+				continue
+			}
+			parents := strings.Fields(fields[1])
+			if match := mergeSubject.FindStringSubmatch(fields[3]); match != nil && len(parents) == 2 {
+				merged, _ := runGit("rev-list", parents[0]+".."+parents[1])
+				for _, sha := range strings.Fields(merged) {
+					prs[sha], dates[sha] = match[1], fields[2]
+				}
+				continue
+			}
+			if strings.TrimSpace(fields[4]) != "" {
+				commits = append(commits, fields)
+			}
+		}
+		for _, fields := range commits {
+			sha, date, subject := fields[0], fields[2], fields[3]
+			pr := prs[sha]
+			if match := squashSubject.FindStringSubmatch(subject); match != nil {
+				pr = match[1]
+				subject = strings.TrimSuffix(subject, match[0])
+			} else if dates[sha] != "" {
+				date = dates[sha]
+			}
+			summary := changelogSummary(subject)
+			files, _ := runGit("diff-tree", "--no-commit-id", "-r", "--name-only", "--root", sha)
+			entry := changelogEntry{Date: date, Summary: summary, PR: pr, Source: strings.Fields(fields[4])[0]}
+			entries = append(entries, placeEntry(entry, sha, changelogDirs(strings.Split(files, "\n")))...)
+		}
+	}
+	return entries, nil
+}
+
+// changelogSummary returns an extraction subject without the prefix or
+// conventional prompt header marking it.
+func changelogSummary(subject string) string {
+	summary := bracketPrefix.ReplaceAllString(subject, "")
+	if match := conventionalHeader.FindString(summary); match != "" && hasPromptHeader(summary) {
+		summary = strings.TrimPrefix(summary, match)
+	}
+	return summary
+}
+
+// placeEntry returns entry in the changelog of each of dirs, with the
+// version of the skill there at commit, or with prrompt.changelogDir once
+// in the central changelog, naming them.
+func placeEntry(entry changelogEntry, commit string, dirs []string) []changelogEntry {
+	if len(dirs) == 0 {
+		return nil
+	}
+	if central := getChangelogDir(); central != "" {
+		if len(dirs) == 1 {
+			entry.Version = skillVersion(commit, dirs[0])
+		}
+		entry.Dir = central
+		entry.Summary = "`" + strings.Join(dirs, "`, `") + "`: " + entry.Summary
+		return []changelogEntry{entry}
+	}
+	var entries []changelogEntry
+	for _, dir := range dirs {
+		entry.Dir, entry.Version = dir, skillVersion(commit, dir)
+		entries = append(entries, entry)
+	}
+	return entries
+}
+
+// changelogDirs returns the directories of the skills and prompts among
+// files, leaving out archived copies and changelogs.
+func changelogDirs(files []string) []string {
+	archive := getArchiveDir() + "/"
+	seen := make(map[string]bool)
+	var dirs []string
+	for _, file := range files {
+		if file == "" || path.Base(file) == changelogFile || strings.HasPrefix(file, archive) || !isPromptFile(file) {
+			continue
+		}
+		dir := skillPath(file)
+		if dir == file {
+			dir = path.Dir(file)
+		}
+		if !seen[dir] {
+			seen[dir] = true
+			dirs = append(dirs, dir)
+		}
+	}
+	sort.Strings(dirs)
+	return dirs
+}
+
+// skillVersion returns the frontmatter version of the skill in dir at
+// commit, or "".
+func skillVersion(commit, dir string) string {
+	content, err := runGit("show", commit+":"+path.Join(dir, "SKILL.md"))
+	if err != nil {
+		return ""
+	}
+	fields, _ := parseFrontmatter(content)
+	return strings.Trim(fields["version"], `"'`)
+}
+
+// commitChangelogs commits entries on top of parent, newest first in each
+// changelog, and returns the skills it changed. The commit is "" when every
+// entry is already there.
+func commitChangelogs(parent string, entries []changelogEntry) (commit string, dirs []string, err error) {
+	tree, changed, err := changelogTree(parent, entries)
+	if err != nil || tree == "" {
+		return "", nil, err
+	}
+	// The skip marker keeps the hook from extracting the changelogs again
+	msg := changelogSubject(changed) + "\n\n" + strings.Join(changed, "\n") + "\n\n" + defaultSkipMarkers[0]
+	args := append([]string{"commit-tree", tree, "-p", parent, "-m", msg}, commitSigningArgs()...)
+	commit, err = runGitWithEnv(committerEnv(), args...)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to commit: %s", truncate(commit, 200))
+	}
+	return commit, changed, nil
+}
+
+// changelogSubject is the subject of the commit updating the changelogs of
+// dirs.
+func changelogSubject(dirs []string) string {
+	return prefixSubject(fmt.Sprintf("Update the changelog of %s", countOf(len(dirs), "skill")))
+}
+
+// changelogTree writes the tree of parent, a commit or tree, with entries
+// added to the changelogs, and returns it with the skills it changed. The
+// tree is "" when every entry is already there.
+func changelogTree(parent string, entries []changelogEntry) (tree string, dirs []string, err error) {
+	tmpDir, err := makeTempDir("prrompt-changelog-")
+	if err != nil {
+		return "", nil, err
+	}
+	defer removeTemp(tmpDir)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tmpDir, "index")}
+	if output, err := runGitWithEnv(env, "read-tree", parent); err != nil {
+		return "", nil, fmt.Errorf("failed to read tree: %s", output)
+	}
+
+	byDir := make(map[string][]changelogEntry)
+	for _, entry := range entries {
+		byDir[entry.Dir] = append(byDir[entry.Dir], entry)
+	}
+	for dir := range byDir {
+		dirs = append(dirs, dir)
+	}
+	sort.Strings(dirs)
+	var changed []string
+	for _, dir := range dirs {
+		file := path.Join(dir, changelogFile)
+		existing := showFile(parent, file)
+		var added strings.Builder
+		for i := len(byDir[dir]) - 1; i >= 0; i-- {
+			if entry := byDir[dir][i]; !strings.Contains(existing, entry.marker()) {
+				added.WriteString(entry.String() + "\n")
+			}
+		}
+		if added.Len() == 0 {
+			continue
+		}
+		header, rest := "# Changelog\n\n", strings.TrimPrefix(existing, "# Changelog")
+		content := header + added.String() + strings.TrimLeft(rest, "\n")
+		blob, err := runGitBytes(nil, []byte(strings.TrimRight(content, "\n")+"\n"), "hash-object", "-w", "--stdin")
+		if err != nil {
+			return "", nil, fmt.Errorf("failed to write %s: %w", file, err)
+		}
+		if output, err := runGitWithEnv(env, "update-index", "--add", "--cacheinfo", "100644,"+strings.TrimSpace(string(blob))+","+file); err != nil {
+			return "", nil, fmt.Errorf("failed to update %s: %s", file, output)
+		}
+		changed = append(changed, dir)
+	}
+	if len(changed) == 0 {
+		return "", nil, nil
+	}
+
+	tree, err = runGitWithEnv(env, "write-tree")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to write tree: %s", tree)
+	}
+	return tree, changed, nil
+}
+
+// updateChangelogs appends the extractions merged in revs to the skills'
+// changelogs, committed to the base branch or proposed in a PR.
+func updateChangelogs(revs []string, mode string, dryRun bool) error {
+	entries, err := mergedExtractions(revs)
+	if err != nil {
+		return err
+	}
+	if len(entries) == 0 {
+		infof("No merged extractions to add to changelogs")
+		return nil
+	}
+	if dryRun {
+		for _, entry := range entries {
+			fmt.Printf("%s:\n%s\n", path.Join(entry.Dir, changelogFile), redact(entry.String()))
+		}
+		return nil
+	}
+
+	base, remote := getBaseBranch(), getRemote()
+	parent, err := runGit("rev-parse", "--verify", "-q", startPointOf(base)+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("failed to resolve %s", base)
+	}
+	commit, dirs, err := commitChangelogs(parent, entries)
+	if err != nil {
+		return err
+	}
+	if commit == "" {
+		infof("The changelogs are up to date")
+		return nil
+	}
+	pushing := hasRemote(remote) && getBoolConfig("prrompt.push", true)
+
+	if mode == changelogPR {
+		branch := getBranchPrefix() + "/changelog-" + shortSHA(commit)
+		if output, err := runGit("update-ref", "refs/heads/"+branch, commit); err != nil {
+			return fmt.Errorf("failed to create %s: %s", branch, output)
+		}
+		if !pushing {
+			infof("Changelogs of %s committed to %s", countOf(len(dirs), "skill"), branch)
+			return nil
+		}
+		if err := timedPush(remote, branch); err != nil {
+			return fmt.Errorf("failed to push %s: %w", branch, err)
+		}
+		subject, _ := runGit("log", "-1", "--format=%s", commit)
+		body := "Adds the merged prompt changes to the changelogs of:\n\n- `" + strings.Join(dirs, "`\n- `") + "`"
+		prURL := createPR(base, branch, redact(subject), redact(body), prMetadata{Labels: getListConfig("prrompt.prLabels")})
+		if prURL == "" {
+			prURL = generatePRURL(base, branch)
+		}
+		infof("Proposed the changelogs of %s: %s", countOf(len(dirs), "skill"), prURL)
+		return nil
+	}
+
+	if pushing {
+		if err := timedPush(remote, commit+":refs/heads/"+base); err != nil {
+			return fmt.Errorf("failed to push the changelogs to %s: %w", base, err)
+		}
+	}
+	// Bring the local base branch along when it is where the commit starts
+	if current, _ := runGit("rev-parse", "--abbrev-ref", "HEAD"); current == base {
+		if output, err := runGit("merge", "--ff-only", "-q", commit); err != nil {
+			warnf("failed to fast-forward %s: %s", base, output)
+		}
+	} else if _, err := runGit("update-ref", "refs/heads/"+base, commit, parent); err != nil && !pushing {
+		return fmt.Errorf("%s moved, changelogs are in %s", base, shortSHA(commit))
+	}
+	infof("Updated the changelogs of %s on %s", countOf(len(dirs), "skill"), base)
+	return nil
+}
+
+// addExtractionChangelogs commits the changelog entries of infos on top of
+// promptBranch with prrompt.changelog=extract, dated and authored as the
+// source commits, so the PR shows how each prompt evolves. With the branch
+// checked out, the changelogs are checked out along.
+func addExtractionChangelogs(promptBranch string, infos []*CommitInfo, checkedOut bool) error {
+	tip, err := runGit("rev-parse", "--verify", "-q", "refs/heads/"+promptBranch)
+	if err != nil {
+		return fmt.Errorf("no branch %s", promptBranch)
+	}
+	entries, err := extractionChangelogEntries(tip, infos)
+	if err != nil {
+		return err
+	}
+	commit, dirs, err := commitChangelogs(tip, entries)
+	if err != nil || commit == "" {
+		return err
+	}
+	if output, err := runGitRetry("update-ref", "-m", "prrompt: changelog", "refs/heads/"+promptBranch, commit, tip); err != nil {
+		return fmt.Errorf("failed to update %s: %s", promptBranch, output)
+	}
+	if checkedOut {
+		var files []string
+		for _, dir := range dirs {
+			files = append(files, path.Join(dir, changelogFile))
+		}
+		if output, err := runGit(append([]string{"checkout", commit, "--"}, files...)...); err != nil {
+			return fmt.Errorf("failed to check out the changelogs: %s", output)
+		}
+	}
+	verbosef("Added %d changelog entries on %s", len(entries), promptBranch)
+	return nil
+}
+
+// extractionChangelogEntries returns the changelog entries of infos
+// extracted to tip, a commit or tree.
+func extractionChangelogEntries(tip string, infos []*CommitInfo) ([]changelogEntry, error) {
+	var entries []changelogEntry
+	for _, info := range infos {
+		meta, err := runGit("show", "-s", "--format=%as%x1f%an", info.SHA)
+		date, author, found := strings.Cut(meta, "\x1f")
+		if err != nil || !found {
+			return nil, fmt.Errorf("failed to read commit %s", shortSHA(info.SHA))
+		}
+		subject, _, _ := strings.Cut(extractionMessage(info), "\n")
+		entry := changelogEntry{Date: date, Summary: changelogSummary(subject), Source: info.SHA, Author: author}
+		entries = append(entries, placeEntry(entry, tip, changelogDirs(info.PromptFiles))...)
+	}
+	return entries, nil
+}
+
+// runChangelog implements `prrompt changelog [--pr] [--dry-run] [<commit or
+// range>...]`. A commit stands for what it merged; without one, the CI push
+// or HEAD is used.
+func runChangelog(args []string) error {
+	mode, dryRun := getChangelogMode(), false
+	var revs []string
+	for _, arg := range args {
+		switch {
+		case arg == "--pr":
+			mode = changelogPR
+		case arg == "--dry-run":
+			dryRun = true
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("usage: %s changelog [--pr] [--dry-run] [<commit or range>...]", toolName)
+		default:
+			revs = append(revs, arg)
+		}
+	}
+	if mode == changelogOff || mode == changelogExtract {
+		mode = changelogCommit
+	}
+	if len(revs) == 0 {
+		if revs = ciCommits(); len(revs) == 0 {
+			revs = []string{"HEAD"}
+		}
+	}
+	return updateChangelogs(revs, mode, dryRun)
+}
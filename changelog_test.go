@@ -0,0 +1,107 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_Changelog(t *testing.T) {
+	repo, _ := setupPushableRepo(t)
+	skill := "---\nname: review\ndescription: Reviews code\nversion: 1.2.0\n---\n# Review\n"
+	commitSHA := commitFiles(t, repo.Dir, "Tighten the review skill", map[string]string{".claude/skills/review/SKILL.md": skill})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", result, err)
+	}
+	// The PR is merged on GitHub
+	runGit("push", "-q", "origin", "main")
+	runGit("checkout", "-q", "main")
+	runGit("merge", "-q", "--no-ff", "-m", "Merge pull request #12 from acme/"+result.Branch, result.Branch)
+	runGit("push", "-q", "origin", "main")
+
+	if err := runChangelog([]string{"HEAD"}); err != nil {
+		t.Fatalf("changelog failed: %v", err)
+	}
+	changelog, err := runGit("show", "origin/main:.claude/skills/review/CHANGELOG.md")
+	if err != nil {
+		t.Fatalf("Expected the changelog pushed to main: %s", changelog)
+	}
+	heading := "## " + time.Now().Format("2006-01-02") + " (version 1.2.0)"
+	if !strings.HasPrefix(changelog, "# Changelog\n\n"+heading) || !strings.Contains(changelog, "- Tighten the review skill ([#12](https://github.com/acme/widgets/pull/12))") {
+		t.Errorf("Unexpected changelog:\n%s", changelog)
+	}
+	if local, _ := runGit("rev-parse", "main"); local != mustRevParse(t, "origin/main") {
+		t.Error("Expected the local main fast-forwarded to the changelog commit")
+	}
+	if message, _ := runGit("log", "-1", "--format=%B", "main"); !strings.Contains(message, "[skip prrompt]") {
+		t.Errorf("Expected the changelog commit to skip extraction, got %q", message)
+	}
+
+	// Rerunning adds nothing
+	before := mustRevParse(t, "main")
+	if err := runChangelog([]string{"HEAD~1"}); err != nil {
+		t.Fatalf("changelog failed: %v", err)
+	}
+	if after := mustRevParse(t, "main"); after != before {
+		t.Error("Expected no second changelog commit")
+	}
+}
+
+func mustRevParse(t *testing.T, rev string) string {
+	t.Helper()
+	sha, err := runGit("rev-parse", rev)
+	if err != nil {
+		t.Fatalf("failed to resolve %s: %s", rev, sha)
+	}
+	return sha
+}
+
+func Test_ExtractionChangelog(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.changelog", "extract")
+	skill := "---\nname: review\ndescription: Reviews code\nversion: 1.2.0\n---\n# Review\n"
+	commitSHA := commitFiles(t, repo.Dir, "Tighten the review skill", map[string]string{".claude/skills/review/SKILL.md": skill})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", result, err)
+	}
+	changelog, err := runGit("show", result.Branch+":.claude/skills/review/CHANGELOG.md")
+	if err != nil {
+		t.Fatalf("Expected the changelog on the prompt branch: %s", changelog)
+	}
+	heading := "## " + time.Now().Format("2006-01-02") + " (version 1.2.0)"
+	entry := "- Tighten the review skill (`" + commitSHA[:7] + "` by Test User) <!-- prrompt: " + commitSHA[:7] + " -->"
+	if changelog != "# Changelog\n\n"+heading+"\n\n"+entry {
+		t.Errorf("Unexpected changelog:\n%s", changelog)
+	}
+	if source, _ := runGit("log", "-1", "--format=%(trailers:key="+trailerSourceCommit+",valueonly)", result.Branch+"~1"); source != commitSHA {
+		t.Errorf("Expected the changelog on top of the extraction commit, got %q", source)
+	}
+	if status, _ := runGit("status", "--porcelain", "--untracked-files=no"); status != "" {
+		t.Errorf("Expected a clean work tree, got:\n%s", status)
+	}
+
+	// One changelog for every prompt
+	runGit("config", "prrompt.changelogDir", "prompts")
+	commitSHA = commitFiles(t, repo.Dir, "Add a greeting", map[string]string{"prompts/greet.md": "Say hi"})
+	result, err = processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", result, err)
+	}
+	changelog, _ = runGit("show", result.Branch+":prompts/CHANGELOG.md")
+	if !strings.Contains(changelog, "- `prompts`: Add a greeting (`"+commitSHA[:7]+"` by Test User)") {
+		t.Errorf("Unexpected central changelog:\n%s", changelog)
+	}
+}
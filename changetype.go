@@ -0,0 +1,79 @@
+package main
+
+import "strings"
+
+// Change types of an extraction, from the statuses of its prompt files.
+const (
+	changeNewSkill = "New Skill"
+	changeUpdate   = "Update"
+	changeRemoval  = "Removal"
+	changeRename   = "Rename"
+)
+
+// classifyChange returns the change type of the prompt files extracted from
+// infos, oldest commit first: only additions are a New Skill, only deletions
+// a Removal and only renames a Rename. Anything else is an Update.
+func classifyChange(infos []*CommitInfo) string {
+	net := make(map[string]string)
+	for _, info := range infos {
+		renameSources := make(map[string]bool, len(info.Renames))
+		for _, oldPath := range info.Renames {
+			renameSources[oldPath] = true
+		}
+		for _, file := range info.PromptFiles {
+			status := info.FileStatus[file]
+			if renameSources[file] {
+				continue
+			}
+			switch previous, seen := net[file]; {
+			case !seen:
+				net[file] = status
+			case previous == "A" && status == "D":
+				// Added and removed again within the extraction
+				delete(net, file)
+			case previous == "A":
+				// Still new, however often it changed since
+			case status == "D":
+				net[file] = "D"
+			default:
+				net[file] = "M"
+			}
+		}
+	}
+
+	kinds := make(map[string]bool)
+	for _, status := range net {
+		switch status {
+		case "A":
+			kinds[changeNewSkill] = true
+		case "D":
+			kinds[changeRemoval] = true
+		case "R":
+			kinds[changeRename] = true
+		default:
+			kinds[changeUpdate] = true
+		}
+	}
+	if len(kinds) != 1 {
+		return changeUpdate
+	}
+	for kind := range kinds {
+		return kind
+	}
+	return changeUpdate
+}
+
+// changeLabel is the PR label of a change type, e.g. "change:new-skill".
+func changeLabel(kind string) string {
+	return "change:" + strings.ReplaceAll(strings.ToLower(kind), " ", "-")
+}
+
+// changeTitle prefixes a PR title with the change type, after the commit
+// prefix when the title starts with it: "[prompt] New Skill: Add greeter".
+func changeTitle(title, kind string) string {
+	prefix := prefixSubject("")
+	if prefix != "" && strings.HasPrefix(title, prefix) {
+		return prefix + kind + ": " + strings.TrimPrefix(title, prefix)
+	}
+	return kind + ": " + title
+}
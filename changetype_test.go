@@ -0,0 +1,49 @@
+package main
+
+import "testing"
+
+func Test_ClassifyChange(t *testing.T) {
+	commit := func(status map[string]string, renames map[string]string) *CommitInfo {
+		info := &CommitInfo{FileStatus: status, Renames: renames}
+		for file := range status {
+			info.PromptFiles = append(info.PromptFiles, file)
+		}
+		return info
+	}
+	tests := []struct {
+		name  string
+		infos []*CommitInfo
+		want  string
+	}{
+		{"added", []*CommitInfo{commit(map[string]string{"a.md": "A", "b.md": "A"}, nil)}, changeNewSkill},
+		{"modified", []*CommitInfo{commit(map[string]string{"a.md": "M"}, nil)}, changeUpdate},
+		{"deleted", []*CommitInfo{commit(map[string]string{"a.md": "D"}, nil)}, changeRemoval},
+		{"renamed", []*CommitInfo{commit(map[string]string{"a.md": "D", "b.md": "R"}, map[string]string{"b.md": "a.md"})}, changeRename},
+		{"mixed", []*CommitInfo{commit(map[string]string{"a.md": "A", "b.md": "D"}, nil)}, changeUpdate},
+		{"added then edited", []*CommitInfo{
+			commit(map[string]string{"a.md": "A"}, nil),
+			commit(map[string]string{"a.md": "M"}, nil),
+		}, changeNewSkill},
+		{"edited then removed", []*CommitInfo{
+			commit(map[string]string{"a.md": "M"}, nil),
+			commit(map[string]string{"a.md": "D"}, nil),
+		}, changeRemoval},
+	}
+	for _, tt := range tests {
+		if got := classifyChange(tt.infos); got != tt.want {
+			t.Errorf("%s: expected %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func Test_ChangeTitleAndLabel(t *testing.T) {
+	if got := changeTitle("[prompt] Add greeter", changeNewSkill); got != "[prompt] New Skill: Add greeter" {
+		t.Errorf("Unexpected title %q", got)
+	}
+	if got := changeTitle("chore(prompts): drop greeter", changeRemoval); got != "Removal: chore(prompts): drop greeter" {
+		t.Errorf("Unexpected title %q", got)
+	}
+	if got := changeLabel(changeNewSkill); got != "change:new-skill" {
+		t.Errorf("Unexpected label %q", got)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Identity extraction commits are made with when the runner has none.
+const (
+	ciBotName  = "github-actions[bot]"
+	ciBotEmail = "41898282+github-actions[bot]@users.noreply.github.com"
+)
+
+// pushEvent is the part of a GitHub push event payload prrompt reads.
+type pushEvent struct {
+	Before string `json:"before"`
+	After  string `json:"after"`
+}
+
+// ciCommits returns the commits to process in a GitHub Actions run: the
+// pushed range of a push event, else GITHUB_SHA.
+func ciCommits() []string {
+	if os.Getenv("GITHUB_EVENT_NAME") == "push" {
+		if data, err := os.ReadFile(os.Getenv("GITHUB_EVENT_PATH")); err == nil {
+			var event pushEvent
+			if json.Unmarshal(data, &event) == nil && event.After != "" {
+				if event.Before == "" || strings.Trim(event.Before, "0") == "" {
+					// A new branch: only the pushed commit is known to be new
+					return []string{event.After}
+				}
+				if _, err := runGit("cat-file", "-e", event.Before+"^{commit}"); err == nil {
+					return []string{event.Before + ".." + event.After}
+				}
+				warnf("commit %s before the push is not available (shallow clone?), processing %s only; use fetch-depth: 0", shortSHA(event.Before), shortSHA(event.After))
+				return []string{event.After}
+			}
+		}
+	}
+	if sha := os.Getenv("GITHUB_SHA"); sha != "" {
+		return []string{sha}
+	}
+	return nil
+}
+
+// setupUnattended makes git and prrompt run without a terminal: git never
+// prompts, commits fall back to the bot identity when none is configured,
+// and PRs are opened through the API when a token is available.
+func setupUnattended() {
+	os.Setenv("GIT_TERMINAL_PROMPT", "0")
+	if _, err := gitConfig("--get", "user.email"); err != nil {
+		for _, pair := range [][2]string{
+			{"GIT_AUTHOR_NAME", ciBotName}, {"GIT_AUTHOR_EMAIL", ciBotEmail},
+			{"GIT_COMMITTER_NAME", ciBotName}, {"GIT_COMMITTER_EMAIL", ciBotEmail},
+		} {
+			if os.Getenv(pair[0]) == "" {
+				os.Setenv(pair[0], pair[1])
+			}
+		}
+	}
+	if _, err := gitConfig("--get", "prrompt.prTool"); err != nil && (os.Getenv("GITHUB_TOKEN") != "" || os.Getenv("GH_TOKEN") != "") {
+		os.Setenv("PRROMPT_PR_TOOL", prToolAPI)
+	}
+}
+
+// runCI implements `prrompt ci [<commit|range>...]` for CI pipelines. It
+// never prompts, takes what it processes from the GitHub Actions
+// environment unless given commits, and reports the outcome in its exit
+// code.
+func runCI(args []string) int {
+	commits := args
+	if len(commits) == 0 {
+		commits = ciCommits()
+	}
+	if len(commits) == 0 {
+		fmt.Println("No commits to process: pass a commit or range, or run in GitHub Actions (GITHUB_SHA)")
+		return exitConfig
+	}
+
+	setupUnattended()
+	// Actions checks out a detached HEAD; extraction returns to a branch
+	if branch, _ := runGit("rev-parse", "--abbrev-ref", "HEAD"); branch == "HEAD" {
+		if name, ok := strings.CutPrefix(os.Getenv("GITHUB_REF"), "refs/heads/"); ok {
+			if output, err := runGit("checkout", "-q", "-B", name); err != nil {
+				fmt.Printf("failed to check out %s: %s\n", name, output)
+				return exitFailed
+			}
+		}
+	}
+
+	results, err := processCommits(commits, getRangeMode())
+	if err != nil {
+		fmt.Printf("%v\n", err)
+	}
+	if code := runExitCode(results, err); code != exitOK {
+		return code
+	}
+	extracted := 0
+	for _, result := range results {
+		if result.Status != statusExtracted {
+			continue
+		}
+		extracted++
+		if !result.Pushed && getBoolConfig("prrompt.push", true) {
+			fmt.Printf("%s was not pushed\n", result.Branch)
+			return exitPushFailed
+		}
+	}
+	infof("Processed %d commits, extracted %d", len(results), extracted)
+	// A push to the base branch is where extraction PRs get merged
+	mode := getChangelogMode()
+	if branch, _ := currentBranch(); (mode == changelogCommit || mode == changelogPR) && branch == getBaseBranch() {
+		if err := updateChangelogs(commits, mode, false); err != nil {
+			warnf("failed to update the changelogs: %v", err)
+		}
+	}
+	return exitOK
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_RunCI(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	before, _ := runGitInDir(repo.Dir, "rev-parse", commitSHA+"^")
+	runGitInDir(repo.Dir, "checkout", "-q", "--detach")
+
+	eventFile := filepath.Join(t.TempDir(), "event.json")
+	os.WriteFile(eventFile, []byte(`{"before": "`+before+`", "after": "`+commitSHA+`"}`), 0644)
+	t.Setenv("GITHUB_EVENT_NAME", "push")
+	t.Setenv("GITHUB_EVENT_PATH", eventFile)
+	t.Setenv("GITHUB_SHA", commitSHA)
+	t.Setenv("GITHUB_REF", "refs/heads/"+repo.BranchName)
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if code := runCI(nil); code != exitOK {
+		t.Fatalf("Expected exit code %d, got %d", exitOK, code)
+	}
+	branch := defaultBranchPrefix + "/" + commitSHA[:7]
+	if _, err := runGitInDir(repo.Dir, "rev-parse", "--verify", "refs/remotes/origin/"+branch); err != nil {
+		t.Errorf("Expected %s to be pushed", branch)
+	}
+	if current, _ := runGitInDir(repo.Dir, "rev-parse", "--abbrev-ref", "HEAD"); current != repo.BranchName {
+		t.Errorf("Expected to end on %s, got %s", repo.BranchName, current)
+	}
+
+	t.Setenv("GITHUB_EVENT_NAME", "")
+	t.Setenv("GITHUB_SHA", "")
+	if code := runCI(nil); code != exitConfig {
+		t.Errorf("Expected exit code %d without commits, got %d", exitConfig, code)
+	}
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"strings"
+)
+
+// codeownersPaths are where GitHub looks for the CODEOWNERS file, in order.
+var codeownersPaths = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+// codeownersRule is a CODEOWNERS line: a gitignore-like pattern and its
+// owners, which may be none to unassign a path.
+type codeownersRule struct {
+	Pattern string
+	Owners  []string
+}
+
+// readCodeowners returns the rules of the CODEOWNERS file on the branch
+// PRs target, as GitHub reads it from there, else in HEAD.
+func readCodeowners() []codeownersRule {
+	target := getTargetBranch()
+	for _, rev := range []string{"refs/remotes/" + getRemote() + "/" + target, "refs/heads/" + target, "HEAD"} {
+		for _, file := range codeownersPaths {
+			if blobAt(rev, file) == "" {
+				continue
+			}
+			content, err := runGit("show", rev+":"+file)
+			if err != nil {
+				continue
+			}
+			return parseCodeowners(content)
+		}
+	}
+	return nil
+}
+
+func parseCodeowners(content string) []codeownersRule {
+	var rules []codeownersRule
+	for _, line := range strings.Split(content, "\n") {
+		if i := strings.Index(line, "#"); i >= 0 && (i == 0 || line[i-1] != '\\') {
+			line = line[:i]
+		}
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		rules = append(rules, codeownersRule{Pattern: strings.ReplaceAll(fields[0], `\#`, "#"), Owners: fields[1:]})
+	}
+	return rules
+}
+
+// codeownersOf returns the owners of file: those of the last matching rule.
+func codeownersOf(rules []codeownersRule, file string) []string {
+	for i := len(rules) - 1; i >= 0; i-- {
+		pattern := rules[i].Pattern
+		// A leading **/ matches at any depth, as a name without a slash does
+		if rest := strings.TrimPrefix(pattern, "**/"); rest != pattern && !strings.Contains(strings.TrimSuffix(rest, "/"), "/") {
+			pattern = rest
+		}
+		if controlPatternMatch(pattern, file) {
+			return rules[i].Owners
+		}
+	}
+	return nil
+}
+
+// codeownersReviewers returns the owners of the extracted prompt files as
+// reviewers: users and org/team slugs without their @. Owners given by
+// email can't be requested and are left out.
+func codeownersReviewers(infos []*CommitInfo) []string {
+	if !getBoolConfig("prrompt.codeowners", true) {
+		return nil
+	}
+	rules := readCodeowners()
+	if len(rules) == 0 {
+		return nil
+	}
+	var reviewers []string
+	seen := make(map[string]bool)
+	for _, info := range infos {
+		for _, file := range info.PromptFiles {
+			for _, owner := range codeownersOf(rules, file) {
+				if !strings.HasPrefix(owner, "@") {
+					continue
+				}
+				owner = strings.TrimPrefix(owner, "@")
+				if key := strings.ToLower(owner); !seen[key] {
+					seen[key] = true
+					reviewers = append(reviewers, owner)
+				}
+			}
+		}
+	}
+	return reviewers
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_codeownersOf(t *testing.T) {
+	rules := parseCodeowners(`# Owners of the prompts
+*                     @acme/everyone
+*.md                  docs@acme.test
+/.claude/skills/      @alice @acme/prompt-owners
+**/drafts             @bob
+.claude/skills/legacy/
+`)
+	tests := map[string][]string{
+		"main.go":                         {"@acme/everyone"},
+		"README.md":                       {"docs@acme.test"},
+		".claude/skills/review/SKILL.md":  {"@alice", "@acme/prompt-owners"},
+		".claude/skills/drafts/SKILL.md":  {"@bob"},
+		"prompts/drafts/idea.md":          {"@bob"},
+		".claude/skills/legacy/SKILL.md":  {},
+		"other/.claude/skills/x/SKILL.md": {"docs@acme.test"},
+	}
+	for file, want := range tests {
+		if got := codeownersOf(rules, file); !reflect.DeepEqual(got, want) {
+			t.Errorf("codeownersOf(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func Test_CodeownersReviewers(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.prTool", "api")
+	runGitInDir(repo.Dir, "config", "prrompt.prReviewers", "Alice")
+	// CODEOWNERS is read from the base branch
+	runGitInDir(repo.Dir, "checkout", "-q", "main")
+	commitFiles(t, repo.Dir, "Add CODEOWNERS", map[string]string{
+		".github/CODEOWNERS": "prompts/ @alice @carol @acme/prompt-owners prompts@acme.test\n",
+	})
+	runGitInDir(repo.Dir, "checkout", "-q", repo.BranchName)
+
+	var reviewers map[string][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/acme/widgets/pulls":
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 7, "html_url": "https://github.com/acme/widgets/pull/7", "user": {"login": "carol"}}`))
+		case "/repos/acme/widgets/pulls/7/requested_reviewers":
+			json.NewDecoder(r.Body).Decode(&reviewers)
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+	oldURL := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = oldURL }()
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if _, err := processCommit(commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	// alice once, carol as the author left out, the team, not the email
+	if strings.Join(reviewers["reviewers"], ",") != "Alice" || strings.Join(reviewers["team_reviewers"], ",") != "prompt-owners" {
+		t.Errorf("Expected the code owners as reviewers, got %v", reviewers)
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.codeowners", "false")
+	if got := codeownersReviewers([]*CommitInfo{{PromptFiles: []string{"prompts/test.md"}}}); got != nil {
+		t.Errorf("Expected no code owners with prrompt.codeowners=false, got %v", got)
+	}
+}
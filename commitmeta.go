@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	signAuto  = "auto"
+	signTrue  = "true"
+	signFalse = "false"
+)
+
+const (
+	committerUser = "user"
+	committerTool = "tool"
+
+	toolCommitterEmail = "prrompt@localhost"
+)
+
+// getSignMode returns prrompt.sign: "auto" signs extraction commits when
+// commit.gpgSign is set, "true" always signs them and "false" never does.
+func getSignMode() string {
+	value, err := gitConfig("--get", "prrompt.sign")
+	if err != nil {
+		return signAuto
+	}
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "yes", "on", "1":
+		return signTrue
+	case "false", "no", "off", "0":
+		return signFalse
+	}
+	return signAuto
+}
+
+// commitSigningArgs returns the flags that make `git commit` and `git
+// commit-tree` sign as configured. Signing uses git's own settings
+// (user.signingKey, gpg.format, gpg.program), so SSH signing works too.
+func commitSigningArgs() []string {
+	switch getSignMode() {
+	case signTrue:
+		return []string{"-S"}
+	case signFalse:
+		return []string{"--no-gpg-sign"}
+	}
+	if getBoolConfig("commit.gpgSign", false) {
+		return []string{"-S"}
+	}
+	return nil
+}
+
+// commitAuthorEnv returns the environment that makes a new commit carry
+// the author name, email and date of sha.
+func commitAuthorEnv(sha string) ([]string, error) {
+	author, err := runGit("log", "-1", "--format=%an%n%ae%n%aI", sha)
+	fields := strings.SplitN(author, "\n", 3)
+	if err != nil || len(fields) != 3 {
+		return nil, fmt.Errorf("failed to read the author of %s", shortSHA(sha))
+	}
+	return []string{"GIT_AUTHOR_NAME=" + fields[0], "GIT_AUTHOR_EMAIL=" + fields[1], "GIT_AUTHOR_DATE=" + fields[2]}, nil
+}
+
+// getCommitterMode returns prrompt.committer: "user" commits extractions as
+// the git identity running prrompt, "tool" as prrompt itself, so the prompt
+// history tells extracted commits from hand-made ones.
+func getCommitterMode() string {
+	value, _ := gitConfig("--get", "prrompt.committer")
+	if strings.ToLower(strings.TrimSpace(value)) == committerTool {
+		return committerTool
+	}
+	return committerUser
+}
+
+// committerEnv returns the environment that sets the committer of an
+// extraction commit as configured; the commit date is always the time of
+// extraction.
+func committerEnv() []string {
+	if getCommitterMode() != committerTool {
+		return nil
+	}
+	return []string{"GIT_COMMITTER_NAME=" + toolName, "GIT_COMMITTER_EMAIL=" + toolCommitterEmail}
+}
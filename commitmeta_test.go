@@ -0,0 +1,96 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_CommitSigningArgs(t *testing.T) {
+	repo := setupTestRepo(t)
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	tests := []struct {
+		sign, gpgSign string
+		want          []string
+	}{
+		{"", "", nil},
+		{"", "true", []string{"-S"}},
+		{"auto", "false", nil},
+		{"true", "", []string{"-S"}},
+		{"yes", "false", []string{"-S"}},
+		{"false", "true", []string{"--no-gpg-sign"}},
+	}
+	for _, tt := range tests {
+		runGitInDir(repo.Dir, "config", "--unset", "prrompt.sign")
+		runGitInDir(repo.Dir, "config", "--unset", "commit.gpgSign")
+		if tt.sign != "" {
+			runGitInDir(repo.Dir, "config", "prrompt.sign", tt.sign)
+		}
+		if tt.gpgSign != "" {
+			runGitInDir(repo.Dir, "config", "commit.gpgSign", tt.gpgSign)
+		}
+		if got := commitSigningArgs(); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("sign=%q commit.gpgSign=%q: expected %v, got %v", tt.sign, tt.gpgSign, tt.want, got)
+		}
+	}
+}
+
+func Test_ExtractionKeepsAuthorAndSignsOff(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.signoff", "true")
+	commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/a.md": "# A"})
+	if output, err := runGitInDir(repo.Dir, "commit", "--amend", "--no-edit",
+		"--author", "Ada Lovelace <ada@example.com>", "--date", "2024-02-03T04:05:06+01:00"); err != nil {
+		t.Fatalf("failed to amend: %s", output)
+	}
+	sha, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(sha)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	author, _ := runGitInDir(repo.Dir, "log", "-1", "--format=%an <%ae> %aI", result.Branch)
+	if author != "Ada Lovelace <ada@example.com> 2024-02-03T04:05:06+01:00" {
+		t.Errorf("Expected the source author and date, got %q", author)
+	}
+	committer, _ := runGitInDir(repo.Dir, "config", "user.name")
+	body, _ := runGitInDir(repo.Dir, "log", "-1", "--format=%B", result.Branch)
+	if !strings.Contains(body, "Signed-off-by: "+committer) {
+		t.Errorf("Expected a Signed-off-by trailer, got %q", body)
+	}
+	if !strings.Contains(body, "Prrompt-Source-Commit: "+sha) {
+		t.Errorf("Expected the provenance trailers to be kept, got %q", body)
+	}
+}
+
+func Test_CommitterMode(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.committer", "tool")
+	sha := commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/a.md": "# A"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(sha)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	identity, _ := runGitInDir(repo.Dir, "log", "-1", "--format=%an|%cn <%ce>", result.Branch)
+	if identity != "Test User|prrompt <prrompt@localhost>" {
+		t.Errorf("Expected the user as author and prrompt as committer, got %q", identity)
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.committer", "user")
+	if env := committerEnv(); env != nil {
+		t.Errorf("Expected git's own committer for \"user\", got %v", env)
+	}
+}
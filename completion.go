@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// completionRequest is one line read by `prrompt completion-server`.
+type completionRequest struct {
+	ID     any    `json:"id"`
+	Method string `json:"method"`
+	// Prefix filters skill names; Path is the file to classify, relative
+	// to the repository root or absolute.
+	Prefix string `json:"prefix,omitempty"`
+	Path   string `json:"path,omitempty"`
+}
+
+type completionResponse struct {
+	ID     any    `json:"id"`
+	Result any    `json:"result,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type completionSkill struct {
+	Name        string `json:"name"`
+	Path        string `json:"path"`
+	Description string `json:"description,omitempty"`
+}
+
+type completionClassification struct {
+	Path     string `json:"path"`
+	Prompt   bool   `json:"prompt"`
+	Excluded bool   `json:"excluded"`
+	Skill    bool   `json:"skill"`
+	Message  string `json:"message"`
+}
+
+// listSkills returns the Claude skills in the working tree, tracked or not,
+// whose name starts with prefix, by name.
+func listSkills(toplevel, prefix string) ([]completionSkill, error) {
+	output, err := runGitInDir(toplevel, "ls-files", "--cached", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files: %s", output)
+	}
+	skills := []completionSkill{}
+	seen := make(map[string]bool)
+	for _, file := range strings.Split(output, "\n") {
+		if file == "" || seen[file] || !isSkillFile(file) {
+			continue
+		}
+		seen[file] = true
+		skill := completionSkill{Path: file}
+		if data, err := os.ReadFile(filepath.Join(toplevel, file)); err == nil {
+			if fields, ok := parseFrontmatter(string(data)); ok {
+				skill.Name, skill.Description = fields["name"], fields["description"]
+			}
+		}
+		if skill.Name == "" {
+			// The directory of a SKILL.md, else the file name
+			skill.Name = strings.TrimSuffix(path.Base(file), ".md")
+			if path.Base(file) == "SKILL.md" {
+				skill.Name = path.Base(path.Dir(file))
+			}
+		}
+		if strings.HasPrefix(skill.Name, prefix) {
+			skills = append(skills, skill)
+		}
+	}
+	sort.Slice(skills, func(i, j int) bool { return skills[i].Name < skills[j].Name })
+	return skills, nil
+}
+
+// classifyPath tells an editor what prrompt will do with file on commit.
+func classifyPath(toplevel, file string) (completionClassification, error) {
+	if filepath.IsAbs(file) {
+		rel, err := filepath.Rel(toplevel, file)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			return completionClassification{}, fmt.Errorf("%s is outside the repository", file)
+		}
+		file = rel
+	}
+	file = filepath.ToSlash(filepath.Clean(file))
+	c := completionClassification{
+		Path:     file,
+		Prompt:   isPromptFile(file),
+		Excluded: isExcludedFile(file),
+		Skill:    isSkillFile(file),
+	}
+	switch {
+	case c.Prompt:
+		c.Message = "This file will be auto-extracted on commit"
+	case c.Excluded:
+		c.Message = "This file matches prrompt.excludePatterns and is never extracted"
+	default:
+		c.Message = "This file is not a prompt"
+	}
+	return c, nil
+}
+
+// handleCompletion answers one request.
+func handleCompletion(toplevel string, req completionRequest) completionResponse {
+	resp := completionResponse{ID: req.ID}
+	var err error
+	switch req.Method {
+	case "roots":
+		exclude := getExcludePatterns()
+		if exclude == nil {
+			exclude = []string{}
+		}
+		resp.Result = map[string][]string{"promptPatterns": getPromptPatterns(), "excludePatterns": exclude}
+	case "skills":
+		resp.Result, err = listSkills(toplevel, req.Prefix)
+	case "classify":
+		if req.Path == "" {
+			err = fmt.Errorf("classify needs a path")
+			break
+		}
+		resp.Result, err = classifyPath(toplevel, req.Path)
+	default:
+		err = fmt.Errorf("unknown method %q (roots, skills or classify)", req.Method)
+	}
+	if err != nil {
+		resp.Result = nil
+		resp.Error = err.Error()
+	}
+	return resp
+}
+
+// runCompletionServer implements `prrompt completion-server`: it answers
+// one JSON request per input line with one JSON response line, for editor
+// integrations. Configuration is read for every request, so edits to it
+// apply without a restart.
+func runCompletionServer(in io.Reader, out io.Writer) error {
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+	encoder := json.NewEncoder(out)
+	scanner := bufio.NewScanner(in)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var req completionRequest
+		resp := completionResponse{}
+		if err := json.Unmarshal([]byte(line), &req); err != nil {
+			resp.Error = fmt.Sprintf("invalid request: %v", err)
+		} else {
+			resp = handleCompletion(toplevel, req)
+		}
+		if err := encoder.Encode(resp); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
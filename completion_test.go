@@ -0,0 +1,74 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_CompletionServer(t *testing.T) {
+	repo := setupTestRepo(t)
+	commitFiles(t, repo.Dir, "Add skills", map[string]string{
+		".claude/skills/review/SKILL.md": "---\nname: review-diff\ndescription: Review a diff\n---\n",
+		".claude/skills/summarize.md":    "# Summarize",
+	})
+	os.MkdirAll(filepath.Join(repo.Dir, ".claude/skills/draft"), 0755)
+	os.WriteFile(filepath.Join(repo.Dir, ".claude/skills/draft/SKILL.md"), []byte("# Draft"), 0644)
+	runGitInDir(repo.Dir, "config", "prrompt.excludePatterns", "prompts/generated/")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	in := strings.NewReader(strings.Join([]string{
+		`{"id": 1, "method": "skills"}`,
+		`{"id": 2, "method": "skills", "prefix": "rev"}`,
+		`{"id": 3, "method": "classify", "path": "` + filepath.Join(repo.Dir, "prompts/a.md") + `"}`,
+		`{"id": 4, "method": "classify", "path": "prompts/generated/b.md"}`,
+		`{"id": 5, "method": "roots"}`,
+		`{"id": 6, "method": "nope"}`,
+		`not json`,
+	}, "\n"))
+	var out bytes.Buffer
+	if err := runCompletionServer(in, &out); err != nil {
+		t.Fatalf("completion-server failed: %v", err)
+	}
+
+	var responses []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp map[string]any
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("Invalid response line %q: %v", line, err)
+		}
+		responses = append(responses, resp)
+	}
+	if len(responses) != 7 {
+		t.Fatalf("Expected 7 responses, got %d:\n%s", len(responses), out.String())
+	}
+
+	names := func(resp map[string]any) []string {
+		var names []string
+		for _, skill := range resp["result"].([]any) {
+			names = append(names, skill.(map[string]any)["name"].(string))
+		}
+		return names
+	}
+	if got := strings.Join(names(responses[0]), ","); got != "draft,review-diff,summarize" {
+		t.Errorf("Expected all skills by name, got %s", got)
+	}
+	if got := strings.Join(names(responses[1]), ","); got != "review-diff" {
+		t.Errorf("Expected skills filtered by prefix, got %s", got)
+	}
+	if c := responses[2]["result"].(map[string]any); c["path"] != "prompts/a.md" || c["prompt"] != true {
+		t.Errorf("Expected an absolute prompt path to be classified as a prompt, got %v", c)
+	}
+	if c := responses[3]["result"].(map[string]any); c["prompt"] != false || c["excluded"] != true {
+		t.Errorf("Expected an excluded path, got %v", c)
+	}
+	if responses[4]["result"] == nil || responses[5]["error"] == nil || responses[6]["error"] == nil {
+		t.Errorf("Unexpected responses %v", responses[4:])
+	}
+}
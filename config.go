@@ -0,0 +1,544 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// configKey describes a prrompt setting for diagnostics.
+type configKey struct {
+	Key   string
+	Value func() string
+}
+
+var configKeys = []configKey{
+	{"prrompt.commitPrefix", getCommitPrefix},
+	{"prrompt.commitPrefixStyle", getCommitPrefixStyle},
+	{"prrompt.branchPrefix", getBranchPrefix},
+	{"prrompt.branchName", getBranchName},
+	{"prrompt.stayOnBranch", func() string { return strconv.FormatBool(stayOnBranch()) }},
+	{"prrompt.slugStyle", getSlugStyle},
+	{"prrompt.shaLength", func() string { return strconv.Itoa(getSHALength()) }},
+	{"prrompt.baseBranch", getBaseBranch},
+	{"prrompt.stagingBranch", getStagingBranch},
+	{"prrompt.changelog", getChangelogMode},
+	{"prrompt.changelogDir", func() string {
+		if dir := getChangelogDir(); dir != "" {
+			return dir
+		}
+		return "(next to each skill)"
+	}},
+	{"prrompt.remote", getRemote},
+	{"prrompt.forgeBaseURL", getForgeBaseURL},
+	{"prrompt.fetchBase", func() string { return strconv.FormatBool(getBoolConfig("prrompt.fetchBase", true)) }},
+	{"prrompt.push", func() string { return strconv.FormatBool(getBoolConfig("prrompt.push", true)) }},
+	{"prrompt.notes", func() string { return strconv.FormatBool(getBoolConfig("prrompt.notes", false)) }},
+	{"prrompt.protectBranches", func() string {
+		return strconv.FormatBool(getBoolConfig("prrompt.protectBranches", false))
+	}},
+	{"prrompt.prTool", getPRTool},
+	{"prrompt.apiReserve", func() string { return strconv.Itoa(getAPIReserve()) }},
+	{"prrompt.prLabels", func() string { return strings.Join(getListConfig("prrompt.prLabels"), ",") }},
+	{"prrompt.prReviewers", func() string { return strings.Join(getListConfig("prrompt.prReviewers"), ",") }},
+	{"prrompt.prAssignees", func() string { return strings.Join(getListConfig("prrompt.prAssignees"), ",") }},
+	{"prrompt.codeowners", func() string { return strconv.FormatBool(getBoolConfig("prrompt.codeowners", true)) }},
+	{"prrompt.notifyURL", func() string { return withoutCredentials(getNotifyURL()) }},
+	{"prrompt.notifyFormat", func() string { return getNotifyFormat(getNotifyURL()) }},
+	{"prrompt.promptPatterns", func() string { return strings.Join(getPromptPatterns(), ",") }},
+	{"prrompt.excludePatterns", func() string { return strings.Join(getExcludePatterns(), ",") }},
+	{"prrompt.archivePatterns", func() string { return strings.Join(getArchivePatterns(), ",") }},
+	{"prrompt.archiveDir", getArchiveDir},
+	{"prrompt.messagePatterns", func() string {
+		value, _ := gitConfig("--get-all", "prrompt.messagePatterns")
+		return strings.ReplaceAll(value, "\n", ", ")
+	}},
+	{"prrompt.subjectRewrite", func() string { return strconv.FormatBool(getBoolConfig("prrompt.subjectRewrite", false)) }},
+	{"prrompt.subjectStrip", func() string {
+		value, _ := gitConfig("--get-all", "prrompt.subjectStrip")
+		return strings.ReplaceAll(value, "\n", ", ")
+	}},
+	{"prrompt.maxFileSize", func() string { return formatFileSize(getMaxFileSize()) }},
+	{"prrompt.binaryFiles", getBinaryFiles},
+	{"prrompt.wipCommits", getWIPCommits},
+	{"prrompt.changeType", func() string { return strconv.FormatBool(getBoolConfig("prrompt.changeType", true)) }},
+	{"prrompt.versionBump", func() string { return strconv.FormatBool(getBoolConfig("prrompt.versionBump", false)) }},
+	{"prrompt.prSummary", func() string { return strconv.FormatBool(getBoolConfig("prrompt.prSummary", true)) }},
+	{"prrompt.prDiffLines", func() string {
+		if lines := getPRDiffLines(); lines > 0 {
+			return strconv.Itoa(lines)
+		}
+		return "off"
+	}},
+	{"prrompt.strict", func() string { return strconv.FormatBool(getBoolConfig("prrompt.strict", false)) }},
+	{"prrompt.postMerge", func() string { return strconv.FormatBool(getBoolConfig("prrompt.postMerge", false)) }},
+	{"prrompt.pushTimeout", func() string { return getPushTimeout().String() }},
+	{"prrompt.retries", func() string { return strconv.Itoa(getRetries()) }},
+	{"prrompt.retryDelay", func() string { return getRetryDelay().String() }},
+	{"prrompt.committer", getCommitterMode},
+	{"prrompt.sign", getSignMode},
+	{"prrompt.signoff", func() string { return strconv.FormatBool(getBoolConfig("prrompt.signoff", false)) }},
+	{"prrompt.enabled", func() string { return strconv.FormatBool(getBoolConfig("prrompt.enabled", true)) }},
+	{"prrompt.replaceRefs", getReplaceRefs},
+	{"prrompt.skipMarkers", func() string { return strings.Join(getSkipMarkers(), ",") }},
+	{"prrompt.logLevel", getLogLevel},
+	{"prrompt.logFile", func() string { return strconv.FormatBool(getBoolConfig("prrompt.logFile", false)) }},
+	{"prrompt.tmpDir", getTmpDir},
+	{"prrompt.verbosity", getVerbosity},
+	{"prrompt.dedupe", getDedupeMode},
+	{"prrompt.allowEmptyExtraction", func() string {
+		return strconv.FormatBool(getBoolConfig("prrompt.allowEmptyExtraction", false))
+	}},
+	{"prrompt.validateSkills", func() string { return strconv.FormatBool(getBoolConfig("prrompt.validateSkills", true)) }},
+	{"prrompt.lint", getLintMode},
+	{"prrompt.lint.requiredKeys", func() string { return strings.Join(getListConfig("prrompt.lint.requiredKeys"), ",") }},
+	{"prrompt.lint.maxFileSize", func() string {
+		if size := getLintMaxFileSize(); size > 0 {
+			return strconv.Itoa(size)
+		}
+		return "off"
+	}},
+	{"prrompt.lint.forbiddenPhrase", func() string { return strings.Join(getForbiddenPhrases(), ", ") }},
+	{"prrompt.tokenEstimate", getTokenEstimate},
+	{"prrompt.tokenBudget", func() string {
+		if budget := getTokenBudget(); budget > 0 {
+			return strconv.Itoa(budget)
+		}
+		return "off"
+	}},
+	{"prrompt.tokenCounts", func() string { return strconv.FormatBool(getBoolConfig("prrompt.tokenCounts", false)) }},
+	{"prrompt.removeFromSource", func() string {
+		return strconv.FormatBool(getBoolConfig("prrompt.removeFromSource", false))
+	}},
+	{"prrompt.onBaseBranch", getOnBaseBranch},
+	{"prrompt.mergeStrategy", getMergeStrategy},
+	{"prrompt.lockTimeout", func() string { return strconv.Itoa(int(getLockTimeout().Seconds())) }},
+	{"prrompt.rangeMode", getRangeMode},
+	{"prrompt.batchSquash", getBatchSquash},
+	{"prrompt.splitBy", getSplitBy},
+	{"prrompt.webhookSecret", func() string {
+		if secret, _ := gitConfig("--get", "prrompt.webhookSecret"); secret != "" {
+			return "(set)"
+		}
+		return ""
+	}},
+	{"prrompt.webhookHosts", func() string { return strings.Join(getWebhookHosts(), ",") }},
+	{"prrompt.squashWindow", func() string {
+		window, untilPushed := getSquashWindow()
+		if untilPushed {
+			return squashUntilPushed
+		}
+		if window > 0 {
+			return window.String()
+		}
+		return "off"
+	}},
+	{"prrompt.registry.url", func() string { return withoutCredentials(getRegistryURL()) }},
+	{"prrompt.registry.token", func() string {
+		if token, _ := gitConfig("--get", "prrompt.registry.token"); token != "" {
+			return "(set)"
+		}
+		return ""
+	}},
+	{"prrompt.ai.summary", func() string { return strconv.FormatBool(getBoolConfig("prrompt.ai.summary", false)) }},
+	{"prrompt.ai.endpoint", func() string {
+		value, _ := gitConfig("--get", "prrompt.ai.endpoint")
+		return withoutCredentials(value)
+	}},
+	{"prrompt.ai.model", getAIModel},
+	{"prrompt.ai.token", func() string {
+		if token, _ := gitConfig("--get", "prrompt.ai.token"); token != "" {
+			return "(set)"
+		}
+		return ""
+	}},
+	{"prrompt.mirror.url", getMirrorURL},
+	{"prrompt.mirror.mode", getMirrorMode},
+	{"prrompt.mirror.pathPrefix", getMirrorPathPrefix},
+	{"prrompt.mirror.baseBranch", getMirrorBaseBranch},
+	{"prrompt.redactPattern", func() string {
+		value, _ := gitConfig("--get-all", "prrompt.redactPattern")
+		return strings.ReplaceAll(value, "\n", ", ")
+	}},
+}
+
+// configFileNames are the repository-level config files, relative to the
+// top of the work tree. Values there apply when git config does not set the
+// key.
+var configFileNames = []string{".prrompt.yaml", ".prrompt.yml"}
+
+// gitConfig runs `git config` with args, resolving conditional includes and
+// worktree-scoped config even when a hook environment points GIT_DIR at the
+// common directory of a linked worktree. prrompt settings can be overridden
+// by environment variables (see configEnvNames); lookups not answered by git
+// config fall back to the repository's .prrompt.yaml. Together that makes
+// the precedence flags > env > git config > file > defaults, flags being
+// the `-c` settings git passes on in the command scope.
+func gitConfig(args ...string) (string, error) {
+	if flagValue, ok := flagConfigLookup(args); ok {
+		return flagValue, nil
+	}
+	if envValue, ok := envConfigLookup(args); ok {
+		return envValue, nil
+	}
+	if file := worktreeConfigFile(); file != "" {
+		if value, err := runGit(append([]string{"config", "--file", file}, args...)...); err == nil {
+			return value, nil
+		}
+	}
+	value, err := runGit(append([]string{"config", "--includes"}, args...)...)
+	if err == nil {
+		return value, nil
+	}
+	if fileValue, ok := fileConfigLookup(args); ok {
+		return fileValue, nil
+	}
+	return value, err
+}
+
+// configQuery picks the prrompt key and mode out of a `git config
+// [--type=...] --get|--get-all <key>` query.
+func configQuery(args []string) (key, mode string, ok bool) {
+	if len(args) < 2 {
+		return "", "", false
+	}
+	key = args[len(args)-1]
+	mode = args[len(args)-2]
+	if !strings.HasPrefix(key, "prrompt.") || (mode != "--get" && mode != "--get-all") {
+		return "", "", false
+	}
+	return key, mode, true
+}
+
+// configValue formats a value found outside git config the way git would
+// print it for the query.
+func configValue(args []string, value string) string {
+	for _, arg := range args {
+		if arg == "--type=bool" {
+			switch strings.ToLower(value) {
+			case "true", "yes", "on", "1":
+				return "true"
+			default:
+				return "false"
+			}
+		}
+	}
+	return value
+}
+
+// envAliases are shorter environment variable names for some settings.
+var envAliases = map[string]string{
+	"prrompt.promptPatterns": "PRROMPT_PATTERNS",
+}
+
+// configEnvNames returns the environment variables that override key: the
+// key without "prrompt.", in upper snake case (prrompt.mirror.baseBranch is
+// PRROMPT_MIRROR_BASE_BRANCH), and its alias if it has one.
+func configEnvNames(key string) []string {
+	var b strings.Builder
+	b.WriteString("PRROMPT_")
+	for i, r := range strings.TrimPrefix(key, "prrompt.") {
+		switch {
+		case r == '.':
+			b.WriteByte('_')
+		case unicode.IsUpper(r) && i > 0:
+			b.WriteByte('_')
+			b.WriteRune(r)
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	names := []string{b.String()}
+	if alias, ok := envAliases[key]; ok {
+		names = append(names, alias)
+	}
+	return names
+}
+
+// envConfigLookup answers a query from the environment.
+func envConfigLookup(args []string) (string, bool) {
+	key, _, ok := configQuery(args)
+	if !ok {
+		return "", false
+	}
+	for _, name := range configEnvNames(key) {
+		if value, set := os.LookupEnv(name); set {
+			return configValue(args, value), true
+		}
+	}
+	return "", false
+}
+
+// commandConfig returns the settings of the command scope, in the order
+// git reads them: `git -c` before an external command
+// (GIT_CONFIG_PARAMETERS), then GIT_CONFIG_COUNT, which `prrompt -c` uses.
+func commandConfig() [][2]string {
+	pairs := parseConfigParameters(os.Getenv("GIT_CONFIG_PARAMETERS"))
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	for i := 0; i < count; i++ {
+		if key, ok := os.LookupEnv(fmt.Sprintf("GIT_CONFIG_KEY_%d", i)); ok {
+			pairs = append(pairs, [2]string{key, os.Getenv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", i))})
+		}
+	}
+	return pairs
+}
+
+// parseConfigParameters splits GIT_CONFIG_PARAMETERS, a list of single
+// quoted 'key'='value' entries ('key=value' for older git, 'key' alone for
+// true). A quote inside one closes it, adds \' and reopens it.
+func parseConfigParameters(s string) [][2]string {
+	var pairs [][2]string
+	for s = strings.TrimSpace(s); s != ""; s = strings.TrimSpace(s) {
+		key, rest, ok := cutQuoted(s)
+		if !ok {
+			break
+		}
+		value := "true"
+		if after, found := strings.CutPrefix(rest, "="); found {
+			if value, rest, ok = cutQuoted(after); !ok {
+				break
+			}
+		} else if k, v, found := strings.Cut(key, "="); found {
+			key, value = k, v
+		}
+		pairs = append(pairs, [2]string{key, value})
+		s = rest
+	}
+	return pairs
+}
+
+// cutQuoted returns the shell single quoted word s starts with, unquoted,
+// and what follows it.
+func cutQuoted(s string) (word, rest string, ok bool) {
+	var b strings.Builder
+	for strings.HasPrefix(s, "'") {
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", "", false
+		}
+		b.WriteString(s[1 : end+1])
+		s = s[end+2:]
+		if !strings.HasPrefix(s, `\'`) {
+			return b.String(), s, true
+		}
+		b.WriteByte('\'')
+		s = s[2:]
+	}
+	return "", "", false
+}
+
+// flagConfigLookup answers a query from the command scope, whose values
+// win over the environment as well as over git config.
+func flagConfigLookup(args []string) (string, bool) {
+	key, mode, ok := configQuery(args)
+	if !ok {
+		return "", false
+	}
+	var values []string
+	for _, pair := range commandConfig() {
+		if strings.EqualFold(pair[0], key) {
+			values = append(values, configValue(args, pair[1]))
+		}
+	}
+	if len(values) == 0 {
+		return "", false
+	}
+	if mode == "--get-all" {
+		return strings.Join(values, "\n"), true
+	}
+	return values[len(values)-1], true
+}
+
+// repoFileKeys are the settings a committed .prrompt.yaml may set, without
+// the "prrompt." prefix: how the repository's prompts are found, named and
+// described. Anything naming a host, endpoint, token or file outside the
+// repository comes from git config or the environment only, so committing
+// in a cloned repository never sends prompts or credentials where the
+// repository picks.
+var repoFileKeys = map[string]bool{
+	"commitPrefix": true, "commitPrefixStyle": true, "branchPrefix": true, "branchName": true,
+	"slugStyle": true, "shaLength": true, "baseBranch": true, "stagingBranch": true,
+	"changelog": true, "changelogDir": true, "remote": true, "push": true,
+	"prTool": true, "prLabels": true, "prReviewers": true, "prAssignees": true,
+	"promptPatterns": true, "excludePatterns": true, "archivePatterns": true, "archiveDir": true,
+	"messagePatterns": true, "subjectRewrite": true, "subjectStrip": true, "skipMarkers": true,
+	"maxFileSize": true, "binaryFiles": true, "wipCommits": true, "changeType": true,
+	"versionBump": true, "prSummary": true, "prDiffLines": true, "dedupe": true,
+	"validateSkills": true, "lint": true, "lint.requiredKeys": true, "lint.maxFileSize": true,
+	"lint.forbiddenPhrase": true, "tokenEstimate": true, "tokenBudget": true, "tokenCounts": true,
+	"rangeMode": true, "splitBy": true, "squashWindow": true,
+}
+
+// fileConfigLookup answers a query from the config file, for the keys in
+// repoFileKeys.
+func fileConfigLookup(args []string) (string, bool) {
+	key, mode, ok := configQuery(args)
+	if !ok || !repoFileKeys[strings.TrimPrefix(key, "prrompt.")] {
+		return "", false
+	}
+
+	_, values := loadConfigFile()
+	entries, ok := values[strings.TrimPrefix(key, "prrompt.")]
+	if !ok || len(entries) == 0 {
+		return "", false
+	}
+
+	value := strings.Join(entries, ",")
+	if mode == "--get-all" {
+		value = strings.Join(entries, "\n")
+	}
+	return configValue(args, value), true
+}
+
+// loadConfigFile reads the repository's .prrompt.yaml. Only the subset
+// written by `prrompt init` is understood: `key: value` pairs with dotted
+// keys (without the "prrompt." prefix), and `- item` lists under a key.
+func loadConfigFile() (string, map[string][]string) {
+	values := make(map[string][]string)
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", values
+	}
+	for _, name := range configFileNames {
+		path := filepath.Join(toplevel, name)
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		return path, parseConfigFile(string(data))
+	}
+	return "", values
+}
+
+func parseConfigFile(data string) map[string][]string {
+	values := make(map[string][]string)
+	currentKey := ""
+	for _, line := range strings.Split(data, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if item, found := strings.CutPrefix(trimmed, "- "); found && currentKey != "" {
+			values[currentKey] = append(values[currentKey], unquote(item))
+			continue
+		}
+		key, value, found := strings.Cut(trimmed, ":")
+		if !found {
+			continue
+		}
+		currentKey = strings.TrimSpace(key)
+		value = strings.TrimSpace(value)
+		if value != "" {
+			values[currentKey] = append(values[currentKey], unquote(value))
+		}
+	}
+	return values
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
+
+// configSource describes where key was loaded from, e.g. "local
+// (file:.git/config)" or "env (PRROMPT_PUSH)", or "default" when it is not
+// set.
+func configSource(key string) string {
+	if key == "prrompt.baseBranch" && os.Getenv(baseEnv) != "" {
+		return "env (" + baseEnv + ")"
+	}
+	if _, ok := flagConfigLookup([]string{"--get-all", key}); ok {
+		return "command (-c)"
+	}
+	for _, name := range configEnvNames(key) {
+		if _, set := os.LookupEnv(name); set {
+			return "env (" + name + ")"
+		}
+	}
+	if file := worktreeConfigFile(); file != "" {
+		if _, err := runGit("config", "--file", file, "--get-all", key); err == nil {
+			return "worktree (file:" + file + ")"
+		}
+	}
+	output, err := runGit("config", "--includes", "--show-scope", "--show-origin", "--get-all", key)
+	if err != nil || output == "" {
+		if _, ok := fileConfigLookup([]string{"--get-all", key}); ok {
+			path, _ := loadConfigFile()
+			return "file (" + path + ")"
+		}
+		if key == "prrompt.baseBranch" {
+			_, source := detectBaseBranch()
+			return "detected (" + source + ")"
+		}
+		return "default"
+	}
+	line, _, _ := strings.Cut(output, "\n")
+	fields := strings.SplitN(line, "\t", 3)
+	if len(fields) < 2 {
+		return "default"
+	}
+	return fields[0] + " (" + fields[1] + ")"
+}
+
+// worktreeConfigFile returns the config.worktree of the linked worktree we
+// are in when GIT_DIR is overridden to a different directory, in which case
+// git itself would not read it. It returns "" in every other case.
+func worktreeConfigFile() string {
+	gitDirEnv := os.Getenv("GIT_DIR")
+	if gitDirEnv == "" {
+		return ""
+	}
+	worktreeGitDir := findWorktreeGitDir()
+	if worktreeGitDir == "" {
+		return ""
+	}
+	absGitDir, err := filepath.Abs(gitDirEnv)
+	if err != nil || filepath.Clean(absGitDir) == filepath.Clean(worktreeGitDir) {
+		return ""
+	}
+	value, err := runGit("config", "--type=bool", "--get", "extensions.worktreeConfig")
+	if err != nil || value != "true" {
+		return ""
+	}
+	file := filepath.Join(worktreeGitDir, "config.worktree")
+	if _, err := os.Stat(file); err != nil {
+		return ""
+	}
+	return file
+}
+
+// findWorktreeGitDir walks up from the working directory to the nearest
+// .git file of a linked worktree and returns the git dir it points to.
+func findWorktreeGitDir() string {
+	dir, err := os.Getwd()
+	if err != nil {
+		return ""
+	}
+	for {
+		data, err := os.ReadFile(filepath.Join(dir, ".git"))
+		if err == nil {
+			target, found := strings.CutPrefix(strings.TrimSpace(string(data)), "gitdir: ")
+			if !found {
+				return ""
+			}
+			if !filepath.IsAbs(target) {
+				target = filepath.Join(dir, target)
+			}
+			return filepath.Clean(target)
+		}
+		if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+			// A regular .git directory: not a linked worktree
+			return ""
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return ""
+		}
+		dir = parent
+	}
+}
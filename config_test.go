@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_WorktreeConfigWithGitDirOverride(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "extensions.worktreeConfig", "true")
+
+	worktree := filepath.Join(t.TempDir(), "wt")
+	if out, err := runGitInDir(repo.Dir, "worktree", "add", "-b", "wt-branch", worktree); err != nil {
+		t.Fatalf("Failed to add worktree: %s", out)
+	}
+	runGitInDir(worktree, "config", "--worktree", "prrompt.baseBranch", "develop")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(worktree)
+
+	// Like some hook environments: GIT_DIR points at the common directory
+	t.Setenv("GIT_DIR", filepath.Join(repo.Dir, ".git"))
+
+	if got := getBaseBranch(); got != "develop" {
+		t.Errorf("Expected worktree-scoped baseBranch develop, got %q", got)
+	}
+	if source := configSource("prrompt.baseBranch"); !strings.HasPrefix(source, "worktree") {
+		t.Errorf("Expected worktree source, got %q", source)
+	}
+	if source := configSource("prrompt.commitPrefix"); source != "default" {
+		t.Errorf("Expected default source for unset key, got %q", source)
+	}
+}
+
+func Test_ConditionalIncludeConfig(t *testing.T) {
+	repo := setupTestRepo(t)
+	included := filepath.Join(t.TempDir(), "prrompt.inc")
+	os.WriteFile(included, []byte("[prrompt]\n\tbranchPrefix = included-prefix\n"), 0644)
+	runGitInDir(repo.Dir, "config", "includeIf.gitdir:"+repo.Dir+"/.git.path", included)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if got := getBranchPrefix(); got != "included-prefix" {
+		t.Errorf("Expected branchPrefix from includeIf, got %q", got)
+	}
+	if source := configSource("prrompt.branchPrefix"); !strings.Contains(source, included) {
+		t.Errorf("Expected source to name the included file, got %q", source)
+	}
+}
+
+func Test_EnvConfigOverrides(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.branchPrefix", "from-git")
+	runGitInDir(repo.Dir, "config", "prrompt.push", "true")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if names := configEnvNames("prrompt.mirror.baseBranch"); names[0] != "PRROMPT_MIRROR_BASE_BRANCH" {
+		t.Errorf("Unexpected env name %v", names)
+	}
+
+	t.Setenv("PRROMPT_BRANCH_PREFIX", "from-env")
+	t.Setenv("PRROMPT_PUSH", "no")
+	t.Setenv("PRROMPT_PATTERNS", "ai/")
+	if got := getBranchPrefix(); got != "from-env" {
+		t.Errorf("Expected the env to override git config, got %s", got)
+	}
+	if getBoolConfig("prrompt.push", true) {
+		t.Error("Expected PRROMPT_PUSH=no to disable pushing")
+	}
+	if got := getPromptPatterns(); len(got) != 1 || got[0] != "ai/" {
+		t.Errorf("Expected PRROMPT_PATTERNS to set the prompt patterns, got %v", got)
+	}
+	if got := configSource("prrompt.branchPrefix"); got != "env (PRROMPT_BRANCH_PREFIX)" {
+		t.Errorf("Unexpected source %q", got)
+	}
+}
+
+func Test_FlagsBeatEnvConfig(t *testing.T) {
+	repo := setupTestRepo(t)
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	t.Setenv("GIT_CONFIG_COUNT", "")
+	t.Setenv("GIT_CONFIG_KEY_0", "")
+	t.Setenv("GIT_CONFIG_VALUE_0", "")
+	t.Setenv("GIT_CONFIG_PARAMETERS", "")
+	t.Setenv("PRROMPT_PUSH", "true")
+	t.Setenv("PRROMPT_BRANCH_PREFIX", "from-env")
+
+	// prrompt -c prrompt.push=false
+	if err := addConfigParameter("prrompt.push=false"); err != nil {
+		t.Fatal(err)
+	}
+	if getBoolConfig("prrompt.push", true) {
+		t.Error("Expected -c prrompt.push=false to win over PRROMPT_PUSH=true")
+	}
+	if source := configSource("prrompt.push"); source != "command (-c)" {
+		t.Errorf("Expected the command line as the source, got %q", source)
+	}
+
+	// git -c prrompt.branchPrefix=... prrompt, as git runs an alias
+	os.Setenv("GIT_CONFIG_PARAMETERS", `'prrompt.branchprefix'='it'\''s-flags' 'core.quotepath'`)
+	if got := getBranchPrefix(); got != "it's-flags" {
+		t.Errorf("Expected the git -c value, got %q", got)
+	}
+	if pairs := parseConfigParameters(`'a.b=1' 'c.d'='' 'e.f'`); len(pairs) != 3 || pairs[0] != [2]string{"a.b", "1"} || pairs[1] != [2]string{"c.d", ""} || pairs[2] != [2]string{"e.f", "true"} {
+		t.Errorf("Unexpected pairs %q", pairs)
+	}
+}
+
+func Test_ConfigFileOnlySetsRepositoryKeys(t *testing.T) {
+	repo := setupTestRepo(t)
+	os.WriteFile(filepath.Join(repo.Dir, ".prrompt.yaml"), []byte("branchPrefix: team\nforgeBaseURL: https://evil.example\nai.endpoint: https://evil.example/v1\nnotifyURL: https://evil.example/hook\n"), 0644)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if got := getBranchPrefix(); got != "team" {
+		t.Errorf("Expected the branch prefix from the file, got %s", got)
+	}
+	for _, key := range []string{"prrompt.forgeBaseURL", "prrompt.ai.endpoint", "prrompt.notifyURL"} {
+		if value, err := gitConfig("--get", key); err == nil {
+			t.Errorf("Expected %s not to be read from the file, got %q", key, value)
+		}
+	}
+	problems := strings.Join(validateConfig(), "\n")
+	if !strings.Contains(problems, "prrompt.forgeBaseURL: only read from git config") {
+		t.Errorf("Expected the file's forgeBaseURL reported, got:\n%s", problems)
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.forgeBaseURL", "https://git.example.com")
+	if value, _ := gitConfig("--get", "prrompt.forgeBaseURL"); value != "https://git.example.com" {
+		t.Errorf("Expected forgeBaseURL from git config, got %q", value)
+	}
+}
@@ -0,0 +1,339 @@
+package main
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// configValidators check raw values of the settings that have a syntax
+// beyond free text. Keys not listed accept any value.
+var configValidators = map[string]func(string) error{
+	"prrompt.branchPrefix":         validBranchName,
+	"prrompt.baseBranch":           validBranchName,
+	"prrompt.mirror.baseBranch":    validBranchName,
+	"prrompt.branchName":           oneOf(branchNameSHA, branchNameSkill),
+	"prrompt.commitPrefixStyle":    oneOf(prefixStyleBracket, prefixStyleConventional, prefixStyleTrailer, prefixStyleNone),
+	"prrompt.slugStyle":            oneOf(slugStyleASCII, slugStyleUnicode),
+	"prrompt.shaLength":            validSHALength,
+	"prrompt.prTool":               oneOf(prToolURL, prToolGH, prToolGlab, prToolAPI),
+	"prrompt.logLevel":             oneOf(logLevelNames...),
+	"prrompt.verbosity":            oneOf(verbosityLow, verbosityHigh),
+	"prrompt.dedupe":               oneOf(dedupeSkip, dedupeWarn, dedupeForce),
+	"prrompt.lint":                 oneOf(lintOff, lintWarn, lintBlock),
+	"prrompt.tokenEstimate":        oneOf(estimateChars, estimateWords),
+	"prrompt.onBaseBranch":         oneOf(onBaseBranchSkip, onBaseBranchParent),
+	"prrompt.mergeStrategy":        oneOf(mergeStrategySkip, mergeStrategyFirstParent),
+	"prrompt.mirror.mode":          oneOf(mirrorModeAlso, mirrorModeOnly),
+	"prrompt.rangeMode":            oneOf(rangeModePerCommit, rangeModeCombined, rangeModeSkill),
+	"prrompt.batchSquash":          oneOf(batchSquashPerCommit, batchSquashNet),
+	"prrompt.splitBy":              oneOf(splitByNone, splitByPattern, splitByDirectory),
+	"prrompt.notifyFormat":         oneOf(notifyFormatJSON, notifyFormatSlack, notifyFormatTeams),
+	"prrompt.push":                 validBool,
+	"prrompt.fetchBase":            validBool,
+	"prrompt.protectBranches":      validBool,
+	"prrompt.removeFromSource":     validBool,
+	"prrompt.signoff":              validBool,
+	"prrompt.notes":                validBool,
+	"prrompt.stayOnBranch":         validBool,
+	"prrompt.codeowners":           validBool,
+	"prrompt.committer":            oneOf(committerUser, committerTool),
+	"prrompt.enabled":              validBool,
+	"prrompt.replaceRefs":          oneOf(replaceRefsUse, replaceRefsIgnore),
+	"prrompt.sign":                 oneOf(signAuto, signTrue, signFalse, "yes", "no", "on", "off", "1", "0"),
+	"prrompt.logFile":              validBool,
+	"prrompt.tmpDir":               validTmpDir,
+	"prrompt.allowEmptyExtraction": validBool,
+	"prrompt.validateSkills":       validBool,
+	"prrompt.tokenCounts":          validBool,
+	"prrompt.subjectRewrite":       validBool,
+	"prrompt.apiReserve":           validCount,
+	"prrompt.lint.maxFileSize":     validCount,
+	"prrompt.tokenBudget":          validCount,
+	"prrompt.lockTimeout":          validCount,
+	"prrompt.squashWindow":         validSquashWindow,
+	"prrompt.pushTimeout":          validDuration,
+	"prrompt.retries":              validCount,
+	"prrompt.retryDelay":           validDuration,
+	"prrompt.stagingBranch":        validBranchName,
+	"prrompt.changelog":            oneOf(changelogOff, changelogCommit, changelogPR, changelogExtract),
+	"prrompt.forgeBaseURL":         validURL,
+	"prrompt.ai.summary":           validBool,
+	"prrompt.ai.endpoint":          validURL,
+	"prrompt.wipCommits":           oneOf(wipExtract, wipSkip),
+	"prrompt.strict":               validBool,
+	"prrompt.postMerge":            validBool,
+	"prrompt.changeType":           validBool,
+	"prrompt.prSummary":            validBool,
+	"prrompt.versionBump":          validBool,
+	"prrompt.prDiffLines":          validCount,
+	"prrompt.maxFileSize":          validFileSize,
+	"prrompt.binaryFiles":          oneOf(binaryWarn, binarySkip, binaryInclude),
+	"prrompt.promptPatterns":       validPatterns,
+	"prrompt.excludePatterns":      validPatterns,
+	"prrompt.archivePatterns":      validPatterns,
+	"prrompt.redactPattern":        validRegexp,
+	"prrompt.messagePatterns":      validRegexp,
+	"prrompt.subjectStrip":         validRegexp,
+}
+
+// multiValuedKeys are set with one git config entry per value.
+var multiValuedKeys = map[string]bool{
+	"prrompt.redactPattern":        true,
+	"prrompt.messagePatterns":      true,
+	"prrompt.lint.forbiddenPhrase": true,
+	"prrompt.subjectStrip":         true,
+}
+
+func oneOf(values ...string) func(string) error {
+	return func(value string) error {
+		for _, allowed := range values {
+			if strings.EqualFold(strings.TrimSpace(value), allowed) {
+				return nil
+			}
+		}
+		return fmt.Errorf("must be one of %s", strings.Join(values, ", "))
+	}
+}
+
+func validBool(value string) error {
+	switch strings.ToLower(strings.TrimSpace(value)) {
+	case "true", "false", "yes", "no", "on", "off", "1", "0":
+		return nil
+	}
+	return fmt.Errorf("must be true or false")
+}
+
+func validCount(value string) error {
+	if n, err := strconv.Atoi(strings.TrimSpace(value)); err != nil || n < 0 {
+		return fmt.Errorf("must be a non-negative number")
+	}
+	return nil
+}
+
+func validSHALength(value string) error {
+	if n, err := strconv.Atoi(strings.TrimSpace(value)); err != nil || n < minSHALength || n > 40 {
+		return fmt.Errorf("must be a number from %d to 40", minSHALength)
+	}
+	return nil
+}
+
+func validFileSize(value string) error {
+	if _, err := parseFileSize(value); err != nil {
+		return fmt.Errorf("must be a size in bytes like 500k or 2m")
+	}
+	return nil
+}
+
+func validDuration(value string) error {
+	if d, err := time.ParseDuration(strings.TrimSpace(value)); err != nil || d < 0 {
+		return fmt.Errorf("must be a duration like 30s or 2m")
+	}
+	return nil
+}
+
+func validTmpDir(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return fmt.Errorf("must be a directory")
+	}
+	if insideWorkTree(tmpDirPath(value)) {
+		return fmt.Errorf("must be outside the work tree, or under .git")
+	}
+	return nil
+}
+
+func validURL(value string) error {
+	u, err := url.Parse(strings.TrimSpace(value))
+	if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+		return fmt.Errorf("must be an http or https URL like https://github.example.com")
+	}
+	return nil
+}
+
+func validSquashWindow(value string) error {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == squashUntilPushed || value == "until pushed" {
+		return nil
+	}
+	if window, err := time.ParseDuration(value); err != nil || window < 0 {
+		return fmt.Errorf("must be a duration like 1h or %s", squashUntilPushed)
+	}
+	return nil
+}
+
+func validBranchName(value string) error {
+	if _, err := runGit("check-ref-format", "--branch", value); err != nil {
+		return fmt.Errorf("%q is not a valid branch name", value)
+	}
+	return nil
+}
+
+func validPatterns(value string) error {
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if _, err := path.Match(pattern, ""); err != nil {
+			return fmt.Errorf("invalid pattern %q", pattern)
+		}
+	}
+	return nil
+}
+
+func validRegexp(value string) error {
+	if _, err := regexp.Compile(value); err != nil {
+		return fmt.Errorf("invalid regular expression: %v", err)
+	}
+	return nil
+}
+
+func findConfigKey(key string) *configKey {
+	if !strings.HasPrefix(key, "prrompt.") {
+		key = "prrompt." + key
+	}
+	for i := range configKeys {
+		if strings.EqualFold(configKeys[i].Key, key) {
+			return &configKeys[i]
+		}
+	}
+	return nil
+}
+
+// runConfig implements `prrompt config list|get|set|validate`.
+func runConfig(args []string) error {
+	usage := fmt.Errorf("usage: %s config list | get <key> | set <key> <value> | validate", toolName)
+	if len(args) == 0 {
+		return usage
+	}
+	switch {
+	case args[0] == "list" && len(args) == 1:
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, key := range configKeys {
+			fmt.Fprintf(w, "%s\t%s\t%s\n", key.Key, key.Value(), configSource(key.Key))
+		}
+		return w.Flush()
+	case args[0] == "get" && len(args) == 2:
+		key := findConfigKey(args[1])
+		if key == nil {
+			return fmt.Errorf("unknown setting %s", args[1])
+		}
+		fmt.Println(key.Value())
+		return nil
+	case args[0] == "set" && len(args) == 3:
+		key := findConfigKey(args[1])
+		if key == nil {
+			return fmt.Errorf("unknown setting %s", args[1])
+		}
+		if validate := configValidators[key.Key]; validate != nil {
+			if err := validate(args[2]); err != nil {
+				return fmt.Errorf("%s: %w", key.Key, err)
+			}
+		}
+		gitArgs := []string{"config", key.Key, args[2]}
+		if multiValuedKeys[key.Key] {
+			gitArgs = []string{"config", "--replace-all", key.Key, args[2]}
+		}
+		if output, err := runGit(gitArgs...); err != nil {
+			return fmt.Errorf("failed to set %s: %s", key.Key, output)
+		}
+		fmt.Printf("%s = %s\n", key.Key, key.Value())
+		return nil
+	case args[0] == "validate" && len(args) == 1:
+		problems := validateConfig()
+		for _, problem := range problems {
+			fmt.Printf("  ✗ %s\n", problem)
+		}
+		if len(problems) > 0 {
+			return fmt.Errorf("%d configuration problems", len(problems))
+		}
+		fmt.Println("✓ Configuration is valid")
+		return nil
+	}
+	return usage
+}
+
+// validateConfig checks every prrompt setting from git config, the config
+// file and the environment against the schema, including keys prrompt
+// doesn't know.
+func validateConfig() []string {
+	var texts []string
+	for _, problem := range configProblems() {
+		texts = append(texts, problem.Text)
+	}
+	return texts
+}
+
+// invalidSettings returns the problems with settings prrompt knows; unknown
+// keys may be meant for another version.
+func invalidSettings() []string {
+	var texts []string
+	for _, problem := range configProblems() {
+		if !problem.Unknown {
+			texts = append(texts, problem.Text)
+		}
+	}
+	return texts
+}
+
+// configProblem is a setting validateConfig objects to.
+type configProblem struct {
+	Text    string
+	Unknown bool
+}
+
+func configProblems() []configProblem {
+	var problems []configProblem
+	check := func(key, value, source string) {
+		known := findConfigKey(key)
+		if known == nil {
+			problems = append(problems, configProblem{fmt.Sprintf("%s: unknown setting (%s)", key, source), true})
+			return
+		}
+		if validate := configValidators[known.Key]; validate != nil {
+			if err := validate(value); err != nil {
+				problems = append(problems, configProblem{fmt.Sprintf("%s: %v (%s)", known.Key, err, source), false})
+			}
+		}
+	}
+
+	if output, err := runGit("config", "--includes", "--get-regexp", `^prrompt\.`); err == nil {
+		for _, line := range strings.Split(output, "\n") {
+			key, value, _ := strings.Cut(line, " ")
+			if key != "" {
+				check(key, value, "git config")
+			}
+		}
+	}
+	if file, values := loadConfigFile(); file != "" {
+		keys := make([]string, 0, len(values))
+		for key := range values {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if findConfigKey("prrompt."+key) != nil && !repoFileKeys[key] {
+				problems = append(problems, configProblem{fmt.Sprintf("prrompt.%s: only read from git config, not the repository's config file (%s)", key, file), false})
+				continue
+			}
+			for _, value := range values[key] {
+				check("prrompt."+key, value, file)
+			}
+		}
+	}
+	for _, key := range configKeys {
+		for _, name := range configEnvNames(key.Key) {
+			if value, set := os.LookupEnv(name); set {
+				check(key.Key, value, name)
+			}
+		}
+	}
+	if value := os.Getenv(baseEnv); value != "" {
+		check("prrompt.baseBranch", value, baseEnv)
+	}
+	return problems
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ConfigSetAndValidate(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if err := runConfig([]string{"set", "dedupe", "warn"}); err != nil {
+		t.Fatalf("config set failed: %v", err)
+	}
+	if got := getDedupeMode(); got != dedupeWarn {
+		t.Errorf("Expected dedupe to be warn, got %s", got)
+	}
+	if err := runConfig([]string{"set", "dedupe", "sometimes"}); err == nil {
+		t.Error("Expected an invalid value to be refused")
+	}
+	if err := runConfig([]string{"set", "noSuchKey", "1"}); err == nil {
+		t.Error("Expected an unknown key to be refused")
+	}
+	if problems := validateConfig(); len(problems) != 0 {
+		t.Errorf("Expected a valid configuration, got %v", problems)
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.branchPrefix", "bad..prefix")
+	runGitInDir(repo.Dir, "config", "prrompt.lockTimeout", "-3")
+	os.WriteFile(filepath.Join(repo.Dir, ".prrompt.yaml"), []byte("promptPatterns: prompts/[\nunknownKey: 1\n"), 0644)
+	problems := strings.Join(validateConfig(), "\n")
+	for _, want := range []string{"prrompt.branchPrefix", "prrompt.lockTimeout", "prrompt.promptPatterns", "prrompt.unknownKey: unknown setting"} {
+		if !strings.Contains(problems, want) {
+			t.Errorf("Expected a problem with %s, got:\n%s", want, problems)
+		}
+	}
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// findDuplicate reports the first ref (the start point, usually the base
+// branch, then existing prompt branches) whose prompt files already match the commit's, or "" if none do.
+func findDuplicate(info *CommitInfo) (string, error) {
+	want := make(map[string]string, len(info.PromptFiles))
+	for _, file := range info.PromptFiles {
+		want[file] = blobAt(info.SHA, file)
+	}
+
+	refs := []string{info.localStartPoint()}
+	branches, err := runGit("for-each-ref", "--format=%(refname:short)", "refs/heads/"+getBranchPrefix()+"/")
+	if err != nil {
+		return "", fmt.Errorf("failed to list prompt branches: %w", err)
+	}
+	for _, branch := range strings.Split(branches, "\n") {
+		if branch != "" {
+			refs = append(refs, branch)
+		}
+	}
+
+	for _, ref := range refs {
+		if _, err := runGit("rev-parse", "--verify", "-q", ref); err != nil {
+			continue
+		}
+		if hasBlobs(ref, want) {
+			return ref, nil
+		}
+	}
+	return "", nil
+}
+
+func hasBlobs(ref string, want map[string]string) bool {
+	for file, blob := range want {
+		if blobAt(ref, file) != blob {
+			return false
+		}
+	}
+	return true
+}
+
+// blobAt returns the object id of path at ref, or "" when it does not exist.
+func blobAt(ref, path string) string {
+	blob, err := runGit("rev-parse", "--verify", "-q", ref+":"+path)
+	if err != nil {
+		return ""
+	}
+	return blob
+}
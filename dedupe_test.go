@@ -0,0 +1,115 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_DedupeExistingPromptBranch(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	firstSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, firstSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	// Re-commit the identical prompt change on another branch
+	runGitInDir(repo.Dir, "checkout", "-b", "other-branch", "main")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file again")
+	secondSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, secondSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	branches, _ := runGitInDir(repo.Dir, "branch", "--list", defaultBranchPrefix+"/*")
+	if strings.Contains(branches, secondSHA[:7]) {
+		t.Errorf("Duplicate prompt change should be skipped. Branches: %s", branches)
+	}
+
+	// force extracts regardless
+	runGitInDir(repo.Dir, "config", "prrompt.dedupe", "force")
+	if err := runPrrompt(t, repo.Dir, secondSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	branches, _ = runGitInDir(repo.Dir, "branch", "--list", defaultBranchPrefix+"/*")
+	if !strings.Contains(branches, secondSHA[:7]) {
+		t.Errorf("dedupe=force should extract duplicates. Branches: %s", branches)
+	}
+}
+
+func Test_DedupeContentOnBase(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	// Same prompt content lands on main first
+	runGitInDir(repo.Dir, "checkout", "main")
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt on main")
+
+	runGitInDir(repo.Dir, "checkout", repo.BranchName)
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt on feature")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	branches, _ := runGitInDir(repo.Dir, "branch", "--list", defaultBranchPrefix+"/*")
+	if branches != "" {
+		t.Errorf("Content already on base should be skipped. Branches: %s", branches)
+	}
+}
+
+func Test_AllowEmptyExtraction(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.allowEmptyExtraction", "true")
+
+	runGitInDir(repo.Dir, "checkout", "main")
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt on main")
+
+	runGitInDir(repo.Dir, "checkout", repo.BranchName)
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt on feature")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	promptBranch := defaultBranchPrefix + "/" + commitSHA[:7]
+	if changed, _ := runGitInDir(repo.Dir, "diff-tree", "--no-commit-id", "--name-only", "-r", promptBranch); changed != "" {
+		t.Errorf("Expected an empty extraction commit, got changes: %s", changed)
+	}
+	trailers, _ := runGitInDir(repo.Dir, "log", "--format=%(trailers:only,unfold)", "-n", "1", promptBranch)
+	if !strings.Contains(trailers, trailerDuplicateOf+": main") || !strings.Contains(trailers, trailerSourceCommit+": "+commitSHA) {
+		t.Errorf("Expected provenance and %s trailers, got: %s", trailerDuplicateOf, trailers)
+	}
+
+	// Reprocessing finds the commit's own branch and skips
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Errorf("Expected rerun to skip, got: %v", err)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// runDiff implements `prrompt diff [--stat] [<sha>]`: it prints what
+// extracting the commit would put on its prompt branch, the branch, the
+// final message and the diff from the start point, after the same checks
+// and filtering as the hook, without creating a branch or commit.
+func runDiff(w io.Writer, args []string) error {
+	rev, stat := "HEAD", false
+	var revs []string
+	for _, arg := range args {
+		switch {
+		case arg == "--stat":
+			stat = true
+		case strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("usage: %s diff [--stat] [<sha>]", toolName)
+		default:
+			revs = append(revs, arg)
+		}
+	}
+	if len(revs) > 1 {
+		return fmt.Errorf("usage: %s diff [--stat] [<sha>]", toolName)
+	} else if len(revs) == 1 {
+		rev = revs[0]
+	}
+	sha, err := runGit("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("unknown commit: %s", rev)
+	}
+
+	result := &Result{Status: statusSkipped, Commit: sha}
+	info, err := prepareCommit(sha, result)
+	if err != nil {
+		return err
+	}
+	if info == nil {
+		fmt.Fprintf(w, "Nothing would be extracted from %s (%s)\n", shortSHA(sha), result.Reason)
+		return nil
+	}
+
+	type part struct {
+		branch string
+		info   *CommitInfo
+	}
+	var parts []part
+	base := promptBranchName(info)
+	if groups := splitCommit(info); groups != nil {
+		fmt.Fprintf(w, "%s would be split into %d prompt branches (prrompt.splitBy=%s)\n\n", shortSHA(sha), len(groups), getSplitBy())
+		for _, group := range groups {
+			parts = append(parts, part{splitBranchName(base, group), group.infos[0]})
+		}
+	} else {
+		if session := sessionBranch(info); session != "" {
+			base = session
+			info.AppendTo = session
+		}
+		parts = append(parts, part{base, info})
+	}
+
+	for i, p := range parts {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		if err := previewExtraction(w, p.branch, p.info, stat); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// previewExtraction prints the extraction commit of info on promptBranch
+// as plumbingCommit would write it, and with prrompt.changelog=extract the
+// changelog commit that follows it.
+func previewExtraction(w io.Writer, promptBranch string, info *CommitInfo, stat bool) error {
+	onto, from := "", ""
+	if info.AppendTo != "" {
+		onto, _ = runGit("rev-parse", "--verify", "-q", "refs/heads/"+info.AppendTo)
+		from = "appended to its tip"
+	}
+	if start := info.localStartPoint(); onto == "" && start != emptyTree() {
+		var err error
+		if onto, err = runGit("rev-parse", "--verify", "-q", start+"^{commit}"); err != nil {
+			return fmt.Errorf("failed to resolve %s", start)
+		}
+		from = "from " + start
+	} else if onto == "" {
+		from = "as a new history"
+	}
+	if getTokenBudget() > 0 || getBoolConfig("prrompt.tokenCounts", false) {
+		info.TokenCounts = countPromptTokens(info)
+	}
+	tree, err := plumbingTree(info, onto)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(w, "Branch: %s (%s)\n\n", promptBranch, from)
+	for _, line := range strings.Split(signoff(buildCommitMessage(info)), "\n") {
+		fmt.Fprintln(w, strings.TrimRight("    "+line, " "))
+	}
+	if getAIEndpoint() != "" {
+		fmt.Fprintln(w, "    (and the model's summary of the prompt diff)")
+	}
+	fmt.Fprintln(w)
+
+	if onto == "" {
+		onto = emptyTree()
+	}
+	if ontoTree, _ := runGit("rev-parse", onto+"^{tree}"); tree == ontoTree {
+		fmt.Fprintf(w, "No prompt changes left on %s\n", shortSHA(onto))
+		return nil
+	}
+	args := []string{"diff", "-M", "--stat", onto, tree}
+	if !stat {
+		args = []string{"diff", "-M", "--stat", "--patch", onto, tree}
+	}
+	diff, err := runGit(args...)
+	if err != nil {
+		return fmt.Errorf("failed to diff %s: %s", promptBranch, truncate(diff, 200))
+	}
+	fmt.Fprintln(w, diff)
+
+	if getChangelogMode() != changelogExtract {
+		return nil
+	}
+	entries, err := extractionChangelogEntries(tree, []*CommitInfo{info})
+	if err != nil {
+		return err
+	}
+	changelogs, dirs, err := changelogTree(tree, entries)
+	if err != nil || changelogs == "" {
+		return err
+	}
+	fmt.Fprintf(w, "\nThen: %s\n\n", changelogSubject(dirs))
+	diff, err = runGit(append(args[:len(args)-2], tree, changelogs)...)
+	if err != nil {
+		return fmt.Errorf("failed to diff the changelogs: %s", truncate(diff, 200))
+	}
+	fmt.Fprintln(w, diff)
+	return nil
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_DiffPreview(t *testing.T) {
+	repo := setupTestRepo(t)
+	sha := commitFiles(t, repo.Dir, "Tune the greeting", map[string]string{
+		"prompts/greet.md": "Say hi\n",
+		"main.go":          "package main\n",
+	})
+	code := commitFiles(t, repo.Dir, "Change code", map[string]string{"util.go": "package main\n"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	before, _ := runGit("for-each-ref", "--format=%(refname)")
+	var out bytes.Buffer
+	if err := runDiff(&out, []string{sha}); err != nil {
+		t.Fatalf("runDiff failed: %v", err)
+	}
+	preview := out.String()
+	for _, want := range []string{
+		"Branch: " + promptBranchName(&CommitInfo{SHA: sha}) + " (from main)",
+		"    [prompt] Tune the greeting",
+		"    " + trailerSourceCommit + ": " + sha,
+		"+++ b/prompts/greet.md",
+		"+Say hi",
+	} {
+		if !strings.Contains(preview, want) {
+			t.Errorf("Expected %q in the preview, got:\n%s", want, preview)
+		}
+	}
+	if strings.Contains(preview, "main.go") {
+		t.Errorf("Expected the code file filtered out, got:\n%s", preview)
+	}
+	if after, _ := runGit("for-each-ref", "--format=%(refname)"); after != before {
+		t.Errorf("Expected no refs created, got:\n%s", after)
+	}
+
+	out.Reset()
+	if err := runDiff(&out, []string{"--stat", sha}); err != nil || strings.Contains(out.String(), "+Say hi") || !strings.Contains(out.String(), "prompts/greet.md | 1 +") {
+		t.Errorf("Expected only the stat, got %v:\n%s", err, out.String())
+	}
+
+	// The changelog commit extraction adds with prrompt.changelog=extract
+	runGitInDir(repo.Dir, "config", "prrompt.changelog", changelogExtract)
+	out.Reset()
+	if err := runDiff(&out, []string{sha}); err != nil {
+		t.Fatalf("runDiff failed: %v", err)
+	}
+	if preview := out.String(); !strings.Contains(preview, "Then: [prompt] Update the changelog of 1 skill") || !strings.Contains(preview, "+++ b/prompts/CHANGELOG.md") || !strings.Contains(preview, "Tune the greeting") {
+		t.Errorf("Expected the changelog commit in the preview, got:\n%s", preview)
+	}
+	runGitInDir(repo.Dir, "config", "--unset", "prrompt.changelog")
+
+	out.Reset()
+	if err := runDiff(&out, []string{code}); err != nil || !strings.Contains(out.String(), "Nothing would be extracted from "+code[:7]+" ("+reasonNoPromptFiles+")") {
+		t.Errorf("Expected nothing to extract from a code commit, got %v:\n%s", err, out.String())
+	}
+	if err := runDiff(&out, []string{"--patch"}); err == nil {
+		t.Error("Expected an unknown flag to fail")
+	}
+}
@@ -0,0 +1,390 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+)
+
+// minGitVersion is the oldest git with everything prrompt uses
+// (rev-parse --path-format).
+var minGitVersion = [2]int{2, 31}
+
+// Outcomes of a doctor check.
+const (
+	checkOK   = "ok"
+	checkWarn = "warn"
+	checkFail = "fail"
+)
+
+// doctorCheck is one environment check of `prrompt doctor`; Fix says what
+// to do when it did not pass.
+type doctorCheck struct {
+	Name   string
+	Status string
+	Detail string
+	Fix    string
+}
+
+// runDoctor prints diagnostics about the current repository's prrompt setup
+// and fails if any check does.
+func runDoctor() error {
+	if _, err := runGit("rev-parse", "--git-dir"); err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	fmt.Println("Configuration:")
+	if gitDir := os.Getenv("GIT_DIR"); gitDir != "" {
+		fmt.Printf("  GIT_DIR is set to %s\n", gitDir)
+	}
+	if file := worktreeConfigFile(); file != "" {
+		fmt.Printf("  Reading worktree config from %s\n", file)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, key := range configKeys {
+		fmt.Fprintf(w, "  %s\t%s\t%s\n", key.Key, key.Value(), configSource(key.Key))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	fmt.Println("\nChecks:")
+	failed := 0
+	for _, check := range doctorChecks() {
+		mark := "✓"
+		switch check.Status {
+		case checkWarn:
+			mark = "!"
+		case checkFail:
+			mark = "✗"
+			failed++
+		}
+		fmt.Printf("  %s %-18s %s\n", mark, check.Name, check.Detail)
+		if check.Status != checkOK && check.Fix != "" {
+			fmt.Printf("      Fix: %s\n", check.Fix)
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d checks failed", failed)
+	}
+	return nil
+}
+
+// doctorChecks runs every environment check.
+func doctorChecks() []doctorCheck {
+	checks := []doctorCheck{checkGitVersion()}
+	checks = append(checks, checkHooks()...)
+	checks = append(checks, checkConfigValid(), checkBaseBranch(), checkReplaceRefs(), checkRemote(), checkForge(), checkPRTool())
+	return append(checks, checkState()...)
+}
+
+// parseGitVersion returns the major and minor version of `git version`
+// output such as "git version 2.39.3 (Apple Git-145)".
+func parseGitVersion(output string) (major, minor int, ok bool) {
+	fields := strings.Fields(output)
+	if len(fields) < 3 {
+		return 0, 0, false
+	}
+	parts := strings.SplitN(fields[2], ".", 3)
+	if len(parts) < 2 {
+		return 0, 0, false
+	}
+	major, err1 := strconv.Atoi(parts[0])
+	minor, err2 := strconv.Atoi(parts[1])
+	return major, minor, err1 == nil && err2 == nil
+}
+
+func checkGitVersion() doctorCheck {
+	check := doctorCheck{Name: "git version", Status: checkOK}
+	output, _ := runGit("version")
+	major, minor, ok := parseGitVersion(output)
+	if !ok {
+		check.Status, check.Detail = checkWarn, fmt.Sprintf("could not parse %q", output)
+		return check
+	}
+	check.Detail = strings.TrimPrefix(output, "git version ")
+	if major < minGitVersion[0] || major == minGitVersion[0] && minor < minGitVersion[1] {
+		check.Status = checkFail
+		check.Fix = fmt.Sprintf("upgrade git to %d.%d or later", minGitVersion[0], minGitVersion[1])
+	}
+	return check
+}
+
+// hookCommand returns the program a hook script prrompt wrote runs, or "".
+// Hooks that look prrompt up on PATH first run it from there when they can.
+func hookCommand(script string) string {
+	for _, line := range strings.Split(script, "\n") {
+		if value, found := strings.CutPrefix(line, hookFallbackVar+"="); found {
+			if path, err := exec.LookPath(toolName); err == nil {
+				return path
+			}
+			return strings.ReplaceAll(strings.Trim(value, "'"), `'\''`, "'")
+		}
+		fields := strings.Fields(line)
+		if len(fields) > 1 && fields[0] == "exec" {
+			fields = fields[1:]
+		}
+		if len(fields) > 1 && (strings.Contains(line, `"$COMMIT_SHA"`) || fields[1] == "pre-commit") {
+			return fields[0]
+		}
+	}
+	return ""
+}
+
+// checkHook checks the named hook in the hooks directory git uses, which
+// core.hooksPath may move away from where `prrompt install` writes.
+func checkHook(name, hooksDir, installDir string) doctorCheck {
+	check := doctorCheck{Name: name + " hook", Status: checkFail}
+	hookPath := filepath.Join(hooksDir, name)
+	stat, err := os.Stat(hookPath)
+	if err != nil {
+		if hooksDir != installDir {
+			if data, err := os.ReadFile(filepath.Join(installDir, name)); err == nil && strings.Contains(string(data), toolName) {
+				check.Detail = fmt.Sprintf("core.hooksPath is %s, so the hook in %s never runs", hooksDir, installDir)
+				check.Fix = fmt.Sprintf("call %s from %s, or unset core.hooksPath", toolName, hookPath)
+				return check
+			}
+		}
+		check.Detail = "not installed"
+		check.Fix = fmt.Sprintf("run '%s install'", toolName)
+		return check
+	}
+	data, _ := os.ReadFile(hookPath)
+	if !strings.Contains(string(data), toolName) {
+		check.Status = checkWarn
+		check.Detail = fmt.Sprintf("%s does not run %s", hookPath, toolName)
+		check.Fix = fmt.Sprintf("add '%s \"$(git rev-parse HEAD)\"' to it", toolName)
+		return check
+	}
+	if stat.Mode()&0111 == 0 {
+		check.Detail = hookPath + " is not executable"
+		check.Fix = "chmod +x " + hookPath
+		return check
+	}
+	if command := hookCommand(string(data)); filepath.IsAbs(command) {
+		if _, err := os.Stat(command); err != nil {
+			check.Detail = fmt.Sprintf("runs %s, which does not exist", command)
+			check.Fix = fmt.Sprintf("run '%s install --repair'", toolName)
+			return check
+		}
+	}
+	check.Status, check.Detail = checkOK, hookPath
+	return check
+}
+
+func checkHooks() []doctorCheck {
+	if !getBoolConfig("prrompt.enabled", true) {
+		return []doctorCheck{{Name: "hooks", Status: checkOK, Detail: "prrompt.enabled is false, nothing is extracted here"}}
+	}
+	commonDir, _ := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	installDir := filepath.Join(commonDir, "hooks")
+	hooksDir, err := runGit("rev-parse", "--path-format=absolute", "--git-path", "hooks")
+	if err != nil {
+		hooksDir = installDir
+	}
+	checks := []doctorCheck{checkHook("post-commit", hooksDir, installDir)}
+	if getBoolConfig("prrompt.strict", false) {
+		checks = append(checks, checkHook("pre-commit", hooksDir, installDir))
+	}
+	if getBoolConfig("prrompt.postMerge", false) {
+		checks = append(checks, checkHook("post-merge", hooksDir, installDir))
+	}
+	return checks
+}
+
+func checkConfigValid() doctorCheck {
+	problems := validateConfig()
+	if len(problems) == 0 {
+		return doctorCheck{Name: "configuration", Status: checkOK, Detail: "valid"}
+	}
+	return doctorCheck{
+		Name:   "configuration",
+		Status: checkFail,
+		Detail: strings.Join(problems, "; "),
+		Fix:    fmt.Sprintf("correct them with '%s config set <key> <value>'", toolName),
+	}
+}
+
+func checkBaseBranch() doctorCheck {
+	check := doctorCheck{Name: "base branch", Status: checkFail}
+	base := getBaseBranch()
+	if base == "" {
+		check.Detail = "could not be detected"
+		check.Fix = "set prrompt.baseBranch"
+		return check
+	}
+	for _, ref := range []string{"refs/heads/" + base, "refs/remotes/" + getRemote() + "/" + base} {
+		if _, err := runGit("rev-parse", "--verify", "-q", ref); err == nil {
+			check.Status, check.Detail = checkOK, base
+			if staging := getStagingBranch(); staging != "" {
+				check.Detail += fmt.Sprintf(", through %s", staging)
+			}
+			return check
+		}
+	}
+	check.Detail = fmt.Sprintf("%s does not exist", base)
+	check.Fix = fmt.Sprintf("set prrompt.baseBranch, or fetch %s", base)
+	return check
+}
+
+func checkRemote() doctorCheck {
+	check := doctorCheck{Name: "remote", Status: checkOK}
+	remote := getRemote()
+	switch {
+	case !getBoolConfig("prrompt.push", true):
+		check.Detail = "pushing is disabled (prrompt.push=false)"
+	case !hasRemote(remote):
+		check.Status = checkWarn
+		check.Detail = fmt.Sprintf("no remote %q, prompt branches stay local", remote)
+		check.Fix = fmt.Sprintf("add it with 'git remote add %s <url>', or set prrompt.remote", remote)
+	case !remoteReachable(remote):
+		check.Status = checkWarn
+		check.Detail = fmt.Sprintf("%s is unreachable, pushes will be queued", remote)
+		check.Fix = fmt.Sprintf("check your connection, then run '%s push'", toolName)
+	default:
+		url, _ := runGit("ls-remote", "--get-url", remote)
+		check.Detail = fmt.Sprintf("%s (%s)", remote, withoutCredentials(url))
+	}
+	return check
+}
+
+func checkForge() doctorCheck {
+	check := doctorCheck{Name: "forge", Status: checkOK}
+	if repoPath := getGitHubRepoPath(); repoPath != "" {
+		check.Detail = "GitHub, " + repoPath
+		return check
+	}
+	url, err := gitConfig("--get", "remote."+getRemote()+".url")
+	switch {
+	case err != nil:
+		check.Detail = "none, no remote"
+	case strings.Contains(strings.ToLower(url), "gitlab"):
+		check.Detail = "GitLab"
+	default:
+		check.Status = checkWarn
+		check.Detail = "unknown, PR links are not generated"
+		check.Fix = fmt.Sprintf("open PRs for pushed branches by hand, or set prrompt.prTool=%s for GitLab", prToolGlab)
+	}
+	return check
+}
+
+func checkPRTool() doctorCheck {
+	check := doctorCheck{Name: "pr tool", Status: checkOK}
+	tool := getPRTool()
+	switch tool {
+	case prToolAPI:
+		if getGitHubToken() == "" {
+			check.Status = checkFail
+			check.Detail = "api, but neither GITHUB_TOKEN nor GH_TOKEN is set"
+			check.Fix = "export GITHUB_TOKEN, or set prrompt.prTool=" + prToolURL
+			return check
+		}
+		check.Detail = "api, with a token"
+	case prToolGH, prToolGlab:
+		if _, err := exec.LookPath(tool); err != nil {
+			check.Status = checkFail
+			check.Detail = fmt.Sprintf("%s is not installed", tool)
+			check.Fix = fmt.Sprintf("install %s, or set prrompt.prTool=%s", tool, prToolURL)
+			return check
+		}
+		check.Detail = tool
+	default:
+		check.Detail = tool
+		if getGitHubToken() == "" {
+			check.Detail += ", no GitHub token (process-pr uses unauthenticated rate limits)"
+		}
+	}
+	return check
+}
+
+// checkReplaceRefs reports replace refs and grafts, which make ancestry
+// checks see a history other than the recorded one.
+func checkReplaceRefs() doctorCheck {
+	check := doctorCheck{Name: "replace refs", Status: checkOK, Detail: "none"}
+	replaceRefs, grafts := replacedHistory()
+	var found []string
+	if replaceRefs > 0 {
+		found = append(found, fmt.Sprintf("%d replace refs", replaceRefs))
+	}
+	if grafts {
+		found = append(found, "a grafts file")
+	}
+	switch {
+	case len(found) == 0:
+	case grafts:
+		// Besides rewriting history, grafts make git print a deprecation
+		// hint into the output prrompt parses
+		check.Status = checkWarn
+		check.Detail = strings.Join(found, " and ") + " rewrite history"
+		check.Fix = "run 'git replace --convert-graft-file' to turn the grafts into replace refs"
+	case getReplaceRefs() == replaceRefsIgnore:
+		check.Detail = strings.Join(found, " and ") + ", ignored for ancestry checks"
+	default:
+		check.Status = checkWarn
+		check.Detail = strings.Join(found, " and ") + " rewrite history, ancestry checks follow them"
+		check.Fix = "set prrompt.replaceRefs=ignore to check the commits as recorded"
+	}
+	return check
+}
+
+// checkState looks for work left behind under .git/prrompt/: an interrupted
+// extraction, a stale lock and queued pushes.
+func checkState() []doctorCheck {
+	var checks []doctorCheck
+	journalCheck := doctorCheck{Name: "extraction", Status: checkOK, Detail: "none interrupted"}
+	if j, err := loadJournal(); err != nil {
+		journalCheck.Status, journalCheck.Detail = checkFail, err.Error()
+		journalCheck.Fix = fmt.Sprintf("run '%s recover', or delete the journal", toolName)
+	} else if j != nil && j.isRunning() {
+		journalCheck.Detail = fmt.Sprintf("%s in progress (pid %d)", j.PromptBranch, j.PID)
+	} else if j != nil {
+		journalCheck.Status = checkFail
+		journalCheck.Detail = fmt.Sprintf("extracting %s to %s was interrupted at %s", shortSHA(j.Commit), j.PromptBranch, j.Step)
+		journalCheck.Fix = fmt.Sprintf("run '%s recover'", toolName)
+	}
+	checks = append(checks, journalCheck)
+
+	if path, err := lockPath(); err == nil {
+		if data, err := os.ReadFile(path); err == nil {
+			lockCheck := doctorCheck{Name: "lock", Status: checkOK}
+			if pid, _ := strconv.Atoi(strings.TrimSpace(string(data))); processAlive(pid) {
+				lockCheck.Detail = fmt.Sprintf("held by pid %d", pid)
+			} else {
+				lockCheck.Status = checkWarn
+				lockCheck.Detail = fmt.Sprintf("%s was left by a process that is gone", path)
+				lockCheck.Fix = "nothing, the next run takes it over; or delete it"
+			}
+			checks = append(checks, lockCheck)
+		}
+	}
+
+	pushCheck := doctorCheck{Name: "pending pushes", Status: checkOK, Detail: "none"}
+	if pending, err := loadPendingPushes(); err != nil {
+		pushCheck.Status, pushCheck.Detail = checkFail, err.Error()
+		pushCheck.Fix = "delete the corrupt file"
+	} else if len(pending) > 0 {
+		pushCheck.Status = checkWarn
+		pushCheck.Detail = fmt.Sprintf("%d prompt branches waiting to be pushed", len(pending))
+		pushCheck.Fix = fmt.Sprintf("run '%s push'", toolName)
+	}
+	checks = append(checks, pushCheck)
+
+	if getRegistryURL() == "" {
+		return checks
+	}
+	outboxCheck := doctorCheck{Name: "registry outbox", Status: checkOK, Detail: "empty"}
+	if outbox, err := loadRegistryOutbox(); err != nil {
+		outboxCheck.Status, outboxCheck.Detail = checkFail, err.Error()
+		outboxCheck.Fix = "delete the corrupt file"
+	} else if len(outbox) > 0 {
+		outboxCheck.Status = checkWarn
+		outboxCheck.Detail = fmt.Sprintf("%d skill changes waiting to be published", len(outbox))
+		outboxCheck.Fix = fmt.Sprintf("check prrompt.registry.url is up, then run '%s push'", toolName)
+	}
+	return append(checks, outboxCheck)
+}
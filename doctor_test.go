@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ParseGitVersion(t *testing.T) {
+	tests := map[string][2]int{
+		"git version 2.43.0":                 {2, 43},
+		"git version 2.39.3 (Apple Git-145)": {2, 39},
+		"git version 2.45.1.windows.1":       {2, 45},
+	}
+	for output, want := range tests {
+		if major, minor, ok := parseGitVersion(output); !ok || major != want[0] || minor != want[1] {
+			t.Errorf("parseGitVersion(%q) = %d.%d, %v", output, major, minor, ok)
+		}
+	}
+	if _, _, ok := parseGitVersion("git"); ok {
+		t.Errorf("Expected garbage to be rejected")
+	}
+}
+
+func Test_CheckHook(t *testing.T) {
+	installDir, hooksDir := t.TempDir(), t.TempDir()
+	exe, _ := os.Executable()
+	script := "#!/bin/sh\n" + exe + " \"$COMMIT_SHA\" --branch \"$BRANCH\"\n"
+
+	if check := checkHook("post-commit", installDir, installDir); check.Status != checkFail || !strings.Contains(check.Fix, "install") {
+		t.Errorf("Expected a missing hook to fail, got %+v", check)
+	}
+	os.WriteFile(filepath.Join(installDir, "post-commit"), []byte(script), 0755)
+	if check := checkHook("post-commit", installDir, installDir); check.Status != checkOK {
+		t.Errorf("Expected the installed hook to pass, got %+v", check)
+	}
+	if check := checkHook("post-commit", hooksDir, installDir); check.Status != checkFail || !strings.Contains(check.Detail, "core.hooksPath") {
+		t.Errorf("Expected a core.hooksPath conflict, got %+v", check)
+	}
+
+	os.Chmod(filepath.Join(installDir, "post-commit"), 0644)
+	if check := checkHook("post-commit", installDir, installDir); check.Status != checkFail || !strings.HasPrefix(check.Fix, "chmod +x") {
+		t.Errorf("Expected a non-executable hook to fail, got %+v", check)
+	}
+	os.WriteFile(filepath.Join(installDir, "post-commit"), []byte("#!/bin/sh\n/gone/prrompt \"$COMMIT_SHA\"\n"), 0755)
+	os.Chmod(filepath.Join(installDir, "post-commit"), 0755)
+	if check := checkHook("post-commit", installDir, installDir); check.Status != checkFail || !strings.Contains(check.Detail, "/gone/prrompt") {
+		t.Errorf("Expected a hook running a missing binary to fail, got %+v", check)
+	}
+	os.WriteFile(filepath.Join(hooksDir, "post-commit"), []byte("#!/bin/sh\nmake lint\n"), 0755)
+	if check := checkHook("post-commit", hooksDir, installDir); check.Status != checkWarn {
+		t.Errorf("Expected a foreign hook to warn, got %+v", check)
+	}
+}
+
+func Test_DoctorChecks(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.prTool", "api")
+	runGitInDir(repo.Dir, "config", "prrompt.baseBranch", "trunk")
+	t.Setenv("GITHUB_TOKEN", "")
+	t.Setenv("GH_TOKEN", "")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if err := queuePendingPush(pendingPush{Branch: "prompt-update/abc", Remote: "origin"}); err != nil {
+		t.Fatal(err)
+	}
+	path, _ := journalPath()
+	os.MkdirAll(filepath.Dir(path), 0755)
+	os.WriteFile(path, []byte(`{"commit":"0123456789abcdef","promptBranch":"prompt-update/0123456","step":"cherry-picked","pid":999999999}`), 0644)
+
+	checks := make(map[string]doctorCheck)
+	for _, check := range doctorChecks() {
+		checks[check.Name] = check
+	}
+	for name, status := range map[string]string{
+		"git version":    checkOK,
+		"base branch":    checkFail,
+		"pr tool":        checkFail,
+		"extraction":     checkFail,
+		"pending pushes": checkWarn,
+		"configuration":  checkOK,
+	} {
+		if check := checks[name]; check.Status != status {
+			t.Errorf("Expected %s to be %s, got %+v", name, status, check)
+		}
+	}
+	if fix := checks["extraction"].Fix; !strings.Contains(fix, "recover") {
+		t.Errorf("Expected the interrupted extraction to point at recover, got %q", fix)
+	}
+	if err := runDoctor(); err == nil {
+		t.Errorf("Expected doctor to fail")
+	}
+}
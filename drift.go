@@ -0,0 +1,72 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// DriftFile is a prompt file whose working tree or index state differs from
+// HEAD.
+type DriftFile struct {
+	Status string // two-letter `git status --porcelain` code, e.g. " M" or "??"
+	Path   string
+}
+
+// findDrift lists uncommitted changes to prompt files, including untracked
+// ones.
+func findDrift() ([]DriftFile, error) {
+	// Not runGit: trimming would eat the leading space of the first status
+	output, err := exec.Command("git", "status", "--porcelain=v1", "-z", "--untracked-files=all").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get status: %w", err)
+	}
+
+	var drift []DriftFile
+	entries := strings.Split(string(output), "\x00")
+	for i := 0; i < len(entries); i++ {
+		entry := entries[i]
+		if len(entry) < 4 {
+			continue
+		}
+		status, file := entry[:2], entry[3:]
+		if status[0] == 'R' || status[0] == 'C' {
+			// Followed by the original path
+			i++
+		}
+		if isPromptFile(file) {
+			drift = append(drift, DriftFile{Status: status, Path: file})
+		}
+	}
+	return drift, nil
+}
+
+// runDrift implements `prrompt drift [--exit-code]`, reporting prompt edits
+// that were never committed.
+func runDrift(args []string) error {
+	exitCode := false
+	for _, arg := range args {
+		if arg != "--exit-code" {
+			return fmt.Errorf("unexpected argument: %s", arg)
+		}
+		exitCode = true
+	}
+
+	drift, err := findDrift()
+	if err != nil {
+		return err
+	}
+	if len(drift) == 0 {
+		fmt.Println("No uncommitted prompt changes")
+		return nil
+	}
+
+	fmt.Printf("Uncommitted prompt changes: %d\n", len(drift))
+	for _, file := range drift {
+		fmt.Printf("  %s %s\n", file.Status, file.Path)
+	}
+	if exitCode {
+		return fmt.Errorf("%d prompt files have uncommitted changes", len(drift))
+	}
+	return nil
+}
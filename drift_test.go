@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_findDrift(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	tracked := filepath.Join(repo.Dir, "prompts/tracked.md")
+	os.MkdirAll(filepath.Dir(tracked), 0755)
+	os.WriteFile(tracked, []byte("# Tracked"), 0644)
+	runGitInDir(repo.Dir, "add", tracked)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt")
+
+	os.WriteFile(tracked, []byte("# Tracked, edited"), 0644)
+	os.WriteFile(filepath.Join(repo.Dir, "prompts/new prompt.md"), []byte("# New"), 0644)
+	os.WriteFile(filepath.Join(repo.Dir, "main.go"), []byte("package main"), 0644)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	drift, err := findDrift()
+	if err != nil {
+		t.Fatalf("findDrift failed: %v", err)
+	}
+	want := map[string]string{"prompts/tracked.md": " M", "prompts/new prompt.md": "??"}
+	if len(drift) != len(want) {
+		t.Fatalf("Expected %d drifted files, got %v", len(want), drift)
+	}
+	for _, file := range drift {
+		if want[file.Path] != file.Status {
+			t.Errorf("Unexpected drift entry %q %q", file.Status, file.Path)
+		}
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"strings"
+)
+
+// defaultMaxFileSize keeps generated artifacts that match a prompt pattern
+// off prompt branches (prrompt.maxFileSize).
+const defaultMaxFileSize = 1 << 20
+
+// What prrompt.binaryFiles does with binary prompt files.
+const (
+	binarySkip    = "skip"
+	binaryWarn    = "warn"
+	binaryInclude = "include"
+)
+
+const defaultBinaryFiles = binaryWarn
+
+// binarySniffLen is how much of a file is checked for NUL bytes, as git
+// does to tell binary files from text.
+const binarySniffLen = 8000
+
+const lfsPointerPrefix = "version https://git-lfs.github.com/spec/v1\n"
+
+// lfsPointerMaxSize bounds the size of a Git LFS pointer file.
+const lfsPointerMaxSize = 1024
+
+// lfsChecked and lfsInstalled cache whether git-lfs is available.
+var lfsChecked, lfsInstalled bool
+
+// parseFileSize parses a size in bytes with an optional k, m or g suffix,
+// like git's integer config values.
+func parseFileSize(value string) (int64, error) {
+	value = strings.ToLower(strings.TrimSpace(value))
+	multiplier := int64(1)
+	switch {
+	case strings.HasSuffix(value, "k"):
+		multiplier = 1 << 10
+	case strings.HasSuffix(value, "m"):
+		multiplier = 1 << 20
+	case strings.HasSuffix(value, "g"):
+		multiplier = 1 << 30
+	}
+	if multiplier > 1 {
+		value = value[:len(value)-1]
+	}
+	size, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return size * multiplier, nil
+}
+
+// formatFileSize renders size the way prrompt.maxFileSize is written.
+func formatFileSize(size int64) string {
+	switch {
+	case size >= 1<<30 && size%(1<<30) == 0:
+		return fmt.Sprintf("%dg", size>>30)
+	case size >= 1<<20 && size%(1<<20) == 0:
+		return fmt.Sprintf("%dm", size>>20)
+	case size >= 1<<10 && size%(1<<10) == 0:
+		return fmt.Sprintf("%dk", size>>10)
+	}
+	return strconv.FormatInt(size, 10)
+}
+
+// getMaxFileSize returns prrompt.maxFileSize in bytes; 0 is no limit.
+func getMaxFileSize() int64 {
+	value, err := gitConfig("--get", "prrompt.maxFileSize")
+	if err != nil {
+		return defaultMaxFileSize
+	}
+	size, err := parseFileSize(value)
+	if err != nil {
+		return defaultMaxFileSize
+	}
+	return size
+}
+
+func getBinaryFiles() string {
+	value, _ := gitConfig("--get", "prrompt.binaryFiles")
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case binarySkip, binaryWarn, binaryInclude:
+		return value
+	}
+	return defaultBinaryFiles
+}
+
+// isBinaryBlob reports whether the start of the blob at spec has a NUL byte.
+func isBinaryBlob(spec string) bool {
+	cmd := exec.Command("git", "cat-file", "blob", spec)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return false
+	}
+	if err := cmd.Start(); err != nil {
+		return false
+	}
+	head := make([]byte, binarySniffLen)
+	n, _ := io.ReadFull(stdout, head)
+	cmd.Process.Kill()
+	cmd.Wait()
+	return bytes.IndexByte(head[:n], 0) >= 0
+}
+
+// admitPromptFile decides whether a prompt file changed by sha goes to the
+// prompt branch, given prrompt.maxFileSize and prrompt.binaryFiles, and
+// warns about the files it leaves out. Git LFS pointers are always small
+// text, so they are admitted; pushing the prompt branch then needs git-lfs
+// to upload their objects.
+func admitPromptFile(sha, file string) bool {
+	spec := sha + ":" + file
+	output, err := runGit("cat-file", "-s", spec)
+	if err != nil {
+		// Deleted files and submodules have no blob to check
+		return true
+	}
+	size, _ := strconv.ParseInt(output, 10, 64)
+	if size <= lfsPointerMaxSize {
+		if content, err := runGit("cat-file", "blob", spec); err == nil && strings.HasPrefix(content, lfsPointerPrefix) {
+			if !lfsChecked {
+				_, err := runGit("lfs", "version")
+				lfsChecked, lfsInstalled = true, err == nil
+			}
+			if !lfsInstalled {
+				warnf("%s is a Git LFS pointer but git-lfs is not installed, so its content cannot be pushed with the prompt branch", file)
+			}
+			return true
+		}
+	}
+	if limit := getMaxFileSize(); limit > 0 && size > limit {
+		warnf("leaving out %s: its %s bytes are over prrompt.maxFileSize (%s)", file, strconv.FormatInt(size, 10), formatFileSize(limit))
+		return false
+	}
+	mode := getBinaryFiles()
+	if mode == binaryInclude || !isBinaryBlob(spec) {
+		return true
+	}
+	if mode == binarySkip {
+		warnf("leaving out binary file %s (prrompt.binaryFiles=%s)", file, binarySkip)
+		return false
+	}
+	warnf("%s is a binary file; set prrompt.binaryFiles=%s to leave binaries out", file, binarySkip)
+	return true
+}
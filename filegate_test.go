@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_ParseFileSize(t *testing.T) {
+	tests := map[string]int64{"0": 0, "512": 512, "500k": 500 << 10, "2M": 2 << 20, "1g": 1 << 30}
+	for value, want := range tests {
+		if got, err := parseFileSize(value); err != nil || got != want {
+			t.Errorf("parseFileSize(%q) = %d, %v; expected %d", value, got, err, want)
+		}
+		if got, _ := parseFileSize(formatFileSize(want)); got != want {
+			t.Errorf("Expected %s to round-trip", formatFileSize(want))
+		}
+	}
+	for _, value := range []string{"", "big", "-1", "1t"} {
+		if _, err := parseFileSize(value); err == nil {
+			t.Errorf("Expected %q to be rejected", value)
+		}
+	}
+}
+
+func Test_AnalyzeGatesLargeAndBinaryFiles(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.maxFileSize", "1k")
+	sha := commitFiles(t, repo.Dir, "Add prompt assets", map[string]string{
+		"prompts/a.md":         "# A",
+		"prompts/fixture.json": strings.Repeat("x", 2000),
+		"prompts/logo.png":     "\x89PNG\r\n\x1a\n\x00\x00",
+		"prompts/big.bin":      "version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize 123456789\n",
+	})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	info, err := analyzeCommit(sha)
+	if err != nil {
+		t.Fatalf("analyzeCommit failed: %v", err)
+	}
+	if want := []string{"prompts/a.md", "prompts/big.bin", "prompts/logo.png"}; !reflect.DeepEqual(info.PromptFiles, want) {
+		t.Errorf("Expected binaries to be extracted with a warning by default, got %v", info.PromptFiles)
+	}
+	if want := []string{"prompts/fixture.json"}; !reflect.DeepEqual(info.SkippedFiles, want) || !reflect.DeepEqual(info.OtherFiles, want) {
+		t.Errorf("Expected the large file to be left out, got skipped %v, other %v", info.SkippedFiles, info.OtherFiles)
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.binaryFiles", "skip")
+	runGitInDir(repo.Dir, "config", "prrompt.maxFileSize", "0")
+	if info, err = analyzeCommit(sha); err != nil {
+		t.Fatalf("analyzeCommit failed: %v", err)
+	}
+	if want := []string{"prompts/a.md", "prompts/big.bin", "prompts/fixture.json"}; !reflect.DeepEqual(info.PromptFiles, want) {
+		t.Errorf("Expected only the binary to be left out, got %v", info.PromptFiles)
+	}
+	if want := []string{"prompts/logo.png"}; !reflect.DeepEqual(info.SkippedFiles, want) {
+		t.Errorf("Expected the binary to be skipped, got %v", info.SkippedFiles)
+	}
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runForeach runs a prrompt subcommand in every git repository matching the
+// --repos globs and prints a consolidated report. Each run happens inside its
+// repository, so per-repo configuration is honoured.
+func runForeach(args []string) error {
+	var patterns []string
+	var command []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--":
+			command = args[i+1:]
+			i = len(args)
+		case args[i] == "--repos" && i+1 < len(args):
+			patterns = append(patterns, args[i+1])
+			i++
+		case strings.HasPrefix(args[i], "--repos="):
+			patterns = append(patterns, strings.TrimPrefix(args[i], "--repos="))
+		default:
+			return fmt.Errorf("unexpected argument: %s", args[i])
+		}
+	}
+	if len(patterns) == 0 || len(command) == 0 {
+		return fmt.Errorf("usage: %s foreach --repos <glob> -- <command> [args...]", toolName)
+	}
+
+	repos, err := findRepos(patterns)
+	if err != nil {
+		return err
+	}
+	if len(repos) == 0 {
+		return fmt.Errorf("no git repositories match %s", strings.Join(patterns, ", "))
+	}
+
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+
+	var failed []string
+	for _, repo := range repos {
+		cmd := exec.Command(exePath, command...)
+		cmd.Dir = repo
+		output, err := cmd.CombinedOutput()
+
+		status := "ok"
+		if err != nil {
+			status = "failed"
+			failed = append(failed, repo)
+		}
+		fmt.Printf("==> %s (%s)\n", repo, status)
+		for _, line := range strings.Split(strings.TrimSpace(string(output)), "\n") {
+			if line != "" {
+				fmt.Printf("    %s\n", line)
+			}
+		}
+	}
+
+	fmt.Printf("\n%d repositories: %d ok, %d failed\n", len(repos), len(repos)-len(failed), len(failed))
+	if len(failed) > 0 {
+		return fmt.Errorf("%s failed in: %s", strings.Join(command, " "), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// findRepos expands the glob patterns (with ~ for the home directory) and
+// returns the matching directories that are git work trees.
+func findRepos(patterns []string) ([]string, error) {
+	seen := make(map[string]bool)
+	var repos []string
+	for _, pattern := range patterns {
+		if pattern == "~" || strings.HasPrefix(pattern, "~/") {
+			home, err := os.UserHomeDir()
+			if err != nil {
+				return nil, fmt.Errorf("failed to resolve home directory: %w", err)
+			}
+			pattern = filepath.Join(home, strings.TrimPrefix(pattern, "~"))
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", pattern, err)
+		}
+		for _, match := range matches {
+			if seen[match] {
+				continue
+			}
+			if info, err := os.Stat(match); err != nil || !info.IsDir() {
+				continue
+			}
+			cmd := exec.Command("git", "rev-parse", "--is-inside-work-tree")
+			cmd.Dir = match
+			if out, err := cmd.Output(); err != nil || strings.TrimSpace(string(out)) != "true" {
+				continue
+			}
+			seen[match] = true
+			repos = append(repos, match)
+		}
+	}
+	return repos, nil
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_findRepos(t *testing.T) {
+	root := t.TempDir()
+	for _, name := range []string{"alpha", "beta"} {
+		runGitInDir(root, "init", "-b", "main", name)
+	}
+	os.MkdirAll(filepath.Join(root, "not-a-repo"), 0755)
+	os.WriteFile(filepath.Join(root, "file.txt"), []byte("x"), 0644)
+
+	repos, err := findRepos([]string{filepath.Join(root, "*"), filepath.Join(root, "alpha")})
+	if err != nil {
+		t.Fatalf("findRepos failed: %v", err)
+	}
+
+	want := []string{filepath.Join(root, "alpha"), filepath.Join(root, "beta")}
+	if len(repos) != len(want) {
+		t.Fatalf("Expected repos %v, got %v", want, repos)
+	}
+	for i := range want {
+		if repos[i] != want[i] {
+			t.Errorf("Expected repos %v, got %v", want, repos)
+		}
+	}
+}
+
+func Test_ForeachRequiresCommand(t *testing.T) {
+	if err := runForeach([]string{"--repos", "/tmp/*"}); err == nil {
+		t.Error("foreach without a command should fail")
+	}
+}
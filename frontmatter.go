@@ -0,0 +1,32 @@
+package main
+
+import "strings"
+
+// parseFrontmatter returns the top-level `key: value` pairs of a YAML
+// frontmatter block delimited by "---" lines at the start of content. Nested
+// values and lists are not interpreted. ok is false when there is no
+// frontmatter.
+func parseFrontmatter(content string) (fields map[string]string, ok bool) {
+	content = strings.TrimPrefix(content, "\ufeff")
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) != "---" {
+		return nil, false
+	}
+
+	fields = make(map[string]string)
+	for _, line := range lines[1:] {
+		if strings.TrimSpace(line) == "---" {
+			return fields, true
+		}
+		if line == "" || line[0] == ' ' || line[0] == '\t' || line[0] == '#' {
+			continue
+		}
+		key, value, found := strings.Cut(line, ":")
+		if !found {
+			continue
+		}
+		fields[strings.TrimSpace(key)] = unquote(strings.TrimSpace(value))
+	}
+	// No closing delimiter
+	return nil, false
+}
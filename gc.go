@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Background `git gc` and `git maintenance` repack objects and pack refs
+// while a long backfill runs. A read can then miss an object that just
+// moved into a pack, and a ref update can find a packed-refs or ref lock
+// taken. Both go away on a retry.
+const gcRetries = 4
+
+var gcRetryDelay = 200 * time.Millisecond
+
+// transientGitErrors are the messages of failures that concurrent
+// maintenance causes and a retry fixes.
+var transientGitErrors = []string{
+	".lock': File exists",
+	"Another git process seems to be running",
+	"unable to read",
+	"bad object",
+	"is corrupt",
+	"objects/pack/",
+	"packed object",
+	"failed to read object",
+	"did not receive expected object",
+}
+
+func isTransientGitError(output string) bool {
+	for _, marker := range transientGitErrors {
+		if strings.Contains(output, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// errGCInterference marks failures that persisted through the retries.
+var errGCInterference = errors.New("git gc or git maintenance seems to be changing the repository at the same time")
+
+// retryGit runs a git command through run until it succeeds or fails for a
+// reason other than concurrent maintenance, waiting longer each time. When
+// the retries run out, the error says so and the output is git's last.
+func retryGit(run func() (string, error)) (string, error) {
+	output, err := run()
+	for attempt := 1; err != nil && isTransientGitError(output+err.Error()); attempt++ {
+		if attempt == gcRetries {
+			return output, fmt.Errorf("%w; rerun once it is done, or pause it with 'git maintenance stop'", errGCInterference)
+		}
+		verbosef("git failed, retrying in case of concurrent maintenance: %s", truncate(output, 200))
+		time.Sleep(gcRetryDelay * time.Duration(attempt))
+		output, err = run()
+	}
+	return output, err
+}
+
+// runGitRetry is runGit for plumbing reads and ref updates, retried while
+// concurrent maintenance interferes.
+func runGitRetry(args ...string) (string, error) {
+	return retryGit(func() (string, error) { return runGit(args...) })
+}
+
+// gcRunning returns the pid of a `git gc` running on this repository, from
+// the gc.pid file git keeps while it runs, or 0.
+func gcRunning() int {
+	commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return 0
+	}
+	data, err := os.ReadFile(filepath.Join(commonDir, "gc.pid"))
+	if err != nil {
+		return 0
+	}
+	// "<pid> <hostname>"
+	fields := strings.Fields(string(data))
+	if len(fields) == 0 {
+		return 0
+	}
+	hostname, _ := os.Hostname()
+	pid, _ := strconv.Atoi(fields[0])
+	if len(fields) > 1 && fields[1] != hostname || !processAlive(pid) {
+		return 0
+	}
+	return pid
+}
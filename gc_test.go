@@ -0,0 +1,65 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func Test_RetryGit(t *testing.T) {
+	defer func(delay time.Duration) { gcRetryDelay = delay }(gcRetryDelay)
+	gcRetryDelay = 0
+
+	calls := 0
+	output, err := retryGit(func() (string, error) {
+		if calls++; calls < 3 {
+			return "fatal: Unable to create '.git/packed-refs.lock': File exists.", errors.New("exit status 128")
+		}
+		return "ok", nil
+	})
+	if err != nil || output != "ok" || calls != 3 {
+		t.Errorf("Expected success on the third try, got %q, %v after %d calls", output, err, calls)
+	}
+
+	calls = 0
+	_, err = retryGit(func() (string, error) {
+		calls++
+		return "fatal: cannot lock ref 'refs/heads/x': reference already exists", errors.New("exit status 128")
+	})
+	if err == nil || errors.Is(err, errGCInterference) || calls != 1 {
+		t.Errorf("Expected a permanent failure not to be retried, got %v after %d calls", err, calls)
+	}
+
+	calls = 0
+	output, err = retryGit(func() (string, error) {
+		calls++
+		return "error: packed object 1234 (stored in .git/objects/pack/pack-1.pack) is corrupt", errors.New("exit status 128")
+	})
+	if !errors.Is(err, errGCInterference) || calls != gcRetries || output == "" {
+		t.Errorf("Expected a gc interference error after %d calls, got %v after %d", gcRetries, err, calls)
+	}
+}
+
+func Test_GCRunning(t *testing.T) {
+	repo := setupTestRepo(t)
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if pid := gcRunning(); pid != 0 {
+		t.Errorf("Expected no gc running, got pid %d", pid)
+	}
+	hostname, _ := os.Hostname()
+	pidFile := filepath.Join(repo.Dir, ".git", "gc.pid")
+	os.WriteFile(pidFile, []byte(fmt.Sprintf("%d %s", os.Getpid(), hostname)), 0644)
+	if pid := gcRunning(); pid != os.Getpid() {
+		t.Errorf("Expected gc pid %d, got %d", os.Getpid(), pid)
+	}
+	os.WriteFile(pidFile, []byte(fmt.Sprintf("%d other-host", os.Getpid())), 0644)
+	if pid := gcRunning(); pid != 0 {
+		t.Errorf("Expected a gc on another host to be ignored, got pid %d", pid)
+	}
+}
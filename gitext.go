@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// commandName is how prrompt was invoked, for usage messages: "git prrompt"
+// when run by git as the git-prrompt external command.
+var commandName = toolName
+
+// invocationName returns the command name for argv0.
+func invocationName(argv0 string) string {
+	if filepath.Base(argv0) == "git-"+toolName {
+		return "git " + toolName
+	}
+	return toolName
+}
+
+// parseGitOptions applies the options git takes before a command, so that
+// `prrompt -C dir ...` behaves like `git -C dir prrompt ...`: -C changes
+// directory (relative to the previous one when repeated) and -c sets a
+// config value for this run, for prrompt and every git command it runs.
+// prrompt never pages, so --no-pager and -P are accepted and ignored; as
+// `git prrompt`, git itself honors -p and pager.prrompt. It returns the
+// rest of the arguments.
+func parseGitOptions(args []string) ([]string, error) {
+	for len(args) > 0 {
+		switch arg := args[0]; {
+		case arg == "-C" || arg == "-c":
+			if len(args) < 2 {
+				return nil, fmt.Errorf("option %s needs a value", arg)
+			}
+			var err error
+			if arg == "-C" {
+				err = changeDir(args[1])
+			} else {
+				err = addConfigParameter(args[1])
+			}
+			if err != nil {
+				return nil, err
+			}
+			args = args[2:]
+		case strings.HasPrefix(arg, "-C") && len(arg) > 2:
+			if err := changeDir(arg[2:]); err != nil {
+				return nil, err
+			}
+			args = args[1:]
+		case arg == "--no-pager" || arg == "-P":
+			args = args[1:]
+		default:
+			return args, nil
+		}
+	}
+	return args, nil
+}
+
+func changeDir(dir string) error {
+	if dir == "" {
+		// As with git, -C "" stays put
+		return nil
+	}
+	if err := os.Chdir(dir); err != nil {
+		return fmt.Errorf("cannot change to %s: %w", dir, err)
+	}
+	return nil
+}
+
+// addConfigParameter passes name=value on to git the way `git -c` would,
+// through GIT_CONFIG_COUNT and friends, keeping values already set that
+// way. A name without a value sets a boolean to true.
+func addConfigParameter(param string) error {
+	name, value, found := strings.Cut(param, "=")
+	if !found {
+		value = "true"
+	}
+	if !strings.Contains(name, ".") || strings.HasPrefix(name, ".") || strings.HasSuffix(name, ".") {
+		return fmt.Errorf("invalid config key %q, expected <section>.<key>=<value>", name)
+	}
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	os.Setenv(fmt.Sprintf("GIT_CONFIG_KEY_%d", count), name)
+	os.Setenv(fmt.Sprintf("GIT_CONFIG_VALUE_%d", count), value)
+	os.Setenv("GIT_CONFIG_COUNT", strconv.Itoa(count+1))
+	return nil
+}
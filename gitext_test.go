@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func Test_InvocationName(t *testing.T) {
+	for argv0, want := range map[string]string{
+		"prrompt":                          "prrompt",
+		"/usr/local/bin/prrompt":           "prrompt",
+		"/usr/lib/git-core/../git-prrompt": "git prrompt",
+	} {
+		if got := invocationName(argv0); got != want {
+			t.Errorf("%s: expected %q, got %q", argv0, want, got)
+		}
+	}
+}
+
+func Test_ParseGitOptions(t *testing.T) {
+	repo := setupTestRepo(t)
+	os.MkdirAll(filepath.Join(repo.Dir, "sub"), 0755)
+	for _, name := range []string{"GIT_CONFIG_COUNT", "GIT_CONFIG_KEY_0", "GIT_CONFIG_VALUE_0", "GIT_CONFIG_KEY_1", "GIT_CONFIG_VALUE_1"} {
+		t.Setenv(name, "")
+	}
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+
+	args, err := parseGitOptions([]string{"-C", repo.Dir, "-Csub", "--no-pager", "-c", "prrompt.branchPrefix=experiments", "-c", "prrompt.fetchBase", "status", "-c", "x"})
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if want := []string{"status", "-c", "x"}; !reflect.DeepEqual(args, want) {
+		t.Errorf("Expected the command and its own arguments, got %v", args)
+	}
+	if dir, _ := os.Getwd(); filepath.Base(dir) != "sub" {
+		t.Errorf("Expected to be in %s/sub, got %s", repo.Dir, dir)
+	}
+	if prefix := getBranchPrefix(); prefix != "experiments" {
+		t.Errorf("Expected -c to set the branch prefix, got %q", prefix)
+	}
+	if value, _ := runGit("config", "prrompt.fetchBase"); value != "true" {
+		t.Errorf("Expected -c without a value to set true, got %q", value)
+	}
+
+	for _, bad := range [][]string{{"-C"}, {"-c", "nokey"}, {"-C", filepath.Join(repo.Dir, "missing")}} {
+		if _, err := parseGitOptions(bad); err == nil {
+			t.Errorf("%v: expected an error", bad)
+		}
+	}
+}
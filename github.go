@@ -0,0 +1,229 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const defaultGitHubAPIURL = "https://api.github.com"
+
+// githubAPIURL is the GitHub REST API root. Tests point it at a local server.
+var githubAPIURL = defaultGitHubAPIURL
+
+var httpClient = &http.Client{Timeout: 30 * time.Second}
+
+type PullRequest struct {
+	Number int    `json:"number"`
+	Title  string `json:"title"`
+	State  string `json:"state"`
+	Head   struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"head"`
+	Base struct {
+		Ref string `json:"ref"`
+		SHA string `json:"sha"`
+	} `json:"base"`
+}
+
+// getGitHubToken returns the token used for GitHub API calls; empty means
+// unauthenticated requests, which only work for public repositories.
+func getGitHubToken() string {
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		return token
+	}
+	return os.Getenv("GH_TOKEN")
+}
+
+func githubGet(path string, v any) error {
+	return githubDo(http.MethodGet, path, nil, v)
+}
+
+func githubPost(path string, body, v any) error {
+	return githubDo(http.MethodPost, path, body, v)
+}
+
+func githubPut(path string, body, v any) error {
+	return githubDo(http.MethodPut, path, body, v)
+}
+
+// githubQuota is the rate limit state reported by the last API response.
+var githubQuota struct {
+	Known     bool
+	Remaining int
+	Limit     int
+	Reset     time.Time
+}
+
+// errRateLimited is returned instead of making requests once the quota is
+// (nearly) used up, so callers can fall back rather than fail half-way.
+var errRateLimited = errors.New("GitHub API rate limit nearly exhausted")
+
+const defaultAPIReserve = 5
+
+// getAPIReserve returns the number of requests kept in reserve: optional
+// API work is skipped when no more than this many remain.
+func getAPIReserve() int {
+	value, err := gitConfig("--type=int", "--get", "prrompt.apiReserve")
+	if err != nil {
+		return defaultAPIReserve
+	}
+	reserve, err := strconv.Atoi(value)
+	if err != nil || reserve < 0 {
+		return defaultAPIReserve
+	}
+	return reserve
+}
+
+// checkGitHubQuota returns errRateLimited, with when the quota resets,
+// if the last response left no more than the reserve.
+func checkGitHubQuota() error {
+	if !githubQuota.Known || githubQuota.Remaining > getAPIReserve() || time.Now().After(githubQuota.Reset) {
+		return nil
+	}
+	return fmt.Errorf("%w (%d of %d left, resets at %s)", errRateLimited,
+		githubQuota.Remaining, githubQuota.Limit, githubQuota.Reset.Format(time.Kitchen))
+}
+
+func recordGitHubQuota(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	limit, _ := strconv.Atoi(resp.Header.Get("X-RateLimit-Limit"))
+	reset, _ := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	githubQuota.Known = true
+	githubQuota.Remaining = remaining
+	githubQuota.Limit = limit
+	githubQuota.Reset = time.Unix(reset, 0)
+	verbosef("GitHub API: %d of %d requests left, resets at %s", remaining, limit, githubQuota.Reset.Format(time.Kitchen))
+}
+
+// githubDo makes an API request, retrying network errors and server errors
+// with backoff. Rate limits are not waited out but returned as
+// errRateLimited.
+func githubDo(method, path string, body, v any) error {
+	return githubDoAt(getGitHubAPIURL(), method, path, body, v)
+}
+
+// githubDoAt is githubDo against the API at root.
+func githubDoAt(root, method, path string, body, v any) error {
+	if err := checkGitHubQuota(); err != nil {
+		return err
+	}
+
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return err
+		}
+	}
+	return withRetries(method+" "+path, func() error {
+		var payload io.Reader
+		if body != nil {
+			payload = bytes.NewReader(data)
+		}
+		req, err := http.NewRequest(method, root+path, payload)
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Accept", "application/vnd.github+json")
+		if body != nil {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		if token := getGitHubToken(); token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+
+		resp, err := httpClient.Do(req)
+		if err != nil {
+			return temporary(err)
+		}
+		defer resp.Body.Close()
+		recordGitHubQuota(resp)
+
+		rateLimited := resp.StatusCode == http.StatusTooManyRequests ||
+			(resp.StatusCode == http.StatusForbidden && (resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""))
+		if rateLimited {
+			return fmt.Errorf("%s %s: %w (%s)", method, path, errRateLimited, resp.Status)
+		}
+		if resp.StatusCode >= 500 {
+			return temporary(fmt.Errorf("%s %s: %s", method, path, resp.Status))
+		}
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s %s: %s", method, path, resp.Status)
+		}
+		if v == nil {
+			return nil
+		}
+		return json.NewDecoder(resp.Body).Decode(v)
+	})
+}
+
+func fetchPullRequest(repoPath string, number int) (*PullRequest, error) {
+	var pr PullRequest
+	if err := githubGet(fmt.Sprintf("/repos/%s/pulls/%d", repoPath, number), &pr); err != nil {
+		return nil, err
+	}
+	return &pr, nil
+}
+
+// createPullRequest opens a pull request, labels it and requests reviews
+// and assignees, and returns its web URL. Failing to triage is only a
+// warning since the PR exists by then.
+func createPullRequest(repoPath, base, head, title, body string, meta prMetadata) (string, error) {
+	request := map[string]string{"title": title, "head": head, "base": base, "body": body}
+	var created struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+		User    struct {
+			Login string `json:"login"`
+		} `json:"user"`
+	}
+	if err := githubPost(fmt.Sprintf("/repos/%s/pulls", repoPath), request, &created); err != nil {
+		return "", err
+	}
+	if err := checkGitHubQuota(); err != nil && (len(meta.Labels) > 0 || len(meta.Reviewers) > 0 || len(meta.Assignees) > 0) {
+		warnf("not triaging PR #%d: %v", created.Number, err)
+		return created.HTMLURL, nil
+	}
+
+	if len(meta.Labels) > 0 {
+		path := fmt.Sprintf("/repos/%s/issues/%d/labels", repoPath, created.Number)
+		if err := githubPost(path, map[string][]string{"labels": meta.Labels}, nil); err != nil {
+			warnf("failed to label PR #%d: %v", created.Number, err)
+		}
+	}
+	if len(meta.Reviewers) > 0 {
+		// org/team slugs are team reviewers, anything else a user. The
+		// author, often a code owner of their prompts, can't be requested.
+		reviewers := map[string][]string{"reviewers": {}, "team_reviewers": {}}
+		for _, reviewer := range meta.Reviewers {
+			if _, team, found := strings.Cut(reviewer, "/"); found {
+				reviewers["team_reviewers"] = append(reviewers["team_reviewers"], team)
+			} else if !strings.EqualFold(reviewer, created.User.Login) {
+				reviewers["reviewers"] = append(reviewers["reviewers"], reviewer)
+			}
+		}
+		path := fmt.Sprintf("/repos/%s/pulls/%d/requested_reviewers", repoPath, created.Number)
+		if err := githubPost(path, reviewers, nil); err != nil {
+			warnf("failed to request reviewers on PR #%d: %v", created.Number, err)
+		}
+	}
+	if len(meta.Assignees) > 0 {
+		path := fmt.Sprintf("/repos/%s/issues/%d/assignees", repoPath, created.Number)
+		if err := githubPost(path, map[string][]string{"assignees": meta.Assignees}, nil); err != nil {
+			warnf("failed to assign PR #%d: %v", created.Number, err)
+		}
+	}
+	return created.HTMLURL, nil
+}
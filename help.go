@@ -0,0 +1,425 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// helpFlag documents a flag of a command.
+type helpFlag struct {
+	Flag        string `json:"flag"`
+	Description string `json:"description"`
+}
+
+// helpExample is a runnable example: what it does and the command line.
+type helpExample struct {
+	Description string `json:"description"`
+	Command     string `json:"command"`
+}
+
+// helpCommand documents one command line form.
+type helpCommand struct {
+	Name     string        `json:"name"`
+	Usage    string        `json:"usage"`
+	Summary  string        `json:"summary"`
+	Flags    []helpFlag    `json:"flags,omitempty"`
+	Examples []helpExample `json:"examples,omitempty"`
+}
+
+// helpTopic groups the commands for one task, for `prrompt help <topic>`.
+type helpTopic struct {
+	Name     string        `json:"name"`
+	Summary  string        `json:"summary"`
+	Commands []helpCommand `json:"commands"`
+	Settings []string      `json:"settings,omitempty"`
+}
+
+var helpTopics = []helpTopic{
+	{
+		Name:    "extraction",
+		Summary: "Extract prompt changes of commits into prompt-only branches",
+		Commands: []helpCommand{
+			{
+				Name:    "process",
+				Usage:   "<sha>... | <from>..<to> [--combine|--by-skill]",
+				Summary: "Process commits, to a branch each, one combined branch or a branch per skill",
+				Flags: []helpFlag{
+					{"--combine", "Extract all commits to one combined branch"},
+					{"--by-skill", "Extract the commits to a branch per skill, with its net change and the commits it came from"},
+					{"--output=json", "Print the result as JSON (other output goes to stderr)"},
+					{"--result-file <path>", "Also write the JSON result to <path>"},
+					{"--base <ref>", "Base for this run only, over prrompt.baseBranch (also PRROMPT_BASE)"},
+					{"--mainline <n>", "Extract a merge commit against its parent <n> (also PRROMPT_MAINLINE)"},
+					{"--ai-summary", "Summarize the prompt changes with the model at prrompt.ai.endpoint (also prrompt.ai.summary)"},
+					{"--stay", "Stay on the prompt branch to keep editing it, instead of returning to the source branch (also prrompt.stayOnBranch)"},
+					{"--since-last-run", "Process the commits made on the current branch since the previous such run"},
+				},
+				Examples: []helpExample{
+					{"Process a specific commit", "prrompt abc1234"},
+					{"Extract the prompt changes of the last five commits to one branch", "prrompt HEAD~5..HEAD --combine"},
+					{"Backfill the history of main as one PR per skill", "prrompt main~500..main --by-skill"},
+					{"Process what was committed since the last cron run", "prrompt process --since-last-run"},
+				},
+			},
+			{
+				Name:    "process-pr",
+				Usage:   "<n>",
+				Summary: "Extract prompt changes of GitHub PR <n> into a branch",
+				Examples: []helpExample{
+					{"Extract prompts buried in a feature PR (uses GITHUB_TOKEN if set)", "prrompt process-pr 123"},
+				},
+			},
+			{
+				Name:    "ci",
+				Usage:   "[<commit|range>...]",
+				Summary: "Process pushed commits in CI; exits as processing commits does (see EXIT STATUS), and 2 when there is nothing to process",
+				Examples: []helpExample{
+					{"Process the commits of a GitHub Actions push", "prrompt ci"},
+				},
+			},
+			{
+				Name:    "serve",
+				Usage:   "[--addr <addr>] [--cache-dir <dir>]",
+				Summary: "Receive GitHub/GitLab push webhooks at /webhook and extract prompts centrally",
+				Flags: []helpFlag{
+					{"--addr <addr>", "Address to listen on (default \":8080\")"},
+					{"--cache-dir <dir>", "Where the repositories are cloned (default: the user cache directory)"},
+				},
+				Examples: []helpExample{
+					{"Serve webhooks signed with a shared secret", "PRROMPT_WEBHOOK_SECRET=s3cret prrompt serve --addr :9000"},
+				},
+			},
+			{
+				Name:    "push",
+				Usage:   "[--list | --retry-failed]",
+				Summary: "Push prompt branches queued while offline or after a failed push, and open their PRs",
+				Flags: []helpFlag{
+					{"--list", "Only list the queued branches, with why their push failed"},
+					{"--retry-failed", "Only push the branches whose push failed, not those queued offline"},
+				},
+			},
+			{
+				Name:    "promote",
+				Usage:   "[--dry-run]",
+				Summary: "Open a roll-up PR from prrompt.stagingBranch to the base branch with the skill changes accumulated there, or update the open one",
+				Flags:   []helpFlag{{"--dry-run", "Print the PR title and body instead"}},
+				Examples: []helpExample{
+					{"Promote the reviewed prompts every Monday, from cron", "0 9 * * 1 cd /srv/repo && prrompt promote"},
+				},
+			},
+			{
+				Name:    "changelog",
+				Usage:   "[--pr] [--dry-run] [<commit or range>...]",
+				Summary: "Add an entry per merged extraction to the CHANGELOG.md of each skill it changed, committed to the base branch or proposed in a PR",
+				Flags: []helpFlag{
+					{"--pr", "Open a PR with the changelogs instead of committing them to the base branch"},
+					{"--dry-run", "Print the entries instead"},
+				},
+				Examples: []helpExample{
+					{"Add what the last merge brought", "prrompt changelog HEAD"},
+					{"Backfill the changelogs since a release", "prrompt changelog v1.0..main"},
+				},
+			},
+			{
+				Name:    "queue",
+				Usage:   "add <sha>... | <from>..<to> | status | retry | clear",
+				Summary: "Queue commits of the current branch for `prrompt worker`, show the queue, requeue failed jobs or clear finished ones",
+				Examples: []helpExample{
+					{"Queue the history of main for a backfill", "prrompt queue add $(git rev-list --max-parents=0 HEAD)..main"},
+				},
+			},
+			{
+				Name:    "worker",
+				Usage:   "[--jobs <n>]",
+				Summary: "Extract the queued commits, n at a time, building prompt branches without checking them out; rerun it to resume after an interruption",
+				Flags:   []helpFlag{{"--jobs <n>", "Extractions to run at once (default: the number of CPUs)"}},
+				Examples: []helpExample{
+					{"Backfill with eight workers, then push the branches", "prrompt worker --jobs 8 && prrompt push"},
+				},
+			},
+			{
+				Name:    "split",
+				Usage:   "--staged [-m <message>]",
+				Summary: "Commit the staged changes as a commit of the other files and one of the prompt files",
+				Flags:   []helpFlag{{"-m <message>", "Message for both commits; the prompt one gets a \"(prompts)\" suffix"}},
+			},
+			{
+				Name:    "pre-commit",
+				Summary: "Fail commits mixing prompt and other files when prrompt.strict is set; run by the pre-commit hook",
+			},
+			{
+				Name:    "post-merge",
+				Usage:   "[--branch <name>] [--repo <path>]",
+				Summary: "Extract the prompt changes of the commits a merge or pull brought in when prrompt.postMerge is set; run by the post-merge hook",
+			},
+			{
+				Name:    "from-stash",
+				Usage:   "[<stash>]",
+				Summary: "Extract the prompt changes of a stash entry (default stash@{0}) to a prompt branch, leaving the stash alone",
+				Examples: []helpExample{
+					{"Rescue prompt edits saved in an older stash", "prrompt from-stash stash@{2}"},
+				},
+			},
+			{
+				Name:    "review",
+				Usage:   "[<sha>]",
+				Summary: "List the files of a commit (default HEAD) as classified, with their diffs; toggle which ones are extracted, edit the title and message, then extract",
+				Examples: []helpExample{
+					{"Extract a prompt the patterns missed", "prrompt review HEAD~1"},
+				},
+			},
+			{
+				Name:    "recover",
+				Summary: "Roll back an interrupted extraction",
+			},
+		},
+		Settings: []string{"prrompt.branchPrefix", "prrompt.branchName", "prrompt.baseBranch", "prrompt.rangeMode", "prrompt.splitBy", "prrompt.squashWindow", "prrompt.dedupe", "prrompt.lint", "prrompt.wipCommits"},
+	},
+	{
+		Name:    "setup",
+		Summary: "Set prrompt up in a repository and check it works",
+		Commands: []helpCommand{
+			{
+				Name:    "init",
+				Usage:   "[--yes]",
+				Summary: "Interactive first-time setup (config and hook)",
+				Flags:   []helpFlag{{"--yes", "Accept the detected defaults without asking"}},
+				Examples: []helpExample{
+					{"Set up with the detected defaults", "prrompt init --yes"},
+				},
+			},
+			{
+				Name:    "import-config",
+				Usage:   "--from <path|git-town|husky> [--output <file>] [--dry-run] [--force]",
+				Summary: "Write a .prrompt.yaml equivalent to a previous prompt-sync setup: a shell script, a YAML list of paths and branches, git-town or husky hooks, and list the settings without an equivalent",
+				Flags: []helpFlag{
+					{"--from", "A script or YAML file, or git-town or husky to read their configuration"},
+					{"--output", "The file to write (default: the repository's .prrompt.yaml)"},
+					{"--dry-run", "Print the configuration instead"},
+					{"--force", "Replace an existing configuration file"},
+				},
+				Examples: []helpExample{
+					{"Review what a sync script maps to", "prrompt import-config --from scripts/sync-prompts.sh --dry-run"},
+					{"Take over the husky hooks", "prrompt import-config --from husky"},
+				},
+			},
+			{
+				Name:    "install",
+				Usage:   "[--global] [--repair]",
+				Summary: "Install the git post-commit hook, and the pre-commit hook with prrompt.strict. The hooks find prrompt on PATH, falling back to the path it was installed from",
+				Flags: []helpFlag{
+					{"--global", "Install hooks for every repository through the global core.hooksPath; they still run each repository's own hooks"},
+					{"--repair", "Rewrite the hooks prrompt installed, such as older ones with a hard-coded path"},
+				},
+				Examples: []helpExample{
+					{"Install the hook", "prrompt install"},
+					{"Extract prompts in all repositories, except where prrompt.enabled is false", "prrompt install --global"},
+					{"Repair the hooks in every repository under ~/code", "prrompt foreach --repos '~/code/*' -- install --repair"},
+				},
+			},
+			{
+				Name:    "self-update",
+				Usage:   "[--check] [--force]",
+				Summary: "Replace this executable in place with the latest GitHub release for the platform when it is newer, so the path the hook runs stays current. The checksum it verifies comes from the same release, so it catches a corrupted download but not a tampered release",
+				Flags: []helpFlag{
+					{"--check", "Only report whether a newer release exists"},
+					{"--force", "Update development builds, reinstall the same version, or go back from a newer one"},
+				},
+			},
+			{
+				Name:    "version",
+				Summary: "Show the version, and the commit and date it was built from",
+			},
+			{
+				Name:    "simulate",
+				Summary: "Dry-run the pipeline on a scratch commit to check setup",
+			},
+			{
+				Name:    "presets",
+				Usage:   "list | show <name>",
+				Summary: "List built-in prompt layouts and how to apply one",
+				Examples: []helpExample{
+					{"Show the settings for Claude skills and commands", "prrompt presets show claude"},
+				},
+			},
+		},
+	},
+	{
+		Name:    "config",
+		Summary: "Show, change and check prrompt settings",
+		Commands: []helpCommand{
+			{
+				Name:    "config",
+				Usage:   "list | get <key> | set <key> <value> | validate",
+				Summary: "Show, change and check prrompt settings",
+				Examples: []helpExample{
+					{"Configure custom prompt patterns", "prrompt config set promptPatterns prompts/,.claude/skills/,docs/prompts/"},
+					{"Check git config, .prrompt.yaml and PRROMPT_* variables", "prrompt config validate"},
+				},
+			},
+			{
+				Name:    "match",
+				Usage:   "<path>... | --commit <sha>",
+				Summary: "Show for each path, or each file a commit changes, whether it is a prompt file, the pattern or control file that matched it and the exclusion that suppressed it",
+				Examples: []helpExample{
+					{"Find out why a file was not extracted", "prrompt match prompts/drafts/idea.md"},
+					{"Explain the extraction of the last commit", "prrompt match --commit HEAD"},
+				},
+			},
+			{
+				Name:    "diff",
+				Usage:   "[--stat] [<sha>]",
+				Summary: "Preview what extracting a commit (default HEAD) would put on its prompt branch: the branch and start point, the final commit message and the diff, after the same checks and filtering as the hook. No branch or commit is created",
+				Examples: []helpExample{
+					{"Check what the last commit would extract", "prrompt diff"},
+					{"List the files a commit would extract", "prrompt diff --stat 1a2b3c4"},
+				},
+			},
+			{
+				Name:    "show",
+				Usage:   "<branch|sha>:<path>",
+				Summary: "Print a prompt file as an extraction captured it, without a checkout. The revision is a prompt branch (with or without its prefix, here or on the remote), an extraction commit or a source commit; the path may be a mirror path, a skill directory or name, or the end of a path the extraction changed",
+				Examples: []helpExample{
+					{"Read the proposed skill of a prompt branch", "prrompt show prompt-update/1a2b3c4:.claude/skills/review/SKILL.md"},
+					{"Fetch what a commit's extraction captured, by skill name", "prrompt show 1a2b3c4:review > review.md"},
+				},
+			},
+			{
+				Name:    "doctor",
+				Summary: "Show effective configuration and where it comes from, then check the hook, git version, base branch, remote, forge, PR tool and leftover state, with a fix for each problem",
+			},
+		},
+	},
+	{
+		Name:    "workspace",
+		Summary: "Inspect prompt files and work across repositories",
+		Commands: []helpCommand{
+			{
+				Name:    "drift",
+				Usage:   "[--exit-code]",
+				Summary: "List prompt files with uncommitted changes",
+				Flags:   []helpFlag{{"--exit-code", "Exit with 1 when there are uncommitted prompt changes"}},
+			},
+			{
+				Name:    "status",
+				Summary: "Check created prompt branches were not force-pushed since",
+			},
+			{
+				Name:    "refs",
+				Usage:   "sync [--remote <name>]",
+				Summary: "Share prrompt metadata (refs/prrompt/*) with a remote",
+			},
+			{
+				Name:    "foreach",
+				Usage:   "--repos <glob> -- <command>",
+				Summary: "Run a prrompt command in every matching repository",
+				Examples: []helpExample{
+					{"Run a command across all repositories under ~/code", "prrompt foreach --repos '~/code/*' -- --version"},
+				},
+			},
+			{
+				Name:    "completion-server",
+				Summary: "Answer JSON line requests from editors: prompt roots, skill names, whether a path is a prompt",
+				Examples: []helpExample{
+					{"Ask whether a file will be extracted", `echo '{"id": 1, "method": "classify", "path": "prompts/review.md"}' | prrompt completion-server`},
+				},
+			},
+			{
+				Name:    "plugins",
+				Usage:   "list",
+				Summary: "List plugins (prrompt-<name> executables on PATH)",
+			},
+		},
+	},
+}
+
+// findHelp returns the topic named name, or a topic holding only the
+// command named name.
+func findHelp(name string) *helpTopic {
+	for i := range helpTopics {
+		if helpTopics[i].Name == name {
+			return &helpTopics[i]
+		}
+	}
+	for _, topic := range helpTopics {
+		for _, command := range topic.Commands {
+			if command.Name == name {
+				return &helpTopic{Name: command.Name, Summary: command.Summary, Commands: []helpCommand{command}}
+			}
+		}
+	}
+	return nil
+}
+
+// runHelp implements `prrompt help [<topic|command>]`.
+func runHelp(args []string) error {
+	if len(args) == 0 {
+		showHelp()
+		fmt.Println("\nTOPICS:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, topic := range helpTopics {
+			fmt.Fprintf(w, "    %s\t%s\n", topic.Name, topic.Summary)
+		}
+		w.Flush()
+		fmt.Printf("\nRun '%s help <topic>' or '%s help <command>' for details and examples\n", toolName, toolName)
+		return nil
+	}
+	topic := findHelp(args[0])
+	if topic == nil {
+		return fmt.Errorf("no help for %q; run '%s help' for the topics", args[0], toolName)
+	}
+	printHelpTopic(topic)
+	return nil
+}
+
+func printHelpTopic(topic *helpTopic) {
+	fmt.Printf("%s %s - %s\n", toolName, topic.Name, topic.Summary)
+	for _, command := range topic.Commands {
+		usage := toolName + " " + command.Name
+		if command.Name == "process" {
+			usage = toolName + " [process]"
+		}
+		if command.Usage != "" {
+			usage += " " + command.Usage
+		}
+		fmt.Printf("\n    %s\n        %s\n", usage, command.Summary)
+		if len(command.Flags) > 0 {
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, flag := range command.Flags {
+				fmt.Fprintf(w, "        %s\t%s\n", flag.Flag, flag.Description)
+			}
+			w.Flush()
+		}
+		for _, example := range command.Examples {
+			fmt.Printf("\n        # %s\n        %s\n", example.Description, example.Command)
+		}
+	}
+	if len(topic.Settings) > 0 {
+		fmt.Println("\nSETTINGS:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for _, key := range topic.Settings {
+			if known := findConfigKey(key); known != nil {
+				fmt.Fprintf(w, "    %s\t%s\n", known.Key, known.Value())
+			}
+		}
+		w.Flush()
+	}
+}
+
+// helpJSON is the document `prrompt --help-json` prints for tools that
+// build a UI around the CLI.
+func helpJSON() []byte {
+	settings := make([]string, 0, len(configKeys))
+	for _, key := range configKeys {
+		settings = append(settings, key.Key)
+	}
+	data, _ := json.MarshalIndent(struct {
+		Name     string      `json:"name"`
+		Version  string      `json:"version"`
+		Topics   []helpTopic `json:"topics"`
+		Settings []string    `json:"settings"`
+	}{toolName, getVersion(), helpTopics, settings}, "", "  ")
+	return append(data, '\n')
+}
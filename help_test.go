@@ -0,0 +1,43 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func Test_FindHelp(t *testing.T) {
+	if topic := findHelp("extraction"); topic == nil || len(topic.Commands) == 0 {
+		t.Fatalf("Expected the extraction topic, got %+v", topic)
+	}
+	topic := findHelp("ci")
+	if topic == nil || len(topic.Commands) != 1 || topic.Commands[0].Name != "ci" {
+		t.Fatalf("Expected help for the ci command, got %+v", topic)
+	}
+	if findHelp("nonexistent") != nil {
+		t.Error("Expected no help for an unknown name")
+	}
+	if err := runHelp([]string{"nonexistent"}); err == nil {
+		t.Error("Expected an error for an unknown topic")
+	}
+
+	for _, topic := range helpTopics {
+		for _, key := range topic.Settings {
+			if findConfigKey(key) == nil {
+				t.Errorf("Topic %s lists unknown setting %s", topic.Name, key)
+			}
+		}
+	}
+}
+
+func Test_HelpJSON(t *testing.T) {
+	var doc struct {
+		Name   string      `json:"name"`
+		Topics []helpTopic `json:"topics"`
+	}
+	if err := json.Unmarshal(helpJSON(), &doc); err != nil {
+		t.Fatalf("Expected valid JSON: %v", err)
+	}
+	if doc.Name != toolName || len(doc.Topics) != len(helpTopics) {
+		t.Errorf("Unexpected document: %+v", doc)
+	}
+}
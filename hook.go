@@ -0,0 +1,226 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// hookFallbackVar is the hook script variable holding the path prrompt was
+// installed from, used when prrompt is not on the hook's PATH (GUI clients
+// often run hooks with a minimal one).
+const hookFallbackVar = "PRROMPT_FALLBACK"
+
+// postCommitMarker identifies the post-commit hook prrompt installs.
+const postCommitMarker = "# Skill Extractor Post-Commit Hook"
+
+// globalHookMarker identifies the dispatcher `prrompt install --global`
+// writes under every hook name.
+const globalHookMarker = "# prrompt global hook"
+
+// globalHookNames are the hooks the dispatcher is installed under: the
+// ones prrompt runs from, and those git behaves the same for whether the
+// hook is missing or exits 0, to pass on the repository's own (setting
+// core.hooksPath globally hides them).
+var globalHookNames = []string{
+	"post-commit", "pre-commit", "post-merge",
+	"applypatch-msg", "pre-applypatch", "post-applypatch", "pre-merge-commit",
+	"prepare-commit-msg", "commit-msg", "pre-rebase", "post-checkout",
+	"pre-push", "pre-auto-gc", "post-rewrite", "sendemail-validate",
+	"p4-changelist", "p4-prepare-changelist", "p4-post-changelist", "p4-pre-submit",
+	"pre-receive", "update", "post-receive", "post-update",
+}
+
+// unsafeGlobalHookNames are hooks the dispatcher is never installed under:
+// their mere presence changes what git does (push-to-checkout replaces
+// updateInstead, proc-receive must speak its protocol), or git runs them on
+// every ref or index update. Repositories with their own lose them under a
+// global core.hooksPath.
+var unsafeGlobalHookNames = []string{"push-to-checkout", "proc-receive", "reference-transaction", "post-index-change", "fsmonitor-watchman"}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// hookLookup is the start of every hook prrompt writes: it finds prrompt on
+// PATH, else at exePath, and otherwise explains how to fix the hook without
+// failing the git command.
+func hookLookup(exePath string) string {
+	return fmt.Sprintf(`%[1]s=%[2]s
+PRROMPT=$(command -v %[3]s || true)
+[ -n "$PRROMPT" ] || PRROMPT="$%[1]s"
+if [ ! -x "$PRROMPT" ]; then
+	echo "%[3]s: not found on PATH or at $%[1]s, prompts were not extracted; reinstall it, then run '%[3]s install --repair'" >&2
+	exit 0
+fi
+`, hookFallbackVar, shellQuote(exePath), toolName)
+}
+
+func postCommitHookScript(exePath string) string {
+	return `#!/bin/sh
+` + postCommitMarker + `
+
+` + hookLookup(exePath) + `
+COMMIT_SHA=$(git rev-parse HEAD)
+BRANCH=$(git symbolic-ref --quiet --short HEAD)
+REPO=$(git rev-parse --show-toplevel)
+"$PRROMPT" "$COMMIT_SHA" --branch "$BRANCH" --repo "$REPO"
+`
+}
+
+func preCommitHookScript(exePath string) string {
+	return "#!/bin/sh\n" + preCommitMarker + "\n\n" + hookLookup(exePath) + "\nexec \"$PRROMPT\" pre-commit\n"
+}
+
+// isPrromptHook reports whether script is a hook prrompt wrote.
+func isPrromptHook(name, script string) bool {
+	switch name {
+	case "pre-commit":
+		return strings.Contains(script, preCommitMarker)
+	case "post-merge":
+		return strings.Contains(script, postMergeMarker)
+	}
+	return strings.Contains(script, postCommitMarker)
+}
+
+// repairHooks rewrites the hooks prrompt installed in hooksDir with the
+// current script and executable path, e.g. after the binary moved. Hooks of
+// other tools are left alone.
+func repairHooks(hooksDir, exePath string) error {
+	scripts := map[string]string{
+		"post-commit": postCommitHookScript(exePath),
+		"pre-commit":  preCommitHookScript(exePath),
+		"post-merge":  postMergeHookScript(exePath),
+	}
+	repaired := 0
+	for _, name := range []string{"post-commit", "pre-commit", "post-merge"} {
+		hookPath := filepath.Join(hooksDir, name)
+		data, err := os.ReadFile(hookPath)
+		if err != nil || !isPrromptHook(name, string(data)) {
+			continue
+		}
+		if string(data) == scripts[name] {
+			fmt.Printf("✓ %s is up to date\n", hookPath)
+			continue
+		}
+		if err := os.WriteFile(hookPath, []byte(scripts[name]), 0755); err != nil {
+			return fmt.Errorf("failed to write hook: %w", err)
+		}
+		os.Chmod(hookPath, 0755)
+		repaired++
+		fmt.Printf("✓ Repaired %s\n", hookPath)
+	}
+	if repaired == 0 {
+		if _, err := os.Stat(filepath.Join(hooksDir, "post-commit")); err != nil {
+			return fmt.Errorf("no %s hook installed in %s, run '%s install'", toolName, hooksDir, toolName)
+		}
+	}
+	return nil
+}
+
+// globalHookScript is the dispatcher installed under every name in the
+// global hooks directory. It runs the repository's own hook first; a
+// failing pre-commit hook stops the commit before prrompt runs, and a hook
+// from `prrompt install` runs prrompt itself. Squash merges are left to the
+// post-commit hook of the commit that follows.
+func globalHookScript(exePath string) string {
+	return `#!/bin/sh
+` + globalHookMarker + `
+
+HOOK=$(basename "$0")
+LOCAL="$(git rev-parse --git-common-dir)/hooks/$HOOK"
+[ -x "$LOCAL" ] && [ ! "$LOCAL" -ef "$0" ] || LOCAL=
+case "$HOOK" in
+post-commit|pre-commit|post-merge) ;;
+*)
+	[ -z "$LOCAL" ] || exec "$LOCAL" "$@"
+	exit 0
+	;;
+esac
+if [ -n "$LOCAL" ]; then
+	"$LOCAL" "$@"
+	STATUS=$?
+	if [ "$HOOK" = pre-commit ] && [ "$STATUS" -ne 0 ]; then
+		exit "$STATUS"
+	fi
+	if grep -q -e '` + postCommitMarker + `' -e '` + preCommitMarker + `' -e '` + postMergeMarker + `' "$LOCAL"; then
+		exit 0
+	fi
+fi
+
+` + hookLookup(exePath) + `
+if [ "$HOOK" = pre-commit ]; then
+	exec "$PRROMPT" pre-commit
+fi
+BRANCH=$(git symbolic-ref --quiet --short HEAD)
+REPO=$(git rev-parse --show-toplevel)
+if [ "$HOOK" = post-merge ]; then
+	[ "$1" = 1 ] && exit 0
+	exec "$PRROMPT" post-merge --branch "$BRANCH" --repo "$REPO"
+fi
+COMMIT_SHA=$(git rev-parse HEAD)
+"$PRROMPT" "$COMMIT_SHA" --branch "$BRANCH" --repo "$REPO"
+`
+}
+
+// globalHooksDir returns where `prrompt install --global` puts the
+// dispatcher: $XDG_CONFIG_HOME/prrompt/hooks, else ~/.config/prrompt/hooks.
+func globalHooksDir() (string, error) {
+	if dir := os.Getenv("XDG_CONFIG_HOME"); dir != "" {
+		return filepath.Join(dir, toolName, "hooks"), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".config", toolName, "hooks"), nil
+}
+
+// installGlobalHooks implements `prrompt install --global`: it writes the
+// dispatcher to the global hooks directory and points the global
+// core.hooksPath at it, so every repository runs prrompt without its own
+// install. A core.hooksPath set to another directory is left alone.
+func installGlobalHooks(exePath string) error {
+	hooksDir, err := globalHooksDir()
+	if err != nil {
+		return fmt.Errorf("failed to find the global hooks directory: %w", err)
+	}
+	current, _ := runGit("config", "--global", "--type=path", "--get", "core.hooksPath")
+	if current != "" && filepath.Clean(current) != hooksDir {
+		return fmt.Errorf("core.hooksPath is already set globally to %s; unset it, or call %s from the post-commit hook there", current, toolName)
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", hooksDir, err)
+	}
+	script := []byte(globalHookScript(exePath))
+	for _, name := range globalHookNames {
+		hookPath := filepath.Join(hooksDir, name)
+		if err := os.WriteFile(hookPath, script, 0755); err != nil {
+			return fmt.Errorf("failed to write hook: %w", err)
+		}
+		os.Chmod(hookPath, 0755)
+	}
+	// Earlier versions installed the dispatcher under these too
+	for _, name := range unsafeGlobalHookNames {
+		hookPath := filepath.Join(hooksDir, name)
+		if data, err := os.ReadFile(hookPath); err == nil && strings.Contains(string(data), globalHookMarker) {
+			os.Remove(hookPath)
+		}
+	}
+	if output, err := runGit("config", "--global", "core.hooksPath", hooksDir); err != nil {
+		return fmt.Errorf("failed to set core.hooksPath: %s", output)
+	}
+	fmt.Printf("✓ Installed global hooks in %s and set core.hooksPath\n", hooksDir)
+	fmt.Printf("Repositories run their own hooks as before. Opt one out with 'git config %s.enabled false'\n", toolName)
+	fmt.Printf("Repositories that set their own core.hooksPath, e.g. with husky, need to call %s from its post-commit hook\n", toolName)
+	if commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir"); err == nil {
+		for _, name := range unsafeGlobalHookNames {
+			if info, err := os.Stat(filepath.Join(commonDir, "hooks", name)); err == nil && info.Mode()&0111 != 0 {
+				warnf("this repository's %s hook no longer runs; set core.hooksPath to .git/hooks here and call %s from its post-commit hook", name, toolName)
+			}
+		}
+	}
+	return nil
+}
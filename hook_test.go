@@ -0,0 +1,188 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// fakePrrompt writes an executable appending its arguments to dir/args.
+func fakePrrompt(t *testing.T, dir string) string {
+	t.Helper()
+	path := filepath.Join(dir, toolName)
+	script := "#!/bin/sh\necho \"$0 $*\" >> " + filepath.Join(dir, "args") + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func runHookScript(t *testing.T, dir, script, path string) (string, error) {
+	t.Helper()
+	hookPath := filepath.Join(t.TempDir(), "post-commit")
+	os.WriteFile(hookPath, []byte(script), 0755)
+	cmd := exec.Command("/bin/sh", hookPath)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "PATH="+path)
+	output, err := cmd.CombinedOutput()
+	return string(output), err
+}
+
+func Test_PostCommitHookLookup(t *testing.T) {
+	repo := setupTestRepo(t)
+	gitDir := filepath.Dir(mustLookPath(t, "git"))
+	fallbackDir, pathDir := t.TempDir(), t.TempDir()
+	fallback := fakePrrompt(t, fallbackDir)
+	script := postCommitHookScript(fallback)
+
+	// Not on PATH: the installed path is used
+	if output, err := runHookScript(t, repo.Dir, script, gitDir); err != nil {
+		t.Fatalf("hook failed: %v: %s", err, output)
+	}
+	args, _ := os.ReadFile(filepath.Join(fallbackDir, "args"))
+	if !strings.HasPrefix(string(args), fallback+" ") || !strings.Contains(string(args), "--branch feature-branch --repo "+repo.Dir) {
+		t.Errorf("Expected the fallback to run with the hook arguments, got %q", args)
+	}
+
+	// On PATH: it wins over the installed path
+	onPath := fakePrrompt(t, pathDir)
+	if output, err := runHookScript(t, repo.Dir, script, pathDir+":"+gitDir); err != nil {
+		t.Fatalf("hook failed: %v: %s", err, output)
+	}
+	if args, _ := os.ReadFile(filepath.Join(pathDir, "args")); !strings.HasPrefix(string(args), onPath+" ") {
+		t.Errorf("Expected prrompt from PATH to run, got %q", args)
+	}
+
+	// Nowhere: a clear message, without failing the commit
+	output, err := runHookScript(t, repo.Dir, postCommitHookScript("/gone/prrompt"), gitDir)
+	if err != nil || !strings.Contains(output, "not found on PATH or at /gone/prrompt") || !strings.Contains(output, "install --repair") {
+		t.Errorf("Expected a hint and exit status 0, got %v: %q", err, output)
+	}
+}
+
+func mustLookPath(t *testing.T, name string) string {
+	t.Helper()
+	path, err := exec.LookPath(name)
+	if err != nil {
+		t.Skipf("%s not found", name)
+	}
+	return path
+}
+
+func Test_RepairHooks(t *testing.T) {
+	hooksDir := t.TempDir()
+	if err := repairHooks(hooksDir, "/new/prrompt"); err == nil {
+		t.Errorf("Expected repairing without a hook to fail")
+	}
+
+	old := "#!/bin/sh\n# Skill Extractor Post-Commit Hook\n\nCOMMIT_SHA=$(git rev-parse HEAD)\n/old/prrompt \"$COMMIT_SHA\"\n"
+	os.WriteFile(filepath.Join(hooksDir, "post-commit"), []byte(old), 0755)
+	foreign := "#!/bin/sh\nnpx lint-staged\n"
+	os.WriteFile(filepath.Join(hooksDir, "pre-commit"), []byte(foreign), 0755)
+
+	if err := repairHooks(hooksDir, "/new/prrompt"); err != nil {
+		t.Fatalf("repair failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(hooksDir, "post-commit")); string(data) != postCommitHookScript("/new/prrompt") {
+		t.Errorf("Expected the post-commit hook to be rewritten, got %q", data)
+	}
+	if data, _ := os.ReadFile(filepath.Join(hooksDir, "pre-commit")); string(data) != foreign {
+		t.Errorf("Expected another tool's hook to be left alone")
+	}
+}
+
+func Test_ShellQuote(t *testing.T) {
+	if got := shellQuote("/Users/o'neil/bin/prrompt"); got != `'/Users/o'\''neil/bin/prrompt'` {
+		t.Errorf("Unexpected quoting %s", got)
+	}
+}
+
+func Test_InstallGlobalHooks(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("XDG_CONFIG_HOME", filepath.Join(home, ".config"))
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(home, ".gitconfig"))
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "--unset", "core.hooksPath")
+	exeDir := t.TempDir()
+	exe := fakePrrompt(t, exeDir)
+
+	if err := installGlobalHooks(exe); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+	hooksDir := filepath.Join(home, ".config", "prrompt", "hooks")
+	if value, _ := runGitInDir(repo.Dir, "config", "--global", "core.hooksPath"); value != hooksDir {
+		t.Errorf("Expected the global core.hooksPath to be %s, got %q", hooksDir, value)
+	}
+
+	// Hooks whose presence changes git's behavior are not written, and
+	// ones an earlier version wrote are removed
+	os.WriteFile(filepath.Join(hooksDir, "reference-transaction"), []byte(globalHookScript(exe)), 0755)
+	if err := installGlobalHooks(exe); err != nil {
+		t.Fatalf("reinstall failed: %v", err)
+	}
+	for _, name := range unsafeGlobalHookNames {
+		if _, err := os.Stat(filepath.Join(hooksDir, name)); err == nil {
+			t.Errorf("Expected no global %s hook", name)
+		}
+	}
+
+	// The repository's own hooks still run, before prrompt
+	localHooks := filepath.Join(repo.Dir, ".git", "hooks")
+	os.MkdirAll(localHooks, 0755)
+	os.WriteFile(filepath.Join(localHooks, "pre-commit"), []byte("#!/bin/sh\ntouch \"$(git rev-parse --git-dir)/ran-pre-commit\"\n"), 0755)
+	os.WriteFile(filepath.Join(localHooks, "commit-msg"), []byte("#!/bin/sh\necho checked >> \"$1\"\n"), 0755)
+	commit := func() error {
+		cmd := exec.Command("git", "commit", "--allow-empty", "-m", "Change")
+		cmd.Dir = repo.Dir
+		cmd.Env = append(os.Environ(), "PATH="+filepath.Dir(mustLookPath(t, "git"))+":/usr/bin:/bin")
+		return cmd.Run()
+	}
+	if err := commit(); err != nil {
+		t.Fatalf("commit failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(repo.Dir, ".git", "ran-pre-commit")); err != nil {
+		t.Errorf("Expected the repository's pre-commit hook to run")
+	}
+	if message, _ := runGitInDir(repo.Dir, "log", "-1", "--format=%B"); !strings.Contains(message, "checked") {
+		t.Errorf("Expected the repository's commit-msg hook to run, got %q", message)
+	}
+	sha, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	args, _ := os.ReadFile(filepath.Join(exeDir, "args"))
+	if !strings.Contains(string(args), exe+" pre-commit\n") || !strings.Contains(string(args), exe+" "+sha+" --branch feature-branch") {
+		t.Errorf("Expected prrompt to run from both hooks, got %q", args)
+	}
+
+	// A failing pre-commit hook stops the commit before prrompt runs
+	os.Remove(filepath.Join(exeDir, "args"))
+	os.WriteFile(filepath.Join(localHooks, "pre-commit"), []byte("#!/bin/sh\nexit 3\n"), 0755)
+	if err := commit(); err == nil {
+		t.Errorf("Expected the failing pre-commit hook to stop the commit")
+	}
+	if _, err := os.Stat(filepath.Join(exeDir, "args")); err == nil {
+		t.Errorf("Expected prrompt not to run after a failing pre-commit hook")
+	}
+
+	// A hooks path set by something else is not taken over
+	runGitInDir(repo.Dir, "config", "--global", "core.hooksPath", "/elsewhere")
+	if err := installGlobalHooks(exe); err == nil || !strings.Contains(err.Error(), "/elsewhere") {
+		t.Errorf("Expected an existing core.hooksPath to be refused, got %v", err)
+	}
+}
+
+func Test_Disabled(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.enabled", "false")
+	sha := commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/a.md": "# A"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(sha)
+	if err != nil || result.Status != statusSkipped || result.Reason != reasonDisabled {
+		t.Errorf("Expected the commit to be skipped as disabled, got %+v, %v", result, err)
+	}
+}
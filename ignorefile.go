@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Per-directory control files, read from the work tree. Patterns apply to
+// the directory they are in and everything below it, with gitignore-like
+// semantics: a pattern without a slash matches a file or directory name at
+// any depth, a leading slash anchors it to the directory, and a trailing
+// slash matches directories only.
+const (
+	promptIgnoreFile  = ".prromptignore"  // opt a subtree out, e.g. drafts/
+	promptIncludeFile = ".prromptinclude" // match extra files, e.g. *.txt
+)
+
+// controlFileMatch evaluates the control files from the directory of file
+// up to the repository root. The nearest directory with a matching pattern
+// decides; within one directory an include wins over an ignore. decided is
+// false when no control file has a say.
+func controlFileMatch(file string) (decided, include bool) {
+	rule := controlFileRule(file)
+	return rule.file != "", rule.include
+}
+
+// controlRule is the control file pattern that decided about a file.
+type controlRule struct {
+	file    string // relative to the repository root, "" when none decided
+	pattern string
+	include bool
+}
+
+// controlFileRule returns the control file pattern controlFileMatch goes by.
+func controlFileRule(file string) controlRule {
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return controlRule{}
+	}
+	for dir := path.Dir(file); ; dir = path.Dir(dir) {
+		rel := strings.TrimPrefix(file, dir+"/")
+		if dir == "." {
+			rel = file
+		}
+		for _, name := range []string{promptIncludeFile, promptIgnoreFile} {
+			for _, pattern := range readPatternFile(filepath.Join(toplevel, dir, name)) {
+				if controlPatternMatch(pattern, rel) {
+					return controlRule{path.Join(dir, name), pattern, name == promptIncludeFile}
+				}
+			}
+		}
+		if dir == "." || dir == "/" {
+			return controlRule{}
+		}
+	}
+}
+
+func readPatternFile(file string) []string {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil
+	}
+	var patterns []string
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns
+}
+
+// controlPatternMatch matches rel, a path relative to the control file's
+// directory, against one of its patterns.
+func controlPatternMatch(pattern, rel string) bool {
+	dirOnly := strings.HasSuffix(pattern, "/")
+	pattern = strings.TrimSuffix(pattern, "/")
+	anchored := strings.HasPrefix(pattern, "/")
+	pattern = strings.TrimPrefix(pattern, "/")
+	if pattern == "" {
+		return false
+	}
+
+	if !anchored && !strings.Contains(pattern, "/") {
+		segments := strings.Split(rel, "/")
+		for i, segment := range segments {
+			if dirOnly && i == len(segments)-1 {
+				continue
+			}
+			if matched, _ := path.Match(pattern, segment); matched {
+				return true
+			}
+		}
+		return false
+	}
+
+	for candidate := rel; candidate != "."; candidate = path.Dir(candidate) {
+		if dirOnly && candidate == rel {
+			continue
+		}
+		if matched, _ := path.Match(pattern, candidate); matched {
+			return true
+		}
+	}
+	return false
+}
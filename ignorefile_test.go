@@ -0,0 +1,65 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_controlPatternMatch(t *testing.T) {
+	tests := []struct {
+		pattern string
+		rel     string
+		want    bool
+	}{
+		{"drafts/", "drafts/a.md", true},
+		{"drafts/", "sub/drafts/a.md", true},
+		{"drafts/", "drafts", false},
+		{"/drafts/", "sub/drafts/a.md", false},
+		{"*.txt", "notes.txt", true},
+		{"*.txt", "sub/notes.txt", true},
+		{"*.txt", "notes.md", false},
+		{"internal-notes", "internal-notes/x.md", true},
+		{"sub/*.md", "sub/a.md", true},
+		{"sub/*.md", "other/sub/a.md", false},
+	}
+	for _, tt := range tests {
+		if got := controlPatternMatch(tt.pattern, tt.rel); got != tt.want {
+			t.Errorf("controlPatternMatch(%q, %q) = %v, want %v", tt.pattern, tt.rel, got, tt.want)
+		}
+	}
+}
+
+func Test_ControlFiles(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	write := func(name, content string) {
+		file := filepath.Join(repo.Dir, name)
+		os.MkdirAll(filepath.Dir(file), 0755)
+		os.WriteFile(file, []byte(content), 0644)
+	}
+	write("prompts/.prromptignore", "# not ready\ndrafts/\n")
+	write("prompts/drafts/.prromptinclude", "keep.md\n")
+	write("docs/.prromptinclude", "*.prompt.md\n")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	tests := []struct {
+		file string
+		want bool
+	}{
+		{"prompts/review.md", true},
+		{"prompts/drafts/wip.md", false},
+		{"prompts/drafts/keep.md", true},
+		{"docs/summarize.prompt.md", true},
+		{"docs/readme.md", false},
+		{"src/main.go", false},
+	}
+	for _, tt := range tests {
+		if got := isPromptFile(tt.file); got != tt.want {
+			t.Errorf("isPromptFile(%q) = %v, want %v", tt.file, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// importedConfig is what `prrompt import-config` made of another tool's
+// setup: prrompt settings in the order found, and what has no equivalent.
+type importedConfig struct {
+	Settings []importedSetting
+	Unmapped []string
+}
+
+type importedSetting struct {
+	Key    string // without the "prrompt." prefix
+	Values []string
+	From   string
+}
+
+// set adds values to key, keeping each once. Values the key's validator
+// refuses are reported as unmapped instead.
+func (c *importedConfig) set(key, from string, values ...string) {
+	var setting *importedSetting
+	for i := range c.Settings {
+		if c.Settings[i].Key == key {
+			setting = &c.Settings[i]
+		}
+	}
+	if setting == nil {
+		c.Settings = append(c.Settings, importedSetting{Key: key, From: from})
+		setting = &c.Settings[len(c.Settings)-1]
+	}
+	for _, value := range values {
+		if value = strings.TrimSpace(value); value == "" || containsString(setting.Values, value) {
+			continue
+		}
+		if validate := configValidators["prrompt."+key]; validate != nil {
+			if err := validate(value); err != nil {
+				c.unmapped("%s %q: %v", from, value, err)
+				continue
+			}
+		}
+		setting.Values = append(setting.Values, value)
+	}
+}
+
+func (c *importedConfig) unmapped(format string, args ...any) {
+	c.Unmapped = append(c.Unmapped, fmt.Sprintf(format, args...))
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// listSettings are the keys that take several values.
+var listSettings = map[string]bool{
+	"promptPatterns":  true,
+	"excludePatterns": true,
+	"prLabels":        true,
+	"prReviewers":     true,
+	"prAssignees":     true,
+}
+
+// yamlSynonyms map the keys of home-grown prompt-sync configs, lowercased
+// without separators, to prrompt settings.
+var yamlSynonyms = map[string]string{
+	"paths":        "promptPatterns",
+	"prompts":      "promptPatterns",
+	"promptpaths":  "promptPatterns",
+	"promptdirs":   "promptPatterns",
+	"dirs":         "promptPatterns",
+	"directories":  "promptPatterns",
+	"include":      "promptPatterns",
+	"watch":        "promptPatterns",
+	"exclude":      "excludePatterns",
+	"excludes":     "excludePatterns",
+	"excludepaths": "excludePatterns",
+	"ignore":       "excludePatterns",
+	"prefix":       "branchPrefix",
+	"base":         "baseBranch",
+	"target":       "baseBranch",
+	"targetbranch": "baseBranch",
+	"mainbranch":   "baseBranch",
+	"branch":       "baseBranch",
+	"autopush":     "push",
+	"labels":       "prLabels",
+	"reviewers":    "prReviewers",
+	"assignees":    "prAssignees",
+	"exclusions":   "excludePatterns",
+}
+
+// normalizeKey lowercases key and drops "-", "_" and "." for synonyms.
+func normalizeKey(key string) string {
+	return strings.NewReplacer("-", "", "_", "", ".", "").Replace(strings.ToLower(key))
+}
+
+// importYAML maps a flat YAML config of `key: value` pairs and `- item`
+// lists. Keys that are already prrompt settings are taken as they are.
+func importYAML(c *importedConfig, name, data string) {
+	known := make(map[string]string)
+	for _, key := range configKeys {
+		short := strings.TrimPrefix(key.Key, "prrompt.")
+		known[normalizeKey(short)] = short
+	}
+	values := parseConfigFile(data)
+	for _, key := range orderedKeys(data, values) {
+		from := name + " " + key
+		switch normalized := normalizeKey(strings.TrimPrefix(key, "prrompt.")); {
+		case normalized == "branches" && len(values[key]) == 1:
+			c.set("baseBranch", from, values[key]...)
+		case known[normalized] != "":
+			c.set(known[normalized], from, values[key]...)
+		case yamlSynonyms[normalized] != "":
+			c.set(yamlSynonyms[normalized], from, patternValues(yamlSynonyms[normalized], values[key])...)
+		default:
+			c.unmapped("%s: %s", from, strings.Join(values[key], ", "))
+		}
+	}
+}
+
+// orderedKeys returns the keys of values in the order they appear in data.
+func orderedKeys(data string, values map[string][]string) []string {
+	var keys []string
+	for _, line := range strings.Split(data, "\n") {
+		key, _, found := strings.Cut(strings.TrimSpace(line), ":")
+		key = strings.TrimSpace(key)
+		if found && len(values[key]) > 0 && !containsString(keys, key) {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// patternValues turns paths and globs into prompt patterns, which are path
+// prefixes: "prompts" and "prompts/**/*.md" both become "prompts/".
+func patternValues(key string, values []string) []string {
+	if key != "promptPatterns" && key != "excludePatterns" {
+		return values
+	}
+	var patterns []string
+	for _, value := range values {
+		for _, path := range strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' }) {
+			path = strings.TrimPrefix(unquote(path), "./")
+			if key == "excludePatterns" {
+				// Exclude patterns are globs already
+				patterns = append(patterns, path)
+				continue
+			}
+			if i := strings.IndexAny(path, "*?["); i >= 0 {
+				path = path[:strings.LastIndex(path[:i], "/")+1]
+			} else if !strings.HasSuffix(path, "/") && filepath.Ext(path) == "" {
+				path += "/"
+			}
+			if path != "" {
+				patterns = append(patterns, path)
+			}
+		}
+	}
+	return patterns
+}
+
+var (
+	shellAssignment = regexp.MustCompile(`^(?:export\s+|readonly\s+|local\s+)?([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+	shellFlagValue  = map[string]string{
+		"--base": "baseBranch", "-B": "baseBranch", "--target-branch": "baseBranch",
+		"--label": "prLabels", "-l": "prLabels",
+		"--reviewer": "prReviewers", "-r": "prReviewers",
+		"--assignee": "prAssignees", "-a": "prAssignees",
+	}
+)
+
+// importShellScript maps what a prompt-sync script does: the paths it
+// diffs or adds, the branch it creates, the remote it pushes to and the PR
+// it opens, and its NAME=value settings.
+func importShellScript(c *importedConfig, name, data string) {
+	for i, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		from := fmt.Sprintf("%s:%d", name, i+1)
+		if match := shellAssignment.FindStringSubmatch(line); match != nil {
+			importShellVariable(c, from, match[1], unquote(strings.TrimSpace(match[2])))
+			continue
+		}
+		for _, command := range strings.FieldsFunc(line, func(r rune) bool { return r == ';' || r == '|' || r == '&' }) {
+			importShellCommand(c, from, shellFields(command))
+		}
+	}
+}
+
+func importShellVariable(c *importedConfig, from, name, value string) {
+	upper := strings.ToUpper(name)
+	switch {
+	case strings.Contains(value, "$("), strings.Contains(value, "`"):
+		c.unmapped("%s %s is computed", from, name)
+	case strings.Contains(upper, "EXCLUDE") || strings.Contains(upper, "IGNORE"):
+		c.set("excludePatterns", from, patternValues("excludePatterns", []string{value})...)
+	case strings.Contains(upper, "PROMPT") && (strings.Contains(upper, "DIR") || strings.Contains(upper, "PATH") || strings.Contains(upper, "PATTERN") || strings.Contains(upper, "GLOB")):
+		c.set("promptPatterns", from, patternValues("promptPatterns", []string{value})...)
+	case strings.Contains(upper, "PREFIX"):
+		c.set("branchPrefix", from, strings.TrimSuffix(value, "/"))
+	case strings.Contains(upper, "BASE") || strings.Contains(upper, "TARGET") || upper == "MAIN_BRANCH":
+		c.set("baseBranch", from, value)
+	case upper == "REMOTE" || strings.HasSuffix(upper, "_REMOTE"):
+		c.set("remote", from, value)
+	default:
+		c.unmapped("%s %s=%s", from, name, value)
+	}
+}
+
+func importShellCommand(c *importedConfig, from string, args []string) {
+	for len(args) > 0 && (args[0] == "if" || args[0] == "then" || args[0] == "!" || args[0] == "do") {
+		args = args[1:]
+	}
+	if len(args) < 2 {
+		return
+	}
+	switch {
+	case args[0] == "git" && (args[1] == "diff" || args[1] == "diff-tree" || args[1] == "ls-files" || args[1] == "add"):
+		for i, arg := range args {
+			if arg == "--" {
+				c.set("promptPatterns", from, patternValues("promptPatterns", literalArgs(args[i+1:]))...)
+				break
+			}
+		}
+	case args[0] == "git" && (args[1] == "checkout" || args[1] == "switch"):
+		for i := 2; i+1 < len(args); i++ {
+			if args[i] == "-b" || args[i] == "-B" || args[i] == "-c" || args[i] == "-C" {
+				if prefix, rest, found := strings.Cut(args[i+1], "/"); found && strings.Contains(rest, "$") && !strings.Contains(prefix, "$") {
+					c.set("branchPrefix", from, prefix)
+				}
+			}
+		}
+	case args[0] == "git" && args[1] == "push":
+		if remotes := literalArgs(args[2:]); len(remotes) > 0 {
+			c.set("remote", from, remotes[0])
+		}
+	case (args[0] == "gh" && args[1] == "pr") || (args[0] == "glab" && args[1] == "mr"):
+		if len(args) < 3 || args[2] != "create" {
+			return
+		}
+		c.set("prTool", from, args[0])
+		for i := 3; i+1 < len(args); i++ {
+			if key := shellFlagValue[args[i]]; key != "" && !strings.Contains(args[i+1], "$") {
+				c.set(key, from, strings.Split(args[i+1], ",")...)
+			}
+		}
+	}
+}
+
+// literalArgs returns the arguments that are neither flags nor variables.
+func literalArgs(args []string) []string {
+	var literal []string
+	for _, arg := range args {
+		if arg != "" && !strings.HasPrefix(arg, "-") && !strings.Contains(arg, "$") && !strings.ContainsAny(arg, "<>") {
+			literal = append(literal, arg)
+		}
+	}
+	return literal
+}
+
+// shellFields splits a command line into words, honoring single and double
+// quotes.
+func shellFields(line string) []string {
+	var fields []string
+	var word strings.Builder
+	var quote rune
+	inWord := false
+	for _, r := range line {
+		switch {
+		case quote != 0 && r == quote:
+			quote = 0
+		case quote != 0:
+			word.WriteRune(r)
+		case r == '"' || r == '\'':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t':
+			if inWord {
+				fields = append(fields, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		fields = append(fields, word.String())
+	}
+	return fields
+}
+
+// importGitTown maps git-town's main branch, push and hosting settings,
+// from git-town.toml and git config.
+func importGitTown(c *importedConfig, toplevel string) bool {
+	found := false
+	for _, name := range []string{".git-town.toml", "git-town.toml"} {
+		data, err := os.ReadFile(filepath.Join(toplevel, name))
+		if err != nil {
+			continue
+		}
+		found = true
+		values := parseTOML(string(data))
+		for _, key := range tomlKeys(values) {
+			importGitTownSetting(c, name+" "+key, key, values[key], values)
+		}
+	}
+	if config, err := runGit("config", "--get-regexp", `^git-town\.`); err == nil {
+		found = true
+		for _, line := range strings.Split(config, "\n") {
+			key, value, _ := strings.Cut(line, " ")
+			key = strings.TrimPrefix(key, "git-town.")
+			importGitTownSetting(c, "git config git-town."+key, key, value, nil)
+		}
+	}
+	return found
+}
+
+func importGitTownSetting(c *importedConfig, from, key, value string, all map[string]string) {
+	switch key {
+	case "branches.main", "main-branch", "main-branch-name":
+		c.set("baseBranch", from, value)
+	case "push-new-branches", "branches.push-new":
+		c.set("push", from, value)
+	case "hosting.platform", "hosting-platform", "code-hosting-platform":
+		switch strings.ToLower(value) {
+		case "github":
+			c.set("prTool", from, prToolGH)
+		case "gitlab":
+			c.set("prTool", from, prToolGlab)
+		default:
+			c.unmapped("%s = %s", from, value)
+		}
+	case "hosting.origin-hostname", "hosting-origin-hostname", "code-hosting-origin-hostname":
+		if platform := strings.ToLower(all["hosting.platform"]); value != "" && (platform == "" || platform == "github") {
+			c.set("forgeBaseURL", from, "https://"+value)
+		}
+	default:
+		c.unmapped("%s = %s", from, value)
+	}
+}
+
+// parseTOML reads the `key = value` pairs of a TOML file, with the keys of
+// a [section] prefixed by "section.". Arrays and tables in values are kept
+// as their text.
+func parseTOML(data string) map[string]string {
+	values := make(map[string]string)
+	section := ""
+	for _, line := range strings.Split(data, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.Trim(line, "[] ") + "."
+			continue
+		}
+		if key, value, found := strings.Cut(line, "="); found {
+			values[section+strings.TrimSpace(key)] = unquote(strings.TrimSpace(value))
+		}
+	}
+	return values
+}
+
+func tomlKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// importHusky maps the prompt-sync commands of the husky hooks in .husky/.
+func importHusky(c *importedConfig, toplevel string) bool {
+	entries, err := os.ReadDir(filepath.Join(toplevel, ".husky"))
+	if err != nil {
+		return false
+	}
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(toplevel, ".husky", entry.Name()))
+		if err == nil {
+			importShellScript(c, ".husky/"+entry.Name(), string(data))
+		}
+	}
+	return true
+}
+
+// importConfig reads from, a tool name or a file, into an importedConfig.
+func importConfig(from string) (*importedConfig, error) {
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil, fmt.Errorf("not in a git repository")
+	}
+	c := &importedConfig{}
+	switch strings.ToLower(from) {
+	case "git-town":
+		if !importGitTown(c, toplevel) {
+			return nil, fmt.Errorf("no git-town configuration found (git-town.toml or git config git-town.*)")
+		}
+		return c, nil
+	case "husky":
+		if !importHusky(c, toplevel) {
+			return nil, fmt.Errorf("no .husky directory in %s", toplevel)
+		}
+		return c, nil
+	}
+	data, err := os.ReadFile(from)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", from, err)
+	}
+	name := filepath.Base(from)
+	switch ext := strings.ToLower(filepath.Ext(from)); {
+	case ext == ".yml" || ext == ".yaml":
+		importYAML(c, name, string(data))
+	case ext == ".toml" && strings.Contains(name, "git-town"):
+		values := parseTOML(string(data))
+		for _, key := range tomlKeys(values) {
+			importGitTownSetting(c, name+" "+key, key, values[key], values)
+		}
+	case ext == ".sh" || strings.HasPrefix(string(data), "#!"):
+		importShellScript(c, name, string(data))
+	default:
+		return nil, fmt.Errorf("%s is not a shell script, a YAML file or git-town.toml", from)
+	}
+	return c, nil
+}
+
+// yaml renders the settings as a .prrompt.yaml. Settings the file can't
+// set are left as comments saying how to set them with git config.
+func (c *importedConfig) yaml(source string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# prrompt configuration imported from %s, see `prrompt --help`\n", source)
+	for _, setting := range c.Settings {
+		if len(setting.Values) == 0 {
+			continue
+		}
+		if !repoFileKeys[setting.Key] {
+			fmt.Fprintf(&b, "# %s is only read from git config: git config prrompt.%s %s\n", setting.Key, setting.Key, shellQuote(strings.Join(setting.Values, ",")))
+			continue
+		}
+		if listSettings[setting.Key] || len(setting.Values) > 1 {
+			fmt.Fprintf(&b, "%s:\n", setting.Key)
+			for _, value := range setting.Values {
+				fmt.Fprintf(&b, "  - %s\n", value)
+			}
+		} else {
+			fmt.Fprintf(&b, "%s: %s\n", setting.Key, setting.Values[0])
+		}
+	}
+	return b.String()
+}
+
+// runImportConfig implements `prrompt import-config --from <path|tool>
+// [--output <file>] [--dry-run] [--force]`.
+func runImportConfig(args []string) error {
+	usage := fmt.Errorf("usage: %s import-config --from <path|git-town|husky> [--output <file>] [--dry-run] [--force]", toolName)
+	var from, output string
+	dryRun, force := false, false
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--dry-run":
+			dryRun = true
+		case arg == "--force":
+			force = true
+		case (arg == "--from" || arg == "--output") && i+1 < len(args):
+			if arg == "--from" {
+				from = args[i+1]
+			} else {
+				output = args[i+1]
+			}
+			i++
+		case strings.HasPrefix(arg, "--from="):
+			from = strings.TrimPrefix(arg, "--from=")
+		case strings.HasPrefix(arg, "--output="):
+			output = strings.TrimPrefix(arg, "--output=")
+		default:
+			return usage
+		}
+	}
+	if from == "" {
+		return usage
+	}
+	c, err := importConfig(from)
+	if err != nil {
+		return err
+	}
+
+	if len(c.Settings) == 0 {
+		fmt.Printf("Nothing in %s maps to a prrompt setting\n", from)
+	}
+	for _, setting := range c.Settings {
+		if len(setting.Values) > 0 {
+			fmt.Printf("  %-16s %-30s (%s)\n", setting.Key, strings.Join(setting.Values, ", "), setting.From)
+		}
+	}
+	if len(c.Unmapped) > 0 {
+		fmt.Printf("Not mapped, check whether you still need them:\n")
+		for _, line := range c.Unmapped {
+			fmt.Printf("  %s\n", redact(line))
+		}
+	}
+	if len(c.Settings) == 0 {
+		return nil
+	}
+	if dryRun {
+		fmt.Printf("\n%s", c.yaml(from))
+		return nil
+	}
+
+	if output == "" {
+		path, _ := loadConfigFile()
+		if path == "" {
+			toplevel, _ := runGit("rev-parse", "--show-toplevel")
+			path = filepath.Join(toplevel, configFileNames[0])
+		}
+		output = path
+	}
+	if _, err := os.Stat(output); err == nil && !force {
+		return fmt.Errorf("%s already exists, pass --force to replace it or --dry-run to compare", output)
+	}
+	if err := os.WriteFile(output, []byte(c.yaml(from)), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", output, err)
+	}
+	fmt.Printf("✓ Wrote %s\n", output)
+	return nil
+}
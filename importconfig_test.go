@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func importedValues(c *importedConfig) map[string][]string {
+	values := make(map[string][]string)
+	for _, setting := range c.Settings {
+		if len(setting.Values) > 0 {
+			values[setting.Key] = setting.Values
+		}
+	}
+	return values
+}
+
+func Test_importShellScript(t *testing.T) {
+	script := `#!/bin/sh
+set -e
+BRANCH_PREFIX=prompt-sync
+BASE_BRANCH="develop"
+SLACK_WEBHOOK=https://hooks.example.com/T000
+SHA=$(git rev-parse --short HEAD)
+if git diff --name-only HEAD~1 HEAD -- prompts/ ".claude/skills/**/*.md" | grep -q .; then
+  git checkout -b "prompt-sync/$SHA"
+  git push -u origin "prompt-sync/$SHA"
+  gh pr create --base develop --label prompts,ai --reviewer octocat --fill
+fi
+`
+	c := &importedConfig{}
+	importShellScript(c, "sync.sh", script)
+	want := map[string][]string{
+		"branchPrefix":   {"prompt-sync"},
+		"baseBranch":     {"develop"},
+		"promptPatterns": {"prompts/", ".claude/skills/"},
+		"remote":         {"origin"},
+		"prTool":         {"gh"},
+		"prLabels":       {"prompts", "ai"},
+		"prReviewers":    {"octocat"},
+	}
+	if got := importedValues(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected settings %v, want %v", got, want)
+	}
+	if unmapped := strings.Join(c.Unmapped, "\n"); !strings.Contains(unmapped, "sync.sh:5 SLACK_WEBHOOK") || !strings.Contains(unmapped, "SHA is computed") {
+		t.Errorf("Expected the webhook and computed SHA reported, got:\n%s", unmapped)
+	}
+}
+
+func Test_importYAML(t *testing.T) {
+	data := `# prompt sync
+paths:
+  - prompts
+  - ./agents/*.md
+exclude:
+  - "**/drafts/**"
+branch_prefix: prompt-sync
+branches:
+  - main
+notify: "#ai-prompts"
+tokenBudget: 4000
+`
+	c := &importedConfig{}
+	importYAML(c, "sync.yml", data)
+	want := map[string][]string{
+		"promptPatterns":  {"prompts/", "agents/"},
+		"excludePatterns": {"**/drafts/**"},
+		"branchPrefix":    {"prompt-sync"},
+		"baseBranch":      {"main"},
+		"tokenBudget":     {"4000"},
+	}
+	if got := importedValues(c); !reflect.DeepEqual(got, want) {
+		t.Errorf("Unexpected settings %v, want %v", got, want)
+	}
+	if len(c.Unmapped) != 1 || !strings.Contains(c.Unmapped[0], "notify") {
+		t.Errorf("Expected notify reported as unmapped, got %v", c.Unmapped)
+	}
+}
+
+func Test_ImportConfig(t *testing.T) {
+	repo := setupTestRepo(t)
+	os.WriteFile(filepath.Join(repo.Dir, ".git-town.toml"), []byte("push-new-branches = false\n\n[branches]\nmain = \"trunk\"\nperennials = [\"staging\"]\n\n[hosting]\nplatform = \"github\"\norigin-hostname = \"github.example.com\"\n"), 0644)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if err := runImportConfig([]string{"--from", "git-town"}); err != nil {
+		t.Fatalf("import-config failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(repo.Dir, configFileNames[0]))
+	if err != nil {
+		t.Fatalf("Expected %s written: %v", configFileNames[0], err)
+	}
+	values := parseConfigFile(string(data))
+	if values["baseBranch"][0] != "trunk" || values["push"][0] != "false" || values["prTool"][0] != "gh" || len(values["forgeBaseURL"]) != 0 {
+		t.Errorf("Unexpected configuration:\n%s", data)
+	}
+	if !strings.Contains(string(data), "git config prrompt.forgeBaseURL 'https://github.example.com'") {
+		t.Errorf("Expected the forge URL left to git config, got:\n%s", data)
+	}
+	if got := getBaseBranch(); got != "trunk" {
+		t.Errorf("Expected the imported base branch to apply, got %s", got)
+	}
+	if err := runImportConfig([]string{"--from", "git-town"}); err == nil || !strings.Contains(err.Error(), "--force") {
+		t.Errorf("Expected an existing file to be kept without --force, got %v", err)
+	}
+}
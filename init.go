@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// knownPromptDirs are directories AI tools conventionally keep prompts in,
+// proposed as patterns by `prrompt init` when present in the repository.
+var knownPromptDirs = []string{
+	".claude/skills/",
+	".claude/commands/",
+	".claude/agents/",
+	".cursor/rules/",
+	".github/prompts/",
+	"prompts/",
+}
+
+// runInit walks through first-time setup: it proposes prompt patterns from
+// the directories present, asks for the base branch and push preferences,
+// writes the configuration and installs the hook. With --yes every proposal
+// is accepted without asking.
+func runInit(in io.Reader, args []string) error {
+	assumeYes := false
+	for _, arg := range args {
+		if arg == "--yes" || arg == "-y" {
+			assumeYes = true
+		} else {
+			return fmt.Errorf("unexpected argument: %s", arg)
+		}
+	}
+
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	reader := bufio.NewReader(in)
+	ask := func(question, proposal string) string {
+		if assumeYes {
+			return proposal
+		}
+		fmt.Printf("%s [%s]: ", question, proposal)
+		answer, _ := reader.ReadString('\n')
+		answer = strings.TrimSpace(answer)
+		if answer == "" {
+			return proposal
+		}
+		return answer
+	}
+
+	var detected []string
+	for _, dir := range knownPromptDirs {
+		if info, err := os.Stat(filepath.Join(toplevel, dir)); err == nil && info.IsDir() {
+			detected = append(detected, dir)
+		}
+	}
+	if len(detected) == 0 {
+		detected = getPromptPatterns()
+	} else {
+		fmt.Printf("Detected prompt directories: %s\n", strings.Join(detected, ", "))
+	}
+
+	type setting struct {
+		Key   string
+		Value string
+	}
+	settings := []setting{
+		{"promptPatterns", ask("Prompt patterns (comma-separated)", strings.Join(detected, ","))},
+		{"baseBranch", ask("Base branch for prompt branches", getBaseBranch())},
+	}
+
+	push := ask("Push prompt branches automatically? (yes/no)", "yes")
+	if strings.HasPrefix(strings.ToLower(push), "n") {
+		settings = append(settings, setting{"push", "false"})
+	} else {
+		settings = append(settings, setting{"remote", ask("Remote to push to", getRemote())})
+	}
+
+	target := ask("Write configuration to (git/file)", "git")
+	if strings.HasPrefix(strings.ToLower(target), "f") {
+		path := filepath.Join(toplevel, configFileNames[0])
+		var b strings.Builder
+		b.WriteString("# prrompt configuration, see `prrompt --help`\n")
+		for _, setting := range settings {
+			fmt.Fprintf(&b, "%s: %s\n", setting.Key, setting.Value)
+		}
+		if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", path, err)
+		}
+		fmt.Printf("✓ Wrote %s\n", path)
+	} else {
+		for _, setting := range settings {
+			if _, err := runGit("config", "prrompt."+setting.Key, setting.Value); err != nil {
+				return fmt.Errorf("failed to set prrompt.%s: %w", setting.Key, err)
+			}
+		}
+		fmt.Println("✓ Saved configuration to git config")
+	}
+
+	return installHook(false, false)
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_InitWritesConfigFile(t *testing.T) {
+	repo := setupTestRepo(t)
+	os.MkdirAll(filepath.Join(repo.Dir, ".cursor/rules"), 0755)
+	os.MkdirAll(filepath.Join(repo.Dir, ".claude/skills"), 0755)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	// Accept proposed patterns and base branch, decline pushing, write a file
+	input := strings.NewReader("\n\nno\nfile\n")
+	if err := runInit(input, nil); err != nil {
+		t.Fatalf("init failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(repo.Dir, ".prrompt.yaml"))
+	if err != nil {
+		t.Fatalf("Expected .prrompt.yaml to be written: %v", err)
+	}
+	if !strings.Contains(string(data), "promptPatterns: .claude/skills/,.cursor/rules/") {
+		t.Errorf("Expected detected patterns in config file, got:\n%s", data)
+	}
+
+	if got := strings.Join(getPromptPatterns(), ","); got != ".claude/skills/,.cursor/rules/" {
+		t.Errorf("Expected patterns from config file, got %q", got)
+	}
+	if getBoolConfig("prrompt.push", true) {
+		t.Error("Expected push to be disabled by the config file")
+	}
+
+	hook, err := os.ReadFile(filepath.Join(repo.Dir, ".git/hooks/post-commit"))
+	if err != nil || !strings.Contains(string(hook), `"$COMMIT_SHA" --branch "$BRANCH" --repo "$REPO"`) {
+		t.Errorf("Expected post-commit hook to be installed: %v", err)
+	}
+}
+
+func Test_GitConfigOverridesConfigFile(t *testing.T) {
+	repo := setupTestRepo(t)
+	os.WriteFile(filepath.Join(repo.Dir, ".prrompt.yaml"), []byte("baseBranch: develop\npromptPatterns:\n  - agents/\n  - 'skills/'\n"), 0644)
+	runGitInDir(repo.Dir, "config", "prrompt.baseBranch", "trunk")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if got := getBaseBranch(); got != "trunk" {
+		t.Errorf("Expected git config to take precedence, got %q", got)
+	}
+	if got := strings.Join(getPromptPatterns(), ","); got != "agents/,skills/" {
+		t.Errorf("Expected list patterns from config file, got %q", got)
+	}
+	if source := configSource("prrompt.promptPatterns"); !strings.HasPrefix(source, "file") {
+		t.Errorf("Expected file source, got %q", source)
+	}
+}
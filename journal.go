@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Extraction steps recorded in the journal, in order.
+const (
+	stepStarted       = "started"
+	stepBranchCreated = "branch-created"
+	stepCherryPicked  = "cherry-picked"
+	stepCommitted     = "committed"
+	stepPushed        = "pushed"
+)
+
+// journal records the progress of an extraction under .git/prrompt/ so an
+// interrupted run (Ctrl-C, crash, laptop sleep) can be rolled back.
+type journal struct {
+	Commit       string `json:"commit"`
+	SourceBranch string `json:"sourceBranch"`
+	PromptBranch string `json:"promptBranch"`
+	// PreviousTip is where an existing prompt branch that is being appended
+	// to pointed before.
+	PreviousTip string    `json:"previousTip,omitempty"`
+	Step        string    `json:"step"`
+	PID         int       `json:"pid"`
+	Started     time.Time `json:"started"`
+
+	path string
+}
+
+func journalPath() (string, error) {
+	gitDir, err := runGit("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return filepath.Join(gitDir, "prrompt", "journal.json"), nil
+}
+
+func startJournal(info *CommitInfo, promptBranch string) (*journal, error) {
+	path, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+	j := &journal{
+		Commit:       info.SHA,
+		SourceBranch: info.SourceBranch,
+		PromptBranch: promptBranch,
+		PID:          os.Getpid(),
+		Started:      time.Now(),
+		path:         path,
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	return j, j.record(stepStarted)
+}
+
+// record persists that step has completed.
+func (j *journal) record(step string) error {
+	j.Step = step
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return err
+	}
+	// Write then rename so a crash never leaves a truncated journal
+	tmp := j.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return os.Rename(tmp, j.path)
+}
+
+func (j *journal) remove() {
+	os.Remove(j.path)
+}
+
+// loadJournal returns the journal of an unfinished extraction, or nil.
+func loadJournal() (*journal, error) {
+	path, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read journal: %w", err)
+	}
+	j := &journal{path: path}
+	if err := json.Unmarshal(data, j); err != nil {
+		return nil, fmt.Errorf("corrupt journal %s: %w", path, err)
+	}
+	return j, nil
+}
+
+// isRunning reports whether the process that wrote the journal is still
+// alive, i.e. the extraction is in progress rather than interrupted. This
+// matters for the post-commit hook firing inside our own extraction commit.
+func (j *journal) isRunning() bool {
+	return processAlive(j.PID)
+}
+
+// rollback restores the state from before the interrupted extraction: it
+// aborts a pending cherry-pick, returns to the source branch and deletes the
+// prompt branch unless its commit was completed.
+func (j *journal) rollback() error {
+	if j.Step != stepStarted {
+		runGit("cherry-pick", "--abort")
+		runGit("reset", "--merge")
+	}
+	// Already back on the source branch, the tree holds the user's changes
+	// rather than the extraction's, and forcing a checkout would lose them
+	if current, _ := runGit("symbolic-ref", "--short", "-q", "HEAD"); j.SourceBranch != "" && current != j.SourceBranch {
+		if _, err := runGit("checkout", "-f", j.SourceBranch); err != nil {
+			return fmt.Errorf("failed to return to %s: %w", j.SourceBranch, err)
+		}
+	}
+	if j.Step == stepBranchCreated || j.Step == stepCherryPicked {
+		if j.PreviousTip != "" {
+			runGit("update-ref", "refs/heads/"+j.PromptBranch, j.PreviousTip)
+		} else {
+			runGit("branch", "-D", j.PromptBranch)
+		}
+	}
+	j.remove()
+	return nil
+}
+
+// recoverInterrupted rolls back an interrupted extraction found at the
+// start of a run. It reports whether anything was recovered.
+func recoverInterrupted() (bool, error) {
+	j, err := loadJournal()
+	if err != nil || j == nil || j.isRunning() {
+		return false, err
+	}
+	warnf("recovering interrupted extraction of %s (stopped after %s)", j.Commit[:7], j.Step)
+	if err := j.rollback(); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// runRecover implements `prrompt recover`.
+func runRecover() error {
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	j, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	if j == nil {
+		fmt.Println("Nothing to recover")
+		return nil
+	}
+	if j.isRunning() {
+		return fmt.Errorf("extraction of %s is still running (pid %d)", j.Commit[:7], j.PID)
+	}
+	if err := j.rollback(); err != nil {
+		return err
+	}
+	fmt.Printf("✓ Recovered interrupted extraction of %s, back on %s\n", j.Commit[:7], j.SourceBranch)
+	if j.Step == stepCommitted || j.Step == stepPushed {
+		fmt.Printf("Branch %s was kept, its commit was complete\n", j.PromptBranch)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_RecoverInterruptedExtraction(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	// Simulate a run killed mid cherry-pick
+	promptBranch := defaultBranchPrefix + "/" + commitSHA[:7]
+	runGitInDir(repo.Dir, "checkout", "-b", promptBranch, "main")
+	runGitInDir(repo.Dir, "cherry-pick", "--no-commit", commitSHA)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	dead := exec.Command("true")
+	dead.Run()
+	j, err := startJournal(&CommitInfo{SHA: commitSHA, SourceBranch: repo.BranchName}, promptBranch)
+	if err != nil {
+		t.Fatalf("startJournal failed: %v", err)
+	}
+	j.PID = dead.Process.Pid
+	j.record(stepCherryPicked)
+
+	recovered, err := recoverInterrupted()
+	if err != nil || !recovered {
+		t.Fatalf("Expected recovery, got recovered=%v err=%v", recovered, err)
+	}
+
+	currentBranch, _ := runGitInDir(repo.Dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if currentBranch != repo.BranchName {
+		t.Errorf("Expected to be back on %s, but on %s", repo.BranchName, currentBranch)
+	}
+	if branches, _ := runGitInDir(repo.Dir, "branch", "--list", promptBranch); branches != "" {
+		t.Errorf("Expected partial branch %s to be deleted", promptBranch)
+	}
+	if j, _ := loadJournal(); j != nil {
+		t.Error("Expected journal to be removed after recovery")
+	}
+
+	// A normal run afterwards extracts as usual
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed after recovery: %v", err)
+	}
+	files, _ := runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", promptBranch)
+	if !strings.Contains(files, "prompts/test.md") {
+		t.Errorf("Expected prompt file on %s. Files: %s", promptBranch, files)
+	}
+}
+
+func Test_RecoverSkipsRunningExtraction(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	// The journal of this very process, as seen by the post-commit hook
+	// firing inside an extraction
+	j, err := startJournal(&CommitInfo{SHA: strings.Repeat("a", 40), SourceBranch: repo.BranchName}, "prompt-update/aaaaaaa")
+	if err != nil {
+		t.Fatalf("startJournal failed: %v", err)
+	}
+	defer j.remove()
+
+	if recovered, err := recoverInterrupted(); err != nil || recovered {
+		t.Errorf("Expected running extraction to be left alone, got recovered=%v err=%v", recovered, err)
+	}
+}
+
+func Test_StayOnBranch(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.stayOnBranch", "true")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", result, err)
+	}
+	if current, _ := runGit("rev-parse", "--abbrev-ref", "HEAD"); current != result.Branch {
+		t.Errorf("Expected to stay on %s, but on %s", result.Branch, current)
+	}
+	if status, _ := runGit("status", "--porcelain", "--untracked-files=no"); status != "" {
+		t.Errorf("Expected a clean prompt branch, got:\n%s", status)
+	}
+	if j, _ := loadJournal(); j != nil {
+		t.Error("Expected the journal to be removed when staying on the prompt branch")
+	}
+
+	// The env var wins over the config
+	runGit("checkout", "-q", repo.BranchName)
+	next := commitFiles(t, repo.Dir, "Update prompt", map[string]string{"prompts/test.md": "# Test prompt v2"})
+	t.Setenv(stayEnv, "false")
+	if result, err := processCommit(next); err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", result, err)
+	}
+	if current, _ := runGit("rev-parse", "--abbrev-ref", "HEAD"); current != repo.BranchName {
+		t.Errorf("Expected %s=false to return to %s, but on %s", stayEnv, repo.BranchName, current)
+	}
+}
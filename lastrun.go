@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// lastRunPath returns the file recording, per branch, the last commit
+// processed by `prrompt process --since-last-run`.
+func lastRunPath() (string, error) {
+	commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return filepath.Join(commonDir, "prrompt", "last-run.json"), nil
+}
+
+func loadLastRun() (map[string]string, error) {
+	state := make(map[string]string)
+	path, err := lastRunPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("corrupt %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// recordLastRun stores sha as the last commit processed on branch.
+func recordLastRun(branch, sha string) error {
+	state, err := loadLastRun()
+	if err != nil {
+		return err
+	}
+	state[branch] = sha
+	path, _ := lastRunPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(state, "", "  ")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// commitsSinceLastRun returns the current branch and its commits, oldest
+// first, that are not reachable from the last processed one. On the first
+// run for a branch there is nothing to compare against: HEAD is recorded as
+// the starting point and no commits are returned, rather than backfilling
+// the whole history.
+func commitsSinceLastRun() (string, []string, error) {
+	branch, err := runGit("symbolic-ref", "--short", "-q", "HEAD")
+	if err != nil {
+		return "", nil, fmt.Errorf("--since-last-run needs a checked out branch")
+	}
+	head, err := runGit("rev-parse", "HEAD")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to resolve HEAD: %w", err)
+	}
+
+	state, err := loadLastRun()
+	if err != nil {
+		return "", nil, err
+	}
+	last, ok := state[branch]
+	if ok {
+		if _, err := runGit("cat-file", "-e", last+"^{commit}"); err != nil {
+			warnf("last processed commit %s on %s no longer exists, starting over from HEAD", last[:7], branch)
+			ok = false
+		}
+	}
+	if !ok {
+		infof("No previous run on %s, starting from %s", branch, head[:7])
+		return branch, nil, recordLastRun(branch, head)
+	}
+
+	output, err := runGitHistory("rev-list", "--reverse", last+"..HEAD")
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to list new commits: %w", err)
+	}
+	if output == "" {
+		return branch, nil, nil
+	}
+	return branch, strings.Split(output, "\n"), nil
+}
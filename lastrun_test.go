@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ProcessSinceLastRun(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	// The first run only records the starting point
+	results, err := processSinceLastRun()
+	if err != nil || len(results) != 0 {
+		t.Fatalf("Expected nothing to process on the first run, got %d results (err %v)", len(results), err)
+	}
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	runGitInDir(repo.Dir, "commit", "--allow-empty", "-m", "Unrelated change")
+	head, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	results, err = processSinceLastRun()
+	if err != nil {
+		t.Fatalf("processSinceLastRun failed: %v", err)
+	}
+	if len(results) != 2 || results[0].Status != statusExtracted || results[1].Reason != reasonNoPromptFiles {
+		t.Fatalf("Expected the two new commits, oldest first, got %+v", results)
+	}
+
+	state, _ := loadLastRun()
+	if state[repo.BranchName] != head {
+		t.Errorf("Expected last run on %s to be %s, got %s", repo.BranchName, head, state[repo.BranchName])
+	}
+	if results, _ := processSinceLastRun(); len(results) != 0 {
+		t.Errorf("Expected no new commits, got %d", len(results))
+	}
+}
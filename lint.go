@@ -0,0 +1,127 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Lint modes, set with prrompt.lint.
+const (
+	lintOff   = "off"
+	lintWarn  = "warn"  // report violations and extract anyway
+	lintBlock = "block" // refuse to extract until they are fixed
+)
+
+const defaultLintMode = lintOff
+
+func getLintMode() string {
+	value, err := gitConfig("--get", "prrompt.lint")
+	if err != nil {
+		return defaultLintMode
+	}
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case lintWarn, lintBlock:
+		return value
+	}
+	return defaultLintMode
+}
+
+// getLintMaxFileSize returns the largest prompt file size in bytes that
+// passes linting; 0 disables the check.
+func getLintMaxFileSize() int {
+	value, err := gitConfig("--type=int", "--get", "prrompt.lint.maxFileSize")
+	if err != nil {
+		return 0
+	}
+	size, err := strconv.Atoi(value)
+	if err != nil || size < 0 {
+		return 0
+	}
+	return size
+}
+
+func getForbiddenPhrases() []string {
+	value, err := gitConfig("--get-all", "prrompt.lint.forbiddenPhrase")
+	if err != nil || value == "" {
+		return nil
+	}
+	return strings.Split(value, "\n")
+}
+
+type lintViolation struct {
+	File    string
+	Message string
+}
+
+func (v lintViolation) String() string {
+	return v.File + ": " + v.Message
+}
+
+// markdownLink matches the target of inline Markdown links and images.
+var markdownLink = regexp.MustCompile(`\]\(([^)\s]+)(?:\s+"[^"]*")?\)`)
+
+// lintPrompts checks the commit's prompt files as they are in the commit.
+// Deleted files are not checked.
+func lintPrompts(info *CommitInfo) []lintViolation {
+	requiredKeys := getListConfig("prrompt.lint.requiredKeys")
+	maxSize := getLintMaxFileSize()
+	forbidden := getForbiddenPhrases()
+
+	var violations []lintViolation
+	for _, file := range info.PromptFiles {
+		if blobAt(info.SHA, file) == "" {
+			continue
+		}
+		content := showFile(info.SHA, file)
+		report := func(format string, args ...any) {
+			violations = append(violations, lintViolation{file, fmt.Sprintf(format, args...)})
+		}
+
+		if maxSize > 0 && len(content) > maxSize {
+			report("%d bytes exceeds the maximum of %d", len(content), maxSize)
+		}
+		if !utf8.ValidString(content) {
+			report("not valid UTF-8")
+			continue
+		}
+
+		if len(requiredKeys) > 0 && strings.HasSuffix(strings.ToLower(file), ".md") {
+			fields, ok := parseFrontmatter(content)
+			if !ok {
+				report("missing frontmatter (required keys: %s)", strings.Join(requiredKeys, ", "))
+			}
+			for _, key := range requiredKeys {
+				if ok && fields[key] == "" {
+					report("frontmatter is missing %q", key)
+				}
+			}
+		}
+
+		lower := strings.ToLower(content)
+		for _, phrase := range forbidden {
+			if phrase != "" && strings.Contains(lower, strings.ToLower(phrase)) {
+				report("contains forbidden phrase %q", phrase)
+			}
+		}
+
+		for _, match := range markdownLink.FindAllStringSubmatch(content, -1) {
+			target := match[1]
+			if strings.Contains(target, "://") || strings.HasPrefix(target, "#") || strings.HasPrefix(target, "mailto:") {
+				continue
+			}
+			target, _, _ = strings.Cut(target, "#")
+			resolved := path.Join(path.Dir(file), target)
+			if strings.HasPrefix(target, "/") {
+				resolved = strings.TrimPrefix(path.Clean(target), "/")
+			}
+			if _, err := runGit("cat-file", "-e", info.SHA+":"+resolved); err != nil {
+				report("broken link to %s", match[1])
+			}
+		}
+	}
+	return violations
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_LintPrompts(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.lint", "block")
+	runGitInDir(repo.Dir, "config", "prrompt.lint.requiredKeys", "name,description")
+	runGitInDir(repo.Dir, "config", "prrompt.lint.maxFileSize", "200")
+	runGitInDir(repo.Dir, "config", "--add", "prrompt.lint.forbiddenPhrase", "As an AI language model")
+
+	write := func(name, content string) {
+		file := filepath.Join(repo.Dir, name)
+		os.MkdirAll(filepath.Dir(file), 0755)
+		os.WriteFile(file, []byte(content), 0644)
+	}
+	write("prompts/good.md", "---\nname: good\ndescription: Fine\n---\n\nSee [the guide](guide.md#usage) and [docs](https://example.com).\n")
+	write("prompts/guide.md", "---\nname: guide\ndescription: Guide\n---\n")
+	write("prompts/bad.md", "---\nname: bad\n---\n\nAs an AI language model, see [missing](nope.md).\n")
+	write("prompts/big.txt", strings.Repeat("x", 300))
+	write("prompts/binary.txt", "\xff\xfe")
+	runGitInDir(repo.Dir, "add", ".")
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompts")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	info, err := analyzeCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("analyzeCommit failed: %v", err)
+	}
+	var got []string
+	for _, violation := range lintPrompts(info) {
+		got = append(got, violation.String())
+	}
+	want := []string{
+		`prompts/bad.md: frontmatter is missing "description"`,
+		`prompts/bad.md: contains forbidden phrase "As an AI language model"`,
+		`prompts/bad.md: broken link to nope.md`,
+		`prompts/big.txt: 300 bytes exceeds the maximum of 200`,
+		`prompts/binary.txt: not valid UTF-8`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Unexpected violations:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+
+	if _, err := processCommit(commitSHA); err == nil || !strings.Contains(err.Error(), "lint violations") {
+		t.Errorf("Expected lint=block to refuse extraction, got %v", err)
+	}
+	if branches, _ := runGitInDir(repo.Dir, "branch", "--list", defaultBranchPrefix+"/*"); branches != "" {
+		t.Errorf("Expected no prompt branch, got %s", branches)
+	}
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"time"
+)
+
+const defaultLockTimeout = 30 * time.Second
+
+// lockHolderEnv is set while the lock is held so that prrompt processes
+// started underneath us (the post-commit hook firing on our own extraction
+// commit) do not wait on their ancestor. Any child inherits it, so it only
+// counts while the named pid still holds the lock and is one of our
+// ancestors.
+const lockHolderEnv = "PRROMPT_LOCK_HOLDER"
+
+// locksHeld counts the acquireLock calls not released yet, for an
+// interrupt to wait for the run to unwind rather than exit at once.
+var locksHeld atomic.Int32
+
+// getLockTimeout returns how long to wait for another run to finish; 0 means
+// exit immediately.
+func getLockTimeout() time.Duration {
+	value, err := gitConfig("--type=int", "--get", "prrompt.lockTimeout")
+	if err != nil {
+		return defaultLockTimeout
+	}
+	seconds, err := strconv.Atoi(value)
+	if err != nil || seconds < 0 {
+		return defaultLockTimeout
+	}
+	return time.Duration(seconds) * time.Second
+}
+
+func lockPath() (string, error) {
+	commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return filepath.Join(commonDir, "prrompt.lock"), nil
+}
+
+// processAlive reports whether a process with the given pid exists. EPERM
+// means it does, run by another user.
+func processAlive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	if pid == os.Getpid() {
+		return true
+	}
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
+
+// parentPID returns the parent of a process, or 0 when it cannot be found.
+func parentPID(pid int) int {
+	if data, err := os.ReadFile(fmt.Sprintf("/proc/%d/stat", pid)); err == nil {
+		// pid (comm) state ppid ...; comm may itself contain spaces and parens
+		if i := strings.LastIndexByte(string(data), ')'); i >= 0 {
+			if fields := strings.Fields(string(data[i+1:])); len(fields) > 1 {
+				ppid, _ := strconv.Atoi(fields[1])
+				return ppid
+			}
+		}
+		return 0
+	}
+	output, err := exec.Command("ps", "-o", "ppid=", "-p", strconv.Itoa(pid)).Output()
+	if err != nil {
+		return 0
+	}
+	ppid, _ := strconv.Atoi(strings.TrimSpace(string(output)))
+	return ppid
+}
+
+// runningUnder reports whether pid is this process or one it was started
+// under.
+func runningUnder(pid int) bool {
+	for p := os.Getpid(); p > 1; p = parentPID(p) {
+		if p == pid {
+			return true
+		}
+	}
+	return false
+}
+
+// lockPID returns the pid recorded in the lock file, or 0.
+func lockPID(path string) int {
+	data, _ := os.ReadFile(path)
+	pid, _ := strconv.Atoi(strings.TrimSpace(string(data)))
+	return pid
+}
+
+// acquireLock takes .git/prrompt.lock, waiting for a concurrent run for up
+// to prrompt.lockTimeout. Locks left behind by dead processes are taken
+// over. The returned function releases the lock.
+func acquireLock() (func(), error) {
+	path, err := lockPath()
+	if err != nil {
+		return nil, err
+	}
+	if holder, err := strconv.Atoi(os.Getenv(lockHolderEnv)); err == nil && lockPID(path) == holder && runningUnder(holder) {
+		locksHeld.Add(1)
+		return func() { locksHeld.Add(-1) }, nil
+	}
+	deadline := time.Now().Add(getLockTimeout())
+	for waited := false; ; {
+		file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+		if err == nil {
+			fmt.Fprintf(file, "%d\n", os.Getpid())
+			file.Close()
+			os.Setenv(lockHolderEnv, strconv.Itoa(os.Getpid()))
+			locksHeld.Add(1)
+			return func() {
+				os.Unsetenv(lockHolderEnv)
+				os.Remove(path)
+				locksHeld.Add(-1)
+			}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock %s: %w", path, err)
+		}
+
+		pid := lockPID(path)
+		if !processAlive(pid) {
+			// Give a writer that just created the file a moment to fill it in
+			if info, err := os.Stat(path); err == nil && pid == 0 && time.Since(info.ModTime()) < time.Second {
+				time.Sleep(50 * time.Millisecond)
+				continue
+			}
+			verbosef("Removing stale lock %s (pid %d)", path, pid)
+			os.Remove(path)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, withExitCode(exitLocked, fmt.Errorf("another %s run (pid %d) is in progress; remove %s if it is not", toolName, pid, path))
+		}
+		if !waited {
+			infof("Waiting for another %s run (pid %d) to finish...", toolName, pid)
+			waited = true
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_acquireLock(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.lockTimeout", "0")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+	lockFile := filepath.Join(repo.Dir, ".git", "prrompt.lock")
+
+	// Held by a live process
+	holder := exec.Command("sleep", "30")
+	if err := holder.Start(); err != nil {
+		t.Skipf("cannot start holder process: %v", err)
+	}
+	defer holder.Process.Kill()
+	os.WriteFile(lockFile, []byte(strconv.Itoa(holder.Process.Pid)), 0644)
+
+	if _, err := acquireLock(); err == nil || !strings.Contains(err.Error(), "in progress") {
+		t.Errorf("Expected lock to be busy, got %v", err)
+	} else if code := exitCodeOf(fmt.Errorf("wrapped: %w", err)); code != exitLocked {
+		t.Errorf("Expected a busy lock to exit %d, got %d", exitLocked, code)
+	}
+
+	// The holder variable only lets processes started under the holder in
+	t.Setenv(lockHolderEnv, strconv.Itoa(holder.Process.Pid))
+	if _, err := acquireLock(); err == nil {
+		t.Error("Expected an inherited holder variable not to bypass someone else's lock")
+	}
+	os.Unsetenv(lockHolderEnv)
+
+	// Stale once the holder is gone
+	holder.Process.Kill()
+	holder.Wait()
+	release, err := acquireLock()
+	if err != nil {
+		t.Fatalf("Expected stale lock to be taken over, got %v", err)
+	}
+	data, _ := os.ReadFile(lockFile)
+	if strings.TrimSpace(string(data)) != strconv.Itoa(os.Getpid()) {
+		t.Errorf("Expected lock to record our pid, got %q", data)
+	}
+
+	// Nested runs under the holder don't wait on it
+	nested, err := acquireLock()
+	if err != nil {
+		t.Errorf("Expected nested acquire to succeed, got %v", err)
+	} else {
+		nested()
+	}
+
+	release()
+	if _, err := os.Stat(lockFile); !os.IsNotExist(err) {
+		t.Error("Expected lock file to be removed on release")
+	}
+}
+
+func Test_processAlive(t *testing.T) {
+	if !processAlive(os.Getpid()) || processAlive(0) {
+		t.Error("Expected only live processes to be alive")
+	}
+	// pid 1 belongs to root: signalling it fails with EPERM, but it is alive
+	if !processAlive(1) {
+		t.Error("Expected a process of another user to count as alive")
+	}
+	if parentPID(os.Getpid()) != os.Getppid() {
+		t.Errorf("Expected parent %d, got %d", os.Getppid(), parentPID(os.Getpid()))
+	}
+	if !runningUnder(os.Getppid()) || runningUnder(1) {
+		t.Error("Expected only our own ancestors to count")
+	}
+}
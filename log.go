@@ -0,0 +1,149 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	levelQuiet = iota
+	levelNormal
+	levelVerbose
+	levelDebug
+)
+
+var logLevelNames = []string{"quiet", "normal", "verbose", "debug"}
+
+const defaultLogLevel = "normal"
+
+// logger writes leveled messages to stdout and, when prrompt.logFile is
+// enabled, appends every message including debug ones to a log file so
+// there is a trail even when the console is quiet.
+type logger struct {
+	mu    sync.Mutex
+	level int
+	file  *os.File
+}
+
+var appLog = &logger{level: levelNormal}
+
+func parseLogLevel(name string) (int, bool) {
+	for level, levelName := range logLevelNames {
+		if strings.EqualFold(strings.TrimSpace(name), levelName) {
+			return level, true
+		}
+	}
+	return levelNormal, false
+}
+
+// getLogLevel resolves prrompt.logLevel, falling back to the older
+// prrompt.verbosity ("high" meaning verbose).
+func getLogLevel() string {
+	value, err := gitConfig("--get", "prrompt.logLevel")
+	if err == nil {
+		if level, ok := parseLogLevel(value); ok {
+			return logLevelNames[level]
+		}
+	}
+	if getVerbosity() == verbosityHigh {
+		return logLevelNames[levelVerbose]
+	}
+	return defaultLogLevel
+}
+
+// initLogging sets the log level from the -q/-v/--debug flag when given, or
+// from config, and opens the log file when enabled. It is safe to call again,
+// e.g. for another repository.
+func initLogging(flagLevel string) {
+	appLog.mu.Lock()
+	if appLog.file != nil {
+		appLog.file.Close()
+		appLog.file = nil
+	}
+	appLog.mu.Unlock()
+
+	name := flagLevel
+	if name == "" {
+		name = getLogLevel()
+	}
+	level, _ := parseLogLevel(name)
+
+	var file *os.File
+	if getBoolConfig("prrompt.logFile", false) {
+		if gitDir, err := runGit("rev-parse", "--git-common-dir"); err == nil {
+			path := filepath.Join(gitDir, "prrompt", "prrompt.log")
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err == nil {
+				file, _ = os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+			}
+		}
+	}
+
+	appLog.mu.Lock()
+	appLog.level = level
+	appLog.file = file
+	appLog.mu.Unlock()
+}
+
+func (l *logger) logf(level int, format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil && level > l.level {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if level <= l.level {
+		fmt.Println(msg)
+	}
+	if l.file != nil {
+		timestamp := time.Now().Format(time.RFC3339)
+		for _, line := range strings.Split(strings.TrimRight(msg, "\n"), "\n") {
+			fmt.Fprintf(l.file, "%s %-7s %s\n", timestamp, logLevelNames[level], line)
+		}
+	}
+}
+
+// infof logs essential output, hidden with -q.
+func infof(format string, args ...any) { appLog.logf(levelNormal, format, args...) }
+
+// warnf logs a warning, hidden with -q.
+func warnf(format string, args ...any) { appLog.logf(levelNormal, "Warning: "+format, args...) }
+
+// verbosef logs progress details shown with -v.
+func verbosef(format string, args ...any) { appLog.logf(levelVerbose, format, args...) }
+
+// debugf logs internals such as git commands, shown with --debug.
+func debugf(format string, args ...any) { appLog.logf(levelDebug, format, args...) }
+
+func isVerbose() bool {
+	appLog.mu.Lock()
+	defer appLog.mu.Unlock()
+	return appLog.level >= levelVerbose
+}
+
+// parseGlobalFlags removes -q/--quiet, -v/--verbose and --debug from args
+// and returns the remaining arguments and the requested log level.
+func parseGlobalFlags(args []string) ([]string, string) {
+	var rest []string
+	level := ""
+	for i, arg := range args {
+		if arg == "--" {
+			rest = append(rest, args[i:]...)
+			break
+		}
+		switch arg {
+		case "-q", "--quiet":
+			level = logLevelNames[levelQuiet]
+		case "-v", "--verbose":
+			level = logLevelNames[levelVerbose]
+		case "--debug":
+			level = logLevelNames[levelDebug]
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest, level
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_parseGlobalFlags(t *testing.T) {
+	rest, level := parseGlobalFlags([]string{"--debug", "abc1234", "--output=json"})
+	if level != "debug" || strings.Join(rest, " ") != "abc1234 --output=json" {
+		t.Errorf("Unexpected parse: level=%q rest=%v", level, rest)
+	}
+
+	// Flags after -- belong to the wrapped command
+	rest, level = parseGlobalFlags([]string{"foreach", "--repos", "x", "--", "-q"})
+	if level != "" || rest[len(rest)-1] != "-q" {
+		t.Errorf("Expected flags after -- to be kept: level=%q rest=%v", level, rest)
+	}
+}
+
+func Test_LogLevelFromConfig(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if got := getLogLevel(); got != "normal" {
+		t.Errorf("Expected default level normal, got %q", got)
+	}
+	runGitInDir(repo.Dir, "config", "prrompt.verbosity", "high")
+	if got := getLogLevel(); got != "verbose" {
+		t.Errorf("Expected verbosity=high to map to verbose, got %q", got)
+	}
+	runGitInDir(repo.Dir, "config", "prrompt.logLevel", "quiet")
+	if got := getLogLevel(); got != "quiet" {
+		t.Errorf("Expected logLevel to take precedence, got %q", got)
+	}
+}
+
+func Test_LogFileRecordsGitCommands(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.logFile", "true")
+	runGitInDir(repo.Dir, "config", "prrompt.logLevel", "quiet")
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	// Reset logging after returning to the original directory
+	defer initLogging("normal")
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	initLogging("")
+
+	if _, err := processCommit(commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(repo.Dir, ".git/prrompt/prrompt.log"))
+	if err != nil {
+		t.Fatalf("Expected a log file: %v", err)
+	}
+	if !strings.Contains(string(data), "debug   git cherry-pick "+commitSHA) {
+		t.Errorf("Expected git commands in the log file, got:\n%s", data)
+	}
+	if !strings.Contains(string(data), "normal  Branch: "+defaultBranchPrefix) {
+		t.Errorf("Expected normal output in the log file, got:\n%s", data)
+	}
+}
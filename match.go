@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// fileMatch explains how a path is classified, following isPromptFile.
+type fileMatch struct {
+	File   string
+	Prompt bool
+	// Rule is what included the file, or considered it and left it out
+	// when it is not a prompt; Exclusion the exclude pattern that
+	// suppressed a match.
+	Rule      string
+	Exclusion string
+}
+
+// explainMatch returns how isPromptFile decides about file. A control file
+// pattern has the first say, then prrompt.promptPatterns (or the defaults),
+// then prrompt.excludePatterns.
+func explainMatch(file string) fileMatch {
+	m := fileMatch{File: file}
+	if rule := controlFileRule(file); rule.file != "" {
+		m.Rule = fmt.Sprintf("%q in %s", rule.pattern, rule.file)
+		if !rule.include {
+			return m
+		}
+	} else {
+		for _, pattern := range getPromptPatterns() {
+			if strings.HasPrefix(file, pattern) {
+				m.Rule = fmt.Sprintf("%q from prrompt.promptPatterns, %s", pattern, configSource("prrompt.promptPatterns"))
+				break
+			}
+		}
+		if m.Rule == "" {
+			return m
+		}
+	}
+	for _, pattern := range getExcludePatterns() {
+		if matchGlob(pattern, file) {
+			m.Exclusion = fmt.Sprintf("%q from prrompt.excludePatterns, %s", pattern, configSource("prrompt.excludePatterns"))
+			return m
+		}
+	}
+	m.Prompt = true
+	return m
+}
+
+func (m fileMatch) String() string {
+	switch {
+	case m.Prompt:
+		return fmt.Sprintf("%s: prompt, matched by %s", m.File, m.Rule)
+	case m.Exclusion != "":
+		return fmt.Sprintf("%s: excluded, matched by %s but excluded by %s", m.File, m.Rule, m.Exclusion)
+	case m.Rule != "":
+		return fmt.Sprintf("%s: ignored by %s", m.File, m.Rule)
+	}
+	return fmt.Sprintf("%s: not a prompt, no pattern matches", m.File)
+}
+
+// runMatch implements `prrompt match <path>... | --commit <sha>`: it prints
+// for each path, or each file the commit changes, whether it is a prompt
+// file and which pattern or exclusion decided. For a commit it also reports
+// files left out for their size or type and a commit message matching
+// prrompt.messagePatterns.
+func runMatch(args []string) error {
+	usage := fmt.Errorf("usage: %s match <path>... | --commit <sha>", toolName)
+	if len(args) == 0 {
+		return usage
+	}
+	commit, found := strings.CutPrefix(args[0], "--commit=")
+	if args[0] == "--commit" && len(args) == 2 {
+		commit, found = args[1], true
+	} else if found && len(args) != 1 {
+		return usage
+	}
+	if !found {
+		if strings.HasPrefix(args[0], "-") {
+			return usage
+		}
+		prefix, err := runGit("rev-parse", "--show-prefix")
+		if err != nil {
+			return fmt.Errorf("not in a git repository: %w", err)
+		}
+		for _, arg := range args {
+			fmt.Println(explainMatch(path.Join(prefix, arg)))
+		}
+		return nil
+	}
+
+	info, err := analyzeCommit(commit)
+	if err != nil {
+		return err
+	}
+	if isPromptCommit(info.Message) {
+		fmt.Printf("%s: the message matches prrompt.messagePatterns, so every file not excluded is a prompt\n", shortSHA(info.SHA))
+	}
+	prompts := make(map[string]bool, len(info.PromptFiles))
+	for _, file := range info.PromptFiles {
+		prompts[file] = true
+	}
+	skipped := make(map[string]bool, len(info.SkippedFiles))
+	for _, file := range info.SkippedFiles {
+		skipped[file] = true
+	}
+	for _, file := range append(info.PromptFiles, info.OtherFiles...) {
+		switch m := explainMatch(file); {
+		case skipped[file]:
+			fmt.Printf("%s: left out for its size or type (prrompt.maxFileSize, prrompt.binaryFiles)\n", file)
+		case prompts[file] && !m.Prompt:
+			// Renames keep both paths together, and a prompt commit
+			// message takes every file
+			fmt.Printf("%s: prompt, by the commit message or as the other half of a rename\n", file)
+		default:
+			fmt.Println(m)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ExplainMatch(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.excludePatterns", "prompts/drafts/")
+	os.MkdirAll(filepath.Join(repo.Dir, "docs"), 0755)
+	os.WriteFile(filepath.Join(repo.Dir, "docs", promptIncludeFile), []byte("*.txt\n"), 0644)
+	os.MkdirAll(filepath.Join(repo.Dir, "prompts", "old"), 0755)
+	os.WriteFile(filepath.Join(repo.Dir, "prompts", "old", promptIgnoreFile), []byte("*\n"), 0644)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	for file, want := range map[string]string{
+		"prompts/one.md":        `prompts/one.md: prompt, matched by "prompts/" from prrompt.promptPatterns, default`,
+		"prompts/drafts/x.md":   `prompts/drafts/x.md: excluded, matched by "prompts/" from prrompt.promptPatterns, default but excluded by "prompts/drafts/" from prrompt.excludePatterns, local`,
+		"docs/notes.txt":        `docs/notes.txt: prompt, matched by "*.txt" in docs/.prromptinclude`,
+		"prompts/old/legacy.md": `prompts/old/legacy.md: ignored by "*" in prompts/old/.prromptignore`,
+		"main.go":               "main.go: not a prompt, no pattern matches",
+	} {
+		m := explainMatch(file)
+		if got := m.String(); !strings.HasPrefix(got, want) {
+			t.Errorf("explainMatch(%q) = %q, want %q", file, got, want)
+		}
+		if m.Prompt != isPromptFile(file) {
+			t.Errorf("explainMatch(%q) says prompt=%v, isPromptFile disagrees", file, m.Prompt)
+		}
+	}
+}
+
+func Test_MatchCommit(t *testing.T) {
+	repo := setupTestRepo(t)
+	sha := commitFiles(t, repo.Dir, "Add prompt with code", map[string]string{
+		"prompts/one.md": "# One",
+		"main.go":        "package main",
+	})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if err := runMatch([]string{"--commit", sha}); err != nil {
+		t.Errorf("match --commit failed: %v", err)
+	}
+	if err := runMatch([]string{"--commit"}); err == nil {
+		t.Error("Expected --commit without a commit to fail")
+	}
+	if err := runMatch([]string{"--commit", "nonexistent"}); err == nil {
+		t.Error("Expected an unknown commit to fail")
+	}
+}
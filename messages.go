@@ -0,0 +1,105 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// getMessagePatterns compiles every prrompt.messagePatterns value. Like
+// prrompt.redactPattern, the key is multi-valued since values are regular
+// expressions. Invalid patterns are reported and ignored.
+func getMessagePatterns() []*regexp.Regexp {
+	value, err := gitConfig("--get-all", "prrompt.messagePatterns")
+	if err != nil || value == "" {
+		return nil
+	}
+	var patterns []*regexp.Regexp
+	for _, expr := range strings.Split(value, "\n") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			warnf("ignoring invalid prrompt.messagePatterns %q: %v", expr, err)
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+// isPromptCommit reports whether the commit message matches one of
+// prrompt.messagePatterns, in which case all of the commit's files are
+// extracted as prompts.
+func isPromptCommit(message string) bool {
+	for _, re := range getMessagePatterns() {
+		if re.MatchString(message) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	prefixStyleBracket      = "bracket"
+	prefixStyleConventional = "conventional-scope"
+	prefixStyleTrailer      = "trailer"
+	prefixStyleNone         = "none"
+)
+
+// getCommitPrefixStyle returns prrompt.commitPrefixStyle, where the commit
+// prefix goes: "bracket" ("[prompt] Add greeter"), "conventional-scope"
+// ("prompt: Add greeter"), "trailer" (a Prrompt-Prefix trailer) or "none".
+func getCommitPrefixStyle() string {
+	value, _ := gitConfig("--get", "prrompt.commitPrefixStyle")
+	switch style := strings.ToLower(strings.TrimSpace(value)); style {
+	case prefixStyleConventional, prefixStyleTrailer, prefixStyleNone:
+		return style
+	}
+	return prefixStyleBracket
+}
+
+// prefixSubject marks msg, whose first line is the subject, with the commit
+// prefix as prrompt.commitPrefixStyle asks. The trailer and none styles
+// leave the subject alone; extracted commits are still told apart by their
+// provenance trailers.
+func prefixSubject(msg string) string {
+	switch getCommitPrefixStyle() {
+	case prefixStyleConventional:
+		return getCommitPrefix() + ": " + msg
+	case prefixStyleTrailer, prefixStyleNone:
+		return msg
+	}
+	return "[" + getCommitPrefix() + "] " + msg
+}
+
+// prefixTrailers returns the trailer carrying the commit prefix with the
+// trailer style.
+func prefixTrailers() []trailer {
+	if getCommitPrefixStyle() != prefixStyleTrailer {
+		return nil
+	}
+	return []trailer{{trailerPrefix, getCommitPrefix()}}
+}
+
+// conventionalHeader matches a conventional-commit header such as
+// "chore(prompts): " or "skill!: ".
+var conventionalHeader = regexp.MustCompile(`^([a-zA-Z]+)(\(([^)]*)\))?!?: `)
+
+// hasPromptHeader reports whether the subject starts with a conventional
+// commit header whose type or scope names prompts or skills, which already
+// marks it as a prompt change.
+func hasPromptHeader(subject string) bool {
+	match := conventionalHeader.FindStringSubmatch(subject)
+	if match == nil {
+		return false
+	}
+	for _, part := range []string{match[1], match[3]} {
+		part = strings.ToLower(part)
+		if strings.Contains(part, "prompt") || strings.Contains(part, "skill") {
+			return true
+		}
+	}
+	return false
+}
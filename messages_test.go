@@ -0,0 +1,93 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_hasPromptHeader(t *testing.T) {
+	tests := map[string]bool{
+		"chore(prompts): tighten the reviewer": true,
+		"skill: add release notes skill":       true,
+		"feat(skills)!: rename triage":         true,
+		"feat(api): add endpoint":              false,
+		"Update prompts":                       false,
+	}
+	for subject, want := range tests {
+		if got := hasPromptHeader(subject); got != want {
+			t.Errorf("hasPromptHeader(%q) = %v, want %v", subject, got, want)
+		}
+	}
+}
+
+func Test_MessagePatterns(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.messagePatterns", "^prompt(\\(.*\\))?: ")
+
+	templateFile := filepath.Join(repo.Dir, "templates/review.txt")
+	os.MkdirAll(filepath.Dir(templateFile), 0755)
+	os.WriteFile(templateFile, []byte("Review this code"), 0644)
+	runGitInDir(repo.Dir, "add", templateFile)
+	runGitInDir(repo.Dir, "commit", "-m", "prompt(review): add review template")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected the whole commit to be extracted, got %+v (err %v)", result, err)
+	}
+	if len(result.PromptFiles) != 1 || result.PromptFiles[0] != "templates/review.txt" {
+		t.Errorf("Expected templates/review.txt as a prompt file, got %v", result.PromptFiles)
+	}
+	subject, _ := runGitInDir(repo.Dir, "log", "--format=%s", "-n", "1", result.Branch)
+	if subject != "prompt(review): add review template" {
+		t.Errorf("Expected the conventional header to be kept as the title, got %q", subject)
+	}
+	if strings.Contains(subject, "["+defaultCommitPrefix+"]") {
+		t.Errorf("Expected no [%s] prefix, got %q", defaultCommitPrefix, subject)
+	}
+}
+
+func Test_CommitPrefixStyle(t *testing.T) {
+	repo := setupTestRepo(t)
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	info := &CommitInfo{SHA: strings.Repeat("a", 40), SourceBranch: "feature", Message: "Add greeter\n\nMore detail"}
+	tests := []struct {
+		style, subject, trailer, title string
+	}{
+		{"", "[prompt] Add greeter", "", "[prompt] Update: Add greeter"},
+		{"conventional-scope", "prompt: Add greeter", "", "prompt: Update: Add greeter"},
+		{"trailer", "Add greeter", "Prrompt-Prefix: prompt\n", "Update: Add greeter"},
+		{"none", "Add greeter", "", "Update: Add greeter"},
+	}
+	for _, tt := range tests {
+		runGitInDir(repo.Dir, "config", "--unset", "prrompt.commitPrefixStyle")
+		if tt.style != "" {
+			runGitInDir(repo.Dir, "config", "prrompt.commitPrefixStyle", tt.style)
+		}
+		msg := buildCommitMessage(info)
+		if subject, _, _ := strings.Cut(msg, "\n"); subject != tt.subject {
+			t.Errorf("style %q: expected the subject %q, got %q", tt.style, tt.subject, subject)
+		}
+		if !strings.Contains(msg, "\n\n"+tt.trailer+"Prrompt-Source-Commit: "+info.SHA) {
+			t.Errorf("style %q: expected the provenance trailers after %q, got %q", tt.style, tt.trailer, msg)
+		}
+		if title := changeTitle(tt.subject, changeUpdate); title != tt.title {
+			t.Errorf("style %q: expected the PR title %q, got %q", tt.style, tt.title, title)
+		}
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.commitPrefixStyle", "conventional-scope")
+	runGitInDir(repo.Dir, "config", "prrompt.commitPrefix", "chore(prompts)")
+	if subject := prefixSubject("Add greeter"); subject != "chore(prompts): Add greeter" {
+		t.Errorf("Expected a conventional header with a scope, got %q", subject)
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+// pushLatencyBuckets are the upper bounds, in seconds, of the push latency
+// histogram.
+var pushLatencyBuckets = []float64{0.5, 1, 2, 5, 10, 30, 60}
+
+// metrics collects operational counters for long-running modes (server and
+// daemon), exposed in the Prometheus text format by metricsHandler.
+type metrics struct {
+	mu          sync.Mutex
+	started     time.Time
+	lastSuccess time.Time
+	results     map[string]int // by status
+	skipped     map[string]int // by reason
+	failures    int
+	queueDepth  int
+	pushCount   int
+	pushSum     float64
+	pushBuckets []int
+}
+
+var appMetrics = newMetrics()
+
+func newMetrics() *metrics {
+	return &metrics{
+		started:     time.Now(),
+		results:     make(map[string]int),
+		skipped:     make(map[string]int),
+		pushBuckets: make([]int, len(pushLatencyBuckets)),
+	}
+}
+
+func (m *metrics) observeResult(r *Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.results[r.Status]++
+	switch r.Status {
+	case statusError:
+		m.failures++
+	case statusSkipped:
+		m.skipped[r.Reason]++
+	default:
+		m.lastSuccess = time.Now()
+	}
+}
+
+func (m *metrics) observePush(d time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	seconds := d.Seconds()
+	m.pushCount++
+	m.pushSum += seconds
+	for i, bound := range pushLatencyBuckets {
+		if seconds <= bound {
+			m.pushBuckets[i]++
+		}
+	}
+}
+
+func (m *metrics) setQueueDepth(n int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.queueDepth = n
+}
+
+func (m *metrics) writeTo(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fmt.Fprintln(w, "# HELP prrompt_commits_processed_total Commits processed, by result status.")
+	fmt.Fprintln(w, "# TYPE prrompt_commits_processed_total counter")
+	for _, status := range sortedKeys(m.results) {
+		fmt.Fprintf(w, "prrompt_commits_processed_total{status=%q} %d\n", status, m.results[status])
+	}
+
+	fmt.Fprintln(w, "# HELP prrompt_commits_skipped_total Commits skipped, by reason.")
+	fmt.Fprintln(w, "# TYPE prrompt_commits_skipped_total counter")
+	for _, reason := range sortedKeys(m.skipped) {
+		fmt.Fprintf(w, "prrompt_commits_skipped_total{reason=%q} %d\n", reason, m.skipped[reason])
+	}
+
+	fmt.Fprintln(w, "# HELP prrompt_failures_total Commits whose processing failed.")
+	fmt.Fprintln(w, "# TYPE prrompt_failures_total counter")
+	fmt.Fprintf(w, "prrompt_failures_total %d\n", m.failures)
+
+	fmt.Fprintln(w, "# HELP prrompt_queue_depth Jobs waiting to be processed.")
+	fmt.Fprintln(w, "# TYPE prrompt_queue_depth gauge")
+	fmt.Fprintf(w, "prrompt_queue_depth %d\n", m.queueDepth)
+
+	fmt.Fprintln(w, "# HELP prrompt_push_duration_seconds Time spent pushing prompt branches.")
+	fmt.Fprintln(w, "# TYPE prrompt_push_duration_seconds histogram")
+	for i, bound := range pushLatencyBuckets {
+		fmt.Fprintf(w, "prrompt_push_duration_seconds_bucket{le=\"%g\"} %d\n", bound, m.pushBuckets[i])
+	}
+	fmt.Fprintf(w, "prrompt_push_duration_seconds_bucket{le=\"+Inf\"} %d\n", m.pushCount)
+	fmt.Fprintf(w, "prrompt_push_duration_seconds_sum %g\n", m.pushSum)
+	fmt.Fprintf(w, "prrompt_push_duration_seconds_count %d\n", m.pushCount)
+
+	if !m.lastSuccess.IsZero() {
+		fmt.Fprintln(w, "# HELP prrompt_last_success_timestamp_seconds Unix time of the last extraction.")
+		fmt.Fprintln(w, "# TYPE prrompt_last_success_timestamp_seconds gauge")
+		fmt.Fprintf(w, "prrompt_last_success_timestamp_seconds %d\n", m.lastSuccess.Unix())
+	}
+}
+
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for key := range counts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// statusHandler serves /healthz and /metrics for long-running modes. healthy
+// reports whether the mode can still make progress; nil means always
+// healthy.
+func statusHandler(m *metrics, healthy func() error) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+		if healthy != nil {
+			if err := healthy(); err != nil {
+				http.Error(w, err.Error(), http.StatusServiceUnavailable)
+				return
+			}
+		}
+		fmt.Fprintln(w, "ok")
+	})
+	mux.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+		m.writeTo(w)
+	})
+	return mux
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_MetricsEndpoint(t *testing.T) {
+	m := newMetrics()
+	m.observeResult(&Result{Status: statusExtracted})
+	m.observeResult(&Result{Status: statusSkipped, Reason: reasonNoPromptFiles})
+	m.observeResult(&Result{Status: statusError})
+	m.observePush(1500 * time.Millisecond)
+	m.setQueueDepth(3)
+
+	server := httptest.NewServer(statusHandler(m, nil))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/metrics")
+	if err != nil {
+		t.Fatalf("GET /metrics failed: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	for _, want := range []string{
+		`prrompt_commits_processed_total{status="extracted"} 1`,
+		`prrompt_commits_skipped_total{reason="no-prompt-files"} 1`,
+		`prrompt_failures_total 1`,
+		`prrompt_queue_depth 3`,
+		`prrompt_push_duration_seconds_bucket{le="1"} 0`,
+		`prrompt_push_duration_seconds_bucket{le="2"} 1`,
+		`prrompt_push_duration_seconds_count 1`,
+	} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("Expected %q in metrics:\n%s", want, body)
+		}
+	}
+}
+
+func Test_HealthEndpoint(t *testing.T) {
+	healthy := true
+	server := httptest.NewServer(statusHandler(newMetrics(), func() error {
+		if !healthy {
+			return errors.New("worker stalled")
+		}
+		return nil
+	}))
+	defer server.Close()
+
+	resp, _ := http.Get(server.URL + "/healthz")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected 200 from /healthz, got %d", resp.StatusCode)
+	}
+
+	healthy = false
+	resp, _ = http.Get(server.URL + "/healthz")
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("Expected 503 from unhealthy /healthz, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	mirrorModeAlso = "also"
+	mirrorModeOnly = "only"
+)
+
+const defaultMirrorMode = mirrorModeAlso
+
+// getMirrorURL returns the central prompt repository URL; "" disables
+// mirroring.
+func getMirrorURL() string {
+	value, _ := gitConfig("--get", "prrompt.mirror.url")
+	return value
+}
+
+func getMirrorMode() string {
+	value, err := gitConfig("--get", "prrompt.mirror.mode")
+	if err != nil {
+		return defaultMirrorMode
+	}
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == mirrorModeOnly {
+		return mirrorModeOnly
+	}
+	return defaultMirrorMode
+}
+
+func getMirrorBaseBranch() string {
+	value, err := gitConfig("--get", "prrompt.mirror.baseBranch")
+	if err != nil || value == "" {
+		return defaultBaseBranch
+	}
+	return value
+}
+
+// getMirrorPathPrefix returns the directory prompt files are placed under in
+// the mirror, defaulting to the name of the source repository.
+func getMirrorPathPrefix() string {
+	value, err := gitConfig("--get", "prrompt.mirror.pathPrefix")
+	if err == nil && value != "" {
+		return strings.Trim(value, "/")
+	}
+	if repoPath := getGitHubRepoPath(); repoPath != "" {
+		return filepath.Base(repoPath)
+	}
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "repo"
+	}
+	return filepath.Base(toplevel)
+}
+
+// mirrorPrompts commits the commit's prompt files, rewritten under the path
+// prefix, to a branch of the central prompt repository and pushes it. The
+// mirror is kept as a cached clone under .git/prrompt/mirror.
+func mirrorPrompts(info *CommitInfo) error {
+	mirrorURL := getMirrorURL()
+	gitDir, err := runGit("rev-parse", "--absolute-git-dir")
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+	mirrorDir := filepath.Join(gitDir, "prrompt", "mirror")
+
+	if _, err := os.Stat(mirrorDir); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(mirrorDir), 0755); err != nil {
+			return fmt.Errorf("failed to create mirror directory: %w", err)
+		}
+		if _, err := runGit("clone", "--quiet", mirrorURL, mirrorDir); err != nil {
+			return fmt.Errorf("failed to clone mirror %s: %w", mirrorURL, err)
+		}
+	} else {
+		runGitInDir(mirrorDir, "remote", "set-url", "origin", mirrorURL)
+		if _, err := runGitInDir(mirrorDir, "fetch", "--quiet", "origin"); err != nil {
+			return fmt.Errorf("failed to fetch mirror %s: %w", mirrorURL, err)
+		}
+	}
+
+	prefix := getMirrorPathPrefix()
+	base := getMirrorBaseBranch()
+	mirrorBranch := fmt.Sprintf("%s/%s-%s", getBranchPrefix(), prefix, abbrevSHA(info.SHA))
+
+	if _, err := runGitInDir(mirrorDir, "checkout", "-f", "-B", mirrorBranch, "origin/"+base); err != nil {
+		return fmt.Errorf("failed to create mirror branch: %w", err)
+	}
+
+	for _, file := range info.PromptFiles {
+		target := filepath.Join(mirrorDir, prefix, file)
+		if blobAt(info.SHA, file) == "" {
+			os.Remove(target)
+			continue
+		}
+		if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+			return fmt.Errorf("failed to create %s: %w", filepath.Dir(target), err)
+		}
+		if err := os.WriteFile(target, []byte(showFile(info.SHA, file)), 0644); err != nil {
+			return fmt.Errorf("failed to write %s: %w", target, err)
+		}
+	}
+
+	if _, err := runGitInDir(mirrorDir, "add", "-A", "--", prefix); err != nil {
+		return fmt.Errorf("failed to stage mirrored prompts: %w", err)
+	}
+
+	// Commit with the source repository's identity, which may be set only
+	// in its local config
+	commitArgs := []string{}
+	for _, key := range []string{"user.name", "user.email"} {
+		if value, err := gitConfig("--get", key); err == nil && value != "" {
+			commitArgs = append(commitArgs, "-c", key+"="+value)
+		}
+	}
+	commitArgs = append(commitArgs, "commit", "-m", buildCommitMessage(info))
+	if info.DuplicateOf != "" {
+		commitArgs = append(commitArgs, "--allow-empty")
+	}
+	if _, err := runGitInDir(mirrorDir, commitArgs...); err != nil {
+		return fmt.Errorf("failed to commit mirrored prompts: %w", err)
+	}
+	if _, err := runGitInDir(mirrorDir, "push", "--quiet", "origin", mirrorBranch); err != nil {
+		return fmt.Errorf("failed to push mirror branch: %w", err)
+	}
+
+	info.MirrorBranch = mirrorBranch
+	infof("Mirror branch: %s", mirrorBranch)
+	if repoPath := parseGitHubRepoPath(mirrorURL); repoPath != "" {
+		infof("Mirror PR: %s/%s/compare/%s...%s?expand=1", forgeWebURL(), repoPath, base, mirrorBranch)
+	}
+	return nil
+}
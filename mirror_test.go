@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func setupMirrorRemote(t *testing.T) string {
+	mirrorRemote := t.TempDir()
+	runGitInDir(mirrorRemote, "init", "--bare", "-b", "main")
+
+	seed := t.TempDir()
+	runGitInDir(seed, "init", "-b", "main")
+	runGitInDir(seed, "-c", "user.name=Seed", "-c", "user.email=seed@example.com", "commit", "--allow-empty", "-m", "Initial commit")
+	runGitInDir(seed, "push", mirrorRemote, "main")
+	return mirrorRemote
+}
+
+func Test_MirrorPrompts(t *testing.T) {
+	repo := setupTestRepo(t)
+	mirrorRemote := setupMirrorRemote(t)
+	runGitInDir(repo.Dir, "config", "prrompt.mirror.url", mirrorRemote)
+	runGitInDir(repo.Dir, "config", "prrompt.mirror.pathPrefix", "widgets")
+	runGitInDir(repo.Dir, "config", "prrompt.mirror.mode", "only")
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	mirrorBranch := defaultBranchPrefix + "/widgets-" + commitSHA[:7]
+	files, err := runGitInDir(mirrorRemote, "ls-tree", "-r", "--name-only", mirrorBranch)
+	if err != nil {
+		t.Fatalf("Expected %s in the mirror: %s", mirrorBranch, files)
+	}
+	if files != "widgets/prompts/test.md" {
+		t.Errorf("Expected path-rewritten prompt in the mirror, got: %s", files)
+	}
+
+	// mode=only skips the source repository branch
+	branches, _ := runGitInDir(repo.Dir, "branch", "--list", defaultBranchPrefix+"/*")
+	if strings.TrimSpace(branches) != "" {
+		t.Errorf("mirror.mode=only should not create a local prompt branch. Branches: %s", branches)
+	}
+}
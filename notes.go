@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// notesRef holds a note per processed source commit with prrompt.notes,
+// shown by `git log --notes=prrompt`. Unlike .git/prrompt/processed.json
+// it can be pushed, so other clones know what was extracted.
+const notesRef = "refs/notes/prrompt"
+
+// notesFetchRef is where the remote's notes are fetched to before they are
+// merged into notesRef.
+const notesFetchRef = "refs/notes/prrompt-remote"
+
+// prromptNote is what a note records about a source commit's extraction.
+type prromptNote struct {
+	Branch      string
+	Tip         string
+	PRURL       string
+	PromptFiles []string
+}
+
+func (n prromptNote) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Prompt-Branch: %s\n", n.Branch)
+	if n.Tip != "" {
+		fmt.Fprintf(&b, "Prompt-Branch-Tip: %s\n", n.Tip)
+	}
+	if n.PRURL != "" {
+		fmt.Fprintf(&b, "PR: %s\n", n.PRURL)
+	}
+	for _, file := range n.PromptFiles {
+		fmt.Fprintf(&b, "Prompt-File: %s\n", file)
+	}
+	return b.String()
+}
+
+func parseNote(text string) prromptNote {
+	var n prromptNote
+	for _, line := range strings.Split(text, "\n") {
+		key, value, found := strings.Cut(line, ": ")
+		if !found {
+			continue
+		}
+		switch key {
+		case "Prompt-Branch":
+			n.Branch = value
+		case "Prompt-Branch-Tip":
+			n.Tip = value
+		case "PR":
+			n.PRURL = value
+		case "Prompt-File":
+			n.PromptFiles = append(n.PromptFiles, value)
+		}
+	}
+	return n
+}
+
+// readNote returns the note on sha, if it has one.
+func readNote(sha string) (prromptNote, bool) {
+	text, err := runGit("notes", "--ref="+notesRef, "show", sha)
+	if err != nil {
+		return prromptNote{}, false
+	}
+	n := parseNote(text)
+	return n, n.Branch != ""
+}
+
+// listNotes returns the notes by source commit.
+func listNotes() map[string]prromptNote {
+	notes := make(map[string]prromptNote)
+	output, err := runGit("notes", "--ref="+notesRef, "list")
+	if err != nil || output == "" {
+		return notes
+	}
+	for _, line := range strings.Split(output, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			if n, ok := readNote(fields[1]); ok {
+				notes[fields[1]] = n
+			}
+		}
+	}
+	return notes
+}
+
+// writeNotes attaches a note to each extracted commit, replacing an older
+// one, and shares the notes with the remote once a branch was pushed.
+func writeNotes(infos ...*CommitInfo) {
+	if !getBoolConfig("prrompt.notes", false) {
+		return
+	}
+	pushed := false
+	for _, info := range infos {
+		if info.PromptBranch == "" {
+			continue
+		}
+		tip, _ := runGit("rev-parse", "--verify", "--quiet", "refs/heads/"+info.PromptBranch)
+		note := prromptNote{Branch: info.PromptBranch, Tip: tip, PRURL: info.PRURL, PromptFiles: info.PromptFiles}
+		if output, err := runGitRetry("notes", "--ref="+notesRef, "add", "-f", "-m", note.String(), info.SHA); err != nil {
+			warnf("failed to add a note to %s: %s", shortSHA(info.SHA), output)
+			continue
+		}
+		pushed = pushed || info.Pushed
+	}
+	if remote := getRemote(); pushed && hasRemote(remote) {
+		if err := syncNotes(remote); err != nil {
+			warnf("%v, run '%s refs sync' to share the notes", err, toolName)
+		}
+	}
+}
+
+// fetchNotes merges the remote's notes into the local ones. Notes on the
+// same commit are concatenated, keeping every line once.
+func fetchNotes(remote string) error {
+	output, err := runGit("fetch", "--quiet", "--no-tags", remote, "+"+notesRef+":"+notesFetchRef)
+	if err != nil {
+		if strings.Contains(output, "couldn't find remote ref") {
+			return nil
+		}
+		return fmt.Errorf("failed to fetch %s from %s: %s", notesRef, remote, output)
+	}
+	defer runGit("update-ref", "-d", notesFetchRef)
+	if output, err := runGit("notes", "--ref="+notesRef, "merge", "--quiet", "-s", "cat_sort_uniq", notesFetchRef); err != nil {
+		return fmt.Errorf("failed to merge the notes of %s: %s", remote, output)
+	}
+	return nil
+}
+
+// syncNotes fetches and merges the remote's notes, then pushes the result.
+func syncNotes(remote string) error {
+	if err := fetchNotes(remote); err != nil {
+		return err
+	}
+	if _, err := runGit("rev-parse", "--verify", "--quiet", notesRef); err != nil {
+		return nil
+	}
+	if output, err := runGit("push", "--quiet", remote, notesRef+":"+notesRef); err != nil {
+		return fmt.Errorf("failed to push %s to %s: %s", notesRef, remote, output)
+	}
+	return nil
+}
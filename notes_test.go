@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func Test_parseNote(t *testing.T) {
+	note := prromptNote{Branch: "prompt-update/abc1234", Tip: "0123abcd", PRURL: "https://github.com/acme/widgets/pull/7", PromptFiles: []string{"prompts/a.md", "prompts/b.md"}}
+	if got := parseNote(note.String()); !reflect.DeepEqual(got, note) {
+		t.Errorf("parseNote(%q) = %+v, want %+v", note.String(), got, note)
+	}
+}
+
+func Test_Notes(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.notes", "true")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", result, err)
+	}
+	note, ok := readNote(commitSHA)
+	if !ok || note.Branch != result.Branch || !reflect.DeepEqual(note.PromptFiles, []string{"prompts/test.md"}) {
+		t.Fatalf("Expected a note naming %s and prompts/test.md, got %+v", result.Branch, note)
+	}
+	if tip, _ := runGit("rev-parse", result.Branch); note.Tip != tip {
+		t.Errorf("Expected the note to record the tip %s, got %s", tip, note.Tip)
+	}
+	if log, _ := runGit("log", "-1", "--notes=prrompt", commitSHA); !strings.Contains(log, "Prompt-Branch: "+result.Branch) {
+		t.Errorf("Expected git log to show the note, got:\n%s", log)
+	}
+	if remote, _ := runGit("ls-remote", "origin", notesRef); remote == "" {
+		t.Error("Expected the notes pushed with the prompt branch")
+	}
+
+	// Another clone: no local record or branch, only the remote's notes
+	path, _ := processedPath()
+	os.Remove(path)
+	runGit("branch", "-D", result.Branch)
+	runGit("update-ref", "-d", notesRef)
+	if err := fetchNotes("origin"); err != nil {
+		t.Fatalf("fetchNotes failed: %v", err)
+	}
+	again, err := processCommit(commitSHA)
+	if err != nil || again.Reason != reasonAlreadyProcessed || again.Branch != result.Branch {
+		t.Errorf("Expected the noted commit to be skipped, got %+v (err %v)", again, err)
+	}
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	notifyFormatJSON  = "json"
+	notifyFormatSlack = "slack"
+	notifyFormatTeams = "teams"
+)
+
+// notification is the payload posted with prrompt.notifyFormat "json".
+type notification struct {
+	Repository string               `json:"repository"`
+	Branch     string               `json:"branch"`
+	PRURL      string               `json:"prUrl,omitempty"`
+	Author     string               `json:"author"`
+	Files      []string             `json:"files"`
+	Commits    []notificationCommit `json:"commits"`
+}
+
+type notificationCommit struct {
+	SHA     string `json:"sha"`
+	Subject string `json:"subject"`
+}
+
+// getNotifyURL returns the webhook new prompt branches are announced to; ""
+// disables notifications.
+func getNotifyURL() string {
+	value, _ := gitConfig("--get", "prrompt.notifyURL")
+	return strings.TrimSpace(value)
+}
+
+// getNotifyFormat returns the payload format, by default guessed from the
+// webhook host.
+func getNotifyFormat(webhook string) string {
+	value, err := gitConfig("--get", "prrompt.notifyFormat")
+	if err == nil {
+		switch value = strings.ToLower(strings.TrimSpace(value)); value {
+		case notifyFormatJSON, notifyFormatSlack, notifyFormatTeams:
+			return value
+		}
+	}
+	if u, err := url.Parse(webhook); err == nil {
+		switch host := strings.ToLower(u.Hostname()); {
+		case host == "hooks.slack.com":
+			return notifyFormatSlack
+		case strings.HasSuffix(host, ".webhook.office.com"), strings.HasSuffix(host, ".logic.azure.com"):
+			return notifyFormatTeams
+		}
+	}
+	return notifyFormatJSON
+}
+
+// buildNotification describes a pushed prompt branch.
+func buildNotification(infos []*CommitInfo, branch, prURL string) notification {
+	n := notification{Branch: branch, PRURL: prURL, Repository: getGitHubRepoPath()}
+	if n.Repository == "" {
+		if toplevel, err := runGit("rev-parse", "--show-toplevel"); err == nil {
+			n.Repository = filepath.Base(toplevel)
+		}
+	}
+	n.Author, _ = runGit("log", "-1", "--format=%an", infos[0].SHA)
+	seen := make(map[string]bool)
+	for _, info := range infos {
+		subject, _, _ := strings.Cut(info.Message, "\n")
+		n.Commits = append(n.Commits, notificationCommit{info.SHA, redact(subject)})
+		for _, file := range info.PromptFiles {
+			if !seen[file] {
+				seen[file] = true
+				n.Files = append(n.Files, file)
+			}
+		}
+	}
+	return n
+}
+
+// text is the chat message for the notification.
+func (n notification) text() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "New prompt branch %s in %s by %s", n.Branch, n.Repository, n.Author)
+	if len(n.Commits) == 1 {
+		fmt.Fprintf(&b, ": %s", n.Commits[0].Subject)
+	}
+	fmt.Fprintf(&b, "\n%d prompt files: %s", len(n.Files), strings.Join(n.Files, ", "))
+	if n.PRURL != "" {
+		fmt.Fprintf(&b, "\n%s", n.PRURL)
+	}
+	return b.String()
+}
+
+// payload renders the notification in format.
+func (n notification) payload(format string) any {
+	switch format {
+	case notifyFormatSlack:
+		return map[string]string{"text": n.text()}
+	case notifyFormatTeams:
+		return map[string]string{
+			"@type":    "MessageCard",
+			"@context": "https://schema.org/extensions",
+			"summary":  "New prompt branch " + n.Branch,
+			"text":     strings.ReplaceAll(n.text(), "\n", "\n\n"),
+		}
+	}
+	return n
+}
+
+// notifyPush posts to prrompt.notifyURL that a prompt branch was pushed.
+// Failing to notify is only a warning.
+func notifyPush(infos []*CommitInfo, branch, prURL string) {
+	webhook := getNotifyURL()
+	if webhook == "" {
+		return
+	}
+	data, err := json.Marshal(buildNotification(infos, branch, prURL).payload(getNotifyFormat(webhook)))
+	if err != nil {
+		warnf("failed to build notification: %v", err)
+		return
+	}
+	resp, err := httpClient.Post(webhook, "application/json", bytes.NewReader(data))
+	if err != nil {
+		warnf("failed to notify: %v", err)
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		warnf("failed to notify: %s", resp.Status)
+		return
+	}
+	verbosef("✓ Notified %s", withoutCredentials(webhook))
+}
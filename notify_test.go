@@ -0,0 +1,69 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_NotifyPush(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+
+	var got notification
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		json.NewDecoder(r.Body).Decode(&got)
+	}))
+	defer server.Close()
+	runGitInDir(repo.Dir, "config", "prrompt.notifyURL", server.URL)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if requests != 1 {
+		t.Fatalf("Expected one notification, got %d", requests)
+	}
+	if got.Branch != result.Branch || got.Repository != "acme/widgets" || got.Author != "Test User" {
+		t.Errorf("Unexpected notification %+v", got)
+	}
+	if len(got.Files) != 1 || got.Files[0] != "prompts/test.md" || got.PRURL == "" {
+		t.Errorf("Expected the prompt file and PR URL, got %+v", got)
+	}
+}
+
+func Test_NotifyFormats(t *testing.T) {
+	repo := setupTestRepo(t)
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if format := getNotifyFormat("https://hooks.slack.com/services/T0/B0/x"); format != notifyFormatSlack {
+		t.Errorf("Expected slack for a Slack webhook, got %s", format)
+	}
+	if format := getNotifyFormat("https://acme.webhook.office.com/webhookb2/x"); format != notifyFormatTeams {
+		t.Errorf("Expected teams for a Teams webhook, got %s", format)
+	}
+	if format := getNotifyFormat("https://example.com/hook"); format != notifyFormatJSON {
+		t.Errorf("Expected json otherwise, got %s", format)
+	}
+
+	n := notification{Repository: "acme/widgets", Branch: "prompt-update/abc1234", Author: "Ada", Files: []string{"prompts/a.md"},
+		Commits: []notificationCommit{{"abc1234", "Tune the reviewer prompt"}}}
+	slack := n.payload(notifyFormatSlack).(map[string]string)
+	if !strings.Contains(slack["text"], "prompt-update/abc1234") || !strings.Contains(slack["text"], "Tune the reviewer prompt") {
+		t.Errorf("Unexpected Slack text %q", slack["text"])
+	}
+	teams := n.payload(notifyFormatTeams).(map[string]string)
+	if teams["@type"] != "MessageCard" {
+		t.Errorf("Expected a Teams MessageCard, got %v", teams)
+	}
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// reachTimeout bounds the check that a remote's host accepts connections.
+const reachTimeout = 3 * time.Second
+
+// reachableRemotes caches remoteReachable per remote URL for the run.
+var reachableRemotes = map[string]bool{}
+
+// remoteHostPort returns the host and port git connects to for a remote
+// URL, or "" for local repositories.
+func remoteHostPort(remoteURL string) string {
+	if strings.Contains(remoteURL, "://") {
+		u, err := url.Parse(remoteURL)
+		if err != nil || u.Host == "" {
+			return ""
+		}
+		port := u.Port()
+		if port == "" {
+			switch u.Scheme {
+			case "https":
+				port = "443"
+			case "http":
+				port = "80"
+			case "ssh", "git+ssh", "ssh+git":
+				port = "22"
+			case "git":
+				port = "9418"
+			default:
+				return ""
+			}
+		}
+		return net.JoinHostPort(u.Hostname(), port)
+	}
+	// scp-like syntax: [user@]host:path, where host has no slash
+	if host, _, found := strings.Cut(remoteURL, ":"); found && !strings.Contains(host, "/") {
+		if _, h, found := strings.Cut(host, "@"); found {
+			host = h
+		}
+		return net.JoinHostPort(host, "22")
+	}
+	return ""
+}
+
+// remoteReachable reports whether remote can be contacted, so an offline
+// run skips network operations up front instead of waiting for each to
+// fail. It only checks that the host accepts a connection; behind a proxy
+// it assumes it does.
+func remoteReachable(remote string) bool {
+	remoteURL, err := runGit("ls-remote", "--get-url", remote)
+	if err != nil {
+		return false
+	}
+	if reachable, ok := reachableRemotes[remoteURL]; ok {
+		return reachable
+	}
+	reachable := true
+	hostPort := remoteHostPort(remoteURL)
+	proxied := os.Getenv("HTTPS_PROXY") != "" || os.Getenv("https_proxy") != "" || os.Getenv("ALL_PROXY") != ""
+	if hostPort != "" && !proxied {
+		conn, err := net.DialTimeout("tcp", hostPort, reachTimeout)
+		if err != nil {
+			verbosef("Remote %s is unreachable: %v", remote, err)
+			reachable = false
+		} else {
+			conn.Close()
+		}
+	}
+	reachableRemotes[remoteURL] = reachable
+	return reachable
+}
+
+// pendingPush is a prompt branch whose push failed or was skipped offline,
+// waiting for `prrompt push`.
+type pendingPush struct {
+	Branch string `json:"branch"`
+	Remote string `json:"remote"`
+	Base   string `json:"base"`
+	// Commits are the source commits, to open the PR with; empty when the
+	// branch was appended to and already has one.
+	Commits []string  `json:"commits,omitempty"`
+	At      time.Time `json:"at"`
+	// Reason is the push failure code (see pushFailureReason); Error is
+	// why the last push failed, after its retries, empty for a branch
+	// queued offline or left pushing in the background.
+	Reason string `json:"reason,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+func pendingPushPath() (string, error) {
+	commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return filepath.Join(commonDir, "prrompt", "pending-push.json"), nil
+}
+
+func loadPendingPushes() ([]pendingPush, error) {
+	path, err := pendingPushPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var pending []pendingPush
+	if err := json.Unmarshal(data, &pending); err != nil {
+		return nil, fmt.Errorf("corrupt %s: %w", path, err)
+	}
+	return pending, nil
+}
+
+func savePendingPushes(pending []pendingPush) error {
+	path, err := pendingPushPath()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(pending, "", "  ")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// queuePendingPush records that branch still has to be pushed. A branch
+// already queued keeps its first entry, so the PR is opened for all of its
+// commits, and takes the latest error.
+func queuePendingPush(entry pendingPush) error {
+	pending, err := loadPendingPushes()
+	if err != nil {
+		return err
+	}
+	for i := range pending {
+		if pending[i].Branch == entry.Branch {
+			if len(pending[i].Commits) > 0 {
+				pending[i].Commits = append(pending[i].Commits, entry.Commits...)
+			}
+			pending[i].Reason, pending[i].Error = entry.Reason, entry.Error
+			return savePendingPushes(pending)
+		}
+	}
+	entry.At = time.Now().UTC()
+	return savePendingPushes(append(pending, entry))
+}
+
+// runPush implements `prrompt push [--list | --retry-failed]`: it pushes
+// the prompt branches queued while offline or after a failed push, and
+// opens their PRs. With --retry-failed it only pushes those whose push
+// failed.
+func runPush(args []string) error {
+	list, failedOnly := false, false
+	for _, arg := range args {
+		switch {
+		case arg == "--list" && !failedOnly:
+			list = true
+		case arg == "--retry-failed" && !list:
+			failedOnly = true
+		default:
+			return fmt.Errorf("usage: %s push [--list | --retry-failed]", toolName)
+		}
+	}
+	pending, err := loadPendingPushes()
+	if err != nil {
+		return err
+	}
+	if !list {
+		// Skill changes the registry missed go out with the pushes
+		publishRecords(nil)
+	}
+	if len(pending) == 0 {
+		fmt.Println("No pending pushes")
+		return nil
+	}
+	if list {
+		for _, entry := range pending {
+			fmt.Printf("  %s -> %s (since %s)\n", entry.Branch, entry.Remote, entry.At.Local().Format(time.DateTime))
+			if entry.Error != "" {
+				fmt.Printf("          failed (%s): %s\n", entry.Reason, entry.Error)
+				fmt.Printf("          to fix: %s\n", pushRemedy(entry.Reason, entry.Remote, entry.Branch))
+			}
+		}
+		return nil
+	}
+
+	var remaining []pendingPush
+	for _, entry := range pending {
+		if failedOnly && entry.Error == "" {
+			remaining = append(remaining, entry)
+			continue
+		}
+		if !branchExists(entry.Branch) {
+			fmt.Printf("  gone    %s (deleted locally)\n", entry.Branch)
+			continue
+		}
+		if !hasRemote(entry.Remote) || !remoteReachable(entry.Remote) {
+			fmt.Printf("  pending %s (%s is unreachable)\n", entry.Branch, entry.Remote)
+			if entry.Error == "" {
+				entry.Reason = pushFailOffline
+			}
+			remaining = append(remaining, entry)
+			continue
+		}
+		if err := timedPush(entry.Remote, entry.Branch, "-u"); err != nil {
+			entry.Reason = pushFailureReason(err)
+			fmt.Printf("  failed  %s (%s): %v\n", entry.Branch, entry.Reason, err)
+			if !errors.Is(err, errPushInBackground) {
+				entry.Error = err.Error()
+				fmt.Printf("          to fix: %s\n", pushRemedy(entry.Reason, entry.Remote, entry.Branch))
+			}
+			remaining = append(remaining, entry)
+			continue
+		}
+		fmt.Printf("  pushed  %s\n", entry.Branch)
+		if len(entry.Commits) == 0 {
+			continue
+		}
+		var infos []*CommitInfo
+		for _, sha := range entry.Commits {
+			if info, err := analyzeCommit(sha); err == nil {
+				infos = append(infos, info)
+			}
+		}
+		prURL := ""
+		if len(infos) > 0 {
+			ensureTargetBranch(entry.Remote, entry.Base)
+			prURL = openPR(infos, entry.Base, entry.Branch)
+			publishSkills(infos, entry.Branch, prURL)
+			for _, info := range infos {
+				info.PromptBranch, info.Pushed, info.PRURL = entry.Branch, true, prURL
+			}
+			writeNotes(infos...)
+		} else {
+			prURL = generatePRURL(entry.Base, entry.Branch)
+		}
+		if prURL != "" {
+			fmt.Printf("          PR: %s\n", prURL)
+		}
+	}
+	if err := savePendingPushes(remaining); err != nil {
+		return err
+	}
+	if len(remaining) > 0 {
+		return fmt.Errorf("%d prompt branches are still pending", len(remaining))
+	}
+	return nil
+}
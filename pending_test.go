@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_RemoteHostPort(t *testing.T) {
+	for remoteURL, want := range map[string]string{
+		"https://github.com/acme/widgets.git":    "github.com:443",
+		"ssh://git@example.com:2222/widgets.git": "example.com:2222",
+		"git@github.com:acme/widgets.git":        "github.com:22",
+		"/srv/git/widgets.git":                   "",
+		"file:///srv/git/widgets.git":            "",
+		"../widgets":                             "",
+	} {
+		if got := remoteHostPort(remoteURL); got != want {
+			t.Errorf("remoteHostPort(%q) = %q, want %q", remoteURL, got, want)
+		}
+	}
+}
+
+func Test_PendingPush(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	t.Setenv("HTTPS_PROXY", "")
+	t.Setenv("https_proxy", "")
+	t.Setenv("ALL_PROXY", "")
+
+	// Point the remote at a port nothing listens on
+	rewrite, _ := runGitInDir(repo.Dir, "config", "--get-regexp", `^url\..*\.insteadof$`)
+	key, _, _ := strings.Cut(rewrite, " ")
+	runGitInDir(repo.Dir, "config", "--unset", key)
+	runGitInDir(repo.Dir, "config", "url.ssh://127.0.0.1:1/widgets.git.insteadOf", "https://github.com/acme/widgets.git")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if result.Pushed || result.PRURL != "" {
+		t.Errorf("Expected no push and no PR offline, got %+v", result)
+	}
+	pending, _ := loadPendingPushes()
+	if len(pending) != 1 || pending[0].Branch != result.Branch || len(pending[0].Commits) != 1 {
+		t.Fatalf("Expected %s to be queued, got %+v", result.Branch, pending)
+	}
+
+	// Back online
+	runGitInDir(repo.Dir, "config", "--unset", "url.ssh://127.0.0.1:1/widgets.git.insteadOf")
+	runGitInDir(repo.Dir, "config", key, "https://github.com/acme/widgets.git")
+	if err := runPush(nil); err != nil {
+		t.Fatalf("prrompt push failed: %v", err)
+	}
+	if _, err := runGitInDir(repo.Dir, "rev-parse", "--verify", "refs/remotes/origin/"+result.Branch); err != nil {
+		t.Errorf("Expected %s to be pushed", result.Branch)
+	}
+	if pending, _ := loadPendingPushes(); len(pending) != 0 {
+		t.Errorf("Expected the queue to be empty, got %+v", pending)
+	}
+}
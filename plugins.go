@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// pluginPrefix names plugin executables: `prrompt foo` runs prrompt-foo
+// from PATH, like git does for its subcommands.
+const pluginPrefix = "prrompt-"
+
+var pluginName = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// findPlugin returns the path of the plugin executable for a subcommand, or
+// "" when there is none.
+func findPlugin(name string) string {
+	if !pluginName.MatchString(name) {
+		return ""
+	}
+	path, err := exec.LookPath(pluginPrefix + name)
+	if err != nil {
+		return ""
+	}
+	return path
+}
+
+// discoverPlugins returns the plugins on PATH by name, each with the first
+// executable found for it.
+func discoverPlugins() map[string]string {
+	plugins := make(map[string]string)
+	for _, dir := range filepath.SplitList(os.Getenv("PATH")) {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, entry := range entries {
+			name, ok := strings.CutPrefix(entry.Name(), pluginPrefix)
+			if !ok || !pluginName.MatchString(name) || plugins[name] != "" {
+				continue
+			}
+			info, err := entry.Info()
+			if err != nil || info.IsDir() || info.Mode()&0111 == 0 {
+				continue
+			}
+			plugins[name] = filepath.Join(dir, entry.Name())
+		}
+	}
+	return plugins
+}
+
+// runPlugins implements `prrompt plugins list`.
+func runPlugins(args []string) error {
+	if len(args) != 1 || args[0] != "list" {
+		return fmt.Errorf("usage: %s plugins list", toolName)
+	}
+	plugins := discoverPlugins()
+	if len(plugins) == 0 {
+		fmt.Printf("No plugins found (executables named %s<name> on PATH)\n", pluginPrefix)
+		return nil
+	}
+	names := make([]string, 0, len(plugins))
+	for name := range plugins {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Printf("  %-12s %s\n", name, plugins[name])
+	}
+	return nil
+}
+
+// runPlugin runs a plugin with the remaining arguments, passing through the
+// terminal, and returns its exit code.
+func runPlugin(path string, args []string) int {
+	cmd := exec.Command(path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append(os.Environ(), "PRROMPT_VERSION="+getVersion())
+	if err := cmd.Run(); err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return exitErr.ExitCode()
+		}
+		fmt.Printf("failed to run %s: %v\n", path, err)
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_DiscoverPlugins(t *testing.T) {
+	binDir := t.TempDir()
+	os.WriteFile(filepath.Join(binDir, "prrompt-stats"), []byte("#!/bin/sh\nexit 3\n"), 0755)
+	os.WriteFile(filepath.Join(binDir, "prrompt-notes.txt"), []byte("not a plugin"), 0644)
+	t.Setenv("PATH", binDir)
+
+	plugins := discoverPlugins()
+	if len(plugins) != 1 || plugins["stats"] == "" {
+		t.Fatalf("Expected only the stats plugin, got %v", plugins)
+	}
+	path := findPlugin("stats")
+	if path == "" {
+		t.Fatal("Expected findPlugin to find stats")
+	}
+	if code := runPlugin(path, nil); code != 3 {
+		t.Errorf("Expected the plugin's exit code 3, got %d", code)
+	}
+	if findPlugin("HEAD~1") != "" {
+		t.Error("Expected no plugin for a revision")
+	}
+}
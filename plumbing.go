@@ -0,0 +1,257 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runGitBytes runs git with env and stdin and returns its exact stdout, for
+// blob contents that must not be trimmed. It is retried while concurrent
+// maintenance interferes, see retryGit.
+func runGitBytes(env []string, stdin []byte, args ...string) ([]byte, error) {
+	var output []byte
+	_, err := retryGit(func() (string, error) {
+		var err error
+		output, err = runGitBytesOnce(env, stdin, args...)
+		return "", err
+	})
+	return output, err
+}
+
+func runGitBytesOnce(env []string, stdin []byte, args ...string) ([]byte, error) {
+	debugf("git %s", strings.Join(args, " "))
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err != nil && stderr.Len() > 0 {
+		return output, fmt.Errorf("%w: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return output, err
+}
+
+// treeEntry returns the mode and object id of path at rev, or "" when it
+// does not exist there.
+func treeEntry(rev, path string) (mode, blob string) {
+	entry, err := runGitRetry("ls-tree", rev, "--", path)
+	meta, _, found := strings.Cut(entry, "\t")
+	fields := strings.Fields(meta)
+	if err != nil || !found || len(fields) != 3 {
+		return "", ""
+	}
+	return fields[0], fields[2]
+}
+
+// mergeBlobs merges the changes from base to theirs into ours, like the
+// cherry-pick of the work tree backend. It returns the merged blob, or ""
+// when they conflict.
+func mergeBlobs(tmpDir, base, ours, theirs string) (string, error) {
+	var paths []string
+	for i, blob := range []string{ours, base, theirs} {
+		data, err := runGitBytes(nil, nil, "cat-file", "blob", blob)
+		if err != nil {
+			return "", err
+		}
+		path := filepath.Join(tmpDir, fmt.Sprintf("merge-%d", i))
+		if err := os.WriteFile(path, data, 0644); err != nil {
+			return "", err
+		}
+		paths = append(paths, path)
+	}
+	// merge-file exits with the number of conflicts
+	merged, err := runGitBytes(nil, nil, append([]string{"merge-file", "-p"}, paths...)...)
+	if exitErr, ok := err.(*exec.ExitError); ok && exitErr.ExitCode() > 0 && exitErr.ExitCode() < 128 {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+	blob, err := runGitBytes(nil, merged, "hash-object", "-w", "--stdin")
+	return strings.TrimSpace(string(blob)), err
+}
+
+// plumbingCommit writes the extraction commit of info on top of parent, or
+// as a new root when parent is "", without a checkout: the work tree and
+// index are left alone, so several can be built at once. Each prompt file
+// gets the commit's change, merged with the parent's version when both
+// changed it and the commit's version when they conflict, as in
+// resolveConflicts. It returns the new commit. Reads of existing objects
+// are retried while concurrent maintenance interferes.
+func plumbingCommit(info *CommitInfo, parent string) (string, error) {
+	tree, err := plumbingTree(info, parent)
+	if err != nil {
+		return "", err
+	}
+	if parent != "" && info.DuplicateOf == "" {
+		if parentTree, _ := runGit("rev-parse", parent+"^{tree}"); tree == parentTree {
+			return "", fmt.Errorf("no prompt changes left to commit on %s", shortSHA(parent))
+		}
+	}
+
+	args := []string{"commit-tree", tree, "-m", signoff(buildCommitMessage(info))}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	authorEnv, err := commitAuthorEnv(info.SHA)
+	if err != nil {
+		return "", err
+	}
+	commit, err := retryGit(func() (string, error) {
+		return runGitWithEnv(append(authorEnv, committerEnv()...), append(args, commitSigningArgs()...)...)
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to commit: %w: %s", err, truncate(commit, 200))
+	}
+	return commit, nil
+}
+
+// plumbingTree writes the tree of the extraction commit of info on top of
+// parent in a temporary index and returns it.
+func plumbingTree(info *CommitInfo, parent string) (string, error) {
+	tmpDir, err := makeTempDir("prrompt-plumbing-")
+	if err != nil {
+		return "", err
+	}
+	defer removeTemp(tmpDir)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tmpDir, "index")}
+	readTree := []string{"read-tree", "--empty"}
+	if parent != "" {
+		readTree = []string{"read-tree", parent}
+	}
+	if output, err := retryGit(func() (string, error) { return runGitWithEnv(env, readTree...) }); err != nil {
+		return "", fmt.Errorf("failed to read tree: %w: %s", err, truncate(output, 200))
+	}
+
+	setEntry := func(mode, blob, path string) error {
+		args := []string{"update-index", "--force-remove", "--", path}
+		if blob != "" {
+			args = []string{"update-index", "--add", "--cacheinfo", mode + "," + blob + "," + path}
+		}
+		if output, err := runGitWithEnv(env, args...); err != nil {
+			return fmt.Errorf("failed to update %s: %s", path, output)
+		}
+		return nil
+	}
+	planVersionBumps(info, parent)
+	for _, file := range info.PromptFiles {
+		mode, theirs := treeEntry(info.SHA, file)
+		_, base := treeEntry(info.parent(), file)
+		ourMode, ours := "", ""
+		if parent != "" {
+			ourMode, ours = treeEntry(parent, file)
+		}
+		// A mode change alone (chmod +x) still needs the commit's entry
+		switch {
+		case ours == theirs && ourMode == mode:
+			continue
+		case ours != theirs && ours != base && theirs != "" && ours != "" && base != "":
+			merged, err := mergeBlobs(tmpDir, base, ours, theirs)
+			if err != nil {
+				return "", fmt.Errorf("failed to merge %s: %w", file, err)
+			}
+			if merged != "" {
+				theirs = merged
+			} else {
+				verbosef("Resolved conflict in %s from %s", file, shortSHA(info.SHA))
+			}
+		}
+		if theirs, err = bumpedBlob(info, file, theirs); err != nil {
+			return "", fmt.Errorf("failed to bump the version of %s: %w", file, err)
+		}
+		if err := setEntry(mode, theirs, file); err != nil {
+			return "", err
+		}
+	}
+	// Variants of touched experiments and archived copies as in the commit
+	for _, file := range info.VariantFiles {
+		mode, blob := treeEntry(info.SHA, file)
+		if err := setEntry(mode, blob, file); err != nil {
+			return "", err
+		}
+	}
+	if len(getArchivePatterns()) > 0 {
+		date, err := runGit("show", "-s", "--format=%as", info.SHA)
+		if err != nil {
+			return "", fmt.Errorf("failed to read commit date: %w", err)
+		}
+		for _, file := range info.PromptFiles {
+			mode, blob := treeEntry(info.SHA, file)
+			if target := archivePath(file, date); target != "" && blob != "" {
+				if err := setEntry(mode, blob, target); err != nil {
+					return "", err
+				}
+			}
+		}
+	}
+
+	tree, err := runGitWithEnv(env, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to write tree: %s", tree)
+	}
+	return tree, nil
+}
+
+// signoff adds a Signed-off-by trailer of the committer to msg with
+// prrompt.signoff, as `git commit --signoff` does for commits made with
+// commit-tree.
+func signoff(msg string) string {
+	if !getBoolConfig("prrompt.signoff", false) {
+		return msg
+	}
+	// "Name <email> <timestamp> <zone>"
+	ident, err := runGitWithEnv(committerEnv(), "var", "GIT_COMMITTER_IDENT")
+	if end := strings.LastIndex(ident, ">"); err == nil && end > 0 {
+		msg = appendTrailers(msg, []trailer{{"Signed-off-by", ident[:end+1]}})
+	}
+	return msg
+}
+
+// buildPromptBranch writes the extraction commits of infos without a
+// checkout, on the start point of the first or on previousTip when
+// appending, and points promptBranch at the last. The ref is updated
+// against its expected old value, with the ref lock, so a branch that
+// appeared or moved in the meantime is never overwritten.
+func buildPromptBranch(promptBranch string, infos []*CommitInfo, previousTip string) error {
+	parent := previousTip
+	if start := infos[0].startPoint(); parent == "" && start != emptyTree() {
+		var err error
+		if parent, err = runGit("rev-parse", "--verify", "-q", start+"^{commit}"); err != nil {
+			return fmt.Errorf("failed to resolve %s", start)
+		}
+	}
+	commit := parent
+	for _, info := range infos {
+		var err error
+		if commit, err = plumbingCommit(info, commit); err != nil {
+			return err
+		}
+	}
+	old := previousTip
+	if old == "" {
+		old = strings.Repeat("0", len(commit))
+	}
+	// A lock held by maintenance packing refs is retried
+	if output, err := runGitRetry("update-ref", "-m", "prrompt: extract "+shortSHA(infos[len(infos)-1].SHA), "refs/heads/"+promptBranch, commit, old); err != nil {
+		if errors.Is(err, errGCInterference) {
+			return fmt.Errorf("failed to create %s: %w", promptBranch, err)
+		}
+		return fmt.Errorf("failed to create %s: %s", promptBranch, output)
+	}
+	return nil
+}
+
+// workTreeDirty reports whether the work tree has changes of the user's,
+// staged, unstaged or untracked, that a checkout would carry along or trip
+// over.
+func workTreeDirty() bool {
+	status, err := runGit("status", "--porcelain", "--ignore-submodules=dirty")
+	return err != nil || status != ""
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// postMergeMarker identifies the post-merge hook prrompt installs, so that
+// someone else's hook is never overwritten.
+const postMergeMarker = "# prrompt post-merge hook"
+
+// postMergeHookScript runs `prrompt post-merge` after a merge or pull. A
+// squash merge commits nothing yet; the post-commit hook sees its commit.
+func postMergeHookScript(exePath string) string {
+	return `#!/bin/sh
+` + postMergeMarker + `
+
+[ "$1" = 1 ] && exit 0
+` + hookLookup(exePath) + `
+BRANCH=$(git symbolic-ref --quiet --short HEAD)
+REPO=$(git rev-parse --show-toplevel)
+"$PRROMPT" post-merge --branch "$BRANCH" --repo "$REPO"
+`
+}
+
+// installPostMergeHook writes the post-merge hook with prrompt.postMerge,
+// leaving a hook of another tool in place.
+func installPostMergeHook(hooksDir, exePath string) error {
+	hookPath := filepath.Join(hooksDir, "post-merge")
+	if data, err := os.ReadFile(hookPath); err == nil && !strings.Contains(string(data), postMergeMarker) {
+		warnf("%s already exists, add '%s post-merge' to it to extract prompts arriving through merges", hookPath, exePath)
+		return nil
+	}
+	if err := os.WriteFile(hookPath, []byte(postMergeHookScript(exePath)), 0755); err != nil {
+		return fmt.Errorf("failed to write hook: %w", err)
+	}
+	fmt.Printf("✓ Installed post-merge hook at %s\n", hookPath)
+	return nil
+}
+
+// mergedCommits returns the commits the last merge or pull brought in
+// (ORIG_HEAD..HEAD), oldest first, that change prompt files. Merge commits
+// and commits already on the base branch, here or on the remote, are left
+// out.
+func mergedCommits() ([]string, error) {
+	if _, err := runGit("rev-parse", "--verify", "--quiet", "ORIG_HEAD^{commit}"); err != nil {
+		return nil, nil
+	}
+	args := []string{"log", "--reverse", "--no-merges", "--format=%x1e%H", "--name-only", "ORIG_HEAD..HEAD"}
+	base := getBaseBranch()
+	for _, ref := range []string{"refs/heads/" + base, "refs/remotes/" + getRemote() + "/" + base} {
+		if _, err := runGit("rev-parse", "--verify", "--quiet", ref); err == nil {
+			args = append(args, "^"+ref)
+		}
+	}
+	output, err := runGit(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list merged commits: %s", output)
+	}
+	var commits []string
+	for _, record := range strings.Split(output, "\x1e") {
+		lines := strings.Split(strings.TrimSpace(record), "\n")
+		if lines[0] == "" {
+			continue
+		}
+		for _, file := range lines[1:] {
+			if file != "" && isPromptFile(file) {
+				commits = append(commits, lines[0])
+				break
+			}
+		}
+	}
+	return commits, nil
+}
+
+// runPostMerge implements `prrompt post-merge [--branch <name>] [--repo
+// <path>]`, run by the post-merge hook: with prrompt.postMerge it extracts
+// the prompt changes of the commits a merge or pull brought in, as
+// prrompt.rangeMode says. Commits extracted before, here or by whoever made
+// them, are skipped as for any other run.
+func runPostMerge(args []string) ([]*Result, error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--branch" && i+1 < len(args):
+			os.Setenv(sourceBranchEnv, args[i+1])
+			i++
+		case strings.HasPrefix(arg, "--branch="):
+			os.Setenv(sourceBranchEnv, strings.TrimPrefix(arg, "--branch="))
+		case arg == "--repo" && i+1 < len(args):
+			if err := os.Chdir(args[i+1]); err != nil {
+				return nil, err
+			}
+			i++
+		case strings.HasPrefix(arg, "--repo="):
+			if err := os.Chdir(strings.TrimPrefix(arg, "--repo=")); err != nil {
+				return nil, err
+			}
+		default:
+			return nil, withExitCode(exitConfig, fmt.Errorf("usage: %s post-merge [--branch <name>] [--repo <path>]", toolName))
+		}
+	}
+	if !getBoolConfig("prrompt.postMerge", false) || !getBoolConfig("prrompt.enabled", true) || os.Getenv("PRROMPT_SKIP") == "1" {
+		return nil, nil
+	}
+	// prrompt's own merges into prompt branches bring nothing new
+	if branch, err := currentBranch(); err == nil && strings.HasPrefix(branch, getBranchPrefix()+"/") {
+		return nil, nil
+	}
+	commits, err := mergedCommits()
+	if err != nil || len(commits) == 0 {
+		verbosef("The merge brought in no prompt changes")
+		return nil, err
+	}
+	infof("The merge brought in %s with prompt changes", countOf(len(commits), "commit"))
+	return processCommits(commits, getRangeMode())
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_PostMerge(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "checkout", "-q", "-b", "teammate")
+	prompt := commitFiles(t, repo.Dir, "Tighten the review prompt", map[string]string{"prompts/review.md": "# Review\n"})
+	commitFiles(t, repo.Dir, "Fix the parser", map[string]string{"parser.go": "package main\n"})
+	runGitInDir(repo.Dir, "checkout", "-q", repo.BranchName)
+	commitFiles(t, repo.Dir, "Local work", map[string]string{"main.go": "package main\n"})
+	if output, err := runGitInDir(repo.Dir, "merge", "--no-ff", "-q", "-m", "Merge teammate", "teammate"); err != nil {
+		t.Fatalf("merge failed: %s", output)
+	}
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+	// --branch sets it for the rest of the run
+	t.Setenv(sourceBranchEnv, "")
+
+	if results, err := runPostMerge(nil); err != nil || len(results) != 0 {
+		t.Fatalf("Expected nothing extracted without prrompt.postMerge, got %v %+v", err, results)
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.postMerge", "true")
+	commits, err := mergedCommits()
+	if err != nil || len(commits) != 1 || commits[0] != prompt {
+		t.Fatalf("Expected only the merged prompt commit, got %v %v", err, commits)
+	}
+	results, err := runPostMerge([]string{"--branch", repo.BranchName})
+	if err != nil || len(results) != 1 || results[0].Status != statusExtracted {
+		t.Fatalf("runPostMerge failed: %v %+v", err, results)
+	}
+	if got, _ := runGit("show", results[0].Branch+":prompts/review.md"); got != "# Review" {
+		t.Errorf("Expected the merged prompt on %s, got %q", results[0].Branch, got)
+	}
+
+	// Running again, e.g. from a later hook, creates nothing new
+	results, err = runPostMerge(nil)
+	if err != nil || len(results) != 1 || results[0].Status != statusSkipped {
+		t.Errorf("Expected the extracted commit to be skipped, got %v %+v", err, results)
+	}
+
+	if _, err := runPostMerge([]string{"--unknown"}); exitCodeOf(err) != exitConfig {
+		t.Errorf("Expected a usage error to exit with %d, got %v", exitConfig, err)
+	}
+}
+
+func Test_InstallPostMergeHook(t *testing.T) {
+	hooksDir := t.TempDir()
+	if err := installPostMergeHook(hooksDir, "/opt/prrompt"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(hooksDir, "post-merge"))
+	if !isPrromptHook("post-merge", string(data)) || !strings.Contains(string(data), `post-merge --branch "$BRANCH"`) {
+		t.Errorf("Unexpected hook:\n%s", data)
+	}
+
+	// Someone else's hook is left alone
+	foreign := "#!/bin/sh\necho mine\n"
+	os.WriteFile(filepath.Join(hooksDir, "post-merge"), []byte(foreign), 0755)
+	if err := installPostMergeHook(hooksDir, "/opt/prrompt"); err != nil {
+		t.Fatalf("install failed: %v", err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(hooksDir, "post-merge")); string(data) != foreign {
+		t.Errorf("Expected the foreign hook kept, got:\n%s", data)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// processPR extracts the net prompt changes of a whole GitHub pull request
+// into a companion branch. Running it again refreshes the branch.
+func processPR(number int) error {
+	release, err := acquireLock()
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	remote := getRemote()
+	repoPath := getGitHubRepoPath()
+	if repoPath == "" {
+		return fmt.Errorf("remote %q is not a GitHub repository", remote)
+	}
+
+	pr, err := fetchPullRequest(repoPath, number)
+	if err != nil {
+		return fmt.Errorf("failed to fetch PR #%d: %w", number, err)
+	}
+
+	if _, err := runGit("fetch", remote, pr.Base.Ref, fmt.Sprintf("refs/pull/%d/head", number)); err != nil {
+		return fmt.Errorf("failed to fetch PR #%d: %w", number, err)
+	}
+
+	mergeBase, err := runGitHistory("merge-base", pr.Base.SHA, pr.Head.SHA)
+	if err != nil {
+		return fmt.Errorf("failed to find merge base: %w", err)
+	}
+
+	changes, err := runGit("diff", "--name-status", "--no-renames", mergeBase, pr.Head.SHA)
+	if err != nil {
+		return fmt.Errorf("failed to diff PR #%d: %w", number, err)
+	}
+
+	var updated, deleted []string
+	for _, line := range strings.Split(changes, "\n") {
+		status, file, found := strings.Cut(line, "\t")
+		if !found || !isPromptFile(file) {
+			continue
+		}
+		if status == "D" {
+			deleted = append(deleted, file)
+		} else {
+			updated = append(updated, file)
+		}
+	}
+
+	if len(updated)+len(deleted) == 0 {
+		infof("No prompt changes in PR #%d", number)
+		return nil
+	}
+
+	promptBranch := fmt.Sprintf("%s/pr-%d", getBranchPrefix(), number)
+
+	// Recreate the branch from the PR base so a refresh reflects the PR's
+	// current state rather than stacking on an older extraction. It is
+	// built in a scratch index, leaving the checkout and the user's staged
+	// and unstaged changes alone.
+	tmpDir, err := makeTempDir("prrompt-pr-")
+	if err != nil {
+		return err
+	}
+	defer removeTemp(tmpDir)
+	env := []string{"GIT_INDEX_FILE=" + filepath.Join(tmpDir, "index")}
+	if output, err := runGitWithEnv(env, "read-tree", pr.Base.SHA); err != nil {
+		return fmt.Errorf("failed to read tree: %s", output)
+	}
+	for _, file := range updated {
+		mode, blob := treeEntry(pr.Head.SHA, file)
+		if output, err := runGitWithEnv(env, "update-index", "--add", "--cacheinfo", mode+","+blob+","+file); err != nil {
+			return fmt.Errorf("failed to add %s: %s", file, output)
+		}
+	}
+	if len(deleted) > 0 {
+		args := append([]string{"update-index", "--force-remove", "--"}, deleted...)
+		if output, err := runGitWithEnv(env, args...); err != nil {
+			return fmt.Errorf("failed to remove prompt files: %s", output)
+		}
+	}
+	tree, err := runGitWithEnv(env, "write-tree")
+	if err != nil {
+		return fmt.Errorf("failed to write tree: %s", tree)
+	}
+
+	commitMsg := appendTrailers(prefixSubject(fmt.Sprintf("%s (#%d)", pr.Title, number)), append(prefixTrailers(), []trailer{
+		{trailerSourcePR, fmt.Sprintf("%d", number)},
+		{trailerSourceCommit, pr.Head.SHA},
+		{trailerSourceBranch, pr.Head.Ref},
+		{trailerToolVersion, toolVersion()},
+	}...))
+	args := append([]string{"commit-tree", tree, "-p", pr.Base.SHA, "-m", commitMsg}, commitSigningArgs()...)
+	commit, err := runGitWithEnv(committerEnv(), args...)
+	if err != nil {
+		return fmt.Errorf("failed to commit: %s", truncate(commit, 200))
+	}
+	if output, err := runGit("update-ref", "-m", fmt.Sprintf("prrompt: extract PR #%d", number), "refs/heads/"+promptBranch, commit); err != nil {
+		return fmt.Errorf("failed to update %s: %s", promptBranch, output)
+	}
+
+	pushErr := timedPush(remote, promptBranch, "--force", "-u")
+
+	infof("PR #%d prompt files: %d", number, len(updated)+len(deleted))
+	infof("Branch: %s", promptBranch)
+	if pushErr != nil {
+		warnf("failed to push (you may need to push manually): %v", pushErr)
+		infof("To fix: %s", pushRemedy(pushFailureReason(pushErr), remote, promptBranch))
+	}
+	if prURL := generatePRURL(pr.Base.Ref, promptBranch); prURL != "" {
+		infof("PR: %s", prURL)
+	}
+
+	return nil
+}
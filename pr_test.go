@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ProcessPR(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	// Bare origin reachable through a github.com URL via insteadOf
+	originDir := t.TempDir()
+	runGitInDir(originDir, "init", "--bare", "-b", "main")
+	runGitInDir(repo.Dir, "remote", "add", "origin", "https://github.com/acme/widgets.git")
+	runGitInDir(repo.Dir, "config", "url."+originDir+".insteadOf", "https://github.com/acme/widgets.git")
+	runGitInDir(repo.Dir, "push", "origin", "main")
+
+	// Feature PR touching a prompt and code in separate commits
+	promptFile := filepath.Join(repo.Dir, "prompts/review.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Review prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add review prompt")
+
+	codeFile := filepath.Join(repo.Dir, "src/main.go")
+	os.MkdirAll(filepath.Dir(codeFile), 0755)
+	os.WriteFile(codeFile, []byte("package main"), 0644)
+	runGitInDir(repo.Dir, "add", codeFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add code")
+
+	headSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	baseSHA, _ := runGitInDir(repo.Dir, "rev-parse", "main")
+	runGitInDir(repo.Dir, "push", "origin", "HEAD:refs/pull/7/head")
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/widgets/pulls/7" {
+			http.NotFound(w, r)
+			return
+		}
+		pr := PullRequest{Number: 7, Title: "Big feature"}
+		pr.Head.Ref, pr.Head.SHA = repo.BranchName, headSHA
+		pr.Base.Ref, pr.Base.SHA = "main", baseSHA
+		json.NewEncoder(w).Encode(pr)
+	}))
+	defer server.Close()
+	oldURL := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = oldURL }()
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	// Work in progress, staged and not, is left alone
+	os.WriteFile(codeFile, []byte("package main // edited"), 0644)
+	os.WriteFile(filepath.Join(repo.Dir, "notes.txt"), []byte("staged"), 0644)
+	runGitInDir(repo.Dir, "add", "notes.txt")
+
+	if err := processPR(7); err != nil {
+		t.Fatalf("process-pr failed: %v", err)
+	}
+	if status, _ := runGitInDir(repo.Dir, "status", "--porcelain"); status != "A  notes.txt\n M src/main.go" {
+		t.Errorf("Expected the staged and unstaged changes kept, got %q", status)
+	}
+	if content, _ := os.ReadFile(codeFile); string(content) != "package main // edited" {
+		t.Errorf("Expected the unstaged edit kept, got %q", content)
+	}
+
+	currentBranch, _ := runGitInDir(repo.Dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if currentBranch != repo.BranchName {
+		t.Errorf("Expected to be back on %s, but on %s", repo.BranchName, currentBranch)
+	}
+
+	promptBranch := defaultBranchPrefix + "/pr-7"
+	files, _ := runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", promptBranch)
+	if !strings.Contains(files, "prompts/review.md") {
+		t.Errorf("Expected prompt file on %s. Files: %s", promptBranch, files)
+	}
+	if strings.Contains(files, "src/main.go") || strings.Contains(files, "notes.txt") {
+		t.Errorf("Code file should not be on %s. Files: %s", promptBranch, files)
+	}
+
+	trailers, _ := runGitInDir(repo.Dir, "log", "--format=%(trailers:only,unfold)", "-n", "1", promptBranch)
+	if !strings.Contains(trailers, trailerSourcePR+": 7") {
+		t.Errorf("Expected %s trailer, got: %s", trailerSourcePR, trailers)
+	}
+
+	// Refreshing must rebuild the branch rather than stack another commit
+	if err := processPR(7); err != nil {
+		t.Fatalf("refreshing process-pr failed: %v", err)
+	}
+	count, _ := runGitInDir(repo.Dir, "rev-list", "--count", "main.."+promptBranch)
+	if count != "1" {
+		t.Errorf("Expected a single commit on refreshed %s, got %s", promptBranch, count)
+	}
+}
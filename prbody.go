@@ -0,0 +1,168 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// promptFileChange is a prompt file in the summary of a PR body.
+type promptFileChange struct {
+	Status  string // A, M, D, T or R
+	Path    string
+	OldPath string // for renames
+	Added   int
+	Deleted int
+	Binary  bool
+}
+
+// getPRDiffLines returns prrompt.prDiffLines: the longest diff of a Markdown
+// prompt shown inline in the PR body, 0 for none.
+func getPRDiffLines() int {
+	value, err := gitConfig("--type=int", "--get", "prrompt.prDiffLines")
+	if err != nil {
+		return 0
+	}
+	lines, err := strconv.Atoi(value)
+	if err != nil || lines < 0 {
+		return 0
+	}
+	return lines
+}
+
+// promptFileChanges returns the net change to the prompt files of infos,
+// from before the first commit to the last.
+func promptFileChanges(infos []*CommitInfo) []promptFileChange {
+	var files []string
+	seen := make(map[string]bool)
+	for _, info := range infos {
+		for _, file := range info.PromptFiles {
+			if !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	from, to := infos[0].parent(), infos[len(infos)-1].SHA
+	args := append([]string{"diff", "-z", "-M", "--name-status", from, to, "--"}, files...)
+	statuses, err := runGit(args...)
+	if err != nil {
+		return nil
+	}
+	var changes []promptFileChange
+	index := make(map[string]int)
+	fields := strings.Split(statuses, "\x00")
+	for i := 0; i+1 < len(fields) && fields[i] != ""; i += 2 {
+		change := promptFileChange{Status: fields[i][:1], Path: fields[i+1]}
+		if (change.Status == "R" || change.Status == "C") && i+2 < len(fields) {
+			change.OldPath, change.Path = fields[i+1], fields[i+2]
+			i++
+		}
+		index[change.Path] = len(changes)
+		changes = append(changes, change)
+	}
+
+	args[3] = "--numstat"
+	numstat, _ := runGit(args...)
+	records := strings.Split(numstat, "\x00")
+	for i := 0; i < len(records); i++ {
+		counts := strings.SplitN(records[i], "\t", 3)
+		if len(counts) != 3 {
+			continue
+		}
+		file := counts[2]
+		if file == "" && i+2 < len(records) {
+			// A rename: the old and new path follow
+			file = records[i+2]
+			i += 2
+		}
+		j, ok := index[file]
+		if !ok {
+			continue
+		}
+		if counts[0] == "-" {
+			changes[j].Binary = true
+			continue
+		}
+		changes[j].Added, _ = strconv.Atoi(counts[0])
+		changes[j].Deleted, _ = strconv.Atoi(counts[1])
+	}
+	return changes
+}
+
+var fileChangeVerbs = map[string]string{"A": "Added", "M": "Modified", "D": "Deleted", "T": "Changed type of", "R": "Renamed", "C": "Copied"}
+
+func (c promptFileChange) String() string {
+	file := "`" + c.Path + "`"
+	if c.OldPath != "" {
+		file = "`" + c.OldPath + "` → `" + c.Path + "`"
+	}
+	stats := "binary"
+	if !c.Binary {
+		stats = fmt.Sprintf("+%d −%d", c.Added, c.Deleted)
+	}
+	return fmt.Sprintf("%s %s (%s)", fileChangeVerbs[c.Status], file, stats)
+}
+
+// commitLink returns a Markdown link to sha on the forge, or the short SHA
+// when the remote is not on GitHub.
+func commitLink(sha string) string {
+	if repoPath := getGitHubRepoPath(); repoPath != "" {
+		return fmt.Sprintf("[`%s`](%s/%s/commit/%s)", shortSHA(sha), forgeWebURL(), repoPath, sha)
+	}
+	return "`" + shortSHA(sha) + "`"
+}
+
+// prChangeSummary returns the section of the PR body describing infos: the
+// prompt files added, modified or deleted with their line counts, the
+// source commits and branch, and with prrompt.prDiffLines the diffs of
+// small Markdown prompts.
+func prChangeSummary(infos []*CommitInfo) string {
+	changes := promptFileChanges(infos)
+	if len(changes) == 0 {
+		return ""
+	}
+	var b strings.Builder
+	b.WriteString("### Prompt changes\n\n")
+	for _, change := range changes {
+		fmt.Fprintf(&b, "- %s\n", change)
+	}
+
+	var commits []string
+	for _, info := range infos {
+		commits = append(commits, commitLink(info.SHA))
+	}
+	label := "Source commit"
+	if len(commits) > 1 {
+		label = "Source commits"
+	}
+	fmt.Fprintf(&b, "\n%s: %s on `%s`\n", label, strings.Join(commits, ", "), infos[0].SourceBranch)
+
+	if limit := getPRDiffLines(); limit > 0 {
+		from, to := infos[0].parent(), infos[len(infos)-1].SHA
+		for _, change := range changes {
+			if change.Binary || path.Ext(change.Path) != ".md" || change.Added+change.Deleted > limit {
+				continue
+			}
+			args := []string{"diff", "-M", from, to, "--", change.Path}
+			if change.OldPath != "" {
+				args = append(args, change.OldPath)
+			}
+			diff, err := runGit(args...)
+			if err != nil || diff == "" {
+				continue
+			}
+			// The fence outlasts any in the Markdown itself
+			fence := "```"
+			for strings.Contains(diff, fence) {
+				fence += "`"
+			}
+			fmt.Fprintf(&b, "\n<details><summary>%s</summary>\n\n%sdiff\n%s\n%s\n\n</details>\n", change.Path, fence, diff, fence)
+		}
+	}
+	return b.String()
+}
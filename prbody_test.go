@@ -0,0 +1,59 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_prChangeSummary(t *testing.T) {
+	repo, _ := setupPushableRepo(t)
+	commitFiles(t, repo.Dir, "Add prompts", map[string]string{
+		"prompts/old.md":  "# Old\n\nA prompt long enough to be recognised as renamed\n",
+		"prompts/gone.md": "# Gone",
+	})
+	runGitInDir(repo.Dir, "mv", "prompts/old.md", "prompts/renamed.md")
+	runGitInDir(repo.Dir, "rm", "-q", "prompts/gone.md")
+	sha := commitFiles(t, repo.Dir, "Rework prompts", map[string]string{
+		"prompts/test.md":  "# Test prompt\n\nBe brief.",
+		"prompts/added.md": "# Added",
+	})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	info, err := analyzeCommit(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runGitInDir(repo.Dir, "config", "prrompt.prDiffLines", "3")
+	summary := prChangeSummary([]*CommitInfo{info})
+	for _, want := range []string{
+		"### Prompt changes",
+		"- Added `prompts/added.md` (+1 −0)",
+		"- Deleted `prompts/gone.md` (+0 −1)",
+		"- Renamed `prompts/old.md` → `prompts/renamed.md` (+0 −0)",
+		"- Modified `prompts/test.md` (+3 −1)",
+		"Source commit: [`" + sha[:7] + "`](https://github.com/acme/widgets/commit/" + sha + ") on `" + repo.BranchName + "`",
+		"<details><summary>prompts/added.md</summary>",
+		"+# Added",
+	} {
+		if !strings.Contains(summary, want) {
+			t.Errorf("Expected %q in the summary:\n%s", want, summary)
+		}
+	}
+	// Diffs longer than prrompt.prDiffLines stay out
+	if strings.Contains(summary, "<summary>prompts/test.md") {
+		t.Errorf("Expected the four-line diff left out:\n%s", summary)
+	}
+
+	_, body := prTitleAndBody([]*CommitInfo{info})
+	if !strings.Contains(body, "### Prompt changes") {
+		t.Errorf("Expected the summary in the PR body, got:\n%s", body)
+	}
+	runGitInDir(repo.Dir, "config", "prrompt.prSummary", "false")
+	if _, body := prTitleAndBody([]*CommitInfo{info}); strings.Contains(body, "### Prompt changes") {
+		t.Errorf("Expected no summary with prrompt.prSummary=false, got:\n%s", body)
+	}
+}
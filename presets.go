@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// preset is a named bundle of settings for a common prompt layout.
+type preset struct {
+	Name        string
+	Description string
+	Settings    [][2]string
+}
+
+var builtinPresets = []preset{
+	{"claude", "Claude skills, slash commands and subagents", [][2]string{
+		{"prrompt.promptPatterns", ".claude/skills/,.claude/commands/,.claude/agents/"},
+		{"prrompt.validateSkills", "true"},
+	}},
+	{"cursor", "Cursor rules", [][2]string{
+		{"prrompt.promptPatterns", ".cursor/rules/"},
+	}},
+	{"copilot", "GitHub Copilot prompt and instruction files", [][2]string{
+		{"prrompt.promptPatterns", ".github/prompts/,.github/instructions/,.github/copilot-instructions.md"},
+	}},
+	{"generic", "A top-level prompts/ directory", [][2]string{
+		{"prrompt.promptPatterns", "prompts/"},
+	}},
+	{"all", "Every layout prrompt init knows about", [][2]string{
+		{"prrompt.promptPatterns", strings.Join(knownPromptDirs, ",")},
+	}},
+}
+
+func findPreset(name string) *preset {
+	for i := range builtinPresets {
+		if builtinPresets[i].Name == name {
+			return &builtinPresets[i]
+		}
+	}
+	return nil
+}
+
+// matchedPaths returns the preset's prompt patterns that exist in the work
+// tree.
+func (p *preset) matchedPaths() []string {
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return nil
+	}
+	var matched []string
+	for _, setting := range p.Settings {
+		if setting[0] != "prrompt.promptPatterns" {
+			continue
+		}
+		for _, pattern := range strings.Split(setting[1], ",") {
+			if _, err := os.Stat(filepath.Join(toplevel, pattern)); err == nil {
+				matched = append(matched, pattern)
+			}
+		}
+	}
+	return matched
+}
+
+// runPresets implements `prrompt presets list` and `prrompt presets show
+// <name>`.
+func runPresets(args []string) error {
+	switch {
+	case len(args) == 1 && args[0] == "list":
+		for _, p := range builtinPresets {
+			line := fmt.Sprintf("  %-8s %s", p.Name, p.Description)
+			if matched := p.matchedPaths(); len(matched) > 0 {
+				line += " (found: " + strings.Join(matched, ", ") + ")"
+			}
+			fmt.Println(line)
+		}
+		return nil
+	case len(args) == 2 && args[0] == "show":
+		p := findPreset(args[1])
+		if p == nil {
+			return fmt.Errorf("unknown preset %q, see '%s presets list'", args[1], toolName)
+		}
+		fmt.Printf("%s: %s\n", p.Name, p.Description)
+		if matched := p.matchedPaths(); len(matched) > 0 {
+			fmt.Printf("Found in this repository: %s\n", strings.Join(matched, ", "))
+		}
+		fmt.Println("Apply with:")
+		for _, setting := range p.Settings {
+			fmt.Printf("  git config %s %q\n", setting[0], setting[1])
+		}
+		return nil
+	}
+	return fmt.Errorf("usage: %s presets list | presets show <name>", toolName)
+}
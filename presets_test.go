@@ -0,0 +1,30 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_PresetMatchedPaths(t *testing.T) {
+	repo := setupTestRepo(t)
+	os.MkdirAll(filepath.Join(repo.Dir, ".claude/skills"), 0755)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	p := findPreset("claude")
+	if p == nil {
+		t.Fatal("Expected a claude preset")
+	}
+	if matched := p.matchedPaths(); len(matched) != 1 || matched[0] != ".claude/skills/" {
+		t.Errorf("Expected .claude/skills/ to be found, got %v", matched)
+	}
+	if findPreset("nope") != nil {
+		t.Error("Expected no preset named nope")
+	}
+	if err := runPresets([]string{"show", "nope"}); err == nil {
+		t.Error("Expected an error for an unknown preset")
+	}
+}
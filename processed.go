@@ -0,0 +1,224 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// maxProcessedEntries bounds the processed-commit record; the oldest
+// entries are dropped first.
+const maxProcessedEntries = 1000
+
+// processedEntry records a commit's extraction for the idempotence gate.
+type processedEntry struct {
+	ConfigHash string    `json:"configHash"`
+	Branch     string    `json:"branch"`
+	Tip        string    `json:"tip,omitempty"` // the prompt branch after extraction
+	At         time.Time `json:"at"`
+}
+
+func processedPath() (string, error) {
+	commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return filepath.Join(commonDir, "prrompt", "processed.json"), nil
+}
+
+func loadProcessed() (map[string]processedEntry, error) {
+	state := make(map[string]processedEntry)
+	path, err := processedPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return state, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if err := json.Unmarshal(data, &state); err != nil {
+		return nil, fmt.Errorf("corrupt %s: %w", path, err)
+	}
+	return state, nil
+}
+
+// configHash fingerprints the raw configuration (git config, the config file
+// and environment overrides), so a commit is processed again after a setting
+// changed.
+func configHash() string {
+	h := sha256.New()
+	output, _ := runGit("config", "--includes", "--get-regexp", `^prrompt\.`)
+	fmt.Fprintln(h, output)
+	if file, values := loadConfigFile(); file != "" {
+		data, _ := json.Marshal(values)
+		h.Write(data)
+	}
+	for _, key := range configKeys {
+		for _, name := range configEnvNames(key.Key) {
+			if value, set := os.LookupEnv(name); set {
+				fmt.Fprintf(h, "%s=%s\n", name, value)
+			}
+		}
+	}
+	for _, name := range []string{baseEnv, mainlineEnv} {
+		fmt.Fprintf(h, "%s=%s\n", name, os.Getenv(name))
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// alreadyProcessed returns the prompt branch sha was extracted to with the
+// current configuration, or "" if it wasn't or that branch is gone. A
+// commit this clone has no record of counts as extracted when its note
+// (prrompt.notes) names a branch that exists here or on the remote.
+func alreadyProcessed(sha, hash string) string {
+	fullSHA, err := runGit("rev-parse", "--verify", "--quiet", sha+"^{commit}")
+	if err != nil {
+		return ""
+	}
+	state, err := loadProcessed()
+	if err != nil {
+		warnf("%v", err)
+		return ""
+	}
+	entry, ok := state[fullSHA]
+	if !ok {
+		if note, ok := readNote(fullSHA); ok && (branchExists(note.Branch) || branchExists(getRemote()+"/"+note.Branch)) {
+			return note.Branch
+		}
+		return ""
+	}
+	if entry.ConfigHash != hash {
+		return ""
+	}
+	if _, err := runGit("rev-parse", "--verify", "--quiet", "refs/heads/"+entry.Branch); err != nil {
+		return ""
+	}
+	return entry.Branch
+}
+
+// extractedBranch returns the prompt branch info would be extracted to when
+// it already exists, here or on the remote, and carries info's commit: an
+// extraction this clone lost track of, e.g. after .git/prrompt was removed.
+// A commit with a record is left to alreadyProcessed.
+func extractedBranch(info *CommitInfo) string {
+	if state, err := loadProcessed(); err != nil || state[info.SHA].Branch != "" {
+		return ""
+	}
+	branch := promptBranchName(info)
+	for _, ref := range []string{"refs/heads/" + branch, "refs/remotes/" + getRemote() + "/" + branch} {
+		if _, err := runGit("rev-parse", "--verify", "--quiet", ref); err == nil && branchCarries(ref, info.SHA) {
+			return branch
+		}
+	}
+	return ""
+}
+
+// branchCarries reports whether ref has an extraction commit of sha, or of
+// the commit it was rewritten from.
+func branchCarries(ref, sha string) bool {
+	sources, _ := runGit("log", "--format=%(trailers:key="+trailerSourceCommit+",valueonly,separator=%x20)", localStartPointOf(getTargetBranch())+".."+ref)
+	shas := sourceCommits(sha)
+	for _, source := range strings.Fields(sources) {
+		if containsString(shas, source) {
+			return true
+		}
+	}
+	return false
+}
+
+// existingPRURL returns the PR of an extracted branch: the one its note
+// records, the one the API finds with prrompt.prTool=api, else the compare
+// URL.
+func existingPRURL(sha, branch string) string {
+	if note, ok := readNote(sha); ok && note.Branch == branch && note.PRURL != "" {
+		return note.PRURL
+	}
+	if repoPath := getGitHubRepoPath(); getPRTool() == prToolAPI && repoPath != "" {
+		owner, _, _ := strings.Cut(repoPath, "/")
+		var pulls []struct {
+			HTMLURL string `json:"html_url"`
+		}
+		query := url.Values{"head": {owner + ":" + branch}, "state": {"all"}}
+		if err := githubGet(fmt.Sprintf("/repos/%s/pulls?%s", repoPath, query.Encode()), &pulls); err == nil && len(pulls) > 0 {
+			return pulls[0].HTMLURL
+		}
+	}
+	return generatePRURL(getTargetBranch(), branch)
+}
+
+// recordProcessed stores that the commits were extracted to their prompt
+// branch with the configuration hash, and notes them with prrompt.notes.
+func recordProcessed(hash string, infos ...*CommitInfo) error {
+	writeNotes(infos...)
+	state, err := loadProcessed()
+	if err != nil {
+		return err
+	}
+	for _, info := range infos {
+		tip, _ := runGit("rev-parse", "--verify", "--quiet", "refs/heads/"+info.PromptBranch)
+		state[info.SHA] = processedEntry{ConfigHash: hash, Branch: info.PromptBranch, Tip: tip, At: time.Now().UTC()}
+	}
+	return saveProcessed(state)
+}
+
+// recordRewrite gives newSHA, the commit removeFromSource amended sha into,
+// the processed record and note of sha.
+func recordRewrite(sha, newSHA string) {
+	if state, err := loadProcessed(); err == nil {
+		if entry, ok := state[sha]; ok {
+			state[newSHA] = entry
+			if err := saveProcessed(state); err != nil {
+				warnf("failed to record processed commit: %v", err)
+			}
+		}
+	}
+	if _, ok := readNote(sha); ok {
+		runGit("notes", "--ref="+notesRef, "copy", "-f", sha, newSHA)
+	}
+}
+
+// forgetProcessed drops the processed record of sha.
+func forgetProcessed(sha string) {
+	if state, err := loadProcessed(); err == nil {
+		if _, ok := state[sha]; ok {
+			delete(state, sha)
+			saveProcessed(state)
+		}
+	}
+}
+
+// saveProcessed writes the processed-commit record, dropping the oldest
+// entries beyond maxProcessedEntries.
+func saveProcessed(state map[string]processedEntry) error {
+	if len(state) > maxProcessedEntries {
+		shas := make([]string, 0, len(state))
+		for sha := range state {
+			shas = append(shas, sha)
+		}
+		sort.Slice(shas, func(i, j int) bool { return state[shas[i]].At.Before(state[shas[j]].At) })
+		for _, sha := range shas[:len(shas)-maxProcessedEntries] {
+			delete(state, sha)
+		}
+	}
+
+	path, _ := processedPath()
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(state, "", "  ")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp, path)
+}
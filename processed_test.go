@@ -0,0 +1,98 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_AlreadyProcessed(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	first, err := processCommit(commitSHA)
+	if err != nil || first.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", first, err)
+	}
+	second, err := processCommit("HEAD")
+	if err != nil || second.Reason != reasonAlreadyProcessed || second.Branch != first.Branch {
+		t.Errorf("Expected the second run to be a no-op, got %+v (err %v)", second, err)
+	}
+
+	// A changed setting reprocesses the commit, down to the content check
+	runGitInDir(repo.Dir, "config", "prrompt.tokenCounts", "true")
+	third, err := processCommit(commitSHA)
+	if err != nil || third.Reason != reasonDuplicate {
+		t.Errorf("Expected the commit to be processed again, got %+v (err %v)", third, err)
+	}
+
+	// So does deleting the prompt branch
+	runGitInDir(repo.Dir, "config", "--unset", "prrompt.tokenCounts")
+	runGitInDir(repo.Dir, "branch", "-D", first.Branch)
+	if branch := alreadyProcessed(commitSHA, configHash()); branch != "" {
+		t.Errorf("Expected a deleted prompt branch to clear the record, got %s", branch)
+	}
+}
+
+func Test_AlreadyExtracted(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.dedupe", "force")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	first, err := processCommit(commitSHA)
+	if err != nil || first.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", first, err)
+	}
+	// The record is lost, the branch is still there
+	path, _ := processedPath()
+	os.Remove(path)
+	again, err := processCommit(commitSHA)
+	if err != nil || again.Reason != reasonAlreadyExtracted || again.Branch != first.Branch || !strings.Contains(again.PRURL, "/compare/main..."+first.Branch) {
+		t.Errorf("Expected the commit reported as already extracted to %s with its PR, got %+v (err %v)", first.Branch, again, err)
+	}
+
+	// Only on the remote, as in another clone
+	runGit("branch", "-D", first.Branch)
+	if again, _ := processCommit(commitSHA); again.Reason != reasonAlreadyExtracted {
+		t.Errorf("Expected the pushed branch to count as extracted, got %+v", again)
+	}
+}
+
+func Test_WorkerFinishesInterruptedJob(t *testing.T) {
+	repo := setupTestRepo(t)
+	commitSHA := commitFiles(t, repo.Dir, "Add prompt file", map[string]string{"prompts/test.md": "# Test prompt"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	// A worker died after creating the branch, before recording it
+	info, err := analyzeCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("analyzeCommit failed: %v", err)
+	}
+	if err := buildPromptBranch(promptBranchName(info), []*CommitInfo{info}, ""); err != nil {
+		t.Fatalf("buildPromptBranch failed: %v", err)
+	}
+	result, err := (&workerRun{}).processJob(&queueJob{Commit: commitSHA})
+	if err != nil || result.Status != statusExtracted || result.Branch != promptBranchName(info) {
+		t.Fatalf("Expected the job finished, got %+v (err %v)", result, err)
+	}
+	if branch := alreadyProcessed(commitSHA, configHash()); branch != result.Branch {
+		t.Errorf("Expected the finished job recorded, got %q", branch)
+	}
+}
@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// protectBranch applies branch protection to a newly pushed prompt branch
+// through the GitHub API when prrompt.protectBranches is set: no force
+// pushes and no deletions, while normal pushes (squash window appends) and
+// merging the PR keep working. Failing to protect is only a warning.
+func protectBranch(branch string) {
+	if !getBoolConfig("prrompt.protectBranches", false) {
+		return
+	}
+	repoPath := getGitHubRepoPath()
+	if repoPath == "" {
+		warnf("not protecting %s: %s is not a GitHub remote", branch, getRemote())
+		return
+	}
+	protection := map[string]any{
+		"required_status_checks":        nil,
+		"enforce_admins":                false,
+		"required_pull_request_reviews": nil,
+		"restrictions":                  nil,
+		"allow_force_pushes":            false,
+		"allow_deletions":               false,
+	}
+	if err := githubPut(fmt.Sprintf("/repos/%s/branches/%s/protection", repoPath, branch), protection, nil); err != nil {
+		warnf("failed to protect %s: %v", branch, err)
+		return
+	}
+	verbosef("✓ Protected %s against force pushes", branch)
+}
+
+// runStatus implements `prrompt status`: it compares every prompt branch
+// prrompt created with the tip it recorded, and fails if one was rewritten
+// on the remote since.
+func runStatus(args []string) error {
+	if len(args) > 0 {
+		return fmt.Errorf("usage: %s status", toolName)
+	}
+	state, err := loadProcessed()
+	if err != nil {
+		return err
+	}
+	// The latest recorded tip of each branch
+	latest := make(map[string]processedEntry)
+	for _, entry := range state {
+		if entry.Tip == "" {
+			continue
+		}
+		if known, ok := latest[entry.Branch]; !ok || entry.At.After(known.At) {
+			latest[entry.Branch] = entry
+		}
+	}
+	// and of the branches other clones extracted to (prrompt.notes)
+	for _, note := range listNotes() {
+		if _, ok := latest[note.Branch]; !ok && note.Tip != "" {
+			latest[note.Branch] = processedEntry{Branch: note.Branch, Tip: note.Tip}
+		}
+	}
+	if len(latest) == 0 {
+		fmt.Println("No prompt branches recorded")
+		return nil
+	}
+
+	remote := getRemote()
+	remoteTips := make(map[string]string)
+	if hasRemote(remote) {
+		output, err := runGit("ls-remote", "--heads", remote)
+		if err != nil {
+			return fmt.Errorf("failed to list branches on %s: %s", remote, output)
+		}
+		for _, line := range strings.Split(output, "\n") {
+			if sha, ref, found := strings.Cut(line, "\t"); found {
+				remoteTips[strings.TrimPrefix(ref, "refs/heads/")] = sha
+			}
+		}
+	}
+
+	branches := make([]string, 0, len(latest))
+	for branch := range latest {
+		branches = append(branches, branch)
+	}
+	sort.Strings(branches)
+
+	tampered := 0
+	for _, branch := range branches {
+		recorded := latest[branch].Tip
+		tip, onRemote := remoteTips[branch]
+		switch {
+		case !onRemote:
+			fmt.Printf("  local     %s\n", branch)
+		case tip == recorded:
+			fmt.Printf("  ok        %s\n", branch)
+		case fastForwardOf(remote, branch, recorded, tip):
+			fmt.Printf("  updated   %s (new commits on %s)\n", branch, remote)
+		default:
+			tampered++
+			fmt.Printf("  rewritten %s (created at %s, now %s on %s)\n", branch, shortSHA(recorded), shortSHA(tip), remote)
+		}
+	}
+	if tampered > 0 {
+		return fmt.Errorf("%d prompt branches were force-pushed since prrompt created them", tampered)
+	}
+	return nil
+}
+
+// fastForwardOf reports whether tip, the remote's value of branch, contains
+// the recorded commit, fetching it first if needed.
+func fastForwardOf(remote, branch, recorded, tip string) bool {
+	if _, err := runGit("cat-file", "-e", tip+"^{commit}"); err != nil {
+		runGit("fetch", "--quiet", "--no-tags", remote, "refs/heads/"+branch)
+	}
+	_, err := runGitHistory("merge-base", "--is-ancestor", recorded, tip)
+	return err == nil
+}
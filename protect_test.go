@@ -0,0 +1,76 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func Test_ProtectBranch(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.protectBranches", "true")
+
+	var gotPath string
+	var gotBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method == http.MethodPut {
+			gotPath = r.URL.Path
+			json.NewDecoder(r.Body).Decode(&gotBody)
+		}
+		w.Write([]byte("{}"))
+	}))
+	defer server.Close()
+	oldURL := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = oldURL }()
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if _, err := processCommit(commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	want := "/repos/acme/widgets/branches/" + defaultBranchPrefix + "/" + commitSHA[:7] + "/protection"
+	if gotPath != want {
+		t.Errorf("Expected protection at %s, got %q", want, gotPath)
+	}
+	if gotBody["allow_force_pushes"] != false || gotBody["allow_deletions"] != false {
+		t.Errorf("Expected force pushes and deletions to be blocked, got %v", gotBody)
+	}
+}
+
+func Test_StatusDetectsForcePush(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if err := runStatus(nil); err != nil {
+		t.Fatalf("Expected status to pass right after extraction: %v", err)
+	}
+
+	// A new commit on top is fine
+	runGitInDir(repo.Dir, "checkout", "-q", result.Branch)
+	runGitInDir(repo.Dir, "commit", "-q", "--allow-empty", "-m", "Follow-up")
+	runGitInDir(repo.Dir, "push", "-q", "origin", result.Branch)
+	if err := runStatus(nil); err != nil {
+		t.Errorf("Expected a fast-forward not to be reported: %v", err)
+	}
+
+	// Rewriting the branch is
+	runGitInDir(repo.Dir, "reset", "-q", "--hard", "main")
+	runGitInDir(repo.Dir, "commit", "-q", "--allow-empty", "-m", "Rewritten")
+	runGitInDir(repo.Dir, "push", "-q", "-f", "origin", result.Branch)
+	runGitInDir(repo.Dir, "checkout", "-q", repo.BranchName)
+	if err := runStatus(nil); err == nil {
+		t.Error("Expected status to report the force push")
+	}
+}
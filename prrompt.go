@@ -1,10 +1,14 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"net/url"
 	"os"
 	"os/exec"
+	"path"
 	"path/filepath"
+	"strconv"
 	"strings"
 )
 
@@ -15,6 +19,9 @@ const (
 	defaultBranchPrefix = "prompt-update"
 	defaultBaseBranch   = "main"
 	defaultVerbosity    = "low"
+	defaultDedupe       = "skip"
+	defaultTokenEstimate    = "chars"
+	defaultRemote       = "origin"
 )
 
 const (
@@ -22,13 +29,41 @@ const (
 	verbosityHigh   = "high"
 )
 
+const (
+	dedupeSkip  = "skip"
+	dedupeWarn  = "warn"
+	dedupeForce = "force"
+)
+
+// Trailer keys recorded on every extracted commit.
+const (
+	trailerSourceCommit = "Prrompt-Source-Commit"
+	trailerSourceBranch = "Prrompt-Source-Branch"
+	trailerToolVersion  = "Prrompt-Tool-Version"
+	trailerSourcePR     = "Prrompt-Source-PR"
+	trailerExperiment   = "Prrompt-Experiment"
+	trailerDuplicateOf  = "Prrompt-Duplicate-Of"
+	trailerPrefix       = "Prrompt-Prefix"
+)
+
+// trailerRewrittenFrom links a source commit amended by
+// prrompt.removeFromSource to the commit that was extracted.
+const trailerRewrittenFrom = "Prrompt-Rewritten-From"
+
+// defaultSkipMarkers opt a commit out of extraction when found in its
+// message.
+var defaultSkipMarkers = []string{
+	"[skip prrompt]",
+	"[no-prrompt]",
+}
+
 var defaultPromptPatterns = []string{
 	".claude/skills/",
 	"prompts/",
 }
 
 func getCommitPrefix() string {
-	value, err := runGit("config", "--get", "prrompt.commitPrefix")
+	value, err := gitConfig("--get", "prrompt.commitPrefix")
 	if err != nil {
 		return defaultCommitPrefix
 	}
@@ -39,7 +74,7 @@ func getCommitPrefix() string {
 }
 
 func getBranchPrefix() string {
-	value, err := runGit("config", "--get", "prrompt.branchPrefix")
+	value, err := gitConfig("--get", "prrompt.branchPrefix")
 	if err != nil {
 		return defaultBranchPrefix
 	}
@@ -49,19 +84,111 @@ func getBranchPrefix() string {
 	return value
 }
 
-func getBaseBranch() string {
-	value, err := runGit("config", "--get", "prrompt.baseBranch")
+const (
+	mergeStrategySkip        = "skip"
+	mergeStrategyFirstParent = "first-parent"
+)
+
+const defaultMergeStrategy = mergeStrategySkip
+
+// What to do with commits made directly on the base branch, set with
+// prrompt.onBaseBranch.
+const (
+	onBaseBranchSkip   = "skip"
+	onBaseBranchParent = "parent" // branch from the commit's parent
+)
+
+const defaultOnBaseBranch = onBaseBranchSkip
+
+func getOnBaseBranch() string {
+	value, err := gitConfig("--get", "prrompt.onBaseBranch")
+	if err == nil && strings.ToLower(strings.TrimSpace(value)) == onBaseBranchParent {
+		return onBaseBranchParent
+	}
+	return defaultOnBaseBranch
+}
+
+// mainlineEnv selects the parent of merge commits to extract against for
+// one run; `--mainline` sets it.
+const mainlineEnv = "PRROMPT_MAINLINE"
+
+func getMergeStrategy() string {
+	value, err := gitConfig("--get", "prrompt.mergeStrategy")
 	if err != nil {
-		return defaultBaseBranch
+		return defaultMergeStrategy
 	}
-	if value == "" {
-		return defaultBaseBranch
+	if strings.ToLower(strings.TrimSpace(value)) == mergeStrategyFirstParent {
+		return mergeStrategyFirstParent
+	}
+	return defaultMergeStrategy
+}
+
+// getMainline returns the parent number merge commits are extracted
+// against, or 0 when they are skipped.
+func getMainline() (int, error) {
+	if value := os.Getenv(mainlineEnv); value != "" {
+		mainline, err := strconv.Atoi(value)
+		if err != nil || mainline < 1 {
+			return 0, fmt.Errorf("invalid mainline %q: must be a parent number starting at 1", value)
+		}
+		return mainline, nil
+	}
+	if getMergeStrategy() == mergeStrategyFirstParent {
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// baseEnv overrides prrompt.baseBranch for one run; `--base` sets it.
+const baseEnv = "PRROMPT_BASE"
+
+// stayEnv overrides prrompt.stayOnBranch for one run; `--stay` sets it.
+const stayEnv = "PRROMPT_STAY_ON_BRANCH"
+
+// stayOnBranch reports whether extraction leaves the prompt branch checked
+// out rather than returning to the source branch.
+func stayOnBranch() bool {
+	return getBoolConfig("prrompt.stayOnBranch", false)
+}
+
+// disableStay returns to the source branch after every extraction of a run
+// that creates several prompt branches, since the next one starts from the
+// source branch.
+func disableStay(reason string) {
+	if stayOnBranch() {
+		infof("Returning to the source branch after each extraction, %s", reason)
+		os.Setenv(stayEnv, "false")
+	}
+}
+
+// sourceBranchEnv is the branch the commit was made on, as the hook saw it;
+// `--branch` sets it.
+const sourceBranchEnv = "PRROMPT_SOURCE_BRANCH"
+
+// currentBranch returns the branch the commit was made on: the one passed
+// by the hook, else HEAD's. GUI clients and worktrees can fire the hook in
+// a state where re-deriving it gives a different answer.
+func currentBranch() (string, error) {
+	if branch := os.Getenv(sourceBranchEnv); branch != "" {
+		return branch, nil
+	}
+	return runGit("rev-parse", "--abbrev-ref", "HEAD")
+}
+
+func getBaseBranch() string {
+	if value := os.Getenv(baseEnv); value != "" {
+		return value
+	}
+	value, err := gitConfig("--get", "prrompt.baseBranch")
+	if err != nil || value == "" {
+		branch, _ := detectBaseBranch()
+		return branch
 	}
 	return value
 }
 
 func getPromptPatterns() []string {
-	value, err := runGit("config", "--get", "prrompt.promptPatterns")
+	value, err := gitConfig("--get", "prrompt.promptPatterns")
 	if err != nil || value == "" {
 		return defaultPromptPatterns
 	}
@@ -80,8 +207,53 @@ func getPromptPatterns() []string {
 	return result
 }
 
+func getExcludePatterns() []string {
+	value, err := gitConfig("--get", "prrompt.excludePatterns")
+	if err != nil || value == "" {
+		return nil
+	}
+	var result []string
+	for _, pattern := range strings.Split(value, ",") {
+		pattern = strings.TrimSpace(pattern)
+		if pattern != "" {
+			result = append(result, pattern)
+		}
+	}
+	return result
+}
+
+func getSkipMarkers() []string {
+	value, err := gitConfig("--get", "prrompt.skipMarkers")
+	if err != nil || value == "" {
+		return defaultSkipMarkers
+	}
+	var result []string
+	for _, marker := range strings.Split(value, ",") {
+		marker = strings.TrimSpace(marker)
+		if marker != "" {
+			result = append(result, marker)
+		}
+	}
+	if len(result) == 0 {
+		return defaultSkipMarkers
+	}
+	return result
+}
+
+// hasSkipMarker reports whether message contains one of the skip markers,
+// ignoring case.
+func hasSkipMarker(message string) bool {
+	message = strings.ToLower(message)
+	for _, marker := range getSkipMarkers() {
+		if strings.Contains(message, strings.ToLower(marker)) {
+			return true
+		}
+	}
+	return false
+}
+
 func getVerbosity() string {
-	value, err := runGit("config", "--get", "prrompt.verbosity")
+	value, err := gitConfig("--get", "prrompt.verbosity")
 	if err != nil {
 		return defaultVerbosity
 	}
@@ -92,6 +264,68 @@ func getVerbosity() string {
 	return defaultVerbosity
 }
 
+func getDedupeMode() string {
+	value, err := gitConfig("--get", "prrompt.dedupe")
+	if err != nil {
+		return defaultDedupe
+	}
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == dedupeWarn || value == dedupeForce {
+		return value
+	}
+	return defaultDedupe
+}
+
+func getRemote() string {
+	value, err := gitConfig("--get", "prrompt.remote")
+	if err != nil {
+		return defaultRemote
+	}
+	if value == "" {
+		return defaultRemote
+	}
+	return value
+}
+
+// hasRemote reports whether the named remote is configured.
+func hasRemote(name string) bool {
+	_, err := gitConfig("--get", "remote."+name+".url")
+	return err == nil
+}
+
+func getTokenEstimate() string {
+	value, err := gitConfig("--get", "prrompt.tokenEstimate")
+	if err != nil {
+		return defaultTokenEstimate
+	}
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == estimateWords {
+		return estimateWords
+	}
+	return defaultTokenEstimate
+}
+
+// getTokenBudget returns the per-prompt token budget; 0 disables the check.
+func getTokenBudget() int {
+	value, err := gitConfig("--type=int", "--get", "prrompt.tokenBudget")
+	if err != nil {
+		return 0
+	}
+	budget, err := strconv.Atoi(value)
+	if err != nil || budget < 0 {
+		return 0
+	}
+	return budget
+}
+
+func getBoolConfig(key string, defaultValue bool) bool {
+	value, err := gitConfig("--type=bool", "--get", key)
+	if err != nil {
+		return defaultValue
+	}
+	return value == "true"
+}
+
 type CommitInfo struct {
 	SHA         string
 	Message     string
@@ -99,65 +333,813 @@ type CommitInfo struct {
 	OtherFiles  []string
 	IsMixed     bool
 	SourceBranch string
+	TokenCounts []TokenCount
+	// ExcludedFiles matched a prompt pattern but also an exclusion; they
+	// are treated as other files.
+	ExcludedFiles []string
+	// SkippedFiles are prompt files left out for their size or for being
+	// binary (prrompt.maxFileSize, prrompt.binaryFiles); they are treated
+	// as other files too.
+	SkippedFiles []string
+
+	// FileStatus maps each changed path to its status letter (A, M, D,
+	// T, or R for the new path of a rename); Renames maps renamed paths
+	// to their old path.
+	FileStatus map[string]string
+	Renames    map[string]string
+
+	// Parents is the number of parents; for merges, Mainline is the parent
+	// whose diff is extracted, 0 when merges are skipped.
+	Parents  int
+	Mainline int
+
+	// Stash is set for stash entries, extracted against the commit they
+	// were made on; WIP for commits whose subject starts with a WIP marker.
+	Stash bool
+	WIP   bool
+
+	// EditedMessage replaces the message extracted with, as edited in
+	// `prrompt review`.
+	EditedMessage string
+
+	// AISummary is the model's summary of the prompt changes, with
+	// prrompt.ai.summary.
+	AISummary string
+
+	// VersionBumps are the frontmatter versions the extraction bumped, with
+	// prrompt.versionBump.
+	VersionBumps []versionBump
+
+	// DuplicateOf is the ref already holding the prompt content when the
+	// extraction is recorded anyway; the extraction commit may be empty.
+	DuplicateOf string
+
+	// SkillsChecked is the number of skill files validated, SkillIssues
+	// what was wrong with them; both are reported in the commit body.
+	SkillsChecked int
+	SkillIssues   []lintViolation
+
+	// StartPoint is where the prompt branch is created from when it is not
+	// the base branch. AppendTo is the existing prompt branch of the
+	// current session (prrompt.squashWindow) to add to instead.
+	StartPoint string
+	AppendTo   string
+
+	// Experiments are the prompt experiment IDs the commit touches;
+	// VariantFiles are unchanged variants of them pulled into the extraction.
+	Experiments  []string
+	VariantFiles []string
+
+	// Group is the skill path or file group the commit's extraction is
+	// limited to when a range is extracted by skill (prrompt.rangeMode)
+	// or the commit is split (prrompt.splitBy).
+	Group string
+
+	// Set by extraction
+	PromptBranch string
+	Pushed       bool
+	PushFailure  string
+	PRURL        string
+	MirrorBranch string
+}
+
+func processCommit(commitSHA string) (result *Result, err error) {
+	result = &Result{Status: statusSkipped, Commit: commitSHA}
+	defer func() {
+		if err != nil {
+			result.Status = statusError
+			result.Error = err.Error()
+			result.ExitCode = exitCodeOf(err)
+		}
+		appMetrics.observeResult(result)
+	}()
+
+	if os.Getenv("PRROMPT_SKIP") == "1" {
+		result.Reason = reasonSkipEnv
+		return result, nil
+	}
+	if !getBoolConfig("prrompt.enabled", true) {
+		verbosef("prrompt is disabled in this repository (prrompt.enabled=false)")
+		result.Reason = reasonDisabled
+		return result, nil
+	}
+
+	release, err := acquireLock()
+	if err != nil {
+		return result, err
+	}
+	defer release()
+
+	if _, err := recoverInterrupted(); err != nil {
+		return result, fmt.Errorf("error recovering interrupted extraction: %w", err)
+	}
+
+	commitInfo, err := prepareCommit(commitSHA, result)
+	if err != nil || commitInfo == nil {
+		return result, err
+	}
+
+	if groups := splitCommit(commitInfo); groups != nil {
+		if err := extractSplit(commitInfo, groups, result); err != nil {
+			return result, err
+		}
+	} else {
+		promptBranch := promptBranchName(commitInfo)
+		if session := sessionBranch(commitInfo); session != "" {
+			promptBranch = session
+			commitInfo.AppendTo = session
+		}
+		if err := extractAndMirror(promptBranch, []*CommitInfo{commitInfo}); err != nil {
+			return result, err
+		}
+		result.setExtracted(commitInfo)
+	}
+	if err := recordProcessed(configHash(), commitInfo); err != nil {
+		warnf("failed to record processed commit: %v", err)
+	}
+	if getBoolConfig("prrompt.removeFromSource", false) {
+		// Stashes and WIP commits are left as they are
+		if commitInfo.Stash || commitInfo.WIP {
+			verbosef("Leaving %s untouched", shortSHA(commitInfo.SHA))
+		} else if newHead, err := removeFromSource(commitInfo); err != nil {
+			warnf("not removing prompt changes from %s: %v", commitInfo.SourceBranch, err)
+		} else {
+			result.NewSourceTip = newHead
+			infof("Removed the prompt changes from %s (now at %s)", commitInfo.SourceBranch, shortSHA(newHead))
+		}
+	}
+	return result, nil
 }
 
-func processCommit(commitSHA string) error {
+// prepareCommit analyzes a commit and runs every check that decides whether
+// its prompts are extracted. It returns nil, with result.Reason set, when
+// the commit is skipped.
+func prepareCommit(commitSHA string, result *Result) (*CommitInfo, error) {
 	// Check if we're on a prompt branch - if so, skip to avoid recursion
-	currentBranch, err := runGit("rev-parse", "--abbrev-ref", "HEAD")
-	if err == nil && strings.HasPrefix(currentBranch, getBranchPrefix()+"/") {
+	branch, err := currentBranch()
+	if err == nil && strings.HasPrefix(branch, getBranchPrefix()+"/") {
 		// We're on a prompt branch, don't process
-		return nil
+		result.Reason = reasonPromptBranch
+		return nil, nil
+	}
+
+	// The hook may fire twice for the same commit (e.g. GUI amend flows)
+	if branch := alreadyProcessed(commitSHA, configHash()); branch != "" {
+		infof("Commit %s was already processed into %s", shortSHA(commitSHA), branch)
+		result.Reason = reasonAlreadyProcessed
+		result.Branch = branch
+		return nil, nil
 	}
-	
+
 	commitInfo, err := analyzeCommit(commitSHA)
 	if err != nil {
-		return fmt.Errorf("error analyzing commit: %w", err)
+		return nil, fmt.Errorf("error analyzing commit: %w", err)
+	}
+	commitReview.apply(commitInfo)
+	result.setFiles(commitInfo)
+
+	// Rerunning without a record, e.g. with prrompt.dedupe=force, must not
+	// trip over the commit's own branch
+	if branch := extractedBranch(commitInfo); branch != "" {
+		result.Reason = reasonAlreadyExtracted
+		result.Branch = branch
+		result.PRURL = existingPRURL(commitInfo.SHA, branch)
+		if result.PRURL != "" {
+			infof("Commit %s was already extracted into %s: %s", shortSHA(commitInfo.SHA), branch, result.PRURL)
+		} else {
+			infof("Commit %s was already extracted into %s", shortSHA(commitInfo.SHA), branch)
+		}
+		return nil, nil
+	}
+
+	if hasSkipMarker(commitInfo.Message) {
+		verbosef("Skip marker found in %s, not extracting prompts", commitInfo.SHA[:7])
+		result.Reason = reasonSkipMarker
+		return nil, nil
+	}
+
+	if commitInfo.WIP && getWIPCommits() == wipSkip {
+		verbosef("%s is a work-in-progress commit, not extracting prompts (prrompt.wipCommits=%s)", commitInfo.SHA[:7], wipSkip)
+		result.Reason = reasonWIP
+		return nil, nil
+	}
+
+	if commitInfo.Parents > 1 && commitInfo.Mainline == 0 {
+		if len(commitInfo.PromptFiles) > 0 {
+			infof("Skipping merge commit %s; set prrompt.mergeStrategy=first-parent or pass --mainline=1 to extract its prompt changes", commitInfo.SHA[:7])
+		}
+		result.Reason = reasonMergeCommit
+		return nil, nil
 	}
 
 	if len(commitInfo.PromptFiles) == 0 {
-		return nil
+		if len(commitInfo.ExcludedFiles) > 0 && isVerbose() {
+			printExcludedFiles(commitInfo.ExcludedFiles)
+		}
+		result.Reason = reasonNoPromptFiles
+		return nil, nil
+	}
+
+	if commitInfo.SourceBranch == getBaseBranch() && !commitInfo.Stash {
+		if getOnBaseBranch() != onBaseBranchParent {
+			infof("Commit %s is on the base branch %s, not extracting; set prrompt.onBaseBranch=parent to branch from its parent", commitInfo.SHA[:7], commitInfo.SourceBranch)
+			result.Reason = reasonOnBaseBranch
+			return nil, nil
+		}
+		commitInfo.StartPoint = commitInfo.parent()
 	}
 
-	if err := extractPrompts(commitInfo); err != nil {
-		return fmt.Errorf("error extracting prompts: %w", err)
+	if err := groupVariants(commitInfo); err != nil {
+		return nil, fmt.Errorf("error grouping prompt variants: %w", err)
 	}
 
+	if mode := getDedupeMode(); mode != dedupeForce {
+		duplicate, err := findDuplicate(commitInfo)
+		if err != nil {
+			return nil, fmt.Errorf("error checking for duplicates: %w", err)
+		}
+		if duplicate != "" {
+			infof("Prompt changes from %s are already present on %s", commitInfo.SHA[:7], duplicate)
+			result.DuplicateOf = duplicate
+			_, ownErr := runGit("rev-parse", "--verify", "-q", "refs/heads/"+promptBranchName(commitInfo))
+			if mode == dedupeSkip && (ownErr == nil || !getBoolConfig("prrompt.allowEmptyExtraction", false)) {
+				result.Reason = reasonDuplicate
+				return nil, nil
+			}
+			commitInfo.DuplicateOf = duplicate
+		}
+	}
+
+	commitInfo.SkillsChecked, commitInfo.SkillIssues = validateSkills(commitInfo)
+	for _, issue := range commitInfo.SkillIssues {
+		warnf("skill: %s", issue)
+	}
+
+	if mode := getLintMode(); mode != lintOff {
+		violations := lintPrompts(commitInfo)
+		for _, violation := range violations {
+			warnf("lint: %s", violation)
+		}
+		if len(violations) > 0 && mode == lintBlock {
+			return nil, fmt.Errorf("%d lint violations in prompt files, not extracting (prrompt.lint=block)", len(violations))
+		}
+	}
+	return commitInfo, nil
+}
+
+// extractAndMirror extracts the prepared commits to promptBranch and/or
+// the central prompt repository, as configured.
+func extractAndMirror(promptBranch string, infos []*CommitInfo) error {
+	mirrorURL := getMirrorURL()
+	if mirrorURL == "" || getMirrorMode() != mirrorModeOnly {
+		if err := extractCommits(promptBranch, infos); err != nil {
+			return fmt.Errorf("error extracting prompts: %w", err)
+		}
+	}
+
+	if mirrorURL != "" {
+		for _, info := range infos {
+			if err := mirrorPrompts(info); err != nil {
+				return fmt.Errorf("error mirroring prompts: %w", err)
+			}
+		}
+	}
 	return nil
 }
 
 func main() {
+	commandName = invocationName(os.Args[0])
+	args, err := parseGitOptions(os.Args[1:])
+	if err != nil {
+		fmt.Printf("%v\n", err)
+		exit(1)
+	}
+	args, flagLevel := parseGlobalFlags(args)
+	os.Args = append(os.Args[:1], args...)
+	initLogging(flagLevel)
+	cleanupTempsOnSignal()
+
 	if len(os.Args) < 2 {
-		fmt.Printf("Usage: %s <commit-sha>\n", toolName)
+		fmt.Printf("Usage: %s <commit-sha>\n", commandName)
 		fmt.Println("This tool is meant to be run as a git post-commit hook")
-		fmt.Printf("Run '%s --help' for more information\n", toolName)
-		os.Exit(1)
+		fmt.Printf("Run '%s -h' for more information\n", commandName)
+		exit(1)
 	}
 
 	if os.Args[1] == "--help" || os.Args[1] == "-h" {
 		showHelp()
-		os.Exit(0)
+		exit(0)
+	}
+
+	if os.Args[1] == "--help-json" {
+		os.Stdout.Write(helpJSON())
+		exit(0)
+	}
+
+	if os.Args[1] == "help" {
+		if err := runHelp(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "--version" || os.Args[1] == "version" {
+		fmt.Println(versionString())
+		exit(0)
+	}
+
+	if os.Args[1] == "install" {
+		repair, global := false, false
+		for _, arg := range os.Args[2:] {
+			switch arg {
+			case "--repair":
+				repair = true
+			case "--global":
+				global = true
+			default:
+				fmt.Printf("usage: %s install [--global] [--repair]\n", commandName)
+				exit(1)
+			}
+		}
+		if err := installHook(repair, global); err != nil {
+			fmt.Printf("Error installing hook: %v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "self-update" {
+		if err := runSelfUpdate(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "process-pr" {
+		if len(os.Args) < 3 {
+			fmt.Printf("Usage: %s process-pr <number>\n", toolName)
+			exit(1)
+		}
+		number, err := strconv.Atoi(strings.TrimPrefix(os.Args[2], "#"))
+		if err != nil {
+			fmt.Printf("Invalid PR number: %s\n", os.Args[2])
+			exit(1)
+		}
+		if err := processPR(number); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "ci" {
+		exit(runCI(os.Args[2:]))
+	}
+
+	if os.Args[1] == "serve" {
+		if err := runServe(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(exitCodeOf(err))
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "completion-server" {
+		// Keep stdout for responses; warnings go to stderr
+		stdout := os.Stdout
+		os.Stdout = os.Stderr
+		if err := runCompletionServer(os.Stdin, stdout); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "init" {
+		if err := runInit(os.Stdin, os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "import-config" {
+		if err := runImportConfig(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "simulate" {
+		if err := runSimulate(); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "drift" {
+		if err := runDrift(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "recover" {
+		if err := runRecover(); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "refs" {
+		if err := runRefs(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "config" {
+		if err := runConfig(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "pre-commit" {
+		if err := runPreCommit(); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "post-merge" {
+		results, err := runPostMerge(os.Args[2:])
+		if err != nil {
+			fmt.Printf("%v\n", err)
+		}
+		exit(runExitCode(results, err))
+	}
+
+	if os.Args[1] == "split" {
+		if err := runSplit(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "review" {
+		if err := runReview(os.Stdin, os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "from-stash" {
+		if err := runFromStash(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "push" {
+		if err := runPush(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "promote" {
+		if err := runPromote(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "changelog" {
+		if err := runChangelog(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "match" {
+		if err := runMatch(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
 	}
 
-	if os.Args[1] == "--version" || os.Args[1] == "-v" {
-		fmt.Printf("%s version %s\n", toolName, getVersion())
-		os.Exit(0)
+	if os.Args[1] == "diff" {
+		if err := runDiff(os.Stdout, os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "show" {
+		if err := runShow(os.Args[2:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "queue" {
+		if err := runQueue(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "worker" {
+		if err := runWorker(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "status" {
+		if err := runStatus(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "doctor" {
+		if err := runDoctor(); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
 	}
 
-	commitSHA := os.Args[1]
-	
-	if err := processCommit(commitSHA); err != nil {
+	if os.Args[1] == "foreach" {
+		if err := runForeach(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "presets" {
+		if err := runPresets(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if os.Args[1] == "plugins" {
+		if err := runPlugins(os.Args[2:]); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+		exit(0)
+	}
+
+	if path := findPlugin(os.Args[1]); path != "" {
+		exit(runPlugin(path, os.Args[2:]))
+	}
+
+	processArgs := os.Args[1:]
+	if processArgs[0] == "process" {
+		processArgs = processArgs[1:]
+	}
+	opts, err := parseProcessArgs(processArgs)
+	if err != nil {
 		fmt.Printf("%v\n", err)
-		os.Exit(1)
+		exit(exitConfig)
+	}
+	if opts.Repo != "" {
+		if err := os.Chdir(opts.Repo); err != nil {
+			fmt.Printf("%v\n", err)
+			exit(1)
+		}
+	}
+	if opts.Branch != "" {
+		os.Setenv(sourceBranchEnv, opts.Branch)
+	}
+	if opts.Base != "" {
+		os.Setenv(baseEnv, opts.Base)
+	}
+	if opts.Mainline != "" {
+		os.Setenv(mainlineEnv, opts.Mainline)
+	}
+	if opts.Stay {
+		os.Setenv(stayEnv, "true")
+	}
+	if opts.AISummary {
+		os.Setenv("PRROMPT_AI_SUMMARY", "true")
+	}
+
+	// Keep stdout for the JSON document; human-readable output goes to stderr
+	stdout := os.Stdout
+	if opts.OutputJSON {
+		os.Stdout = os.Stderr
+	}
+
+	// Invalid settings fail the run rather than quietly falling back
+	var output []byte
+	var results []*Result
+	if invalid := invalidSettings(); len(invalid) > 0 {
+		err = withExitCode(exitConfig, fmt.Errorf("invalid configuration: %s", strings.Join(invalid, "; ")))
+		result := &Result{Status: statusError, Error: err.Error(), ExitCode: exitConfig}
+		if len(opts.Commits) > 0 {
+			result.Commit = opts.Commits[0]
+		}
+		output = result.JSON()
+	} else if opts.SinceLastRun {
+		results, err = processSinceLastRun()
+		output = resultsJSON(results)
+	} else if len(opts.Commits) == 1 && !strings.Contains(opts.Commits[0], "..") {
+		var result *Result
+		result, err = processCommit(opts.Commits[0])
+		results = []*Result{result}
+		output = result.JSON()
+	} else {
+		mode := getRangeMode()
+		if opts.Combine {
+			mode = rangeModeCombined
+		} else if opts.BySkill {
+			mode = rangeModeSkill
+		}
+		results, err = processCommits(opts.Commits, mode)
+		output = resultsJSON(results)
+	}
+	if err != nil {
+		fmt.Printf("%v\n", err)
+	}
+	if opts.OutputJSON {
+		stdout.Write(output)
+	}
+	if opts.ResultFile != "" {
+		if writeErr := writeResultFile(opts.ResultFile, output); writeErr != nil {
+			fmt.Printf("%v\n", writeErr)
+		}
+	}
+	exit(runExitCode(results, err))
+}
+
+// processSinceLastRun processes the commits made on the current branch since
+// the previous `--since-last-run` invocation, oldest first. It stops at the
+// first failure so that commit is retried next time.
+func processSinceLastRun() ([]*Result, error) {
+	branch, commits, err := commitsSinceLastRun()
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		infof("No new commits on %s since the last run", branch)
+	}
+	var results []*Result
+	for _, sha := range commits {
+		result, err := processCommit(sha)
+		results = append(results, result)
+		if err != nil {
+			return results, fmt.Errorf("%s: %w", sha[:7], err)
+		}
+		if err := recordLastRun(branch, sha); err != nil {
+			return results, err
+		}
+	}
+	return results, nil
+}
+
+// processOptions are the flags of a `prrompt <commit-sha>...` run.
+type processOptions struct {
+	// Commits are the commits or A..B ranges to process.
+	Commits    []string
+	Combine    bool
+	BySkill    bool
+	OutputJSON bool
+	ResultFile string
+	Base       string
+	Mainline   string
+	// Branch and Repo are the source branch and repository as the hook
+	// saw them.
+	Branch string
+	Repo   string
+	// SinceLastRun processes the commits since the previous such run
+	// instead of Commits.
+	SinceLastRun bool
+	// Stay leaves the prompt branch checked out after extraction.
+	Stay bool
+	// AISummary opts in to prrompt.ai.summary for the run.
+	AISummary bool
+}
+
+// parseProcessArgs parses `<commit-sha|range>... [--combine|--by-skill] [--output=json]
+// [--result-file <path>] [--base <ref>] [--mainline <n>] [--branch <name>]
+// [--repo <path>] [--stay] [--ai-summary]`.
+func parseProcessArgs(args []string) (opts processOptions, err error) {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--output=json" || (arg == "--output" && i+1 < len(args) && args[i+1] == "json"):
+			opts.OutputJSON = true
+			if arg == "--output" {
+				i++
+			}
+		case strings.HasPrefix(arg, "--output"):
+			return opts, fmt.Errorf("unsupported output format: %s", arg)
+		case arg == "--result-file" && i+1 < len(args):
+			opts.ResultFile = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--result-file="):
+			opts.ResultFile = strings.TrimPrefix(arg, "--result-file=")
+		case arg == "--base" && i+1 < len(args):
+			opts.Base = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--base="):
+			opts.Base = strings.TrimPrefix(arg, "--base=")
+		case arg == "--mainline" && i+1 < len(args):
+			opts.Mainline = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--mainline="):
+			opts.Mainline = strings.TrimPrefix(arg, "--mainline=")
+		case arg == "--branch" && i+1 < len(args):
+			opts.Branch = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--branch="):
+			opts.Branch = strings.TrimPrefix(arg, "--branch=")
+		case arg == "--repo" && i+1 < len(args):
+			opts.Repo = args[i+1]
+			i++
+		case strings.HasPrefix(arg, "--repo="):
+			opts.Repo = strings.TrimPrefix(arg, "--repo=")
+		case arg == "--since-last-run":
+			opts.SinceLastRun = true
+		case arg == "--stay":
+			opts.Stay = true
+		case arg == "--ai-summary":
+			opts.AISummary = true
+		case arg == "--combine":
+			opts.Combine = true
+		case arg == "--by-skill":
+			opts.BySkill = true
+		case strings.HasPrefix(arg, "-"):
+			return opts, fmt.Errorf("unknown flag: %s", arg)
+		default:
+			opts.Commits = append(opts.Commits, arg)
+		}
 	}
+	if opts.SinceLastRun && len(opts.Commits) > 0 {
+		return opts, fmt.Errorf("--since-last-run does not take a commit")
+	}
+	if len(opts.Commits) == 0 && !opts.SinceLastRun {
+		return opts, fmt.Errorf("usage: %s <commit-sha>... [--output=json] [--result-file <path>] [--base <ref>] [--mainline <n>]", toolName)
+	}
+	return opts, nil
 }
 
 func runGit(args ...string) (string, error) {
+	return runGitInDir("", args...)
+}
+
+// runGitInDir runs git in dir; an empty dir means the current directory.
+func runGitInDir(dir string, args ...string) (string, error) {
+	if dir == "" {
+		debugf("git %s", strings.Join(args, " "))
+	} else {
+		debugf("git -C %s %s", dir, strings.Join(args, " "))
+	}
 	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
 	output, err := cmd.CombinedOutput()
+	if err != nil {
+		debugf("  -> %v: %s", err, truncate(strings.TrimSpace(string(output)), 200))
+	}
 	return strings.TrimSpace(string(output)), err
 }
 
 func isPromptFile(path string) bool {
+	if decided, include := controlFileMatch(path); decided {
+		return include && !isExcludedFile(path)
+	}
+	return matchesPromptPattern(path) && !isExcludedFile(path)
+}
+
+func matchesPromptPattern(path string) bool {
 	for _, pattern := range getPromptPatterns() {
 		if strings.HasPrefix(path, pattern) {
 			return true
@@ -166,7 +1148,79 @@ func isPromptFile(path string) bool {
 	return false
 }
 
+// isExcludedFile reports whether path matches prrompt.excludePatterns, which
+// are checked after the inclusion patterns.
+func isExcludedFile(path string) bool {
+	for _, pattern := range getExcludePatterns() {
+		if matchGlob(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches path against pattern. Patterns without wildcards are
+// prefixes, like prompt patterns; others are path.Match globs tested
+// against the path and each of its parent directories, so "prompts/exp-*"
+// covers everything below prompts/exp-1/.
+func matchGlob(pattern, file string) bool {
+	if !strings.ContainsAny(pattern, "*?[") {
+		return strings.HasPrefix(file, pattern)
+	}
+	pattern = strings.TrimSuffix(pattern, "/")
+	for candidate := file; candidate != "." && candidate != "/"; candidate = path.Dir(candidate) {
+		if matched, _ := path.Match(pattern, candidate); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// parent returns the revision the commit's changes are taken against: the
+// mainline parent of a merge, the empty tree for a root commit, else the
+// first parent.
+func (info *CommitInfo) parent() string {
+	if info.Mainline > 1 {
+		return fmt.Sprintf("%s^%d", info.SHA, info.Mainline)
+	}
+	if info.Parents == 0 {
+		return emptyTree()
+	}
+	return info.SHA + "^"
+}
+
+// emptyTree returns the ID of the empty tree in the repository's hash format.
+func emptyTree() string {
+	tree, err := runGit("hash-object", "-t", "tree", "/dev/null")
+	if err != nil {
+		return "4b825dc642cb6eb9a060e54bf8d69288fbee4904"
+	}
+	return tree
+}
+
+// startPoint returns the revision the prompt branch is created from.
+func (info *CommitInfo) startPoint() string {
+	if info.StartPoint != "" {
+		return info.StartPoint
+	}
+	return baseStartPoint()
+}
+
+// localStartPoint is startPoint without fetching the base.
+func (info *CommitInfo) localStartPoint() string {
+	if info.StartPoint != "" {
+		return info.StartPoint
+	}
+	return localBaseStartPoint()
+}
+
 func analyzeCommit(sha string) (*CommitInfo, error) {
+	// Resolve refs like HEAD to the full SHA used for branch names
+	fullSHA, err := runGit("rev-parse", "--verify", "--quiet", sha+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown commit: %s", sha)
+	}
+	sha = fullSHA
 	info := &CommitInfo{SHA: sha}
 
 	commitMessage, err := runGit("log", "--format=%B", "-n", "1", sha)
@@ -174,63 +1228,422 @@ func analyzeCommit(sha string) (*CommitInfo, error) {
 		return nil, fmt.Errorf("failed to get commit message: %w", err)
 	}
 	info.Message = commitMessage
+	info.WIP = isWIPMessage(commitMessage)
 
-	currentBranch, err := runGit("rev-parse", "--abbrev-ref", "HEAD")
+	branch, err := currentBranch()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get current branch: %w", err)
 	}
-	info.SourceBranch = currentBranch
+	info.SourceBranch = branch
 
-	changedFiles, err := runGit("diff-tree", "--no-commit-id", "--name-only", "-r", sha)
+	parents, err := runGit("rev-list", "--parents", "-n", "1", sha)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get parents: %w", err)
+	}
+	diffArgs := []string{"diff-tree", "--no-commit-id", "--name-status", "-r", "-M", "-C"}
+	if info.Parents = len(strings.Fields(parents)) - 1; info.Parents == 0 {
+		// A root commit is diffed against the empty tree
+		diffArgs = append(diffArgs, "--root")
+	} else if info.Stash = isStash(sha, commitMessage); info.Stash {
+		// A stash entry holds the working tree changes against the commit
+		// it was made on
+		info.Mainline = 1
+		diffArgs = append(diffArgs, info.parent())
+		if branch := stashBranch(commitMessage); branch != "" {
+			info.SourceBranch = branch
+		}
+	} else if info.Parents > 1 {
+		// Classify merges by their diff against the chosen parent, the first
+		// one when they are only being reported
+		mainline, err := getMainline()
+		if err != nil {
+			return nil, err
+		}
+		if mainline > info.Parents {
+			return nil, fmt.Errorf("merge commit %s has no parent %d", sha[:7], mainline)
+		}
+		info.Mainline = mainline
+		diffArgs = append(diffArgs, info.parent())
+	}
+	changes, err := runGit(append(diffArgs, sha)...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get changed files: %w", err)
 	}
 
-	for _, file := range strings.Split(changedFiles, "\n") {
-		file = strings.TrimSpace(file)
-		if file == "" {
-			continue
+	promptCommit := isPromptCommit(info.Message)
+	if promptCommit {
+		verbosef("Commit message matches prrompt.messagePatterns, extracting all of its files")
+	}
+	isPrompt := func(file string) bool {
+		if promptCommit {
+			return !isExcludedFile(file)
 		}
-		if isPromptFile(file) {
+		return isPromptFile(file)
+	}
+
+	// admit gates prompt files by size and type; the files it leaves out
+	// are treated as other files
+	skipped := make(map[string]bool)
+	admit := func(file string, prompt bool) bool {
+		if prompt && !admitPromptFile(sha, file) {
+			skipped[file] = true
+			info.SkippedFiles = append(info.SkippedFiles, file)
+			return false
+		}
+		return prompt
+	}
+
+	classify := func(file string, prompt bool) {
+		if prompt {
 			info.PromptFiles = append(info.PromptFiles, file)
+		} else if skipped[file] {
+			info.OtherFiles = append(info.OtherFiles, file)
+		} else if matchesPromptPattern(file) {
+			info.ExcludedFiles = append(info.ExcludedFiles, file)
+			info.OtherFiles = append(info.OtherFiles, file)
 		} else {
 			info.OtherFiles = append(info.OtherFiles, file)
 		}
 	}
 
+	info.FileStatus = make(map[string]string)
+	for _, line := range strings.Split(changes, "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) < 2 || fields[0] == "" {
+			continue
+		}
+		status := fields[0][:1]
+		switch {
+		case status == "R" && len(fields) == 3:
+			// Keep rename pairs together so neither half is lost
+			oldPath, newPath := fields[1], fields[2]
+			prompt := isPrompt(oldPath) || isPrompt(newPath)
+			if !admit(newPath, prompt) && prompt {
+				prompt = false
+				skipped[oldPath] = true
+			}
+			classify(oldPath, prompt)
+			classify(newPath, prompt)
+			info.FileStatus[oldPath] = "D"
+			info.FileStatus[newPath] = "R"
+			if info.Renames == nil {
+				info.Renames = make(map[string]string)
+			}
+			info.Renames[newPath] = oldPath
+		case status == "C" && len(fields) == 3:
+			// The copy source is unchanged
+			classify(fields[2], admit(fields[2], isPrompt(fields[2])))
+			info.FileStatus[fields[2]] = "A"
+		default:
+			file := fields[len(fields)-1]
+			classify(file, admit(file, isPrompt(file)))
+			info.FileStatus[file] = status
+		}
+	}
+
 	info.IsMixed = len(info.OtherFiles) > 0
 
 	return info, nil
 }
 
+// resolveConflicts settles a conflicted cherry-pick, e.g. a modify/delete
+// when the base changed a prompt file the commit deletes: prompt files are
+// reproduced as they are in the commit and other files are left as on the
+// base. It fails if nothing is conflicted, i.e. the cherry-pick failed for
+// another reason.
+func resolveConflicts(info *CommitInfo) error {
+	conflicted, err := runGit("diff", "--name-only", "--diff-filter=U")
+	if err != nil || conflicted == "" {
+		return fmt.Errorf("no conflicts to resolve")
+	}
+	prompt := make(map[string]bool, len(info.PromptFiles))
+	for _, file := range info.PromptFiles {
+		prompt[file] = true
+	}
+	for _, file := range strings.Split(conflicted, "\n") {
+		ref := "HEAD"
+		if prompt[file] {
+			ref = info.SHA
+		}
+		if blobAt(ref, file) != "" {
+			_, err = runGit("checkout", ref, "--", file)
+		} else {
+			_, err = runGit("rm", "-q", "-f", "--ignore-unmatch", "--", file)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to resolve %s: %w", file, err)
+		}
+		verbosef("Resolved conflict in %s from %s", file, ref)
+	}
+	return nil
+}
+
 func extractPrompts(info *CommitInfo) error {
-	shortSHA := info.SHA[:7]
-	promptBranch := fmt.Sprintf("%s/%s", getBranchPrefix(), shortSHA)
-	verbosity := getVerbosity()
-	isHighVerbosity := verbosity == verbosityHigh
+	return extractCommits(promptBranchName(info), []*CommitInfo{info})
+}
+
+// extractCommits creates promptBranch from the start point of the first
+// commit with one extraction commit per source commit, or their net change
+// with prrompt.batchSquash=net, pushes it and opens the PR, then returns to
+// the source branch.
+func extractCommits(promptBranch string, infos []*CommitInfo) error {
+	first := infos[0]
+	promptFiles := 0
+	for _, info := range infos {
+		if getTokenBudget() > 0 || getBoolConfig("prrompt.tokenCounts", false) {
+			info.TokenCounts = countPromptTokens(info)
+			warnTokenBudget(info.TokenCounts, getTokenBudget())
+		}
+		describeCommit(info)
+		if info.AISummary == "" {
+			info.AISummary = summarizePromptChanges(info)
+		}
+		promptFiles += len(info.PromptFiles)
+	}
+	verbosef("Creating branch %s from %s", promptBranch, first.startPoint())
+
+	// With uncommitted changes, checking the prompt branch out would carry
+	// them along or fail, and returning with checkout -f would discard
+	// them: the branch is built without a checkout instead
+	appending := first.AppendTo != "" && first.AppendTo == promptBranch
+	var j *journal
+	if workTreeDirty() {
+		infof("The work tree has uncommitted changes, building %s without a checkout", promptBranch)
+		previousTip := ""
+		if appending {
+			previousTip, _ = runGit("rev-parse", "refs/heads/"+promptBranch)
+		}
+		if err := buildPromptBranch(promptBranch, infos, previousTip); err != nil {
+			return err
+		}
+	} else {
+		var err error
+		if j, err = checkoutPromptBranch(promptBranch, infos, appending); err != nil {
+			return err
+		}
+	}
+	if len(infos) > 1 && getBatchSquash() == batchSquashNet {
+		if err := squashBatch(promptBranch, infos); err != nil {
+			warnf("keeping a commit per source commit on %s: %v", promptBranch, err)
+		}
+	}
+	if getChangelogMode() == changelogExtract {
+		if err := addExtractionChangelogs(promptBranch, infos, j != nil); err != nil {
+			warnf("not adding to the changelogs on %s: %v", promptBranch, err)
+		}
+	}
+	verbosef("✓ Created skill branch %s", promptBranch)
+
+	// Push to remote
+	remote := getRemote()
+	pushed, failure := false, ""
+	if !getBoolConfig("prrompt.push", true) {
+		verbosef("Push disabled (prrompt.push=false)")
+	} else if !hasRemote(remote) {
+		verbosef("No remote %q configured, skipping push", remote)
+	} else if !remoteReachable(remote) {
+		infof("%s is unreachable, run '%s push' to push %s later", remote, toolName, promptBranch)
+		failure = pushFailOffline
+		queuePush(remote, promptBranch, infos, appending, failure, nil)
+	} else if err := timedPush(remote, promptBranch, "-u"); errors.Is(err, errPushInBackground) {
+		infof("Still pushing %s in the background, run '%s push' once it is done to open the PR", promptBranch, toolName)
+		failure = pushFailBackground
+		queuePush(remote, promptBranch, infos, appending, failure, nil)
+	} else if err != nil {
+		failure = pushFailureReason(err)
+		warnf("failed to push (%s): %v", failure, err)
+		infof("To fix: %s", pushRemedy(failure, remote, promptBranch))
+		queuePush(remote, promptBranch, infos, appending, failure, err)
+	} else {
+		pushed = true
+		if j != nil {
+			j.record(stepPushed)
+		}
+		verbosef("✓ Pushed to %s/%s", remote, promptBranch)
+	}
+	if pushed && !appending {
+		protectBranch(promptBranch)
+	}
 
-	if isHighVerbosity {
-		fmt.Println("\n" + strings.Repeat("=", 60))
-		fmt.Printf("Processing commit: %s\n", shortSHA)
-		fmt.Printf("Message: %s\n", truncate(info.Message, 60))
-		fmt.Printf("Prompt files: %d\n", len(info.PromptFiles))
-		fmt.Printf("Other files: %d\n", len(info.OtherFiles))
-		fmt.Println(strings.Repeat("=", 60))
-		fmt.Printf("\nCreating branch: %s\n", promptBranch)
+	// Return to original branch, forced to drop what the extraction left
+	// in the (otherwise clean) tree. On failure the journal is kept for
+	// `prrompt recover`. With prrompt.stayOnBranch the prompt branch stays
+	// checked out, unless removeFromSource is about to rewrite the source.
+	stayed := false
+	if j != nil && stayOnBranch() && !getBoolConfig("prrompt.removeFromSource", false) {
+		stayed = true
+		j.remove()
+	} else if j != nil {
+		if _, err := runGit("checkout", "-f", first.SourceBranch); err != nil {
+			return fmt.Errorf("failed to return to original branch: %w", err)
+		}
+		j.remove()
 	}
 
-	// Create and checkout new branch from base
-	if _, err := runGit("checkout", "-b", promptBranch, getBaseBranch()); err != nil {
-		return fmt.Errorf("failed to create branch: %w", err)
+	// Open the PR (or print its URL) only when the branch made it to the remote
+	// An appended-to branch already has its PR
+	prURL := ""
+	if pushed && appending {
+		prURL = generatePRURL(getTargetBranch(), promptBranch)
+	} else if pushed {
+		ensureTargetBranch(remote, getTargetBranch())
+		prURL = openPR(infos, getTargetBranch(), promptBranch)
+	}
+	for _, info := range infos {
+		info.PromptBranch = promptBranch
+		info.Pushed = pushed
+		info.PushFailure = failure
+		info.PRURL = prURL
+	}
+	if pushed && !appending {
+		notifyPush(infos, promptBranch, prURL)
+	}
+	if pushed {
+		publishSkills(infos, promptBranch, prURL)
 	}
 
+	infof("Updated prompt files detected: %d", promptFiles)
+	infof("Branch: %s", promptBranch)
+	if prURL != "" {
+		infof("PR: %s", prURL)
+	} else if pushed {
+		infof("Open a PR for %s on %s", promptBranch, remote)
+	}
+	switch {
+	case stayed:
+		infof("Staying on %s (prrompt.stayOnBranch): edit and commit the prompts there, push with 'git push', and return with 'git checkout %s'", promptBranch, first.SourceBranch)
+	case j == nil && stayOnBranch():
+		infof("Staying on %s, which has uncommitted changes; run 'git checkout %s' once they are committed or stashed", first.SourceBranch, promptBranch)
+	}
+
+	return nil
+}
+
+// queuePush records the prompt branch for `prrompt push`, with why it was
+// not pushed and pushErr if its push failed.
+func queuePush(remote, branch string, infos []*CommitInfo, appending bool, failure string, pushErr error) {
+	entry := pendingPush{Branch: branch, Remote: remote, Base: getTargetBranch(), Reason: failure}
+	if pushErr != nil {
+		entry.Error = pushErr.Error()
+	}
+	if !appending {
+		for _, info := range infos {
+			entry.Commits = append(entry.Commits, info.SHA)
+		}
+	}
+	if err := queuePendingPush(entry); err != nil {
+		warnf("failed to queue %s: %v", branch, err)
+	}
+}
+
+func describeCommit(info *CommitInfo) {
+	verbosef("Processing commit %s: %s", info.SHA[:7], truncate(redact(info.Message), 60))
+	verbosef("Prompt files: %d, other files: %d", len(info.PromptFiles), len(info.OtherFiles))
+	if isVerbose() {
+		renameSources := make(map[string]bool, len(info.Renames))
+		for _, oldPath := range info.Renames {
+			renameSources[oldPath] = true
+		}
+		for _, file := range info.PromptFiles {
+			if oldPath, ok := info.Renames[file]; ok {
+				verbosef("  R %s -> %s", oldPath, file)
+			} else if !renameSources[file] {
+				verbosef("  %s %s", info.FileStatus[file], file)
+			}
+		}
+	}
+	if len(info.ExcludedFiles) > 0 && isVerbose() {
+		printExcludedFiles(info.ExcludedFiles)
+	}
+}
+
+// checkoutPromptBranch creates promptBranch from the start point of the
+// first commit, or checks it out when appending, and commits the prompt
+// changes of infos on it, journaling each step. It returns the journal,
+// with the prompt branch still checked out.
+func checkoutPromptBranch(promptBranch string, infos []*CommitInfo, appending bool) (*journal, error) {
+	first := infos[0]
+	// Journal each step so an interrupted run can be rolled back
+	j, err := startJournal(first, promptBranch)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create and checkout new branch from base, or the session's branch
+	// when appending. A root commit extracted from its (empty) parent starts
+	// a new history.
+	if appending {
+		verbosef("Appending to %s (prrompt.squashWindow)", promptBranch)
+		j.PreviousTip, _ = runGit("rev-parse", "refs/heads/"+promptBranch)
+		if _, err := runGit("checkout", "-f", promptBranch); err != nil {
+			j.remove()
+			return nil, fmt.Errorf("failed to check out %s: %w", promptBranch, err)
+		}
+	} else if first.startPoint() == emptyTree() {
+		if _, err := runGit("checkout", "--orphan", promptBranch); err != nil {
+			j.remove()
+			return nil, fmt.Errorf("failed to create branch: %w", err)
+		}
+		runGit("read-tree", "--empty")
+	} else if _, err := runGit("checkout", "--no-track", "-b", promptBranch, first.startPoint()); err != nil {
+		j.remove()
+		return nil, fmt.Errorf("failed to create branch: %w", err)
+	}
+	j.record(stepBranchCreated)
+
+	for i, info := range infos {
+		if i > 0 {
+			clearWorkTree(infos[i-1])
+		}
+		err := interrupted()
+		if err == nil {
+			err = applyCommit(j, info)
+		}
+		if err != nil {
+			if appending {
+				runGit("cherry-pick", "--abort")
+				runGit("checkout", "-f", first.SourceBranch)
+				runGit("update-ref", "refs/heads/"+promptBranch, j.PreviousTip)
+			} else {
+				cleanup(first.SourceBranch, promptBranch)
+			}
+			j.remove()
+			return nil, err
+		}
+	}
+	j.record(stepCommitted)
+	return j, nil
+}
+
+// applyCommit reproduces the prompt changes of one commit on the checked out
+// prompt branch and commits them.
+func applyCommit(j *journal, info *CommitInfo) error {
 	// Cherry-pick without committing
-	if isHighVerbosity {
-		fmt.Printf("Cherry-picking commit %s...\n", shortSHA)
+	verbosef("Cherry-picking commit %s", info.SHA[:7])
+	cherryPick := []string{"cherry-pick", info.SHA, "--no-commit"}
+	if info.Mainline > 0 {
+		cherryPick = append(cherryPick, "-m", strconv.Itoa(info.Mainline))
 	}
-	if _, err := runGit("cherry-pick", info.SHA, "--no-commit"); err != nil {
-		cleanup(info.SourceBranch, promptBranch)
-		return fmt.Errorf("failed to cherry-pick: %w", err)
+	if _, err := runGit("rev-parse", "--verify", "-q", "HEAD"); err != nil {
+		// Nothing to cherry-pick onto on a new root branch: take the prompt
+		// files straight from the (root) commit
+		args := append([]string{"checkout", info.SHA, "--"}, info.PromptFiles...)
+		if _, err := runGit(args...); err != nil {
+			return fmt.Errorf("failed to check out files: %w", err)
+		}
+	} else if _, err := runGit(cherryPick...); err != nil {
+		if resolveErr := resolveConflicts(info); resolveErr != nil {
+			return withExitCode(exitConflict, fmt.Errorf("failed to cherry-pick: %w", err))
+		}
+	}
+	j.record(stepCherryPicked)
+
+	// Bring in the other variants of touched experiments
+	if len(info.VariantFiles) > 0 {
+		args := append([]string{"checkout", info.SHA, "--"}, info.VariantFiles...)
+		if _, err := runGit(args...); err != nil {
+			return fmt.Errorf("failed to add prompt variants: %w", err)
+		}
 	}
 
 	// For mixed commits, unstage non-prompt files (but keep them in working directory)
@@ -240,84 +1653,146 @@ func extractPrompts(info *CommitInfo) error {
 		}
 	}
 
-	// Create commit message
-	commitMsg := fmt.Sprintf("[%s] %s", getCommitPrefix(), info.Message)
-	if info.IsMixed {
-		commitMsg += fmt.Sprintf("\n\nExtracted from %s (%s)", info.SourceBranch, shortSHA)
+	planVersionBumps(info, "HEAD")
+	if err := writeVersionBumps(info); err != nil {
+		return err
+	}
+	if err := archivePrompts(info); err != nil {
+		return err
 	}
 
-	// Commit
-	if _, err := runGit("commit", "-m", commitMsg); err != nil {
-		cleanup(info.SourceBranch, promptBranch)
-		return fmt.Errorf("failed to commit: %w", err)
+	// Commit with provenance trailers, as the source commit's author
+	commitArgs := append([]string{"commit", "-m", buildCommitMessage(info)}, commitSigningArgs()...)
+	if getBoolConfig("prrompt.signoff", false) {
+		commitArgs = append(commitArgs, "--signoff")
+	}
+	if info.DuplicateOf != "" {
+		commitArgs = append(commitArgs, "--allow-empty")
 	}
+	env, err := commitAuthorEnv(info.SHA)
+	if err != nil {
+		return err
+	}
+	if output, err := runGitWithEnv(append(env, committerEnv()...), commitArgs...); err != nil {
+		return fmt.Errorf("failed to commit: %w: %s", err, truncate(output, 200))
+	}
+	return nil
+}
 
-	if isHighVerbosity {
-		fmt.Printf("✓ Created skill branch: %s\n", promptBranch)
+// clearWorkTree drops the non-prompt changes a previous applyCommit left in
+// the work tree so the next cherry-pick starts clean.
+func clearWorkTree(info *CommitInfo) {
+	runGit("reset", "-q", "--hard")
+	for _, file := range info.OtherFiles {
+		if blobAt("HEAD", file) == "" {
+			os.Remove(file)
+		}
 	}
+}
 
-	// Push to remote
-	if _, err := runGit("push", "origin", promptBranch, "-u"); err != nil {
-		if isHighVerbosity {
-			fmt.Printf("Warning: failed to push (you may need to push manually): %v\n", err)
+// buildCommitMessage returns the message for the extracted commit: the
+// prefixed original message, token counts when enabled, and provenance
+// trailers.
+func buildCommitMessage(info *CommitInfo) string {
+	// With prrompt.subjectRewrite the subject is tidied up, and the
+	// original message moves to the body
+	msg := extractionMessage(info)
+	if getBoolConfig("prrompt.subjectRewrite", false) {
+		original := msg
+		subject, _, _ := strings.Cut(original, "\n")
+		if rewritten := rewriteSubject(subject); rewritten != subject {
+			msg = rewritten + "\n\n" + original
 		}
-	} else if isHighVerbosity {
-		fmt.Printf("✓ Pushed to origin/%s\n", promptBranch)
 	}
+	// A conventional prompt header like "chore(prompts): " already marks
+	// the change and is kept as the title
+	if !hasPromptHeader(msg) {
+		msg = prefixSubject(msg)
+	}
+	if info.AISummary != "" {
+		msg = strings.TrimRight(msg, "\n") + "\n\n" + info.AISummary
+	}
+	if len(info.VersionBumps) > 0 {
+		msg = strings.TrimRight(msg, "\n") + "\n\n" + formatVersionBumps(info.VersionBumps)
+	}
+	if len(info.TokenCounts) > 0 {
+		msg = strings.TrimRight(msg, "\n") + "\n\n" + formatTokenCounts(info.TokenCounts)
+	}
+	if info.SkillsChecked > 0 {
+		msg = strings.TrimRight(msg, "\n") + "\n\n" + formatSkillValidation(info.SkillsChecked, info.SkillIssues)
+	}
+	trailers := append(prefixTrailers(), []trailer{
+		{trailerSourceCommit, info.SHA},
+		{trailerSourceBranch, info.SourceBranch},
+		{trailerToolVersion, toolVersion()},
+	}...)
+	for _, experiment := range info.Experiments {
+		trailers = append(trailers, trailer{trailerExperiment, experiment})
+	}
+	if info.DuplicateOf != "" {
+		trailers = append(trailers, trailer{trailerDuplicateOf, info.DuplicateOf})
+	}
+	return appendTrailers(msg, trailers)
+}
 
-	// Return to original branch (force to handle any uncommitted changes)
-	if _, err := runGit("checkout", "-f", info.SourceBranch); err != nil {
-		return fmt.Errorf("failed to return to original branch: %w", err)
+type trailer struct {
+	Key   string
+	Value string
+}
+
+// appendTrailers adds trailers to msg, joining an existing trailer block
+// (e.g. Signed-off-by) when the last paragraph already is one so that
+// `git interpret-trailers` sees a single block.
+func appendTrailers(msg string, trailers []trailer) string {
+	msg = strings.TrimRight(msg, "\n")
+	lines := make([]string, 0, len(trailers))
+	for _, t := range trailers {
+		lines = append(lines, t.Key+": "+t.Value)
 	}
 
-	// Generate PR URL
-	prURL := generatePRURL(promptBranch)
-	
-	if isHighVerbosity {
-		fmt.Printf("\n✓ Skill extraction complete!\n")
-		fmt.Printf("\nCreate PR: %s\n\n", prURL)
-	} else {
-		// Low verbosity: just show the essential info
-		fmt.Printf("Updated prompt files detected: %d\n", len(info.PromptFiles))
-		fmt.Printf("Branch: %s\n", promptBranch)
-		fmt.Printf("PR: %s\n", prURL)
+	paragraphs := strings.Split(msg, "\n\n")
+	if len(paragraphs) > 1 && isTrailerBlock(paragraphs[len(paragraphs)-1]) {
+		return msg + "\n" + strings.Join(lines, "\n") + "\n"
 	}
+	return msg + "\n\n" + strings.Join(lines, "\n") + "\n"
+}
 
-	return nil
+func isTrailerBlock(paragraph string) bool {
+	for _, line := range strings.Split(paragraph, "\n") {
+		key, _, found := strings.Cut(line, ": ")
+		if !found || key == "" || strings.ContainsAny(key, " \t") {
+			return false
+		}
+	}
+	return true
 }
 
+// cleanup abandons a failed extraction: it aborts the cherry-pick, returns
+// to originalBranch and deletes the half-built skillBranch.
 func cleanup(originalBranch, skillBranch string) {
 	runGit("cherry-pick", "--abort")
 	runGit("checkout", originalBranch)
 	runGit("branch", "-D", skillBranch)
 }
 
-func generatePRURL(branch string) string {
-	// Get remote URL
-	remoteURL, err := runGit("config", "--get", "remote.origin.url")
-	if err != nil {
-		return fmt.Sprintf("Create PR manually for branch: %s", branch)
+func generatePRURL(base, branch string, labels ...string) string {
+	repoPath := getGitHubRepoPath()
+	if repoPath == "" {
+		return ""
 	}
 
-	// Parse GitHub repo
-	var repoPath string
-	if strings.HasPrefix(remoteURL, "git@github.com:") {
-		// SSH: git@github.com:user/repo.git
-		repoPath = strings.TrimPrefix(remoteURL, "git@github.com:")
-		repoPath = strings.TrimSuffix(repoPath, ".git")
-	} else if strings.Contains(remoteURL, "github.com/") {
-		// HTTPS: https://github.com/user/repo.git
-		parts := strings.Split(remoteURL, "github.com/")
-		if len(parts) == 2 {
-			repoPath = strings.TrimSuffix(parts[1], ".git")
-		}
+	prURL := fmt.Sprintf("%s/%s/compare/%s...%s?expand=1", forgeWebURL(), repoPath, base, branch)
+	if len(labels) > 0 {
+		prURL += "&labels=" + url.QueryEscape(strings.Join(labels, ","))
 	}
+	return prURL
+}
 
-	if repoPath == "" {
-		return fmt.Sprintf("Create PR manually for branch: %s", branch)
+func printExcludedFiles(files []string) {
+	verbosef("Excluded by prrompt.excludePatterns: %d", len(files))
+	for _, file := range files {
+		verbosef("  - %s", file)
 	}
-
-	return fmt.Sprintf("https://github.com/%s/compare/%s...%s?expand=1", repoPath, getBaseBranch(), branch)
 }
 
 func truncate(s string, maxLen int) string {
@@ -327,93 +1802,296 @@ func truncate(s string, maxLen int) string {
 	return s[:maxLen] + "..."
 }
 
-func getVersion() string {
-	tag, err := runGit("describe", "--tags", "--abbrev=0")
-	if err != nil {
-		return "unknown"
-	}
-	return tag
-}
-
 func showHelp() {
-	fmt.Printf(`%s - Extract prompts from commits and create prompt-only branches
+	fmt.Printf(`%[1]s - Extract prompts from commits and create prompt-only branches
 
 USAGE:
-    %s <commit-sha>     Process a specific commit
-    %s install          Install the git post-commit hook
-    %s --help           Show this help message
+    %[1]s <commit-sha>     Process a specific commit
+    %[1]s <sha>... | <from>..<to> [--combine|--by-skill]
+                             Process several commits with a single checkout, to a
+                             branch each, (--combine) one combined branch or
+                             (--by-skill) a branch per skill
+        --output=json           Print the result as JSON (other output goes to stderr)
+        --result-file <path>    Also write the JSON result to <path>
+        --base <ref>            Base for this run only, over prrompt.baseBranch
+                                (also PRROMPT_BASE)
+        --mainline <n>          Extract a merge commit against its parent <n>
+                                (also PRROMPT_MAINLINE)
+        --stay                  Stay on the prompt branch after extracting one commit
+                                (also PRROMPT_STAY_ON_BRANCH)
+        --ai-summary            Add a model's summary of the prompt changes, with
+                                prrompt.ai.endpoint set (also PRROMPT_AI_SUMMARY)
+        --branch <name>         Branch the commit was made on, as the hook saw it
+        --repo <path>           Repository to run in (both are passed by the hook)
+    %[1]s process --since-last-run
+                             Process the commits made on the current branch since
+                             the previous such run (for cron jobs)
+    %[1]s ci [<commit|range>...]
+                             Process pushed commits in CI (GitHub Actions push range
+                             or GITHUB_SHA); exits as below, with 2 when there is
+                             nothing to process
+    %[1]s serve [--addr <addr>] [--cache-dir <dir>]
+                             Receive GitHub/GitLab push webhooks at /webhook and
+                             extract prompts centrally (default address "%[11]s")
+    %[1]s init [--yes]     Interactive first-time setup (config and hook)
+    %[1]s import-config --from <path|git-town|husky> [--output <file>] [--dry-run] [--force]
+                             Write a .prrompt.yaml from a previous prompt-sync setup
+    %[1]s install [--global] [--repair]
+                             Install the git post-commit hook (and pre-commit hook
+                             with prrompt.strict, post-merge hook with
+                             prrompt.postMerge); --repair updates installed hooks,
+                             --global installs hooks for all repositories
+    %[1]s process-pr <n>   Extract prompt changes of GitHub PR <n> into a branch
+    %[1]s simulate         Dry-run the pipeline on a scratch commit to check setup
+    %[1]s drift [--exit-code]
+                             List prompt files with uncommitted changes
+    %[1]s split --staged [-m <message>]
+                             Commit staged prompt and other files as two commits
+    %[1]s pre-commit       Fail mixed commits with prrompt.strict (run by the hook)
+    %[1]s post-merge       Extract prompts a merge or pull brought in, with
+                             prrompt.postMerge (run by the hook)
+    %[1]s from-stash [<stash>]
+                             Extract the prompt changes of a stash entry (default
+                             stash@{0}), leaving the stash alone
+    %[1]s review [<sha>]   Pick the files to extract and edit the message, then extract
+    %[1]s recover          Roll back an interrupted extraction
+    %[1]s refs sync [--remote <name>]
+                             Share prrompt metadata (refs/prrompt/*) with a remote
+    %[1]s push [--list | --retry-failed]
+                             Push prompt branches queued while offline or after a failed push
+    %[1]s promote [--dry-run]
+                             Open the roll-up PR from prrompt.stagingBranch to the base branch
+    %[1]s changelog [--pr] [--dry-run] [<commit or range>...]
+                             Add merged extractions to the skills' CHANGELOG.md files
+    %[1]s queue add <sha>... | <from>..<to>
+                             Queue commits for a backfill (also: status, retry, clear)
+    %[1]s worker [--jobs <n>]
+                             Extract the queued commits in parallel, without checkouts
+    %[1]s status           Check created prompt branches were not force-pushed since
+    %[1]s self-update [--check] [--force]
+                             Replace this executable with the latest release when
+                             newer; its checksum catches corrupt downloads only
+    %[1]s version          Show the version, commit and build date
+    %[1]s doctor           Show effective configuration and check the hook, git,
+                             base branch, remote, PR tool and leftover state
+    %[1]s config list | get <key> | set <key> <value> | validate
+                             Show, change and check prrompt settings
+    %[1]s match <path>... | --commit <sha>
+                             Show whether files are prompts and which pattern or
+                             exclusion decided
+    %[1]s show <branch|sha>:<path>
+                             Print a prompt file as an extraction captured it
+    %[1]s diff [--stat] [<sha>]
+                             Preview the branch, message and diff extracting a commit would create
+    %[1]s presets list | presets show <name>
+                             List built-in prompt layouts and how to apply one
+    %[1]s completion-server
+                             Answer JSON line requests from editors: prompt roots,
+                             skill names, whether a path is a prompt
+    %[1]s plugins list     List plugins (%[1]s-<name> executables on PATH)
+    %[1]s foreach --repos <glob> -- <command>
+                             Run a %[1]s command in every matching repository
+    %[1]s --help           Show this help message
+    %[1]s help <topic|command>
+                             Task-oriented help with examples (extraction, setup,
+                             config, workspace)
+    %[1]s --help-json      Commands, flags, examples and settings as JSON
+    %[1]s --version        Show the version
+
+GLOBAL FLAGS:
+    -q, --quiet              Only print errors
+    -v, --verbose            Print progress details
+    --debug                  Also print every git command executed
+    -C <path>                Run as if started in <path>, as git -C does
+    -c <name>=<value>        Set a config value for this run, as git -c does
 
 DESCRIPTION:
-    %s is a lightweight git post-commit hook that extracts prompts from commits
+    %[1]s is a lightweight git post-commit hook that extracts prompts from commits
     and creates a new branch containing only the prompt changes. This allows
     prompt updates to be merged independently from other code changes.
 
 INSTALLATION:
-    Run '%s install' in your git repository to install the post-commit hook.
+    Run '%[1]s init' in your git repository to configure prrompt and install the
+    post-commit hook, or '%[1]s install' to install the hook with defaults.
+    With a git-%[1]s symlink to it on PATH, it also runs as 'git %[1]s'.
 
 CONFIGURATION:
-    Configure %s using git config:
+    Configure %[1]s using git config, or a .prrompt.yaml file at the repository
+    root (keys without the "prrompt." prefix; git config takes precedence).
+    Environment variables override both: PRROMPT_ and the key in upper snake
+    case, e.g. PRROMPT_BRANCH_PREFIX, PRROMPT_MIRROR_URL (PRROMPT_PATTERNS for
+    promptPatterns):
     
-    prrompt.commitPrefix      Commit message prefix (default: "%s")
-    prrompt.branchPrefix      Branch name prefix (default: "%s")
-    prrompt.baseBranch        Base branch for prompt branches (default: "%s")
-    prrompt.promptPatterns    Comma-separated patterns for prompt files (default: "%s")
-    prrompt.verbosity         Verbosity level: "low" or "high" (default: "%s")
+    prrompt.commitPrefix      Commit message prefix (default: "%[2]s")
+    prrompt.commitPrefixStyle Where the prefix goes: "bracket" (default), "conventional-scope",
+                              "trailer" or "none"
+    prrompt.branchPrefix      Branch name prefix (default: "%[3]s")
+    prrompt.stayOnBranch      Stay on the prompt branch after extraction instead of returning (default: false)
+    prrompt.branchName        "sha" (<prefix>/<sha>) or "skill" (<prefix>/<skill-name>-<sha>)
+    prrompt.slugStyle         Skill names in branches: "ascii" (transliterated) or "unicode"
+    prrompt.shaLength         SHA characters in branch names, lengthened on collisions (default: 7)
+    prrompt.baseBranch        Base branch for prompt branches (default: origin/HEAD, else "%[4]s")
+    prrompt.stagingBranch     Branch extraction PRs target before '%[1]s promote' rolls them up to the base
+    prrompt.changelog         On merge, '%[1]s ci' updates skill changelogs: "off" (default), "commit" or "pr";
+                              "extract" adds the entries on the prompt branch instead
+    prrompt.changelogDir      Directory of one CHANGELOG.md for all prompts (default: one per skill)
+    prrompt.remote            Remote prompt branches are pushed to (default: "%[5]s")
+    prrompt.forgeBaseURL      Web address of a GitHub Enterprise instance the remote is on
+    prrompt.push              Push prompt branches after extraction (default: true)
+    prrompt.notes             Note each extracted commit's prompt branch in refs/notes/prrompt (default: false)
+    prrompt.protectBranches   Protect pushed prompt branches against force pushes and deletion
+                              through the GitHub API (default: false)
+    prrompt.fetchBase         Branch from a freshly fetched <remote>/<base> (default: true)
+    prrompt.prTool            How to open the PR: "url", "gh", "glab" or "api" (default: "url")
+    prrompt.apiReserve        Skip optional GitHub API calls with this few requests left (default: 5)
+    prrompt.prLabels          Comma-separated labels for created PRs
+    prrompt.prReviewers       Comma-separated reviewers (users or org/team) for created PRs
+    prrompt.prAssignees       Comma-separated assignees for created PRs
+    prrompt.codeowners        Request the CODEOWNERS of the prompt files as reviewers (default: true)
+    prrompt.notifyURL         Webhook POSTed to when a prompt branch is pushed
+    prrompt.notifyFormat      Notification payload: "json", "slack" or "teams" (default: from the URL)
+    prrompt.promptPatterns    Comma-separated patterns for prompt files (default: "%[6]s")
+    prrompt.excludePatterns   Comma-separated prefixes or globs never extracted
+    prrompt.messagePatterns   Regex for commit messages whose files are all prompts
+                              (multi-valued, use --add)
+    prrompt.archivePatterns   Comma-separated prompt patterns also snapshotted into the archive
+    prrompt.archiveDir        Directory of dated prompt snapshots (default: "archive")
+    prrompt.registry.url      Registry endpoint skill metadata is PUT to when a prompt branch is pushed
+    prrompt.registry.token    Bearer token for the registry (also PRROMPT_REGISTRY_TOKEN)
+    prrompt.ai.summary        Add a model's summary of prompt diffs to commits and PRs (default: false)
+    prrompt.ai.endpoint       OpenAI-compatible API for summaries, e.g. https://api.openai.com/v1
+    prrompt.ai.model          Model to summarize with (default: "gpt-4o-mini")
+    prrompt.ai.token          API key for the endpoint (also PRROMPT_AI_TOKEN, else OPENAI_API_KEY)
+    prrompt.mirror.url        Central prompt repository to also commit prompts to
+    prrompt.mirror.mode       "also" (source repo and mirror) or "only" (mirror only)
+    prrompt.mirror.pathPrefix Directory for this repo's prompts in the mirror (default: repo name)
+    prrompt.mirror.baseBranch Base branch in the mirror (default: "%[4]s")
+    prrompt.redactPattern     Regex masked in text prrompt echoes (multi-valued, use --add)
+    prrompt.subjectRewrite    Strip ticket IDs, emojis and code headers from extracted subjects (default: false)
+    prrompt.subjectStrip      Regex also removed from rewritten subjects (multi-valued, use --add)
+    prrompt.committer         Committer of extraction commits: "user" (default) or "tool"
+    prrompt.maxFileSize       Largest prompt file extracted, e.g. 512k or 2m (default: 1m, 0: no limit)
+    prrompt.binaryFiles       Binary prompt files: "warn" (default), "skip" or "include"
+    prrompt.wipCommits        Work-in-progress commits: "extract" (default) without their WIP marker, or "skip"
+    prrompt.changeType        Prefix PR titles and label PRs with the change type (default: true)
+    prrompt.versionBump       Bump the frontmatter version of modified prompts, shown in the PR title (default: false)
+    prrompt.prSummary         End PR bodies with the prompt files changed and source commits (default: true)
+    prrompt.prDiffLines       Inline the diffs of Markdown prompts up to this many lines in PR bodies (default: off)
+    prrompt.strict            Fail commits mixing prompt and other files in a pre-commit hook
+    prrompt.postMerge         Extract prompts arriving through merges and pulls from a post-merge hook (default: false)
+    prrompt.pushTimeout       How long a push may take before asking to wait, background or abort it (default: 2m, 0: no limit)
+    prrompt.retries           Retries of pushes and forge API calls failing for network or server errors (default: 3)
+    prrompt.retryDelay        Wait before the first retry, doubled for each next one (default: 2s)
+    prrompt.sign              Sign extraction commits: "auto" (as commit.gpgSign), "true" or "false"
+    prrompt.signoff           Add a Signed-off-by trailer to extraction commits (default: false)
+    prrompt.enabled           Extract prompts in this repository (default: true)
+    prrompt.replaceRefs       Replace refs and grafts in ancestry checks: "use" (default, as git log) or "ignore"
+    prrompt.skipMarkers       Comma-separated commit message markers that skip extraction
+                              (default: "%[7]s"; PRROMPT_SKIP=1 skips one run)
+    prrompt.logLevel          "quiet", "normal", "verbose" or "debug" (default: "%[8]s")
+    prrompt.logFile           Append a full debug log to .git/prrompt/prrompt.log (default: false)
+    prrompt.tmpDir            Directory for temporary files, outside the work tree (default: $TMPDIR)
+    prrompt.verbosity         Deprecated: "high" is the same as logLevel "verbose"
+    prrompt.dedupe            Already-present prompt content: "skip", "warn" or "force" (default: "%[9]s")
+    prrompt.allowEmptyExtraction
+                              Record duplicates as empty extraction commits (default: false)
+    prrompt.validateSkills    Validate Claude skill frontmatter, noted in the PR body (default: true)
+    prrompt.lint              Check prompt files before extraction: "off", "warn" or "block" (default: "off")
+    prrompt.lint.requiredKeys Comma-separated frontmatter keys Markdown prompts must set
+    prrompt.lint.maxFileSize  Largest prompt file in bytes (default: no limit)
+    prrompt.lint.forbiddenPhrase
+                              Phrase prompts must not contain (multi-valued, use --add)
+    prrompt.tokenEstimate     How tokens are estimated: "chars" or "words" (default: "%[10]s")
+    prrompt.tokenBudget       Warn when a prompt exceeds this many tokens (default: off)
+    prrompt.tokenCounts       Add token counts to the commit/PR body (default: false)
+    prrompt.removeFromSource  Amend the extracted commit, if unpushed HEAD, to drop its prompt
+                              changes (or drop a prompt-only commit) (default: false)
+    prrompt.onBaseBranch      Commits made on the base branch: "skip" or "parent"
+                              (branch from the commit's parent) (default: "skip")
+    prrompt.mergeStrategy     Merge commits: "skip" or "first-parent" (default: "skip")
+    prrompt.lockTimeout       Seconds to wait for a concurrent run, 0 to exit at once (default: 30)
+    prrompt.squashWindow      Append prompt commits to the source branch's recent prompt branch:
+                              a duration like "1h", or "until-pushed" (default: off)
+    prrompt.rangeMode         Several commits per run: "per-commit", "combined" or "skill" (default: "per-commit")
+    prrompt.batchSquash       Commits of a combined or skill branch: "per-commit" or one "net" commit (default: "per-commit")
+    prrompt.splitBy           Split a commit's prompts into a branch per "pattern" or top-level
+                              "directory", or "none" (default: "none")
+    prrompt.webhookSecret     Secret 'prrompt serve' authenticates webhooks with (required)
+    prrompt.webhookHosts      Hosts 'prrompt serve' clones from (default: github.com,gitlab.com)
+
+EXIT STATUS:
+    Processing commits exits 0 when prompts were extracted or there was nothing to
+    extract (the result's status is "skipped"), 1 on other errors, 2 for invalid
+    flags or settings, 3 when the prompt changes do not apply to the prompt branch,
+    4 when a prompt branch was not pushed and 5 when another run holds the lock.
 
 EXAMPLES:
     # Install the hook
-    %s install
+    %[1]s install
     
     # Configure custom prompt patterns
     git config prrompt.promptPatterns "prompts/,.claude/skills/,docs/prompts/"
     
-    # Set verbosity to high
-    git config prrompt.verbosity high
+    # Print progress details on every run
+    git config prrompt.logLevel verbose
     
     # Process a specific commit manually
-    %s abc1234
+    %[1]s abc1234
+
+    # Extract the prompt changes of the last five commits to one branch
+    %[1]s HEAD~5..HEAD --combine
+
+    # Extract prompts buried in a feature PR (uses GITHUB_TOKEN if set)
+    %[1]s process-pr 123
+
+    # Run a command across all repositories under ~/code
+    %[1]s foreach --repos '~/code/*' -- --version
 
 For more information, visit: https://github.com/Ilnicki010/prrompt
-`, toolName, toolName, toolName, toolName, toolName, toolName, toolName, defaultCommitPrefix, defaultBranchPrefix, defaultBaseBranch, strings.Join(defaultPromptPatterns, ","), defaultVerbosity, toolName, toolName)
+`,
+		toolName, defaultCommitPrefix, defaultBranchPrefix, defaultBaseBranch, defaultRemote, strings.Join(defaultPromptPatterns, ","), strings.Join(defaultSkipMarkers, ","), defaultLogLevel, defaultDedupe, defaultTokenEstimate, defaultServeAddr)
 }
 
-func installHook() error {
-	// Get git hooks directory
-	gitDir, err := runGit("rev-parse", "--git-dir")
+// installHook installs the post-commit hook, and the pre-commit and
+// post-merge hooks the configuration asks for, or with repair rewrites the
+// hooks prrompt installed before.
+func installHook(repair, global bool) error {
+	// Get current executable path, the fallback when prrompt is not on PATH
+	exePath, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	if global {
+		// Rewriting the dispatcher is also how it is repaired
+		return installGlobalHooks(exePath)
+	}
+
+	// Get git hooks directory; worktrees share the common directory's
+	gitDir, err := runGit("rev-parse", "--git-common-dir")
 	if err != nil {
 		return fmt.Errorf("not in a git repository: %w", err)
 	}
 
 	hooksDir := filepath.Join(gitDir, "hooks")
 	hookPath := filepath.Join(hooksDir, "post-commit")
-
-	// Get current executable path
-	exePath, err := os.Executable()
-	if err != nil {
-		return fmt.Errorf("failed to get executable path: %w", err)
+	if repair {
+		return repairHooks(hooksDir, exePath)
 	}
 
-	hookContent := fmt.Sprintf(`#!/bin/sh
-# Skill Extractor Post-Commit Hook
-
-COMMIT_SHA=$(git rev-parse HEAD)
-%s "$COMMIT_SHA"
-`, exePath)
+	hookContent := postCommitHookScript(exePath)
 
 	if err := os.WriteFile(hookPath, []byte(hookContent), 0755); err != nil {
 		return fmt.Errorf("failed to write hook: %w", err)
 	}
 
 	fmt.Printf("✓ Installed post-commit hook at %s\n", hookPath)
-	return nil
-}
-
-func init() {
-	if len(os.Args) > 1 && os.Args[1] == "install" {
-		if err := installHook(); err != nil {
-			fmt.Printf("Error installing hook: %v\n", err)
-			os.Exit(1)
+	if getBoolConfig("prrompt.strict", false) {
+		if err := installPreCommitHook(hooksDir, exePath); err != nil {
+			return err
 		}
-		os.Exit(0)
 	}
+	if getBoolConfig("prrompt.postMerge", false) {
+		return installPostMergeHook(hooksDir, exePath)
+	}
+	return nil
 }
\ No newline at end of file
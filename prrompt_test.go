@@ -42,13 +42,6 @@ func setupTestRepo(t *testing.T) testRepo {
 	return testRepo{Dir: tmpDir, BranchName: branchName}
 }
 
-func runGitInDir(dir string, args ...string) (string, error) {
-	cmd := exec.Command("git", args...)
-	cmd.Dir = dir
-	output, err := cmd.CombinedOutput()
-	return strings.TrimSpace(string(output)), err
-}
-
 func runPrrompt(t *testing.T, repoDir string, commitSHA string) error {
 	// Change to repo directory before calling processCommit
 	oldDir, err := os.Getwd()
@@ -61,7 +54,8 @@ func runPrrompt(t *testing.T, repoDir string, commitSHA string) error {
 		t.Fatalf("Failed to change to repo directory: %v", err)
 	}
 	
-	return processCommit(commitSHA)
+	_, err = processCommit(commitSHA)
+	return err
 }
 
 func Test_PromptOnlyCommit(t *testing.T) {
@@ -166,16 +160,30 @@ func Test_MixedCommit(t *testing.T) {
 		t.Errorf("Other file should NOT exist in the prompt branch commit. Files in commit: %s", filesInCommit)
 	}
 
-	// Verify commit message includes extraction info
-	commitMsg, _ := runGitInDir(repo.Dir, "log", "--format=%B", "-n", "1", "HEAD")
-	if !strings.Contains(commitMsg, "Extracted from") {
-		t.Errorf("Expected commit message to contain extraction info, got: %s", commitMsg)
+	// Verify commit message includes provenance trailers
+	trailers, _ := runGitInDir(repo.Dir, "log", "--format=%(trailers:only,unfold)", "-n", "1", "HEAD")
+	if !strings.Contains(trailers, trailerSourceCommit+": "+commitSHA) {
+		t.Errorf("Expected %s trailer with commit SHA, got: %s", trailerSourceCommit, trailers)
+	}
+	if !strings.Contains(trailers, trailerSourceBranch+": "+repo.BranchName) {
+		t.Errorf("Expected %s trailer with source branch %s, got: %s", trailerSourceBranch, repo.BranchName, trailers)
 	}
-	if !strings.Contains(commitMsg, shortSHA) {
-		t.Errorf("Expected commit message to contain commit SHA, got: %s", commitMsg)
+	if !strings.Contains(trailers, trailerToolVersion+": ") {
+		t.Errorf("Expected %s trailer, got: %s", trailerToolVersion, trailers)
+	}
+}
+
+func Test_appendTrailers(t *testing.T) {
+	got := appendTrailers("Fix prompt\n\nSigned-off-by: A <a@example.com>", []trailer{{"Prrompt-Source-Commit", "abc"}})
+	want := "Fix prompt\n\nSigned-off-by: A <a@example.com>\nPrrompt-Source-Commit: abc\n"
+	if got != want {
+		t.Errorf("Expected trailers to join existing block, got: %q", got)
 	}
-	if !strings.Contains(commitMsg, repo.BranchName) {
-		t.Errorf("Expected commit message to contain source branch %s, got: %s", repo.BranchName, commitMsg)
+
+	got = appendTrailers("Fix prompt\n\nLonger body text.", []trailer{{"Prrompt-Source-Commit", "abc"}})
+	want = "Fix prompt\n\nLonger body text.\n\nPrrompt-Source-Commit: abc\n"
+	if got != want {
+		t.Errorf("Expected trailers in a new paragraph, got: %q", got)
 	}
 }
 
@@ -373,4 +381,446 @@ func Test_newPromptFileAdded(t *testing.T) {
 	if !strings.Contains(branches, expectedBranch) {
 		t.Errorf("Expected branch %s not found. Branches: %s", expectedBranch, branches)
 	}
-}
\ No newline at end of file
+}
+func Test_CustomRemote(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	promptsRemote := t.TempDir()
+	runGitInDir(promptsRemote, "init", "--bare", "-b", "main")
+	runGitInDir(repo.Dir, "remote", "add", "prompts", promptsRemote)
+	runGitInDir(repo.Dir, "config", "prrompt.remote", "prompts")
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	expectedBranch := defaultBranchPrefix + "/" + commitSHA[:7]
+	remoteBranches, _ := runGitInDir(promptsRemote, "branch", "--list", expectedBranch)
+	if !strings.Contains(remoteBranches, expectedBranch) {
+		t.Errorf("Expected %s to be pushed to the prompts remote. Branches: %s", expectedBranch, remoteBranches)
+	}
+}
+
+func Test_getGitHubRepoPathUsesConfiguredRemote(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "remote", "add", "origin", "git@github.com:acme/code.git")
+	runGitInDir(repo.Dir, "remote", "add", "prompts", "https://github.com/acme/prompts.git")
+	runGitInDir(repo.Dir, "config", "prrompt.remote", "prompts")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if got := getGitHubRepoPath(); got != "acme/prompts" {
+		t.Errorf("Expected acme/prompts, got %q", got)
+	}
+}
+
+func Test_ExcludePatterns(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.excludePatterns", "prompts/experiments/, prompts/*/draft-*.md")
+
+	files := map[string]string{
+		"prompts/review.md":              "# Review",
+		"prompts/experiments/new.md":     "# Experiment",
+		"prompts/agents/draft-triage.md": "# Draft",
+	}
+	for name, content := range files {
+		file := filepath.Join(repo.Dir, name)
+		os.MkdirAll(filepath.Dir(file), 0755)
+		os.WriteFile(file, []byte(content), 0644)
+	}
+	runGitInDir(repo.Dir, "add", ".")
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompts and experiments")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	filesInCommit, _ := runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", defaultBranchPrefix+"/"+commitSHA[:7])
+	if filesInCommit != "prompts/review.md" {
+		t.Errorf("Expected only prompts/review.md on the prompt branch, got: %s", filesInCommit)
+	}
+}
+
+func Test_matchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		file    string
+		want    bool
+	}{
+		{"prompts/experiments/", "prompts/experiments/a.md", true},
+		{"prompts/experiments/", "prompts/review.md", false},
+		{"prompts/exp-*", "prompts/exp-1/a.md", true},
+		{"*.png", "prompts/logo.png", false},
+		{"prompts/*.png", "prompts/logo.png", true},
+	}
+	for _, tt := range tests {
+		if got := matchGlob(tt.pattern, tt.file); got != tt.want {
+			t.Errorf("matchGlob(%q, %q) = %v, want %v", tt.pattern, tt.file, got, tt.want)
+		}
+	}
+}
+
+func Test_SkipMarker(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Tweak prompt with code [Skip Prrompt]")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	branches, _ := runGitInDir(repo.Dir, "branch", "--list", defaultBranchPrefix+"/*")
+	if branches != "" {
+		t.Errorf("Commit with a skip marker should not be extracted. Branches: %s", branches)
+	}
+
+	// Custom markers replace the defaults
+	runGitInDir(repo.Dir, "config", "prrompt.skipMarkers", "#noextract")
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	branches, _ = runGitInDir(repo.Dir, "branch", "--list", defaultBranchPrefix+"/*")
+	if branches == "" {
+		t.Error("Default marker should not apply when prrompt.skipMarkers is set")
+	}
+}
+
+func Test_SkipEnv(t *testing.T) {
+	repo := setupTestRepo(t)
+	t.Setenv("PRROMPT_SKIP", "1")
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	branches, _ := runGitInDir(repo.Dir, "branch", "--list", defaultBranchPrefix+"/*")
+	if branches != "" {
+		t.Errorf("PRROMPT_SKIP=1 should skip extraction. Branches: %s", branches)
+	}
+}
+
+func Test_BaseOverride(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "branch", "release/3.2", "main")
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	// Move main on so the two bases differ
+	runGitInDir(repo.Dir, "commit", "--allow-empty", "-m", "Later main commit")
+	mainSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	runGitInDir(repo.Dir, "branch", "-f", "main", mainSHA)
+	runGitInDir(repo.Dir, "reset", "--hard", commitSHA)
+
+	t.Setenv(baseEnv, "release/3.2")
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	releaseSHA, _ := runGitInDir(repo.Dir, "rev-parse", "release/3.2")
+	parent, _ := runGitInDir(repo.Dir, "rev-parse", defaultBranchPrefix+"/"+commitSHA[:7]+"^")
+	if parent != releaseSHA {
+		t.Errorf("Expected prompt branch based on release/3.2 (%s), got %s", releaseSHA, parent)
+	}
+	if base, _ := runGitInDir(repo.Dir, "config", "--get", "prrompt.baseBranch"); base != "" {
+		t.Errorf("Override must not touch git config, got prrompt.baseBranch=%s", base)
+	}
+}
+
+func Test_DeletedPromptFile(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	// The prompt exists on main, which changes it after the branch point
+	promptFile := filepath.Join(repo.Dir, "prompts/old.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	runGitInDir(repo.Dir, "checkout", "main")
+	os.WriteFile(promptFile, []byte("# Old prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add old prompt")
+	runGitInDir(repo.Dir, "checkout", "-B", repo.BranchName)
+	runGitInDir(repo.Dir, "checkout", "main")
+	os.WriteFile(promptFile, []byte("# Old prompt, tweaked"), 0644)
+	runGitInDir(repo.Dir, "commit", "-am", "Tweak old prompt")
+	runGitInDir(repo.Dir, "checkout", repo.BranchName)
+
+	runGitInDir(repo.Dir, "rm", "-q", "prompts/old.md")
+	runGitInDir(repo.Dir, "commit", "-m", "Remove old prompt")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	files, _ := runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", defaultBranchPrefix+"/"+commitSHA[:7])
+	if strings.Contains(files, "prompts/old.md") {
+		t.Errorf("Expected prompts/old.md to be deleted on the prompt branch. Files: %s", files)
+	}
+}
+
+func Test_RenamedPromptFile(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	promptFile := filepath.Join(repo.Dir, "prompts/review.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	runGitInDir(repo.Dir, "checkout", "main")
+	os.WriteFile(promptFile, []byte("# Review prompt\n\nLong enough to be detected as a rename.\n"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add review prompt")
+	runGitInDir(repo.Dir, "checkout", "-B", repo.BranchName)
+
+	// Moved out of the prompt root: both halves belong to the extraction
+	os.MkdirAll(filepath.Join(repo.Dir, "docs"), 0755)
+	runGitInDir(repo.Dir, "mv", "prompts/review.md", "docs/review.md")
+	runGitInDir(repo.Dir, "commit", "-m", "Move review prompt to docs")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+	info, err := analyzeCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("analyzeCommit failed: %v", err)
+	}
+	if info.Renames["docs/review.md"] != "prompts/review.md" || len(info.PromptFiles) != 2 || info.IsMixed {
+		t.Errorf("Expected the rename pair as prompt files, got prompt=%v other=%v renames=%v", info.PromptFiles, info.OtherFiles, info.Renames)
+	}
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	files, _ := runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", defaultBranchPrefix+"/"+commitSHA[:7])
+	if strings.Contains(files, "prompts/review.md") || !strings.Contains(files, "docs/review.md") {
+		t.Errorf("Expected the rename on the prompt branch. Files: %s", files)
+	}
+}
+
+func Test_MergeCommit(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	runGitInDir(repo.Dir, "checkout", "-b", "side")
+	promptFile := filepath.Join(repo.Dir, "prompts/merged.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Merged prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt on side branch")
+	runGitInDir(repo.Dir, "checkout", repo.BranchName)
+	runGitInDir(repo.Dir, "commit", "--allow-empty", "-m", "Diverge")
+	runGitInDir(repo.Dir, "merge", "--no-ff", "-m", "Merge side", "side")
+	mergeSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(mergeSHA)
+	if err != nil || result.Reason != reasonMergeCommit {
+		t.Fatalf("Expected merge commit to be skipped, got %+v (err %v)", result, err)
+	}
+
+	t.Setenv(mainlineEnv, "1")
+	result, err = processCommit(mergeSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected merge commit to be extracted with mainline 1, got %+v (err %v)", result, err)
+	}
+	files, _ := runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", result.Branch)
+	if !strings.Contains(files, "prompts/merged.md") {
+		t.Errorf("Expected merged prompt on %s. Files: %s", result.Branch, files)
+	}
+
+	t.Setenv(mainlineEnv, "3")
+	if _, err := processCommit(mergeSHA); err == nil {
+		t.Error("Expected an error for a parent the merge does not have")
+	}
+}
+
+func Test_CommitOnBaseBranch(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "checkout", "main")
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt on main")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil || result.Reason != reasonOnBaseBranch {
+		t.Fatalf("Expected commit on main to be skipped, got %+v (err %v)", result, err)
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.onBaseBranch", "parent")
+	result, err = processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction from the parent, got %+v (err %v)", result, err)
+	}
+	parent, _ := runGitInDir(repo.Dir, "rev-parse", result.Branch+"^")
+	wantParent, _ := runGitInDir(repo.Dir, "rev-parse", commitSHA+"^")
+	if parent != wantParent {
+		t.Errorf("Expected %s to start at %s, got %s", result.Branch, wantParent, parent)
+	}
+	current, _ := runGitInDir(repo.Dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if current != "main" {
+		t.Errorf("Expected to be back on main, got %s", current)
+	}
+}
+
+func Test_RootCommit(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	// An imported history whose root commit adds prompts next to code
+	runGitInDir(repo.Dir, "checkout", "--orphan", "import")
+	runGitInDir(repo.Dir, "rm", "-rf", "-q", "--cached", ".")
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	os.WriteFile(filepath.Join(repo.Dir, "main.go"), []byte("package main"), 0644)
+	runGitInDir(repo.Dir, "add", ".")
+	runGitInDir(repo.Dir, "commit", "-m", "Import prompts")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected the root commit to be extracted, got %+v (err %v)", result, err)
+	}
+	files, _ := runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", result.Branch)
+	if files != "prompts/test.md" {
+		t.Errorf("Expected only the prompt file on %s, got %q", result.Branch, files)
+	}
+	parent, _ := runGitInDir(repo.Dir, "rev-parse", result.Branch+"^")
+	if main, _ := runGitInDir(repo.Dir, "rev-parse", "main"); parent != main {
+		t.Errorf("Expected %s to start at main, got %s", result.Branch, parent)
+	}
+
+	// Committed on the base branch, it is extracted as a new root
+	runGitInDir(repo.Dir, "branch", "-D", result.Branch)
+	runGitInDir(repo.Dir, "config", "prrompt.baseBranch", "import")
+	runGitInDir(repo.Dir, "config", "prrompt.onBaseBranch", "parent")
+	result, err = processCommit(commitSHA)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction as a new root, got %+v (err %v)", result, err)
+	}
+	if count, _ := runGitInDir(repo.Dir, "rev-list", "--count", result.Branch); count != "1" {
+		t.Errorf("Expected %s to be a single root commit, got %s commits", result.Branch, count)
+	}
+	files, _ = runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", result.Branch)
+	if files != "prompts/test.md" {
+		t.Errorf("Expected only the prompt file on %s, got %q", result.Branch, files)
+	}
+	current, _ := runGitInDir(repo.Dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if current != "import" {
+		t.Errorf("Expected to be back on import, got %s", current)
+	}
+}
+
+func Test_SourceBranchFromHook(t *testing.T) {
+	repo := setupTestRepo(t)
+	commitSHA := commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/a.md": "# A"})
+	// HEAD has moved on by the time the hook runs
+	runGitInDir(repo.Dir, "checkout", "-q", "-b", "other")
+	t.Setenv(sourceBranchEnv, repo.BranchName)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if result.SourceBranch != repo.BranchName {
+		t.Errorf("Expected source branch %s from the hook, got %s", repo.BranchName, result.SourceBranch)
+	}
+	trailers, _ := runGitInDir(repo.Dir, "log", "--format=%(trailers:only,unfold)", "-n", "1", result.Branch)
+	if !strings.Contains(trailers, trailerSourceBranch+": "+repo.BranchName) {
+		t.Errorf("Expected the hook's branch in the trailers, got %s", trailers)
+	}
+}
+
+func Test_DirtyWorkTree(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.squashWindow", "1h")
+	commitFiles(t, repo.Dir, "Add code", map[string]string{"main.go": "package main"})
+	sha := commitFiles(t, repo.Dir, "Add prompt", map[string]string{
+		"prompts/test.md": "# v1",
+		"util.go":         "package main",
+	})
+
+	// Uncommitted edits, staged and not, and a new file
+	os.WriteFile(filepath.Join(repo.Dir, "main.go"), []byte("package main // edited"), 0644)
+	os.WriteFile(filepath.Join(repo.Dir, "util.go"), []byte("package main // staged"), 0644)
+	runGitInDir(repo.Dir, "add", "util.go")
+	os.WriteFile(filepath.Join(repo.Dir, "wip.txt"), []byte("uncommitted"), 0644)
+	status, _ := runGitInDir(repo.Dir, "status", "--porcelain")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(sha)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", result, err)
+	}
+	if files, _ := runGit("ls-tree", "-r", "--name-only", result.Branch); files != "prompts/test.md" {
+		t.Errorf("Expected only the prompt on %s, got %q", result.Branch, files)
+	}
+	if branch, _ := currentBranch(); branch != repo.BranchName {
+		t.Errorf("Expected to stay on %s, got %s", repo.BranchName, branch)
+	}
+	if after, _ := runGit("status", "--porcelain"); after != status {
+		t.Errorf("Expected the uncommitted changes kept as %q, got %q", status, after)
+	}
+	if content, _ := os.ReadFile("main.go"); string(content) != "package main // edited" {
+		t.Errorf("Expected the edit of main.go kept, got %q", content)
+	}
+
+	// Within the squash window the next commit is appended, still without a checkout
+	runGit("stash")
+	next := commitFiles(t, repo.Dir, "Edit prompt", map[string]string{"prompts/test.md": "# v2"})
+	runGit("stash", "pop", "--index")
+	again, err := processCommit(next)
+	if err != nil || again.Branch != result.Branch {
+		t.Fatalf("Expected %s appended to, got %+v (err %v)", result.Branch, again, err)
+	}
+	if count, _ := runGit("rev-list", "--count", "main.."+result.Branch); count != "2" {
+		t.Errorf("Expected 2 extraction commits on %s, got %s", result.Branch, count)
+	}
+	if content, _ := runGit("show", result.Branch+":prompts/test.md"); content != "# v2" {
+		t.Errorf("Expected the latest prompt on %s, got %q", result.Branch, content)
+	}
+	if after, _ := runGit("status", "--porcelain"); after != status {
+		t.Errorf("Expected the uncommitted changes kept as %q, got %q", status, after)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// PR tools, selected with prrompt.prTool.
+const (
+	prToolURL  = "url"  // print a compare URL to open by hand
+	prToolGH   = "gh"   // gh pr create
+	prToolGlab = "glab" // glab mr create
+	prToolAPI  = "api"  // GitHub REST API with GITHUB_TOKEN/GH_TOKEN
+)
+
+const defaultPRTool = prToolURL
+
+func getPRTool() string {
+	value, err := gitConfig("--get", "prrompt.prTool")
+	if err != nil {
+		return defaultPRTool
+	}
+	switch value = strings.ToLower(strings.TrimSpace(value)); value {
+	case prToolGH, prToolGlab, prToolAPI:
+		return value
+	}
+	return defaultPRTool
+}
+
+// getListConfig returns a comma-separated setting as a list.
+func getListConfig(key string) []string {
+	value, err := gitConfig("--get", key)
+	if err != nil || value == "" {
+		return nil
+	}
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// prMetadata is what auto-created PRs are triaged with.
+type prMetadata struct {
+	Labels    []string
+	Reviewers []string // users, or org/team slugs
+	Assignees []string
+}
+
+func getPRMetadata(infos []*CommitInfo) prMetadata {
+	labels := getListConfig("prrompt.prLabels")
+	seen := make(map[string]bool)
+	for _, info := range infos {
+		for _, label := range experimentLabels(info.Experiments) {
+			if !seen[label] {
+				seen[label] = true
+				labels = append(labels, label)
+			}
+		}
+	}
+	if getBoolConfig("prrompt.changeType", true) {
+		labels = append(labels, changeLabel(classifyChange(infos)))
+	}
+	// The owners of the prompt files review along with the configured
+	// reviewers
+	reviewers := getListConfig("prrompt.prReviewers")
+	requested := make(map[string]bool)
+	for _, reviewer := range reviewers {
+		requested[strings.ToLower(reviewer)] = true
+	}
+	for _, owner := range codeownersReviewers(infos) {
+		if !requested[strings.ToLower(owner)] {
+			reviewers = append(reviewers, owner)
+		}
+	}
+	return prMetadata{
+		Labels:    labels,
+		Reviewers: reviewers,
+		Assignees: getListConfig("prrompt.prAssignees"),
+	}
+}
+
+// prTitleAndBody splits the extracted commit message into a PR title and
+// body, redacted since they leave git. A branch combining several commits
+// gets a summary title and lists each commit in the body; one extracted by
+// skill names the skill. Bumped prompt versions end the title. With
+// prrompt.changeType the title starts with the change type, and with
+// prrompt.prSummary the body ends with a summary of the prompt changes.
+func prTitleAndBody(infos []*CommitInfo) (string, string) {
+	var title, body string
+	if len(infos) == 1 {
+		title, body, _ = strings.Cut(buildCommitMessage(infos[0]), "\n")
+	} else {
+		title = prefixSubject(fmt.Sprintf("Prompt changes from %d commits", len(infos)))
+		var b strings.Builder
+		if group := infos[0].Group; group != "" {
+			title = prefixSubject(fmt.Sprintf("%s: changes from %d commits", group, len(infos)))
+			fmt.Fprintf(&b, "The net change to %s, from these commits:\n\n", group)
+		}
+		for _, info := range infos {
+			subject, _, _ := strings.Cut(info.Message, "\n")
+			fmt.Fprintf(&b, "- %s %s\n", info.SHA[:7], subject)
+		}
+		for _, info := range infos {
+			if info.AISummary != "" {
+				fmt.Fprintf(&b, "\n%s: %s\n", info.SHA[:7], info.AISummary)
+			}
+		}
+		body = b.String()
+	}
+	title += versionTitle(netVersionBumps(infos))
+	if getBoolConfig("prrompt.changeType", true) {
+		title = changeTitle(title, classifyChange(infos))
+	}
+	if getBoolConfig("prrompt.prSummary", true) {
+		if summary := prChangeSummary(infos); summary != "" {
+			body = strings.TrimSpace(body) + "\n\n" + summary
+		}
+	}
+	return redact(title), redact(strings.TrimSpace(body))
+}
+
+// openPR creates the pull (or merge) request for a pushed prompt branch with
+// the configured tool and returns its URL. With prrompt.prTool=url, or when
+// the tool fails, it returns the compare URL instead.
+func openPR(infos []*CommitInfo, base, branch string) string {
+	meta := getPRMetadata(infos)
+	if getPRTool() == prToolURL {
+		return generatePRURL(base, branch, meta.Labels...)
+	}
+	title, body := prTitleAndBody(infos)
+	return createPR(base, branch, title, body, meta)
+}
+
+// createPR opens a pull (or merge) request from branch into base with the
+// configured tool, falling back to the compare URL.
+func createPR(base, branch, title, body string, meta prMetadata) string {
+	tool := getPRTool()
+	if tool == prToolURL {
+		return generatePRURL(base, branch, meta.Labels...)
+	}
+	var prURL string
+	var err error
+	switch tool {
+	case prToolGH:
+		args := []string{"pr", "create", "--head", branch, "--base", base, "--title", title, "--body", body}
+		for _, label := range meta.Labels {
+			args = append(args, "--label", label)
+		}
+		for _, reviewer := range meta.Reviewers {
+			args = append(args, "--reviewer", reviewer)
+		}
+		for _, assignee := range meta.Assignees {
+			args = append(args, "--assignee", assignee)
+		}
+		prURL, err = runPRTool("gh", args...)
+	case prToolGlab:
+		args := []string{"mr", "create", "--source-branch", branch, "--target-branch", base, "--title", title, "--description", body, "--yes"}
+		if len(meta.Labels) > 0 {
+			args = append(args, "--label", strings.Join(meta.Labels, ","))
+		}
+		if len(meta.Reviewers) > 0 {
+			args = append(args, "--reviewer", strings.Join(meta.Reviewers, ","))
+		}
+		if len(meta.Assignees) > 0 {
+			args = append(args, "--assignee", strings.Join(meta.Assignees, ","))
+		}
+		prURL, err = runPRTool("glab", args...)
+	case prToolAPI:
+		repoPath := getGitHubRepoPath()
+		if repoPath == "" {
+			err = fmt.Errorf("remote %q is not a GitHub repository", getRemote())
+			break
+		}
+		prURL, err = createPullRequest(repoPath, base, branch, title, body, meta)
+	}
+	if err != nil || prURL == "" {
+		warnf("failed to create PR with %s, open it manually: %v", tool, err)
+		return generatePRURL(base, branch, meta.Labels...)
+	}
+	return prURL
+}
+
+// runPRTool runs a forge CLI and returns the URL it prints last. A run
+// failing for network reasons is retried with backoff.
+func runPRTool(name string, args ...string) (string, error) {
+	debugf("%s %s", name, strings.Join(args, " "))
+	var output []byte
+	err := withRetries("Running "+name, func() error {
+		var err error
+		if output, err = exec.Command(name, args...).CombinedOutput(); err != nil {
+			err = fmt.Errorf("%s: %w: %s", name, err, truncate(strings.TrimSpace(string(output)), 200))
+			if isTransientNetworkError(string(output)) {
+				return temporary(err)
+			}
+		}
+		return err
+	})
+	if err != nil {
+		return "", err
+	}
+	lines := strings.Fields(string(output))
+	for i := len(lines) - 1; i >= 0; i-- {
+		if strings.HasPrefix(lines[i], "https://") || strings.HasPrefix(lines[i], "http://") {
+			return lines[i], nil
+		}
+	}
+	return "", fmt.Errorf("%s printed no URL", name)
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupPushableRepo returns a test repo whose origin is a local bare repo
+// reachable through a GitHub URL, and a commit touching a prompt file.
+func setupPushableRepo(t *testing.T) (testRepo, string) {
+	repo := setupTestRepo(t)
+	originDir := t.TempDir()
+	runGitInDir(originDir, "init", "--bare", "-b", "main")
+	runGitInDir(repo.Dir, "remote", "add", "origin", "https://github.com/acme/widgets.git")
+	runGitInDir(repo.Dir, "config", "url."+originDir+".insteadOf", "https://github.com/acme/widgets.git")
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	return repo, commitSHA
+}
+
+func Test_PRToolGH(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.prTool", "gh")
+
+	// A fake gh recording its arguments
+	binDir := t.TempDir()
+	argsFile := filepath.Join(binDir, "args")
+	script := "#!/bin/sh\nprintf '%s\\n' \"$@\" > " + argsFile + "\necho 'https://github.com/acme/widgets/pull/42'\n"
+	os.WriteFile(filepath.Join(binDir, "gh"), []byte(script), 0755)
+	t.Setenv("PATH", binDir+string(os.PathListSeparator)+os.Getenv("PATH"))
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if result.PRURL != "https://github.com/acme/widgets/pull/42" {
+		t.Errorf("Expected PR URL from gh, got %q", result.PRURL)
+	}
+	args, _ := os.ReadFile(argsFile)
+	want := "pr\ncreate\n--head\n" + defaultBranchPrefix + "/" + commitSHA[:7] + "\n--base\nmain\n--title\n[prompt] New Skill: Add prompt file\n"
+	if !strings.HasPrefix(string(args), want) {
+		t.Errorf("Unexpected gh arguments:\n%s", args)
+	}
+}
+
+func Test_PRToolAPI(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.prTool", "api")
+	runGitInDir(repo.Dir, "config", "prrompt.prLabels", "prompts")
+	runGitInDir(repo.Dir, "config", "prrompt.prReviewers", "alice, acme/prompt-owners")
+	runGitInDir(repo.Dir, "config", "prrompt.prAssignees", "bob")
+
+	var created map[string]string
+	triage := make(map[string]map[string][]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method != http.MethodPost:
+			http.NotFound(w, r)
+		case r.URL.Path == "/repos/acme/widgets/pulls":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.WriteHeader(http.StatusCreated)
+			w.Write([]byte(`{"number": 7, "html_url": "https://github.com/acme/widgets/pull/7"}`))
+		default:
+			var body map[string][]string
+			json.NewDecoder(r.Body).Decode(&body)
+			triage[r.URL.Path] = body
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer server.Close()
+	oldURL := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = oldURL }()
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if result.PRURL != "https://github.com/acme/widgets/pull/7" {
+		t.Errorf("Expected PR URL from the API, got %q", result.PRURL)
+	}
+	if created["head"] != defaultBranchPrefix+"/"+commitSHA[:7] || created["base"] != "main" || created["title"] != "[prompt] New Skill: Add prompt file" {
+		t.Errorf("Unexpected PR request: %v", created)
+	}
+	if !strings.Contains(created["body"], trailerSourceCommit) {
+		t.Errorf("Expected provenance in the PR body, got %q", created["body"])
+	}
+
+	if got := triage["/repos/acme/widgets/issues/7/labels"]["labels"]; len(got) != 2 || got[0] != "prompts" || got[1] != "change:new-skill" {
+		t.Errorf("Expected labels to be set, got %v", got)
+	}
+	reviewers := triage["/repos/acme/widgets/pulls/7/requested_reviewers"]
+	if len(reviewers["reviewers"]) != 1 || reviewers["reviewers"][0] != "alice" || len(reviewers["team_reviewers"]) != 1 || reviewers["team_reviewers"][0] != "prompt-owners" {
+		t.Errorf("Expected user and team reviewers, got %v", reviewers)
+	}
+	if got := triage["/repos/acme/widgets/issues/7/assignees"]["assignees"]; len(got) != 1 || got[0] != "bob" {
+		t.Errorf("Expected assignees to be set, got %v", got)
+	}
+}
+
+func Test_GitHubQuota(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.prTool", "api")
+	runGitInDir(repo.Dir, "config", "prrompt.prLabels", "prompts")
+	defer func() { githubQuota.Known = false }()
+
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("X-RateLimit-Remaining", "2")
+		w.Header().Set("X-RateLimit-Limit", "60")
+		w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"number": 7, "html_url": "https://github.com/acme/widgets/pull/7"}`))
+	}))
+	defer server.Close()
+	oldURL := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = oldURL }()
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if result.PRURL != "https://github.com/acme/widgets/pull/7" {
+		t.Errorf("Expected the PR to be created, got %q", result.PRURL)
+	}
+	if len(paths) != 1 {
+		t.Errorf("Expected labelling to be skipped near the rate limit, got requests %v", paths)
+	}
+	if err := githubGet("/repos/acme/widgets/pulls/7", &PullRequest{}); !errors.Is(err, errRateLimited) {
+		t.Errorf("Expected errRateLimited without a request, got %v", err)
+	}
+	if len(paths) != 1 {
+		t.Errorf("Expected no request once the quota is exhausted, got %v", paths)
+	}
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// defaultPushTimeout is how long a push may take before prrompt asks
+// whether to wait for it (prrompt.pushTimeout).
+const defaultPushTimeout = 2 * time.Minute
+
+type pushChoice int
+
+const (
+	pushAbort pushChoice = iota
+	pushWait
+	pushBackground
+)
+
+var (
+	// pushProgressInterval is how often a running push reports progress.
+	pushProgressInterval = 10 * time.Second
+
+	// askPushTimeout decides what happens to a push that took longer than
+	// prrompt.pushTimeout.
+	askPushTimeout = askPushTimeoutOnTTY
+
+	// errPushInBackground is returned for a push left to finish on its own.
+	errPushInBackground = errors.New("push continues in the background")
+)
+
+// Why a prompt branch was not pushed, reported as pushFailure in results
+// and kept with the pending push.
+const (
+	pushFailAuth           = "auth"
+	pushFailNonFastForward = "non-fast-forward"
+	pushFailProtected      = "protected-branch"
+	pushFailRejected       = "rejected"
+	pushFailNetwork        = "network"
+	pushFailNotFound       = "repo-not-found"
+	pushFailTimeout        = "timeout"
+	pushFailOffline        = "offline"
+	pushFailBackground     = "in-background"
+	pushFailUnknown        = "unknown"
+)
+
+// pushFailureMarkers map git's and the forges' messages to a reason, most
+// specific first: a protected branch is also a rejection, and a 403 is not
+// a network problem.
+var pushFailureMarkers = []struct {
+	reason  string
+	markers []string
+}{
+	{pushFailAuth, []string{
+		"Authentication failed", "Permission denied", "could not read Username", "Invalid username or password",
+		"terminal prompts disabled", "Host key verification failed", "returned error: 401", "returned error: 403",
+	}},
+	{pushFailNotFound, []string{
+		"Repository not found", "does not appear to be a git repository", "returned error: 404", "project you were looking for could not be found",
+	}},
+	{pushFailProtected, []string{
+		"protected branch", "GH006", "GH013", "not allowed to push code to protected branches",
+	}},
+	{pushFailNonFastForward, []string{"non-fast-forward", "(fetch first)", "Updates were rejected because"}},
+	{pushFailRejected, []string{"[remote rejected]", "pre-receive hook declined"}},
+}
+
+// classifyPushFailure returns the reason a push failed with output.
+func classifyPushFailure(output string) string {
+	for _, class := range pushFailureMarkers {
+		for _, marker := range class.markers {
+			if strings.Contains(output, marker) {
+				return class.reason
+			}
+		}
+	}
+	if isTransientNetworkError(output) {
+		return pushFailNetwork
+	}
+	return pushFailUnknown
+}
+
+// pushError is a failed push with the reason classified from git's output.
+type pushError struct {
+	reason string
+	err    error
+}
+
+func (e *pushError) Error() string { return e.err.Error() }
+func (e *pushError) Unwrap() error { return e.err }
+
+// pushFailureReason returns the reason code of a failed push.
+func pushFailureReason(err error) string {
+	var p *pushError
+	switch {
+	case errors.As(err, &p):
+		return p.reason
+	case errors.Is(err, errPushInBackground):
+		return pushFailBackground
+	}
+	return pushFailUnknown
+}
+
+// pushRemedy tells what to do about a push of branch to remote that failed
+// for reason.
+func pushRemedy(reason, remote, branch string) string {
+	retry := fmt.Sprintf("'%s push --retry-failed'", toolName)
+	switch reason {
+	case pushFailAuth:
+		return fmt.Sprintf("authenticate with %s (e.g. 'gh auth login', a credential helper or an SSH key), then run %s", remote, retry)
+	case pushFailNotFound:
+		return fmt.Sprintf("check the URL of %s ('git remote get-url %s') and that you have access to it, or set prrompt.remote", remote, remote)
+	case pushFailProtected:
+		return fmt.Sprintf("%s matches a protected branch rule on %s; exempt the prompt branches or change prrompt.branchPrefix, then run %s", branch, remote, retry)
+	case pushFailNonFastForward:
+		return fmt.Sprintf("%s already exists on %s with other commits; delete it there if it is stale, then run %s", branch, remote, retry)
+	case pushFailRejected:
+		return fmt.Sprintf("a hook on %s rejected the push; fix what it reports, then run %s", remote, retry)
+	case pushFailNetwork:
+		return fmt.Sprintf("%s could not be reached; run %s once the connection is back", remote, retry)
+	case pushFailTimeout:
+		return fmt.Sprintf("raise prrompt.pushTimeout if pushes to %s are slow, then run %s", remote, retry)
+	}
+	return fmt.Sprintf("run %s to retry", retry)
+}
+
+// getPushTimeout returns prrompt.pushTimeout; 0 lets pushes take as long
+// as they need.
+func getPushTimeout() time.Duration {
+	value, err := gitConfig("--get", "prrompt.pushTimeout")
+	if err != nil {
+		return defaultPushTimeout
+	}
+	timeout, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || timeout < 0 {
+		return defaultPushTimeout
+	}
+	return timeout
+}
+
+// askPushTimeoutOnTTY asks on the terminal whether to keep waiting for a
+// slow push, leave it running in the background or abort it. Without a
+// terminal, as in CI, the push is aborted.
+func askPushTimeoutOnTTY(branch string, elapsed time.Duration) pushChoice {
+	if os.Getenv("CI") != "" {
+		return pushAbort
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return pushAbort
+	}
+	defer tty.Close()
+	fmt.Fprintf(tty, "Pushing %s is taking %s. [w]ait, continue in the [b]ackground or [a]bort? ", branch, elapsed.Round(time.Second))
+	answer, _ := bufio.NewReader(tty).ReadString('\n')
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "w", "wait":
+		return pushWait
+	case "b", "background":
+		return pushBackground
+	}
+	return pushAbort
+}
+
+// timedPush pushes branch to remote, reporting progress while it runs and
+// recording how long it took. A push failing for network reasons is
+// retried with backoff.
+func timedPush(remote, branch string, flags ...string) error {
+	return withRetries("Pushing "+branch, func() error {
+		start := time.Now()
+		err := pushWithProgress(remote, branch, flags)
+		appMetrics.observePush(time.Since(start))
+		return err
+	})
+}
+
+// pushWithProgress runs git push, printing a line every
+// pushProgressInterval so a slow push in a hook does not look like a frozen
+// terminal. Past prrompt.pushTimeout, askPushTimeout decides whether to
+// keep waiting, return errPushInBackground, or kill the push. Failures are
+// returned as a pushError, and as temporary for network reasons.
+func pushWithProgress(remote, branch string, flags []string) error {
+	args := append(append([]string{"push"}, flags...), remote, branch)
+	debugf("git %s", strings.Join(args, " "))
+
+	// The output goes to a file rather than a pipe, so that a push left in
+	// the background keeps running after prrompt exits
+	output, err := makeTempFile("prrompt-push-")
+	if err != nil {
+		return err
+	}
+	defer output.Close()
+	cmd := exec.Command("git", args...)
+	cmd.Stdout, cmd.Stderr = output, output
+	if err := cmd.Start(); err != nil {
+		removeTemp(output.Name())
+		return err
+	}
+	done := make(chan error, 1)
+	go func() { done <- cmd.Wait() }()
+
+	start := time.Now()
+	ticker := time.NewTicker(pushProgressInterval)
+	defer ticker.Stop()
+	timeout := getPushTimeout()
+	var deadline <-chan time.Time
+	if timeout > 0 {
+		deadline = time.After(timeout)
+	}
+	for {
+		select {
+		case err := <-done:
+			if err != nil {
+				data, _ := os.ReadFile(output.Name())
+				debugf("  -> %v: %s", err, truncate(strings.TrimSpace(string(data)), 200))
+				if message := lastLines(string(data), 3); message != "" {
+					err = fmt.Errorf("%w: %s", err, message)
+				}
+				reason := classifyPushFailure(string(data))
+				err = &pushError{reason, err}
+				if reason == pushFailNetwork {
+					err = temporary(err)
+				}
+			}
+			removeTemp(output.Name())
+			return err
+		case <-ticker.C:
+			infof("Still pushing %s… %ds", branch, int(time.Since(start).Seconds()))
+		case <-deadline:
+			switch askPushTimeout(branch, time.Since(start)) {
+			case pushWait:
+				deadline = time.After(timeout)
+			case pushBackground:
+				debugf("Leaving the push of %s running, output in %s", branch, output.Name())
+				keepTemp(output.Name())
+				return errPushInBackground
+			default:
+				cmd.Process.Kill()
+				<-done
+				removeTemp(output.Name())
+				return &pushError{pushFailTimeout, fmt.Errorf("push timed out after %s (prrompt.pushTimeout)", timeout)}
+			}
+		}
+	}
+}
+
+// lastLines returns the last n non-empty lines of git's output joined with
+// "; ", where the reason a command failed usually is.
+func lastLines(output string, n int) string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > n {
+		lines = lines[len(lines)-n:]
+	}
+	return strings.Join(lines, "; ")
+}
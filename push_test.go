@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// setupSlowRemote adds a remote whose pushes take a second.
+func setupSlowRemote(t *testing.T, repo testRepo, name string) string {
+	originDir := t.TempDir()
+	runGitInDir(originDir, "init", "--bare", "-b", "main")
+	os.WriteFile(filepath.Join(originDir, "hooks", "pre-receive"), []byte("#!/bin/sh\nsleep 1\n"), 0755)
+	runGitInDir(repo.Dir, "remote", "add", name, originDir)
+	return originDir
+}
+
+func Test_TimedPushTimeout(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.pushTimeout", "200ms")
+	oldInterval, oldAsk := pushProgressInterval, askPushTimeout
+	defer func() { pushProgressInterval, askPushTimeout = oldInterval, oldAsk }()
+	pushProgressInterval = 50 * time.Millisecond
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+	head, _ := runGitInDir(repo.Dir, "rev-parse", "main")
+
+	setupSlowRemote(t, repo, "aborted")
+	asked := 0
+	askPushTimeout = func(string, time.Duration) pushChoice { asked++; return pushAbort }
+	err := timedPush("aborted", "main")
+	if err == nil || !strings.Contains(err.Error(), "timed out") || asked != 1 {
+		t.Fatalf("Expected the push to be aborted after asking once, got %v (asked %d)", err, asked)
+	}
+
+	waited := setupSlowRemote(t, repo, "waited")
+	asked = 0
+	askPushTimeout = func(string, time.Duration) pushChoice { asked++; return pushWait }
+	if err := timedPush("waited", "main"); err != nil || asked == 0 {
+		t.Fatalf("Expected the push to finish after waiting, got %v (asked %d)", err, asked)
+	}
+	if tip, _ := runGitInDir(waited, "rev-parse", "main"); tip != head {
+		t.Errorf("Expected main on the remote, got %q", tip)
+	}
+
+	background := setupSlowRemote(t, repo, "background")
+	askPushTimeout = func(string, time.Duration) pushChoice { return pushBackground }
+	if err := timedPush("background", "main"); !errors.Is(err, errPushInBackground) {
+		t.Fatalf("Expected the push to continue in the background, got %v", err)
+	}
+	for i := 0; i < 50; i++ {
+		if tip, _ := runGitInDir(background, "rev-parse", "main"); tip == head {
+			return
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	t.Errorf("Expected the background push to finish")
+}
+
+func Test_PushTimeoutConfig(t *testing.T) {
+	repo := setupTestRepo(t)
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if got := getPushTimeout(); got != defaultPushTimeout {
+		t.Errorf("Expected the default timeout, got %s", got)
+	}
+	runGitInDir(repo.Dir, "config", "prrompt.pushTimeout", "0")
+	if got := getPushTimeout(); got != 0 {
+		t.Errorf("Expected no limit, got %s", got)
+	}
+	runGitInDir(repo.Dir, "config", "prrompt.pushTimeout", "soon")
+	if got := getPushTimeout(); got != defaultPushTimeout {
+		t.Errorf("Expected an invalid value to fall back to the default, got %s", got)
+	}
+}
+
+func Test_ClassifyPushFailure(t *testing.T) {
+	tests := map[string]string{
+		"remote: Invalid username or password.\nfatal: Authentication failed for 'https://github.com/acme/widgets.git/'": pushFailAuth,
+		"git@github.com: Permission denied (publickey).\nfatal: Could not read from remote repository.":                  pushFailAuth,
+		"remote: Repository not found.\nfatal: repository 'https://github.com/acme/gone.git/' not found":                 pushFailNotFound,
+		"remote: error: GH006: Protected branch update failed for refs/heads/prompt-update/abc.":                         pushFailProtected,
+		" ! [rejected]        prompt-update/abc -> prompt-update/abc (non-fast-forward)":                                 pushFailNonFastForward,
+		" ! [remote rejected] prompt-update/abc -> prompt-update/abc (pre-receive hook declined)":                        pushFailRejected,
+		"fatal: unable to access 'https://github.com/acme/widgets.git/': Could not resolve host: github.com":             pushFailNetwork,
+		"error: something else entirely": pushFailUnknown,
+	}
+	for output, want := range tests {
+		if got := classifyPushFailure(output); got != want {
+			t.Errorf("classifyPushFailure(%q) = %s, want %s", output, got, want)
+		}
+	}
+}
+
+func Test_PushFailureReason(t *testing.T) {
+	repo := setupTestRepo(t)
+	commitSHA := commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/test.md": "# Test"})
+	originDir := t.TempDir()
+	runGitInDir(originDir, "init", "--bare", "-b", "main")
+	os.WriteFile(filepath.Join(originDir, "hooks", "pre-receive"), []byte("#!/bin/sh\nexit 1\n"), 0755)
+	runGitInDir(repo.Dir, "remote", "add", "origin", originDir)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if result.Pushed || result.PushFailure != pushFailRejected {
+		t.Errorf("Expected the push to fail as rejected, got pushed=%v pushFailure=%q", result.Pushed, result.PushFailure)
+	}
+	pending, _ := loadPendingPushes()
+	if len(pending) != 1 || pending[0].Reason != pushFailRejected || !strings.Contains(pending[0].Error, "pre-receive hook declined") {
+		t.Errorf("Expected the branch queued with its reason, got %+v", pending)
+	}
+	if remedy := pushRemedy(pushFailRejected, "origin", result.Branch); !strings.Contains(remedy, "push --retry-failed") {
+		t.Errorf("Expected the remedy to point at push --retry-failed, got %q", remedy)
+	}
+}
@@ -0,0 +1,421 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Job states, each a directory under .git/prrompt/queue/. A worker claims a
+// job by renaming it from pending to running with its pid appended, which
+// only one of several workers can do.
+const (
+	jobPending = "pending"
+	jobRunning = "running"
+	jobDone    = "done"
+	jobFailed  = "failed"
+)
+
+var jobStates = []string{jobPending, jobRunning, jobDone, jobFailed}
+
+// queueJob is one commit to extract, queued by `prrompt queue add`.
+type queueJob struct {
+	Commit string    `json:"commit"`
+	Branch string    `json:"branch"`
+	Added  time.Time `json:"added"`
+	Status string    `json:"status,omitempty"`
+	Reason string    `json:"reason,omitempty"`
+	Target string    `json:"target,omitempty"` // the prompt branch
+	Error  string    `json:"error,omitempty"`
+
+	name string // file name, "<order>-<sha>.json"
+}
+
+func queueDir() (string, error) {
+	commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return filepath.Join(commonDir, "prrompt", "queue"), nil
+}
+
+// jobName returns the file name of a job in state; running jobs carry the
+// pid of their worker.
+func jobName(name, state string, pid int) string {
+	if state == jobRunning {
+		return fmt.Sprintf("%s.%d", name, pid)
+	}
+	return name
+}
+
+// listJobs returns the file names in a state directory, oldest first.
+func listJobs(dir, state string) []string {
+	entries, _ := os.ReadDir(filepath.Join(dir, state))
+	var names []string
+	for _, entry := range entries {
+		if !entry.IsDir() && !strings.HasSuffix(entry.Name(), ".tmp") {
+			names = append(names, entry.Name())
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func readJob(path string) (*queueJob, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var job queueJob
+	if err := json.Unmarshal(data, &job); err != nil {
+		return nil, fmt.Errorf("corrupt %s: %w", path, err)
+	}
+	return &job, nil
+}
+
+func writeJob(path string, job *queueJob) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(job, "", "  ")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// enqueueCommits adds a pending job per commit made on branch, skipping
+// commits already in the queue in any state, and returns how many it
+// added.
+func enqueueCommits(dir string, commits []string, branch string) (int, error) {
+	queued := make(map[string]bool)
+	for _, state := range jobStates {
+		for _, name := range listJobs(dir, state) {
+			if _, rest, found := strings.Cut(name, "-"); found {
+				sha, _, _ := strings.Cut(rest, ".")
+				queued[sha] = true
+			}
+		}
+	}
+	order := time.Now().UnixNano()
+	added := 0
+	for _, commit := range commits {
+		sha, err := runGit("rev-parse", "--verify", "--quiet", commit+"^{commit}")
+		if err != nil {
+			return added, fmt.Errorf("not a commit: %s", commit)
+		}
+		if queued[sha] {
+			continue
+		}
+		queued[sha] = true
+		name := fmt.Sprintf("%019d-%s.json", order+int64(added), sha)
+		job := &queueJob{Commit: sha, Branch: branch, Added: time.Now().UTC()}
+		if err := writeJob(filepath.Join(dir, jobPending, name), job); err != nil {
+			return added, err
+		}
+		added++
+	}
+	return added, nil
+}
+
+// reclaimStale puts jobs whose worker died, e.g. on Ctrl-C, back in the
+// pending state and returns how many it moved.
+func reclaimStale(dir string) int {
+	reclaimed := 0
+	for _, name := range listJobs(dir, jobRunning) {
+		dot := strings.LastIndex(name, ".")
+		pid, err := strconv.Atoi(name[dot+1:])
+		if err != nil || processAlive(pid) {
+			continue
+		}
+		if os.Rename(filepath.Join(dir, jobRunning, name), filepath.Join(dir, jobPending, name[:dot])) == nil {
+			reclaimed++
+		}
+	}
+	return reclaimed
+}
+
+// claimJob claims the oldest pending job made on branch, or returns nil
+// when there is none left.
+func claimJob(dir, branch string) *queueJob {
+	os.MkdirAll(filepath.Join(dir, jobRunning), 0755)
+	for _, name := range listJobs(dir, jobPending) {
+		job, err := readJob(filepath.Join(dir, jobPending, name))
+		if err != nil || job.Branch != branch {
+			continue
+		}
+		running := filepath.Join(dir, jobRunning, jobName(name, jobRunning, os.Getpid()))
+		if os.Rename(filepath.Join(dir, jobPending, name), running) != nil {
+			// Another worker got it first
+			continue
+		}
+		job.name = name
+		return job
+	}
+	return nil
+}
+
+// finishJob moves a claimed job to state with its outcome.
+func finishJob(dir string, job *queueJob, state string) error {
+	running := filepath.Join(dir, jobRunning, jobName(job.name, jobRunning, os.Getpid()))
+	if err := writeJob(filepath.Join(dir, state, job.name), job); err != nil {
+		return err
+	}
+	return os.Remove(running)
+}
+
+// runQueue implements `prrompt queue add|status|retry|clear`.
+func runQueue(args []string) error {
+	usage := fmt.Errorf("usage: %s queue add <sha>... | <from>..<to> | status | retry | clear", toolName)
+	if len(args) == 0 {
+		return usage
+	}
+	dir, err := queueDir()
+	if err != nil {
+		return err
+	}
+	switch args[0] {
+	case "add":
+		if len(args) < 2 {
+			return usage
+		}
+		commits, err := expandCommits(args[1:])
+		if err != nil {
+			return err
+		}
+		branch, err := currentBranch()
+		if err != nil {
+			return fmt.Errorf("failed to get current branch: %w", err)
+		}
+		added, err := enqueueCommits(dir, commits, branch)
+		fmt.Printf("Queued %d of %d commits from %s\n", added, len(commits), branch)
+		return err
+	case "status":
+		for _, state := range jobStates {
+			fmt.Printf("%-8s %d\n", state, len(listJobs(dir, state)))
+		}
+		for _, name := range listJobs(dir, jobFailed) {
+			if job, err := readJob(filepath.Join(dir, jobFailed, name)); err == nil {
+				fmt.Printf("  %s: %s\n", shortSHA(job.Commit), job.Error)
+			}
+		}
+		return nil
+	case "retry":
+		retried := 0
+		for _, name := range listJobs(dir, jobFailed) {
+			job, err := readJob(filepath.Join(dir, jobFailed, name))
+			if err != nil {
+				return err
+			}
+			job.Status, job.Error = "", ""
+			if err := writeJob(filepath.Join(dir, jobPending, name), job); err != nil {
+				return err
+			}
+			os.Remove(filepath.Join(dir, jobFailed, name))
+			retried++
+		}
+		fmt.Printf("Requeued %d failed jobs\n", retried)
+		return nil
+	case "clear":
+		for _, state := range []string{jobDone, jobFailed} {
+			if err := os.RemoveAll(filepath.Join(dir, state)); err != nil {
+				return err
+			}
+		}
+		fmt.Println("Cleared done and failed jobs")
+		return nil
+	}
+	return usage
+}
+
+// workerRun is the state one `prrompt worker` shares between its goroutines.
+type workerRun struct {
+	dir   string
+	total int
+	// mu serializes the state files (processed commits, pending pushes)
+	// within the process; the repository lock does so across processes.
+	mu                      sync.Mutex
+	done, extracted, failed int
+}
+
+// processJob extracts one job with the plumbing backend: the prompt branch
+// is created as a ref, never checked out, and queued for `prrompt push`.
+func (w *workerRun) processJob(job *queueJob) (*Result, error) {
+	result := &Result{Status: statusSkipped, Commit: job.Commit}
+	info, err := prepareCommit(job.Commit, result)
+	if err != nil {
+		return result, err
+	}
+	if info == nil {
+		// A job interrupted after creating its branch, which was never
+		// pushed, is finished below
+		if result.Reason != reasonAlreadyExtracted || !branchExists(result.Branch) || branchExists(getRemote()+"/"+result.Branch) {
+			return result, nil
+		}
+		if info, err = analyzeCommit(job.Commit); err != nil {
+			return result, err
+		}
+		result.Reason, result.PRURL = "", ""
+	}
+	promptBranch := promptBranchName(info)
+	if _, err := runGit("rev-parse", "--verify", "-q", "refs/heads/"+promptBranch); err == nil {
+		// Any other branch of that name is left alone
+		if result.Branch != promptBranch {
+			result.Reason, result.Branch = reasonAlreadyProcessed, promptBranch
+			return result, nil
+		}
+	} else {
+		if getTokenBudget() > 0 || getBoolConfig("prrompt.tokenCounts", false) {
+			info.TokenCounts = countPromptTokens(info)
+			warnTokenBudget(info.TokenCounts, getTokenBudget())
+		}
+		if err := buildPromptBranch(promptBranch, []*CommitInfo{info}, ""); err != nil {
+			return result, err
+		}
+	}
+	info.PromptBranch = promptBranch
+	result.setExtracted(info)
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	release, err := acquireLock()
+	if err != nil {
+		return result, err
+	}
+	defer release()
+	if err := recordProcessed(configHash(), info); err != nil {
+		warnf("failed to record processed commit: %v", err)
+	}
+	if remote := getRemote(); getBoolConfig("prrompt.push", true) && hasRemote(remote) {
+		queuePush(remote, promptBranch, []*CommitInfo{info}, false, "", nil)
+	}
+	return result, nil
+}
+
+// report records the outcome of a job and prints the progress.
+func (w *workerRun) report(job *queueJob, result *Result, err error) {
+	appMetrics.observeResult(result)
+	state := jobDone
+	job.Status, job.Reason, job.Target = result.Status, result.Reason, result.Branch
+	if err != nil {
+		state, job.Status, job.Error = jobFailed, statusError, err.Error()
+	}
+	if finishErr := finishJob(w.dir, job, state); finishErr != nil {
+		warnf("failed to finish job %s: %v", shortSHA(job.Commit), finishErr)
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.done++
+	switch {
+	case err != nil:
+		w.failed++
+		fmt.Printf("[%d/%d] %s failed: %v\n", w.done, w.total, shortSHA(job.Commit), err)
+	case result.Status == statusExtracted:
+		w.extracted++
+		infof("[%d/%d] %s -> %s", w.done, w.total, shortSHA(job.Commit), result.Branch)
+	default:
+		verbosef("[%d/%d] %s skipped (%s)", w.done, w.total, shortSHA(job.Commit), result.Reason)
+	}
+}
+
+// runWorker implements `prrompt worker [--jobs <n>]`: it works through the
+// queue with up to n extractions at once, n defaulting to the number of
+// CPUs. Run it again after an interruption to resume: jobs an earlier
+// worker had claimed are picked up again. Several workers can share a
+// queue.
+func runWorker(args []string) error {
+	jobs := runtime.NumCPU()
+	for i := 0; i < len(args); i++ {
+		value, found := strings.CutPrefix(args[i], "--jobs=")
+		if args[i] == "--jobs" && i+1 < len(args) {
+			value, found = args[i+1], true
+			i++
+		}
+		n, err := strconv.Atoi(value)
+		if !found || err != nil || n < 1 {
+			return fmt.Errorf("usage: %s worker [--jobs <n>]", toolName)
+		}
+		jobs = n
+	}
+	dir, err := queueDir()
+	if err != nil {
+		return err
+	}
+	if os.Getenv("PRROMPT_SKIP") == "1" || !getBoolConfig("prrompt.enabled", true) {
+		infof("prrompt is skipped or disabled, leaving the queue alone")
+		return nil
+	}
+	if getMirrorURL() != "" {
+		warnf("the worker does not update the mirror (prrompt.mirror.url)")
+	}
+	if pid := gcRunning(); pid != 0 {
+		warnf("git gc is running (pid %d); failed reads and ref updates are retried while it repacks", pid)
+	}
+	if reclaimed := reclaimStale(dir); reclaimed > 0 {
+		infof("Resuming %d jobs of an interrupted worker", reclaimed)
+	}
+	w := &workerRun{dir: dir, total: len(listJobs(dir, jobPending))}
+	if w.total == 0 {
+		infof("No queued jobs, add some with '%s queue add'", toolName)
+		return nil
+	}
+	infof("Processing %d jobs, %d at a time", w.total, jobs)
+	// Skip what other clones already extracted
+	if remote := getRemote(); getBoolConfig("prrompt.notes", false) && hasRemote(remote) {
+		if err := fetchNotes(remote); err != nil {
+			warnf("%v", err)
+		}
+	}
+	// Fill the per-run caches before the goroutines share them
+	getBaseBranch()
+	baseStartPoint()
+
+	// The source branch is read from the environment (see currentBranch),
+	// so jobs run one branch at a time
+	defer os.Unsetenv(sourceBranchEnv)
+	for {
+		names := listJobs(dir, jobPending)
+		if len(names) == 0 {
+			break
+		}
+		first, err := readJob(filepath.Join(dir, jobPending, names[0]))
+		if err != nil {
+			return err
+		}
+		os.Setenv(sourceBranchEnv, first.Branch)
+		before := w.done
+		var wg sync.WaitGroup
+		for i := 0; i < jobs; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for job := claimJob(dir, first.Branch); job != nil; job = claimJob(dir, first.Branch) {
+					result, err := w.processJob(job)
+					w.report(job, result, err)
+				}
+			}()
+		}
+		wg.Wait()
+		if w.done == before && len(listJobs(dir, jobPending)) == len(names) {
+			return fmt.Errorf("failed to claim %s in %s", names[0], filepath.Join(dir, jobPending))
+		}
+	}
+
+	infof("Done: %d extracted, %d skipped, %d failed", w.extracted, w.done-w.extracted-w.failed, w.failed)
+	if w.extracted > 0 {
+		infof("Run '%s push' to push the new prompt branches and open their PRs", toolName)
+	}
+	if w.failed > 0 {
+		return fmt.Errorf("%d jobs failed, see '%s queue status'; '%s queue retry' requeues them", w.failed, toolName, toolName)
+	}
+	return nil
+}
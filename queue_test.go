@@ -0,0 +1,173 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_Worker(t *testing.T) {
+	repo := setupTestRepo(t)
+	start, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	var shas []string
+	for _, name := range []string{"one", "two", "three", "four"} {
+		shas = append(shas, commitFiles(t, repo.Dir, "Add "+name, map[string]string{
+			"prompts/" + name + ".md": "# " + name,
+			name + ".go":              "package main",
+		}))
+	}
+	commitFiles(t, repo.Dir, "Change code", map[string]string{"main.go": "package main"})
+	os.WriteFile(filepath.Join(repo.Dir, "wip.txt"), []byte("uncommitted"), 0644)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+	head, _ := runGit("rev-parse", "HEAD")
+
+	if err := runQueue([]string{"add", start + "..HEAD"}); err != nil {
+		t.Fatalf("queue add failed: %v", err)
+	}
+	dir, _ := queueDir()
+	if pending := len(listJobs(dir, jobPending)); pending != 5 {
+		t.Fatalf("Expected 5 pending jobs, got %d", pending)
+	}
+	if added, _ := enqueueCommits(dir, shas[:2], repo.BranchName); added != 0 {
+		t.Errorf("Expected queued commits not to be added again, added %d", added)
+	}
+
+	if err := runWorker([]string{"--jobs", "3"}); err != nil {
+		t.Fatalf("worker failed: %v", err)
+	}
+	if pending, done := len(listJobs(dir, jobPending)), len(listJobs(dir, jobDone)); pending != 0 || done != 5 {
+		t.Errorf("Expected 5 done jobs and none pending, got %d done, %d pending", done, pending)
+	}
+	for _, sha := range shas {
+		branch := getBranchPrefix() + "/" + sha[:7]
+		files, err := runGit("ls-tree", "-r", "--name-only", branch)
+		if err != nil {
+			t.Errorf("Expected branch %s: %v", branch, err)
+			continue
+		}
+		if strings.Contains(files, ".go") || !strings.Contains(files, "prompts/") {
+			t.Errorf("Unexpected files on %s: %s", branch, files)
+		}
+		if count, _ := runGit("rev-list", "--count", "main.."+branch); count != "1" {
+			t.Errorf("Expected 1 extraction commit on %s, got %s", branch, count)
+		}
+	}
+	if current, _ := runGit("rev-parse", "HEAD"); current != head {
+		t.Errorf("Expected HEAD to stay at %s, got %s", head, current)
+	}
+	if branch, _ := currentBranch(); branch != repo.BranchName {
+		t.Errorf("Expected to stay on %s, got %s", repo.BranchName, branch)
+	}
+	if status, _ := runGit("status", "--porcelain"); status != "?? wip.txt" {
+		t.Errorf("Expected the work tree untouched, got %q", status)
+	}
+
+	// Requeued commits that were extracted are skipped, not rebuilt
+	branch := getBranchPrefix() + "/" + shas[0][:7]
+	tip, _ := runGit("rev-parse", branch)
+	os.RemoveAll(filepath.Join(dir, jobDone))
+	enqueueCommits(dir, shas[:1], repo.BranchName)
+	if err := runWorker(nil); err != nil {
+		t.Fatalf("second worker failed: %v", err)
+	}
+	job, _ := readJob(filepath.Join(dir, jobDone, listJobs(dir, jobDone)[0]))
+	if job.Reason != reasonAlreadyProcessed || job.Target != branch {
+		t.Errorf("Expected the commit to be skipped as processed, got %+v", job)
+	}
+	if again, _ := runGit("rev-parse", branch); again != tip {
+		t.Errorf("Expected %s to stay at %s, got %s", branch, tip, again)
+	}
+}
+
+func Test_ReclaimStale(t *testing.T) {
+	repo := setupTestRepo(t)
+	sha := commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/one.md": "# One"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	dir, _ := queueDir()
+	enqueueCommits(dir, []string{sha}, repo.BranchName)
+	job := claimJob(dir, repo.BranchName)
+	if job == nil {
+		t.Fatal("Expected to claim the job")
+	}
+	if claimJob(dir, repo.BranchName) != nil {
+		t.Error("Expected a claimed job not to be claimed again")
+	}
+	if reclaimed := reclaimStale(dir); reclaimed != 0 {
+		t.Errorf("Expected a live worker's job to be kept, reclaimed %d", reclaimed)
+	}
+
+	// A worker that died mid-job
+	running := filepath.Join(dir, jobRunning)
+	os.Rename(filepath.Join(running, jobName(job.name, jobRunning, os.Getpid())), filepath.Join(running, jobName(job.name, jobRunning, 999999)))
+	if reclaimed := reclaimStale(dir); reclaimed != 1 {
+		t.Errorf("Expected the dead worker's job to be reclaimed, reclaimed %d", reclaimed)
+	}
+	if pending := listJobs(dir, jobPending); len(pending) != 1 {
+		t.Errorf("Expected the job to be pending again, got %v", pending)
+	}
+}
+
+func Test_PlumbingCommitMerge(t *testing.T) {
+	repo := setupTestRepo(t)
+	commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/one.md": "intro\n\nmiddle\n\nend\n"})
+	base, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	sha := commitFiles(t, repo.Dir, "Edit the end", map[string]string{"prompts/one.md": "intro\n\nmiddle\n\nnew end\n"})
+	runGitInDir(repo.Dir, "checkout", "-q", "-b", "upstream", base)
+	ours := commitFiles(t, repo.Dir, "Edit the intro", map[string]string{"prompts/one.md": "new intro\n\nmiddle\n\nend\n"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	info := &CommitInfo{SHA: sha, Message: "Edit the end", Parents: 1, PromptFiles: []string{"prompts/one.md"}}
+	commit, err := plumbingCommit(info, ours)
+	if err != nil {
+		t.Fatalf("plumbingCommit failed: %v", err)
+	}
+	if content, _ := runGit("show", commit+":prompts/one.md"); content != "new intro\n\nmiddle\n\nnew end" {
+		t.Errorf("Expected both edits merged, got %q", content)
+	}
+	if parent, _ := runGit("rev-parse", commit+"^"); parent != ours {
+		t.Errorf("Expected parent %s, got %s", ours, parent)
+	}
+
+	// A conflicting edit takes the commit's version
+	conflicting := commitFiles(t, repo.Dir, "Edit the end too", map[string]string{"prompts/one.md": "new intro\n\nmiddle\n\nother end\n"})
+	commit, err = plumbingCommit(info, conflicting)
+	if err != nil {
+		t.Fatalf("plumbingCommit failed on a conflict: %v", err)
+	}
+	if content, _ := runGit("show", commit+":prompts/one.md"); content != "intro\n\nmiddle\n\nnew end" {
+		t.Errorf("Expected the commit's version on a conflict, got %q", content)
+	}
+}
+
+func Test_PlumbingCommitModeChange(t *testing.T) {
+	repo := setupTestRepo(t)
+	parent := commitFiles(t, repo.Dir, "Add a script", map[string]string{"prompts/run.sh": "#!/bin/sh\n"})
+	os.Chmod(filepath.Join(repo.Dir, "prompts/run.sh"), 0755)
+	runGitInDir(repo.Dir, "add", "prompts/run.sh")
+	runGitInDir(repo.Dir, "commit", "-q", "-m", "Make the script executable")
+	sha, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	info := &CommitInfo{SHA: sha, Message: "Make the script executable", Parents: 1, PromptFiles: []string{"prompts/run.sh"}}
+	commit, err := plumbingCommit(info, parent)
+	if err != nil {
+		t.Fatalf("plumbingCommit failed: %v", err)
+	}
+	if mode, _ := treeEntry(commit, "prompts/run.sh"); mode != "100755" {
+		t.Errorf("Expected the mode change extracted, got mode %q", mode)
+	}
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+const redactedText = "[REDACTED]"
+
+// getRedactPatterns compiles every prrompt.redactPattern value. The key is
+// multi-valued rather than comma-separated because patterns are regular
+// expressions. Invalid patterns are reported and ignored.
+func getRedactPatterns() []*regexp.Regexp {
+	value, err := gitConfig("--get-all", "prrompt.redactPattern")
+	if err != nil || value == "" {
+		return nil
+	}
+	var patterns []*regexp.Regexp
+	for _, expr := range strings.Split(value, "\n") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			warnf("ignoring invalid prrompt.redactPattern %q: %v", expr, err)
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+// redact masks configured patterns in text that prrompt itself reproduces
+// (console output, PR titles and bodies, notifications). Anything echoing
+// prompt content or commit messages outside of git objects should go through
+// it.
+func redact(text string) string {
+	return redactWith(text, getRedactPatterns())
+}
+
+func redactWith(text string, patterns []*regexp.Regexp) string {
+	for _, re := range patterns {
+		text = re.ReplaceAllString(text, redactedText)
+	}
+	return text
+}
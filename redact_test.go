@@ -0,0 +1,23 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_redact(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "--add", "prrompt.redactPattern", `Project [A-Z][a-z]+`)
+	runGitInDir(repo.Dir, "config", "--add", "prrompt.redactPattern", `cust-\d{4,}`)
+	runGitInDir(repo.Dir, "config", "--add", "prrompt.redactPattern", `(unclosed`)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	got := redact("Tune Project Falcon prompt for cust-12345, see cust-12")
+	want := "Tune [REDACTED] prompt for [REDACTED], see cust-12"
+	if got != want {
+		t.Errorf("redact() = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// prromptRefs is the namespace prrompt keeps shareable metadata under.
+const prromptRefs = "refs/prrompt/"
+
+// syncRefsPrefix is where `prrompt refs sync` fetches a remote's refs to
+// before reconciling them; it is outside prromptRefs so it is never pushed.
+const syncRefsPrefix = "refs/prrompt-sync/"
+
+type refValue struct {
+	Object string
+	Type   string
+}
+
+// runRefs implements `prrompt refs sync [--remote <name>]`. With
+// prrompt.notes it also merges and pushes the notes.
+func runRefs(args []string) error {
+	if len(args) == 0 || args[0] != "sync" {
+		return fmt.Errorf("usage: %s refs sync [--remote <name>]", toolName)
+	}
+	remote := getRemote()
+	for i := 1; i < len(args); i++ {
+		switch {
+		case args[i] == "--remote" && i+1 < len(args):
+			remote = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--remote="):
+			remote = strings.TrimPrefix(args[i], "--remote=")
+		default:
+			return fmt.Errorf("unexpected argument: %s", args[i])
+		}
+	}
+	if !hasRemote(remote) {
+		return fmt.Errorf("remote %q is not configured", remote)
+	}
+	if err := syncRefs(remote); err != nil {
+		return err
+	}
+	if getBoolConfig("prrompt.notes", false) {
+		if err := syncNotes(remote); err != nil {
+			return err
+		}
+		fmt.Printf("  synced   %s\n", notesRef)
+	}
+	return nil
+}
+
+// syncRefs reconciles refs under refs/prrompt/ with remote in both
+// directions. Refs only one side has are copied to the other, and a side
+// that is behind is fast-forwarded. Diverged commit histories are joined
+// with a merge commit that keeps the local tree. Other refs that differ
+// are conflicts: the local value is kept and the remote one is left under
+// refs/prrompt-sync/<remote>/ for inspection.
+func syncRefs(remote string) error {
+	staging := syncRefsPrefix + remote + "/"
+	if output, err := runGit("fetch", "--quiet", "--no-tags", remote, "+"+prromptRefs+"*:"+staging+"*"); err != nil {
+		return fmt.Errorf("failed to fetch %s* from %s: %s", prromptRefs, remote, output)
+	}
+
+	local, err := listRefs(prromptRefs)
+	if err != nil {
+		return err
+	}
+	fetched, err := listRefs(staging)
+	if err != nil {
+		return err
+	}
+
+	names := make(map[string]bool)
+	for name := range local {
+		names[name] = true
+	}
+	for name := range fetched {
+		names[name] = true
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var push []string
+	conflicts := 0
+	for _, name := range sorted {
+		ref := prromptRefs + name
+		ours, haveOurs := local[name]
+		theirs, haveTheirs := fetched[name]
+		switch {
+		case !haveTheirs:
+			push = append(push, ref)
+			fmt.Printf("  pushed   %s\n", ref)
+		case !haveOurs || isAncestor(ours, theirs):
+			if ours.Object != theirs.Object {
+				if _, err := runGit("update-ref", ref, theirs.Object); err != nil {
+					return fmt.Errorf("failed to update %s: %w", ref, err)
+				}
+				fmt.Printf("  fetched  %s\n", ref)
+			}
+		case isAncestor(theirs, ours):
+			push = append(push, ref)
+			fmt.Printf("  pushed   %s\n", ref)
+		case ours.Type == "commit" && theirs.Type == "commit":
+			merge, err := runGit("commit-tree", ours.Object+"^{tree}", "-p", ours.Object, "-p", theirs.Object,
+				"-m", fmt.Sprintf("Merge %s from %s", ref, remote))
+			if err != nil {
+				return fmt.Errorf("failed to merge %s: %s", ref, merge)
+			}
+			if _, err := runGit("update-ref", ref, merge, ours.Object); err != nil {
+				return fmt.Errorf("failed to update %s: %w", ref, err)
+			}
+			push = append(push, ref)
+			fmt.Printf("  merged   %s\n", ref)
+		default:
+			conflicts++
+			fmt.Printf("  conflict %s (kept local, remote value at %s%s)\n", ref, staging, name)
+			continue
+		}
+		runGit("update-ref", "-d", staging+name)
+	}
+
+	if len(push) > 0 {
+		args := []string{"push", "--quiet", remote}
+		for _, ref := range push {
+			args = append(args, ref+":"+ref)
+		}
+		if output, err := runGit(args...); err != nil {
+			return fmt.Errorf("failed to push to %s: %s", remote, output)
+		}
+	}
+
+	if len(sorted) == 0 {
+		fmt.Printf("No %s* refs here or on %s\n", prromptRefs, remote)
+	}
+	if conflicts > 0 {
+		return fmt.Errorf("%d refs differ and could not be merged", conflicts)
+	}
+	return nil
+}
+
+// listRefs returns the refs under prefix by their name below it.
+func listRefs(prefix string) (map[string]refValue, error) {
+	output, err := runGit("for-each-ref", "--format=%(refname) %(objectname) %(objecttype)", prefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s*: %w", prefix, err)
+	}
+	refs := make(map[string]refValue)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			continue
+		}
+		refs[strings.TrimPrefix(fields[0], prefix)] = refValue{fields[1], fields[2]}
+	}
+	return refs, nil
+}
+
+// isAncestor reports whether a is b or, for commits, an ancestor of b.
+func isAncestor(a, b refValue) bool {
+	if a.Object == b.Object {
+		return true
+	}
+	if a.Type != "commit" || b.Type != "commit" {
+		return false
+	}
+	_, err := runGitHistory("merge-base", "--is-ancestor", a.Object, b.Object)
+	return err == nil
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_SyncRefs(t *testing.T) {
+	originDir := t.TempDir()
+	runGitInDir(originDir, "init", "--bare", "-q")
+	first := setupTestRepo(t)
+	second := setupTestRepo(t)
+	for _, repo := range []testRepo{first, second} {
+		runGitInDir(repo.Dir, "remote", "add", "origin", originDir)
+	}
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+
+	// A state blob and a history of records in the first clone
+	os.Chdir(first.Dir)
+	blob, _ := runGitInDir(first.Dir, "hash-object", "-w", "--stdin")
+	runGitInDir(first.Dir, "update-ref", "refs/prrompt/state", blob)
+	tree, _ := runGitInDir(first.Dir, "rev-parse", "HEAD^{tree}")
+	base, _ := runGitInDir(first.Dir, "commit-tree", tree, "-m", "Record")
+	runGitInDir(first.Dir, "update-ref", "refs/prrompt/records", base)
+	if err := syncRefs("origin"); err != nil {
+		t.Fatalf("syncRefs failed: %v", err)
+	}
+
+	// The second clone picks them up and diverges from the first
+	os.Chdir(second.Dir)
+	if err := syncRefs("origin"); err != nil {
+		t.Fatalf("syncRefs failed: %v", err)
+	}
+	if got, _ := runGitInDir(second.Dir, "rev-parse", "refs/prrompt/records"); got != base {
+		t.Fatalf("Expected refs/prrompt/records to be fetched, got %q", got)
+	}
+	theirs, _ := runGitInDir(second.Dir, "commit-tree", tree, "-p", base, "-m", "Second record")
+	runGitInDir(second.Dir, "update-ref", "refs/prrompt/records", theirs)
+	syncRefs("origin")
+
+	os.Chdir(first.Dir)
+	ours, _ := runGitInDir(first.Dir, "commit-tree", tree, "-p", base, "-m", "First record")
+	runGitInDir(first.Dir, "update-ref", "refs/prrompt/records", ours)
+	if err := syncRefs("origin"); err != nil {
+		t.Fatalf("syncRefs failed: %v", err)
+	}
+	merged, _ := runGitInDir(first.Dir, "rev-parse", "refs/prrompt/records")
+	for _, parent := range []string{ours, theirs} {
+		if _, err := runGitInDir(first.Dir, "merge-base", "--is-ancestor", parent, merged); err != nil {
+			t.Errorf("Expected the merged records to contain %s", parent)
+		}
+	}
+	if remote, _ := runGitInDir(originDir, "rev-parse", "refs/prrompt/records"); remote != merged {
+		t.Errorf("Expected the merge to be pushed, remote has %s", remote)
+	}
+}
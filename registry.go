@@ -0,0 +1,274 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// registryAttempts bounds the PUTs of one record before it goes to the
+// outbox; registryRetryDelay is the wait before the first retry, doubled
+// each time.
+const registryAttempts = 3
+
+var registryRetryDelay = time.Second
+
+// errRegistryRejected is a record the registry refused as invalid, which no
+// retry would change.
+var errRegistryRejected = errors.New("rejected by the registry")
+
+// registryRecord is the metadata of a changed skill, PUT to
+// <prrompt.registry.url>/<id>.
+type registryRecord struct {
+	ID          string    `json:"id"`
+	Version     string    `json:"version,omitempty"`
+	Owner       string    `json:"owner,omitempty"`
+	Path        string    `json:"path"`
+	ContentHash string    `json:"contentHash,omitempty"`
+	Deleted     bool      `json:"deleted,omitempty"`
+	Repository  string    `json:"repository"`
+	Commit      string    `json:"commit"`
+	Branch      string    `json:"branch"`
+	PRURL       string    `json:"prUrl,omitempty"`
+	ChangedAt   time.Time `json:"changedAt"`
+}
+
+// getRegistryURL returns the registry endpoint skill changes are published
+// to; "" disables publishing.
+func getRegistryURL() string {
+	value, _ := gitConfig("--get", "prrompt.registry.url")
+	return strings.TrimRight(strings.TrimSpace(value), "/")
+}
+
+// skillMetadata describes the skill at skillDir as of commit: its
+// frontmatter name (else its directory or file name), version and owner,
+// and a hash over its files. A skill the commit deleted is described as it
+// was before.
+func skillMetadata(commit, skillDir string) registryRecord {
+	record := registryRecord{Path: skillDir, Commit: commit}
+
+	// The hash covers each file's mode, blob and path, so it changes with
+	// any file of the skill and not with anything else
+	rev := commit
+	if entries, _ := runGit("ls-tree", "-r", commit, "--", skillDir); entries != "" {
+		lines := strings.Split(entries, "\n")
+		sort.Strings(lines)
+		sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+		record.ContentHash = "sha256:" + hex.EncodeToString(sum[:])
+	} else {
+		record.Deleted = true
+		rev = commit + "^"
+	}
+
+	manifest := skillDir
+	if !strings.HasSuffix(skillDir, ".md") {
+		manifest = path.Join(skillDir, "SKILL.md")
+	}
+	if fields, ok := parseFrontmatter(showFile(rev, manifest)); ok {
+		record.ID, record.Version, record.Owner = fields["name"], fields["version"], fields["owner"]
+	}
+	if record.ID == "" {
+		record.ID = strings.TrimSuffix(path.Base(skillDir), ".md")
+	}
+	if record.Owner == "" {
+		record.Owner, _ = runGit("log", "-1", "--format=%ae", commit)
+	}
+	return record
+}
+
+// registryRecords describes the skills an extraction changed, each as of
+// the last commit that touched it.
+func registryRecords(infos []*CommitInfo, branch, prURL string) []registryRecord {
+	repository := getGitHubRepoPath()
+	if repository == "" {
+		if toplevel, err := runGit("rev-parse", "--show-toplevel"); err == nil {
+			repository = filepath.Base(toplevel)
+		}
+	}
+	last := make(map[string]string)
+	var skills []string
+	for _, info := range infos {
+		for _, file := range info.PromptFiles {
+			skill := skillPath(file)
+			if _, seen := last[skill]; !seen {
+				skills = append(skills, skill)
+			}
+			last[skill] = info.SHA
+		}
+	}
+	now := time.Now().UTC()
+	records := make([]registryRecord, 0, len(skills))
+	for _, skill := range skills {
+		record := skillMetadata(last[skill], skill)
+		record.Repository, record.Branch, record.PRURL, record.ChangedAt = repository, branch, prURL, now
+		records = append(records, record)
+	}
+	return records
+}
+
+// putRecord PUTs record to the registry, retrying network errors, rate
+// limits and server errors. prrompt.registry.token, if set, is sent as a
+// bearer token.
+func putRecord(endpoint string, record registryRecord) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	target := endpoint + "/" + url.PathEscape(record.ID)
+	token, _ := gitConfig("--get", "prrompt.registry.token")
+	delay := registryRetryDelay
+	for attempt := 1; ; attempt++ {
+		req, err := http.NewRequest(http.MethodPut, target, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := httpClient.Do(req)
+		retry := err != nil
+		if err == nil {
+			resp.Body.Close()
+			switch code := resp.StatusCode; {
+			case code >= 200 && code <= 299:
+				return nil
+			case code == http.StatusTooManyRequests || code >= 500:
+				err, retry = fmt.Errorf("%s", resp.Status), true
+			case code == http.StatusUnauthorized || code == http.StatusForbidden:
+				// Kept for when the token is fixed
+				err = fmt.Errorf("%s, check prrompt.registry.token", resp.Status)
+			default:
+				return fmt.Errorf("%w: %s", errRegistryRejected, resp.Status)
+			}
+		}
+		if !retry || attempt == registryAttempts {
+			return err
+		}
+		verbosef("Registry PUT of %s failed (%v), retrying in %s", record.ID, err, delay)
+		time.Sleep(delay)
+		delay *= 2
+	}
+}
+
+func registryOutboxPath() (string, error) {
+	commonDir, err := runGit("rev-parse", "--path-format=absolute", "--git-common-dir")
+	if err != nil {
+		return "", fmt.Errorf("not in a git repository: %w", err)
+	}
+	return filepath.Join(commonDir, "prrompt", "registry-outbox.json"), nil
+}
+
+func loadRegistryOutbox() ([]registryRecord, error) {
+	path, err := registryOutboxPath()
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	var outbox []registryRecord
+	if err := json.Unmarshal(data, &outbox); err != nil {
+		return nil, fmt.Errorf("corrupt %s: %w", path, err)
+	}
+	return outbox, nil
+}
+
+func saveRegistryOutbox(outbox []registryRecord) error {
+	path, err := registryOutboxPath()
+	if err != nil {
+		return err
+	}
+	if len(outbox) == 0 {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, _ := json.MarshalIndent(outbox, "", "  ")
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// publishRecords sends records to the registry after those still in the
+// outbox, and keeps whatever could not be sent there for the next run; a
+// newer record of a skill replaces an older one. Records the registry
+// rejects are dropped. Failures are warnings. With no records it only
+// flushes the outbox.
+func publishRecords(records []registryRecord) {
+	endpoint := getRegistryURL()
+	if endpoint == "" {
+		return
+	}
+	outbox, err := loadRegistryOutbox()
+	if err != nil {
+		warnf("not publishing to the registry: %v", err)
+		return
+	}
+	var queued []registryRecord
+	for _, record := range outbox {
+		if !newerRecord(record, records) {
+			queued = append(queued, record)
+		}
+	}
+	queued = append(queued, records...)
+
+	var remaining []registryRecord
+	for i, record := range queued {
+		err := putRecord(endpoint, record)
+		if errors.Is(err, errRegistryRejected) {
+			warnf("failed to publish %s to the registry, dropping it: %v", record.ID, err)
+			continue
+		}
+		if err != nil {
+			warnf("failed to publish %s to the registry, keeping it for the next run: %v", record.ID, err)
+			// The registry is down; don't wait on every record
+			remaining = append(remaining, queued[i:]...)
+			break
+		}
+		verbosef("✓ Published %s to %s", record.ID, withoutCredentials(endpoint))
+	}
+	if err := saveRegistryOutbox(remaining); err != nil {
+		warnf("failed to save the registry outbox: %v", err)
+	}
+}
+
+// newerRecord reports whether records hold a later change of record's skill.
+func newerRecord(record registryRecord, records []registryRecord) bool {
+	for _, r := range records {
+		if r.Repository == record.Repository && r.Path == record.Path {
+			return true
+		}
+	}
+	return false
+}
+
+// publishSkills publishes the skills a pushed prompt branch changed to
+// prrompt.registry.url.
+func publishSkills(infos []*CommitInfo, branch, prURL string) {
+	if getRegistryURL() == "" {
+		return
+	}
+	publishRecords(registryRecords(infos, branch, prURL))
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+func Test_PublishSkills(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	defer func(delay time.Duration) { registryRetryDelay = delay }(registryRetryDelay)
+	registryRetryDelay = 0
+
+	var got []registryRecord
+	var paths, auth []string
+	failures := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failures > 0 {
+			failures--
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var record registryRecord
+		json.NewDecoder(r.Body).Decode(&record)
+		got = append(got, record)
+		paths = append(paths, r.Method+" "+r.URL.Path)
+		auth = append(auth, r.Header.Get("Authorization"))
+	}))
+	defer server.Close()
+	runGitInDir(repo.Dir, "config", "prrompt.registry.url", server.URL+"/skills/")
+	t.Setenv("PRROMPT_REGISTRY_TOKEN", "s3cret")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	failures = 2
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if len(got) != 1 || paths[0] != "PUT /skills/test" || auth[0] != "Bearer s3cret" {
+		t.Fatalf("Expected one authenticated PUT after the retries, got %v %v %+v", paths, auth, got)
+	}
+	record := got[0]
+	if record.Path != "prompts/test.md" || record.Repository != "acme/widgets" || record.Commit != commitSHA || record.Branch != result.Branch || record.PRURL == "" {
+		t.Errorf("Unexpected record %+v", record)
+	}
+	if !strings.HasPrefix(record.ContentHash, "sha256:") || record.Owner != "test@example.com" || record.Deleted {
+		t.Errorf("Expected a content hash and the author as owner, got %+v", record)
+	}
+
+	// An unreachable registry keeps the records for the next run
+	server.Close()
+	publishRecords([]registryRecord{record})
+	if outbox, _ := loadRegistryOutbox(); len(outbox) != 1 {
+		t.Fatalf("Expected the record in the outbox, got %+v", outbox)
+	}
+	publishRecords([]registryRecord{record})
+	if outbox, _ := loadRegistryOutbox(); len(outbox) != 1 {
+		t.Errorf("Expected a newer record to replace the queued one, got %d", len(outbox))
+	}
+}
+
+func Test_SkillMetadata(t *testing.T) {
+	repo := setupTestRepo(t)
+	sha := commitFiles(t, repo.Dir, "Add greeter", map[string]string{
+		".claude/skills/greeter/SKILL.md":    "---\nname: greeter-bot\nversion: 1.2.0\nowner: team-agents\n---\n# Greeter",
+		".claude/skills/greeter/examples.md": "hello",
+	})
+	edited := commitFiles(t, repo.Dir, "Edit examples", map[string]string{".claude/skills/greeter/examples.md": "hi"})
+	runGitInDir(repo.Dir, "rm", "-rq", ".claude/skills/greeter")
+	runGitInDir(repo.Dir, "commit", "-qm", "Remove greeter")
+	removed, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	record := skillMetadata(sha, ".claude/skills/greeter")
+	if record.ID != "greeter-bot" || record.Version != "1.2.0" || record.Owner != "team-agents" || record.ContentHash == "" {
+		t.Errorf("Unexpected metadata %+v", record)
+	}
+	if again := skillMetadata(edited, ".claude/skills/greeter"); again.ContentHash == record.ContentHash {
+		t.Error("Expected the hash to change with any file of the skill")
+	}
+	if gone := skillMetadata(removed, ".claude/skills/greeter"); !gone.Deleted || gone.ContentHash != "" || gone.ID != "greeter-bot" {
+		t.Errorf("Expected a deleted skill, got %+v", gone)
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+const (
+	replaceRefsUse    = "use"
+	replaceRefsIgnore = "ignore"
+)
+
+// getReplaceRefs returns prrompt.replaceRefs: "use" lets replace refs
+// (refs/replace/*) and grafts rewrite history for prrompt as they do for
+// `git log`, "ignore" checks ancestry against the commits as recorded.
+func getReplaceRefs() string {
+	value, _ := gitConfig("--get", "prrompt.replaceRefs")
+	if strings.ToLower(strings.TrimSpace(value)) == replaceRefsIgnore {
+		return replaceRefsIgnore
+	}
+	return replaceRefsUse
+}
+
+// historyEnv returns the environment for the git commands that decide
+// ancestry: whether a commit is pushed, a branch was force-pushed or lies
+// on top of the last run. With prrompt.replaceRefs=ignore they see neither
+// replace refs nor grafts; an empty GIT_GRAFT_FILE can't be read, so git
+// finds no grafts.
+func historyEnv() []string {
+	if getReplaceRefs() != replaceRefsIgnore {
+		return nil
+	}
+	return []string{"GIT_NO_REPLACE_OBJECTS=1", "GIT_GRAFT_FILE="}
+}
+
+// runGitHistory is runGit for ancestry checks, see historyEnv.
+func runGitHistory(args ...string) (string, error) {
+	return runGitWithEnv(historyEnv(), args...)
+}
+
+// replacedHistory returns the number of replace refs and whether a grafts
+// file (.git/info/grafts, deprecated by git) is in effect.
+func replacedHistory() (replaceRefs int, grafts bool) {
+	if output, err := runGit("for-each-ref", "--format=%(refname)", "refs/replace/"); err == nil && output != "" {
+		replaceRefs = len(strings.Split(output, "\n"))
+	}
+	if path, err := runGit("rev-parse", "--path-format=absolute", "--git-path", "info/grafts"); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			grafts = true
+		}
+	}
+	return replaceRefs, grafts
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_ReplaceRefs(t *testing.T) {
+	repo := setupTestRepo(t)
+	a := commitFiles(t, repo.Dir, "A", map[string]string{"a.txt": "a"})
+	b := commitFiles(t, repo.Dir, "B", map[string]string{"b.txt": "b"})
+	c := commitFiles(t, repo.Dir, "C", map[string]string{"c.txt": "c"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	isAncestorOfC := func() bool {
+		_, err := runGitHistory("merge-base", "--is-ancestor", b, c)
+		return err == nil
+	}
+	if check := checkReplaceRefs(); check.Status != checkOK || check.Detail != "none" {
+		t.Errorf("Expected no replace refs, got %+v", check)
+	}
+
+	// C now seems to sit directly on A
+	if output, err := runGitInDir(repo.Dir, "replace", "--graft", c, a); err != nil {
+		t.Fatalf("failed to graft: %s", output)
+	}
+	if isAncestorOfC() {
+		t.Errorf("Expected the replace ref to hide B by default")
+	}
+	if check := checkReplaceRefs(); check.Status != checkWarn || check.Fix == "" {
+		t.Errorf("Expected a warning with a fix, got %+v", check)
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.replaceRefs", "ignore")
+	if !isAncestorOfC() {
+		t.Errorf("Expected the recorded history with replaceRefs=ignore")
+	}
+	if check := checkReplaceRefs(); check.Status != checkOK || check.Detail != "1 replace refs, ignored for ancestry checks" {
+		t.Errorf("Expected ignored replace refs to be fine, got %+v", check)
+	}
+
+	// Grafts are ignored too, but always warned about
+	runGitInDir(repo.Dir, "replace", "-d", c)
+	os.WriteFile(filepath.Join(repo.Dir, ".git", "info", "grafts"), []byte(c+" "+a+"\n"), 0644)
+	if !isAncestorOfC() {
+		t.Errorf("Expected grafts to be ignored with replaceRefs=ignore")
+	}
+	if check := checkReplaceRefs(); check.Status != checkWarn || check.Detail != "a grafts file rewrite history" {
+		t.Errorf("Expected a warning about the grafts file, got %+v", check)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+const (
+	statusExtracted = "extracted"
+	statusSkipped   = "skipped"
+	statusError     = "error"
+)
+
+// Reasons reported with statusSkipped.
+const (
+	reasonPromptBranch     = "on-prompt-branch"
+	reasonNoPromptFiles    = "no-prompt-files"
+	reasonDuplicate        = "duplicate"
+	reasonSkipMarker       = "skip-marker"
+	reasonSkipEnv          = "skip-env"
+	reasonMergeCommit      = "merge-commit"
+	reasonOnBaseBranch     = "on-base-branch"
+	reasonAlreadyProcessed = "already-processed"
+	reasonAlreadyExtracted = "already-extracted"
+	reasonWIP              = "wip"
+	reasonDisabled         = "disabled"
+)
+
+// Exit codes of a `prrompt <commit-sha>...` run, for wrapper scripts and
+// hooks chained after it. A run with nothing to extract exits 0 as well,
+// with the status "skipped" and a reason in its result.
+const (
+	exitOK         = 0
+	exitFailed     = 1 // any other error
+	exitConfig     = 2 // invalid flags or settings
+	exitConflict   = 3 // the prompt changes do not apply to the prompt branch
+	exitPushFailed = 4 // extracted, but the prompt branch was not pushed
+	exitLocked     = 5 // another run held the lock for too long
+)
+
+// exitError gives err the exit code the run ends with.
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string { return e.err.Error() }
+func (e *exitError) Unwrap() error { return e.err }
+
+func withExitCode(code int, err error) error {
+	return &exitError{code: code, err: err}
+}
+
+// exitCodeOf returns the exit code err ends a run with.
+func exitCodeOf(err error) int {
+	if err == nil {
+		return exitOK
+	}
+	var coded *exitError
+	if errors.As(err, &coded) {
+		return coded.code
+	}
+	return exitFailed
+}
+
+// runExitCode returns the exit code of a run with results and err: that of
+// the error, or of the first result failing in a specific way.
+func runExitCode(results []*Result, err error) int {
+	code := exitCodeOf(err)
+	if code != exitOK && code != exitFailed {
+		return code
+	}
+	for _, result := range results {
+		if result != nil && result.ExitCode != exitOK && (err == nil || result.Status == statusError) {
+			return result.ExitCode
+		}
+	}
+	return code
+}
+
+// pushFailed reports whether the prompt branch of info failed to push; a
+// push left running in the background has not failed (yet).
+func pushFailed(info *CommitInfo) bool {
+	return info.PushFailure != "" && info.PushFailure != pushFailBackground
+}
+
+// Result is the machine-readable outcome of processing a commit, printed by
+// --output=json and written by --result-file.
+type Result struct {
+	Status        string   `json:"status"`
+	Reason        string   `json:"reason,omitempty"`
+	Commit        string   `json:"commit"`
+	SourceBranch  string   `json:"sourceBranch,omitempty"`
+	Branch        string   `json:"branch,omitempty"`
+	Branches      []string `json:"branches,omitempty"`
+	Pushed        bool     `json:"pushed"`
+	PushFailure   string   `json:"pushFailure,omitempty"`
+	PRURL         string   `json:"prUrl,omitempty"`
+	MirrorBranch  string   `json:"mirrorBranch,omitempty"`
+	NewSourceTip  string   `json:"newSourceTip,omitempty"`
+	DuplicateOf   string   `json:"duplicateOf,omitempty"`
+	Experiments   []string `json:"experiments,omitempty"`
+	PromptFiles   []string `json:"promptFiles"`
+	OtherFiles    []string `json:"otherFiles"`
+	ExcludedFiles []string `json:"excludedFiles"`
+	SkippedFiles  []string `json:"skippedFiles,omitempty"`
+	Error         string   `json:"error,omitempty"`
+	ExitCode      int      `json:"exitCode"`
+}
+
+func (r *Result) setFiles(info *CommitInfo) {
+	r.Commit = info.SHA
+	r.SourceBranch = info.SourceBranch
+	r.PromptFiles = info.PromptFiles
+	r.OtherFiles = info.OtherFiles
+	r.ExcludedFiles = info.ExcludedFiles
+	r.SkippedFiles = info.SkippedFiles
+}
+
+// setExtracted records the outcome of a successful extraction.
+func (r *Result) setExtracted(info *CommitInfo) {
+	r.Status = statusExtracted
+	r.Branch = info.PromptBranch
+	r.Pushed = info.Pushed
+	r.PushFailure = info.PushFailure
+	r.PRURL = info.PRURL
+	r.MirrorBranch = info.MirrorBranch
+	r.Experiments = info.Experiments
+	if pushFailed(info) {
+		r.ExitCode = exitPushFailed
+	}
+}
+
+func (r *Result) JSON() []byte {
+	// Empty lists are reported as [] rather than null
+	for _, list := range []*[]string{&r.PromptFiles, &r.OtherFiles, &r.ExcludedFiles} {
+		if *list == nil {
+			*list = []string{}
+		}
+	}
+	data, _ := json.MarshalIndent(r, "", "  ")
+	return append(data, '\n')
+}
+
+// resultsJSON renders the results of a multi-commit run as a JSON array.
+func resultsJSON(results []*Result) []byte {
+	parts := make([]string, 0, len(results))
+	for _, r := range results {
+		parts = append(parts, strings.TrimRight(string(r.JSON()), "\n"))
+	}
+	if len(parts) == 0 {
+		return []byte("[]\n")
+	}
+	return []byte("[\n" + strings.Join(parts, ",\n") + "\n]\n")
+}
+
+func writeResultFile(path string, data []byte) error {
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write result file: %w", err)
+	}
+	return nil
+}
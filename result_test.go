@@ -0,0 +1,137 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_parseProcessArgs(t *testing.T) {
+	opts, err := parseProcessArgs([]string{"--output=json", "abc1234", "--result-file", "out.json", "--base=release/3.2"})
+	if err != nil {
+		t.Fatalf("parseProcessArgs failed: %v", err)
+	}
+	if len(opts.Commits) != 1 || opts.Commits[0] != "abc1234" || !opts.OutputJSON || opts.ResultFile != "out.json" || opts.Base != "release/3.2" {
+		t.Errorf("Unexpected parse: %+v", opts)
+	}
+
+	opts, err = parseProcessArgs([]string{"HEAD~3..HEAD", "def5678", "--combine"})
+	if err != nil || len(opts.Commits) != 2 || !opts.Combine {
+		t.Errorf("Unexpected parse of several commits: %+v, %v", opts, err)
+	}
+
+	opts, err = parseProcessArgs([]string{"abc1234", "--branch", "feature/x", "--repo=/src/app"})
+	if err != nil || opts.Branch != "feature/x" || opts.Repo != "/src/app" {
+		t.Errorf("Unexpected parse of the hook context: %+v, %v", opts, err)
+	}
+
+	if _, err := parseProcessArgs([]string{"abc1234", "--output=yaml"}); err == nil {
+		t.Error("Expected an error for an unsupported output format")
+	}
+	if _, err := parseProcessArgs([]string{"--result-file=out.json"}); err == nil {
+		t.Error("Expected an error without a commit")
+	}
+}
+
+func Test_ResultFile(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte("# Test prompt"), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	resultFile := filepath.Join(t.TempDir(), "result.json")
+	if err := writeResultFile(resultFile, result.JSON()); err != nil {
+		t.Fatalf("writeResultFile failed: %v", err)
+	}
+
+	data, _ := os.ReadFile(resultFile)
+	var decoded map[string]any
+	if err := json.Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Result file is not JSON: %v\n%s", err, data)
+	}
+	if decoded["status"] != statusExtracted {
+		t.Errorf("Expected status %s, got %v", statusExtracted, decoded["status"])
+	}
+	if decoded["branch"] != defaultBranchPrefix+"/"+commitSHA[:7] {
+		t.Errorf("Expected branch in result, got %v", decoded["branch"])
+	}
+	if files, ok := decoded["otherFiles"].([]any); !ok || len(files) != 0 {
+		t.Errorf("Expected otherFiles to be an empty list, got %v", decoded["otherFiles"])
+	}
+
+	// A commit without prompt files is reported as skipped
+	result, _ = processCommit("HEAD~1")
+	if result.Status != statusSkipped || result.Reason != reasonNoPromptFiles {
+		t.Errorf("Expected skipped/%s, got %s/%s", reasonNoPromptFiles, result.Status, result.Reason)
+	}
+}
+
+func Test_runExitCode(t *testing.T) {
+	conflict := withExitCode(exitConflict, errors.New("failed to cherry-pick"))
+	tests := []struct {
+		name    string
+		results []*Result
+		err     error
+		want    int
+	}{
+		{"extracted", []*Result{{Status: statusExtracted, Pushed: true}}, nil, exitOK},
+		{"nothing to do", []*Result{{Status: statusSkipped, Reason: reasonNoPromptFiles}}, nil, exitOK},
+		{"push failed", []*Result{{Status: statusExtracted}, {Status: statusExtracted, ExitCode: exitPushFailed}}, nil, exitPushFailed},
+		{"conflict", []*Result{{Status: statusError, ExitCode: exitConflict}}, fmt.Errorf("error extracting prompts: %w", conflict), exitConflict},
+		{"one of several failed", []*Result{{Status: statusExtracted, ExitCode: exitPushFailed}, {Status: statusError, ExitCode: exitLocked}}, errors.New("1 of 2 commits failed"), exitLocked},
+		{"other error", []*Result{{Status: statusError, ExitCode: exitFailed}}, errors.New("boom"), exitFailed},
+	}
+	for _, tt := range tests {
+		if got := runExitCode(tt.results, tt.err); got != tt.want {
+			t.Errorf("%s: runExitCode = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+	if conflict.Error() != "failed to cherry-pick" {
+		t.Errorf("Expected the message kept, got %q", conflict.Error())
+	}
+}
+
+func Test_ResultExitCodes(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "remote", "add", "origin", filepath.Join(repo.Dir, "missing.git"))
+	sha := commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/test.md": "# Test"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(sha)
+	if err != nil || result.Status != statusExtracted || result.Pushed {
+		t.Fatalf("Expected an extraction that was not pushed, got %+v (err %v)", result, err)
+	}
+	var decoded Result
+	if err := json.Unmarshal(result.JSON(), &decoded); err != nil || decoded.ExitCode != exitPushFailed {
+		t.Errorf("Expected exit code %d in the result, got %+v (err %v)", exitPushFailed, decoded, err)
+	}
+
+	if problems := invalidSettings(); len(problems) != 0 {
+		t.Errorf("Expected no invalid settings, got %v", problems)
+	}
+	runGitInDir(repo.Dir, "config", "prrompt.push", "maybe")
+	runGitInDir(repo.Dir, "config", "prrompt.fromTheFuture", "true")
+	problems := invalidSettings()
+	if len(problems) != 1 || !strings.HasPrefix(problems[0], "prrompt.push: ") {
+		t.Errorf("Expected only the invalid value, got %v", problems)
+	}
+}
@@ -0,0 +1,104 @@
+package main
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Pushes and forge API calls fail now and then for reasons that are gone
+// moments later: a dropped connection, a DNS hiccup, a 502 from GitHub.
+// Those are retried prrompt.retries times, waiting prrompt.retryDelay
+// before the first retry and twice as long before each next one.
+const (
+	defaultRetries    = 3
+	defaultRetryDelay = 2 * time.Second
+	maxRetryDelay     = time.Minute
+)
+
+// transientNetworkErrors are git's and the forge CLIs' messages for
+// network failures a retry may fix. Rejected pushes and authentication
+// failures are not among them.
+var transientNetworkErrors = []string{
+	"Could not resolve host",
+	"Connection timed out",
+	"Connection refused",
+	"Connection reset",
+	"Operation timed out",
+	"Failed to connect",
+	"Network is unreachable",
+	"the remote end hung up unexpectedly",
+	"early EOF",
+	"RPC failed",
+	"The requested URL returned error: 5",
+	"TLS handshake timeout",
+	"HTTP 502",
+	"HTTP 503",
+	"HTTP 504",
+}
+
+func isTransientNetworkError(output string) bool {
+	for _, marker := range transientNetworkErrors {
+		if strings.Contains(output, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// temporaryError marks a failure that withRetries retries.
+type temporaryError struct{ err error }
+
+func (e temporaryError) Error() string { return e.err.Error() }
+func (e temporaryError) Unwrap() error { return e.err }
+
+func temporary(err error) error { return temporaryError{err} }
+
+func isTemporary(err error) bool {
+	var t temporaryError
+	return errors.As(err, &t)
+}
+
+// getRetries returns prrompt.retries, how many times a failed push or API
+// call is retried.
+func getRetries() int {
+	value, err := gitConfig("--type=int", "--get", "prrompt.retries")
+	if err != nil {
+		return defaultRetries
+	}
+	retries, err := strconv.Atoi(value)
+	if err != nil || retries < 0 {
+		return defaultRetries
+	}
+	return retries
+}
+
+// getRetryDelay returns prrompt.retryDelay, the wait before the first
+// retry.
+func getRetryDelay() time.Duration {
+	value, err := gitConfig("--get", "prrompt.retryDelay")
+	if err != nil {
+		return defaultRetryDelay
+	}
+	delay, err := time.ParseDuration(strings.TrimSpace(value))
+	if err != nil || delay < 0 {
+		return defaultRetryDelay
+	}
+	return delay
+}
+
+// withRetries runs op until it succeeds, fails with an error that is not
+// temporary, or the retries run out, backing off exponentially. what names
+// the operation in the progress messages.
+func withRetries(what string, op func() error) error {
+	retries, delay := getRetries(), getRetryDelay()
+	err := op()
+	for attempt := 1; attempt <= retries && isTemporary(err); attempt++ {
+		infof("%s failed, retrying in %s (%d of %d): %v", what, delay, attempt, retries, truncate(err.Error(), 200))
+		time.Sleep(delay)
+		delay = min(delay*2, maxRetryDelay)
+		err = op()
+	}
+	return err
+}
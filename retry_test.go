@@ -0,0 +1,141 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func Test_GitHubRetries(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.retryDelay", "1ms")
+	defer func(url string) { githubAPIURL = url }(githubAPIURL)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	requests, failures, status := 0, 0, http.StatusBadGateway
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if failures > 0 {
+			failures--
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(`{"number": 7}`))
+	}))
+	defer server.Close()
+	githubAPIURL = server.URL
+
+	failures = 2
+	pr, err := fetchPullRequest("acme/widgets", 7)
+	if err != nil || pr.Number != 7 || requests != 3 {
+		t.Fatalf("Expected the PR after two retries, got %+v (err %v, %d requests)", pr, err, requests)
+	}
+
+	requests, failures = 0, 10
+	if _, err := fetchPullRequest("acme/widgets", 7); err == nil || !strings.Contains(err.Error(), "502") || requests != 4 {
+		t.Errorf("Expected a 502 after 3 retries, got %v (%d requests)", err, requests)
+	}
+
+	// Client errors are final
+	requests, failures, status = 0, 1, http.StatusNotFound
+	if _, err := fetchPullRequest("acme/widgets", 7); err == nil || requests != 1 {
+		t.Errorf("Expected a 404 not to be retried, got %v (%d requests)", err, requests)
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.retries", "0")
+	requests, failures, status = 0, 1, http.StatusServiceUnavailable
+	if _, err := fetchPullRequest("acme/widgets", 7); err == nil || requests != 1 {
+		t.Errorf("Expected no retry with prrompt.retries=0, got %v (%d requests)", err, requests)
+	}
+}
+
+// setupFlakyRemote adds a remote whose first failures pushes drop the
+// connection.
+func setupFlakyRemote(t *testing.T, repo testRepo, name string, failures int) string {
+	originDir := t.TempDir()
+	runGitInDir(originDir, "init", "--bare", "-b", "main")
+	script := filepath.Join(t.TempDir(), "receive-pack")
+	attempts := filepath.Join(originDir, "attempts")
+	os.WriteFile(script, []byte("#!/bin/sh\nn=$(cat "+attempts+" 2>/dev/null || echo 0)\necho $((n+1)) > "+attempts+"\n"+
+		"if [ $n -lt "+strconv.Itoa(failures)+" ]; then echo 'fatal: unable to access: Connection reset by peer' >&2; exit 128; fi\n"+
+		"exec git receive-pack \"$@\"\n"), 0755)
+	runGitInDir(repo.Dir, "remote", "add", name, originDir)
+	runGitInDir(repo.Dir, "config", "remote."+name+".receivepack", script)
+	return originDir
+}
+
+func Test_PushRetries(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.retryDelay", "1ms")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+	head, _ := runGit("rev-parse", "main")
+
+	flaky := setupFlakyRemote(t, repo, "flaky", 2)
+	if err := timedPush("flaky", "main"); err != nil {
+		t.Fatalf("Expected the push to succeed on a retry, got %v", err)
+	}
+	if tip, _ := runGitInDir(flaky, "rev-parse", "main"); tip != head {
+		t.Errorf("Expected main on the remote, got %q", tip)
+	}
+
+	// Rejections are not retried
+	rejecting := t.TempDir()
+	runGitInDir(rejecting, "init", "--bare", "-b", "main")
+	os.WriteFile(filepath.Join(rejecting, "hooks", "pre-receive"), []byte("#!/bin/sh\necho x >> attempts\nexit 1\n"), 0755)
+	runGitInDir(repo.Dir, "remote", "add", "rejecting", rejecting)
+	err := timedPush("rejecting", "main")
+	if err == nil || !strings.Contains(err.Error(), "pre-receive hook declined") {
+		t.Errorf("Expected the hook's rejection in the error, got %v", err)
+	}
+	if attempts, _ := os.ReadFile(filepath.Join(rejecting, "attempts")); string(attempts) != "x\n" {
+		t.Errorf("Expected a single attempt, got %q", attempts)
+	}
+}
+
+func Test_PushRetryFailed(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.retryDelay", "1ms")
+	runGitInDir(repo.Dir, "config", "prrompt.retries", "1")
+	runGitInDir(repo.Dir, "branch", "prompt-update/failed")
+	runGitInDir(repo.Dir, "branch", "prompt-update/offline")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	// Still down after one retry: queued with the error
+	origin := setupFlakyRemote(t, repo, "origin", 2)
+	err := timedPush("origin", "prompt-update/failed", "-u")
+	if err == nil {
+		t.Fatal("Expected the push to fail")
+	}
+	queuePendingPush(pendingPush{Branch: "prompt-update/failed", Remote: "origin", Error: err.Error()})
+	queuePendingPush(pendingPush{Branch: "prompt-update/offline", Remote: "origin"})
+
+	if err := runPush([]string{"--retry-failed"}); err == nil {
+		t.Error("Expected the offline branch to be reported as still pending")
+	}
+	if tip, _ := runGitInDir(origin, "rev-parse", "--verify", "-q", "prompt-update/failed"); tip == "" {
+		t.Error("Expected the failed branch to be pushed")
+	}
+	if tip, _ := runGitInDir(origin, "rev-parse", "--verify", "-q", "prompt-update/offline"); tip != "" {
+		t.Error("Expected the offline branch not to be pushed")
+	}
+	pending, _ := loadPendingPushes()
+	if len(pending) != 1 || pending[0].Branch != "prompt-update/offline" {
+		t.Errorf("Expected only the offline branch left, got %+v", pending)
+	}
+	if err := runPush([]string{"--list", "--retry-failed"}); err == nil {
+		t.Error("Expected --list and --retry-failed to be exclusive")
+	}
+}
@@ -0,0 +1,282 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// reviewChoices are what `prrompt review` settled on for a commit: the files
+// to extract and the message. prepareCommit applies them in place of the
+// pattern-based classification.
+type reviewChoices struct {
+	SHA     string
+	Files   map[string]bool
+	Message string
+}
+
+// commitReview holds the choices of the running review, nil otherwise.
+var commitReview *reviewChoices
+
+// apply classifies the commit's files as chosen and sets the message to
+// extract with.
+func (c *reviewChoices) apply(info *CommitInfo) {
+	if c == nil || c.SHA != info.SHA {
+		return
+	}
+	var prompt, other []string
+	for _, file := range append(append([]string{}, info.PromptFiles...), info.OtherFiles...) {
+		if c.Files[file] {
+			prompt = append(prompt, file)
+		} else {
+			other = append(other, file)
+		}
+	}
+	info.PromptFiles, info.OtherFiles = prompt, other
+	info.ExcludedFiles = withoutFiles(info.ExcludedFiles, c.Files)
+	info.SkippedFiles = withoutFiles(info.SkippedFiles, c.Files)
+	info.IsMixed = len(other) > 0
+	info.EditedMessage = c.Message
+}
+
+func withoutFiles(files []string, drop map[string]bool) []string {
+	var kept []string
+	for _, file := range files {
+		if !drop[file] {
+			kept = append(kept, file)
+		}
+	}
+	return kept
+}
+
+// reviewEntry is a line of the review screen: a changed file, with the old
+// path of a rename since both halves move together.
+type reviewEntry struct {
+	Path    string
+	OldPath string
+	Status  string
+	Class   string
+}
+
+func (e reviewEntry) paths() []string {
+	if e.OldPath != "" {
+		return []string{e.OldPath, e.Path}
+	}
+	return []string{e.Path}
+}
+
+// reviewEntries lists the commit's changed files in classification order
+// with the class prrompt gave them.
+func reviewEntries(info *CommitInfo) []reviewEntry {
+	excluded := make(map[string]bool)
+	for _, file := range info.ExcludedFiles {
+		excluded[file] = true
+	}
+	skipped := make(map[string]bool)
+	for _, file := range info.SkippedFiles {
+		skipped[file] = true
+	}
+	renamed := make(map[string]bool)
+	for _, oldPath := range info.Renames {
+		renamed[oldPath] = true
+	}
+	var entries []reviewEntry
+	add := func(files []string, class string) {
+		for _, file := range files {
+			if renamed[file] {
+				continue
+			}
+			entry := reviewEntry{Path: file, OldPath: info.Renames[file], Status: info.FileStatus[file], Class: class}
+			if class == "other" && excluded[file] {
+				entry.Class = "excluded"
+			} else if class == "other" && skipped[file] {
+				entry.Class = "skipped"
+			}
+			entries = append(entries, entry)
+		}
+	}
+	add(info.PromptFiles, "prompt")
+	add(info.OtherFiles, "other")
+	return entries
+}
+
+const reviewCommands = `Commands:
+  <n>...     Toggle files, e.g. "2" or "1-3 5"
+  d <n>      Show the diff of a file
+  t          Edit the title (the PR title)
+  m          Edit the message body
+  e          Extract the selected files
+  q          Quit without extracting`
+
+// runReview implements `prrompt review [<sha>]`: it lists the files of a
+// commit as prrompt classified them, lets the selection be changed and the
+// message edited, then extracts the commit with those choices.
+func runReview(in io.Reader, args []string) error {
+	rev := "HEAD"
+	if len(args) > 1 {
+		return fmt.Errorf("usage: %s review [<sha>]", toolName)
+	} else if len(args) == 1 {
+		rev = args[0]
+	}
+	info, err := analyzeCommit(rev)
+	if err != nil {
+		return err
+	}
+	entries := reviewEntries(info)
+	if len(entries) == 0 {
+		return fmt.Errorf("commit %s changes no files", shortSHA(info.SHA))
+	}
+	selected := make([]bool, len(entries))
+	for i, entry := range entries {
+		selected[i] = entry.Class == "prompt"
+	}
+	subject, body, _ := strings.Cut(extractionMessage(info), "\n")
+	body = strings.TrimSpace(body)
+
+	reader := bufio.NewReader(in)
+	readLine := func() (string, bool) {
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			return "", false
+		}
+		return strings.TrimRight(line, "\r\n"), true
+	}
+	show := func() {
+		fmt.Printf("\nCommit %s on %s\n\n", shortSHA(info.SHA), info.SourceBranch)
+		for i, entry := range entries {
+			mark := " "
+			if selected[i] {
+				mark = "x"
+			}
+			path := entry.Path
+			if entry.OldPath != "" {
+				path = entry.OldPath + " -> " + entry.Path
+			}
+			fmt.Printf("  [%s] %2d  %s  %-8s  %s\n", mark, i+1, entry.Status, entry.Class, path)
+		}
+		fmt.Printf("\nTitle: %s\n", subject)
+		if body != "" {
+			fmt.Printf("Body:  %s\n", truncate(strings.ReplaceAll(body, "\n", " "), 60))
+		}
+		fmt.Print("\n[<n>, d <n>, t, m, e, q, ?]> ")
+	}
+
+	for {
+		show()
+		line, ok := readLine()
+		if !ok {
+			fmt.Println()
+			return fmt.Errorf("review ended, nothing extracted")
+		}
+		command, arg, _ := strings.Cut(strings.TrimSpace(line), " ")
+		switch command {
+		case "":
+		case "?", "h", "help":
+			fmt.Println(reviewCommands)
+		case "q", "quit":
+			infof("Nothing extracted from %s", shortSHA(info.SHA))
+			return nil
+		case "d", "diff":
+			numbers, err := parseSelection(arg, len(entries))
+			if err != nil || len(numbers) == 0 {
+				fmt.Println("Usage: d <n>")
+				continue
+			}
+			for _, n := range numbers {
+				diffArgs := append([]string{"diff", "-M", info.parent(), info.SHA, "--"}, entries[n].paths()...)
+				diff, _ := runGit(diffArgs...)
+				fmt.Println(diff)
+			}
+		case "t", "title":
+			fmt.Printf("Title [%s]: ", subject)
+			if title, ok := readLine(); ok && strings.TrimSpace(title) != "" {
+				subject = strings.TrimSpace(title)
+			}
+		case "m", "message":
+			fmt.Println("Message body, ended by a line with only '.' (an empty body clears it):")
+			var lines []string
+			for {
+				text, ok := readLine()
+				if !ok || text == "." {
+					break
+				}
+				lines = append(lines, text)
+			}
+			body = strings.TrimSpace(strings.Join(lines, "\n"))
+		case "e", "extract":
+			choices := &reviewChoices{SHA: info.SHA, Files: make(map[string]bool), Message: subject}
+			if body != "" {
+				choices.Message += "\n\n" + body
+			}
+			for i, entry := range entries {
+				for _, path := range entry.paths() {
+					choices.Files[path] = selected[i]
+				}
+			}
+			if !anySelected(selected) {
+				fmt.Println("Select at least one file to extract")
+				continue
+			}
+			return extractReviewed(choices)
+		default:
+			numbers, err := parseSelection(line, len(entries))
+			if err != nil {
+				fmt.Printf("%v; ? lists the commands\n", err)
+				continue
+			}
+			for _, n := range numbers {
+				selected[n] = !selected[n]
+			}
+		}
+	}
+}
+
+func anySelected(selected []bool) bool {
+	for _, s := range selected {
+		if s {
+			return true
+		}
+	}
+	return false
+}
+
+// parseSelection parses file numbers and ranges such as "1-3 5" into
+// zero-based indexes below count.
+func parseSelection(text string, count int) ([]int, error) {
+	var indexes []int
+	for _, field := range strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == ',' }) {
+		from, to, isRange := strings.Cut(field, "-")
+		if !isRange {
+			to = from
+		}
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("unknown command %q", field)
+		}
+		last, err := strconv.Atoi(to)
+		if err != nil || first < 1 || last > count || first > last {
+			return nil, fmt.Errorf("no file %s, pick 1 to %d", field, count)
+		}
+		for n := first; n <= last; n++ {
+			indexes = append(indexes, n-1)
+		}
+	}
+	return indexes, nil
+}
+
+// extractReviewed extracts the reviewed commit with the choices made, going
+// through the same checks as a hook run.
+func extractReviewed(choices *reviewChoices) error {
+	commitReview = choices
+	defer func() { commitReview = nil }()
+	result, err := processCommit(choices.SHA)
+	if err != nil {
+		return err
+	}
+	if result.Status != statusExtracted {
+		return fmt.Errorf("nothing extracted from %s (%s)", shortSHA(choices.SHA), result.Reason)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_ReviewSelectsFiles(t *testing.T) {
+	repo := setupTestRepo(t)
+	sha := commitFiles(t, repo.Dir, "Tweak the assistant", map[string]string{
+		"prompts/review.md":      "# Review",
+		"docs/system-prompt.txt": "You are a helpful assistant",
+		"main.go":                "package main",
+	})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	info, _ := analyzeCommit(sha)
+	entries := reviewEntries(info)
+	if len(entries) != 3 || entries[0].Path != "prompts/review.md" || entries[1].Path != "docs/system-prompt.txt" {
+		t.Fatalf("Expected the prompt file listed first, got %+v", entries)
+	}
+
+	// Add the missed prompt, retitle, and extract
+	input := "2\nd 2\nt\nTune the assistant prompts\nm\nThe system prompt lives in docs.\n.\ne\n"
+	if err := runReview(strings.NewReader(input), []string{sha}); err != nil {
+		t.Fatalf("review failed: %v", err)
+	}
+	if commitReview != nil {
+		t.Error("Expected the review choices to be cleared")
+	}
+	branch := promptBranchName(info)
+	files, _ := runGit("diff", "--name-only", "main", branch)
+	if files != "docs/system-prompt.txt\nprompts/review.md" {
+		t.Errorf("Expected both prompts on %s, got %q", branch, files)
+	}
+	message, _ := runGit("log", "-1", "--format=%B", branch)
+	if !strings.Contains(message, "Tune the assistant prompts\n\nThe system prompt lives in docs.") || strings.Contains(message, "Tweak the assistant") {
+		t.Errorf("Expected the edited message, got:\n%s", message)
+	}
+	if current, _ := runGit("rev-parse", "--abbrev-ref", "HEAD"); current != repo.BranchName {
+		t.Errorf("Expected to be back on %s, but on %s", repo.BranchName, current)
+	}
+}
+
+func Test_ReviewQuit(t *testing.T) {
+	repo := setupTestRepo(t)
+	sha := commitFiles(t, repo.Dir, "Add a prompt", map[string]string{"prompts/a.md": "# A"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	// Nothing selected cannot be extracted; quitting leaves no branch
+	if err := runReview(strings.NewReader("1\ne\nq\n"), []string{sha}); err != nil {
+		t.Fatalf("review failed: %v", err)
+	}
+	if branches, _ := runGit("branch", "--list", getBranchPrefix()+"/*"); branches != "" {
+		t.Errorf("Expected no prompt branch, got %s", branches)
+	}
+	if err := runReview(strings.NewReader("7\n"), []string{sha}); err == nil {
+		t.Error("Expected the review to end without extracting at the end of the input")
+	}
+	if _, err := parseSelection("1-3 5", 5); err != nil {
+		t.Errorf("Expected a range to parse: %v", err)
+	}
+	if _, err := parseSelection("6", 5); err == nil {
+		t.Error("Expected a missing file to be refused")
+	}
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+const (
+	defaultServeAddr = ":8080"
+	serveQueueSize   = 100
+)
+
+// defaultWebhookHosts are the hosts `prrompt serve` clones from unless
+// prrompt.webhookHosts says otherwise.
+var defaultWebhookHosts = []string{"github.com", "gitlab.com"}
+
+// objectID matches a full SHA-1 or SHA-256 object name.
+var objectID = regexp.MustCompile(`^[0-9a-f]{40,64}$`)
+
+// pushJob is a push reported by a forge webhook.
+type pushJob struct {
+	Forge    string
+	CloneURL string
+	Branch   string
+	Before   string
+	After    string
+}
+
+// commits returns what to process for the push: the pushed range, or the
+// new tip for a new branch.
+func (job pushJob) commits() []string {
+	if strings.Trim(job.Before, "0") == "" {
+		return []string{job.After}
+	}
+	return []string{job.Before + ".." + job.After}
+}
+
+// githubPush and gitlabPush are the parts of the push payloads prrompt reads.
+type githubPush struct {
+	Ref        string `json:"ref"`
+	Before     string `json:"before"`
+	After      string `json:"after"`
+	Deleted    bool   `json:"deleted"`
+	Repository struct {
+		CloneURL string `json:"clone_url"`
+	} `json:"repository"`
+}
+
+type gitlabPush struct {
+	Ref     string `json:"ref"`
+	Before  string `json:"before"`
+	After   string `json:"after"`
+	Project struct {
+		GitHTTPURL string `json:"git_http_url"`
+	} `json:"project"`
+}
+
+// getWebhookHosts returns prrompt.webhookHosts, the hosts `prrompt serve`
+// clones pushed repositories from, with the host of prrompt.forgeBaseURL.
+func getWebhookHosts() []string {
+	hosts := defaultWebhookHosts
+	if value, err := gitConfig("--get", "prrompt.webhookHosts"); err == nil && strings.TrimSpace(value) != "" {
+		hosts = nil
+		for _, host := range strings.Split(value, ",") {
+			if host = strings.ToLower(strings.TrimSpace(host)); host != "" {
+				hosts = append(hosts, host)
+			}
+		}
+	}
+	if base, err := url.Parse(getForgeBaseURL()); err == nil && base.Hostname() != "" {
+		hosts = append(hosts, strings.ToLower(base.Hostname()))
+	}
+	return hosts
+}
+
+// checkCloneURL refuses clone URLs other than https and ssh ones on hosts,
+// so a payload can't point the server at local paths or other machines.
+func checkCloneURL(cloneURL string, hosts []string) error {
+	u, err := url.Parse(cloneURL)
+	if err != nil || (u.Scheme != "https" && u.Scheme != "ssh") || u.Hostname() == "" {
+		return fmt.Errorf("clone URL %s is not an https or ssh URL", withoutCredentials(cloneURL))
+	}
+	if !containsString(hosts, strings.ToLower(u.Hostname())) {
+		return fmt.Errorf("clone URL host %s is not in prrompt.webhookHosts", u.Hostname())
+	}
+	return nil
+}
+
+// parseWebhook authenticates a GitHub or GitLab webhook request against
+// secret and returns the push it reports, after checking the clone URL is
+// on one of hosts, the range is two object IDs and the branch a valid
+// name: nothing from the payload reaches git otherwise. A nil job with no
+// error means the event is not one prrompt acts on (another event type, a
+// tag, a deleted branch).
+func parseWebhook(r *http.Request, body []byte, secret string, hosts []string) (*pushJob, error) {
+	if secret == "" {
+		return nil, fmt.Errorf("no webhook secret configured")
+	}
+	var job pushJob
+	switch {
+	case r.Header.Get("X-GitHub-Event") != "":
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		want := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+		if !hmac.Equal([]byte(r.Header.Get("X-Hub-Signature-256")), []byte(want)) {
+			return nil, fmt.Errorf("invalid webhook signature")
+		}
+		if r.Header.Get("X-GitHub-Event") != "push" {
+			return nil, nil
+		}
+		var event githubPush
+		if err := json.Unmarshal(body, &event); err != nil {
+			return nil, fmt.Errorf("invalid push payload: %w", err)
+		}
+		if event.Deleted {
+			return nil, nil
+		}
+		job = pushJob{"github", event.Repository.CloneURL, event.Ref, event.Before, event.After}
+	case r.Header.Get("X-Gitlab-Event") != "":
+		if !hmac.Equal([]byte(r.Header.Get("X-Gitlab-Token")), []byte(secret)) {
+			return nil, fmt.Errorf("invalid webhook token")
+		}
+		if r.Header.Get("X-Gitlab-Event") != "Push Hook" {
+			return nil, nil
+		}
+		var event gitlabPush
+		if err := json.Unmarshal(body, &event); err != nil {
+			return nil, fmt.Errorf("invalid push payload: %w", err)
+		}
+		job = pushJob{"gitlab", event.Project.GitHTTPURL, event.Ref, event.Before, event.After}
+	default:
+		return nil, fmt.Errorf("not a GitHub or GitLab webhook")
+	}
+
+	branch, ok := strings.CutPrefix(job.Branch, "refs/heads/")
+	if !ok || strings.Trim(job.After, "0") == "" {
+		return nil, nil
+	}
+	if job.CloneURL == "" {
+		return nil, fmt.Errorf("push payload has no repository URL")
+	}
+	if err := checkCloneURL(job.CloneURL, hosts); err != nil {
+		return nil, err
+	}
+	if !objectID.MatchString(job.Before) || !objectID.MatchString(job.After) {
+		return nil, fmt.Errorf("push payload has an invalid commit range %q..%q", job.Before, job.After)
+	}
+	if _, err := runGit("check-ref-format", "refs/heads/"+branch); err != nil || strings.HasPrefix(branch, "-") {
+		return nil, fmt.Errorf("push payload has an invalid branch name %q", branch)
+	}
+	job.Branch = branch
+	return &job, nil
+}
+
+// webhookHandler accepts push webhooks of repositories on hosts and queues
+// them for the worker.
+func webhookHandler(queue chan<- pushJob, secret string, hosts []string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+		body, err := io.ReadAll(io.LimitReader(r.Body, 25<<20))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		job, err := parseWebhook(r, body, secret, hosts)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if job == nil {
+			fmt.Fprintln(w, "ignored")
+			return
+		}
+		select {
+		case queue <- *job:
+			appMetrics.setQueueDepth(len(queue))
+			w.WriteHeader(http.StatusAccepted)
+			fmt.Fprintln(w, "queued")
+		default:
+			http.Error(w, "queue full", http.StatusServiceUnavailable)
+		}
+	}
+}
+
+var cacheNameUnsafe = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// cachePath returns where the clone of cloneURL is kept under cacheDir:
+// host and path, without credentials or the .git suffix.
+func cachePath(cacheDir, cloneURL string) string {
+	name := cloneURL
+	if u, err := url.Parse(cloneURL); err == nil && u.Host != "" {
+		name = u.Host + "/" + u.Path
+	}
+	name = strings.TrimSuffix(strings.Trim(name, "/"), ".git")
+	return filepath.Join(cacheDir, strings.Trim(cacheNameUnsafe.ReplaceAllString(name, "_"), "_"))
+}
+
+// withoutCredentials returns rawURL without a user or token, for logs.
+func withoutCredentials(rawURL string) string {
+	if u, err := url.Parse(rawURL); err == nil && u.User != nil {
+		u.User = nil
+		return u.String()
+	}
+	return rawURL
+}
+
+// processPushJob brings the cached clone of the pushed repository up to
+// date, checks out the pushed branch and runs the pipeline on the pushed
+// commits, with the repository's own configuration; its .prrompt.yaml can
+// only set the settings in repoFileKeys.
+func processPushJob(cacheDir string, job pushJob) ([]*Result, error) {
+	dir := cachePath(cacheDir, job.CloneURL)
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err != nil {
+		os.MkdirAll(filepath.Dir(dir), 0755)
+		if output, err := runGit("clone", "--quiet", job.CloneURL, dir); err != nil {
+			return nil, fmt.Errorf("failed to clone %s: %s", withoutCredentials(job.CloneURL), output)
+		}
+	} else if output, err := runGitInDir(dir, "fetch", "--quiet", "--prune", "origin"); err != nil {
+		return nil, fmt.Errorf("failed to fetch %s: %s", withoutCredentials(job.CloneURL), output)
+	}
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	if err := os.Chdir(dir); err != nil {
+		return nil, err
+	}
+	// Start every job from a clean checkout of the pushed branch
+	if output, err := runGit("checkout", "-q", "-f", "-B", job.Branch, "origin/"+job.Branch); err != nil {
+		return nil, fmt.Errorf("failed to check out %s: %s", job.Branch, output)
+	}
+	runGit("clean", "-q", "-fd")
+	baseStartPoints = map[string]string{}
+	return processCommits(job.commits(), getRangeMode())
+}
+
+// runServe implements `prrompt serve [--addr <addr>] [--cache-dir <dir>]`:
+// a service that receives push webhooks from GitHub or GitLab and runs the
+// pipeline centrally, one job at a time, instead of a hook on every
+// developer machine.
+func runServe(args []string) error {
+	addr := defaultServeAddr
+	cacheDir := ""
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--addr" && i+1 < len(args):
+			addr = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--addr="):
+			addr = strings.TrimPrefix(args[i], "--addr=")
+		case args[i] == "--cache-dir" && i+1 < len(args):
+			cacheDir = args[i+1]
+			i++
+		case strings.HasPrefix(args[i], "--cache-dir="):
+			cacheDir = strings.TrimPrefix(args[i], "--cache-dir=")
+		default:
+			return fmt.Errorf("unexpected argument: %s", args[i])
+		}
+	}
+	if cacheDir == "" {
+		userCache, err := os.UserCacheDir()
+		if err != nil {
+			return fmt.Errorf("no cache directory, use --cache-dir: %w", err)
+		}
+		cacheDir = filepath.Join(userCache, toolName, "repos")
+	}
+	cacheDir, err := filepath.Abs(cacheDir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", cacheDir, err)
+	}
+
+	secret, _ := gitConfig("--get", "prrompt.webhookSecret")
+	if secret == "" {
+		return withExitCode(exitConfig, fmt.Errorf("prrompt.webhookSecret is not set; set it (or PRROMPT_WEBHOOK_SECRET) to the secret the webhooks are signed with"))
+	}
+	hosts := getWebhookHosts()
+	_, userPRTool := os.LookupEnv("PRROMPT_PR_TOOL")
+
+	queue := make(chan pushJob, serveQueueSize)
+	go func() {
+		for job := range queue {
+			appMetrics.setQueueDepth(len(queue))
+			if !userPRTool {
+				// Let each repository's prrompt.prTool apply
+				os.Unsetenv("PRROMPT_PR_TOOL")
+			}
+			setupUnattended()
+			infof("Processing %s push to %s of %s", job.Forge, job.Branch, withoutCredentials(job.CloneURL))
+			results, err := processPushJob(cacheDir, job)
+			if err != nil {
+				warnf("%s %s: %v", withoutCredentials(job.CloneURL), job.Branch, err)
+				continue
+			}
+			infof("Processed %d commits of %s", len(results), withoutCredentials(job.CloneURL))
+		}
+	}()
+
+	mux := http.NewServeMux()
+	status := statusHandler(appMetrics, func() error {
+		if len(queue) == cap(queue) {
+			return fmt.Errorf("queue full")
+		}
+		return nil
+	})
+	mux.Handle("/healthz", status)
+	mux.Handle("/metrics", status)
+	mux.Handle("/webhook", webhookHandler(queue, secret, hosts))
+
+	infof("Listening on %s for webhooks at /webhook from %s, cache in %s", addr, strings.Join(hosts, ", "), cacheDir)
+	return http.ListenAndServe(addr, mux)
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+)
+
+// runShow implements `prrompt show <branch|sha>:<path>`: it prints a prompt
+// file as an extraction captured it, without checking anything out. The
+// revision is a prompt branch, with or without its prefix, an extraction
+// commit, or a source commit, shown as it was extracted.
+func runShow(args []string) error {
+	if len(args) != 1 || !strings.Contains(args[0], ":") {
+		return fmt.Errorf("usage: %s show <branch|sha>:<path>", toolName)
+	}
+	rev, file, _ := strings.Cut(args[0], ":")
+	commit, err := resolveExtraction(rev)
+	if err != nil {
+		return err
+	}
+	resolved, err := resolveShowPath(commit, file)
+	if err != nil {
+		return err
+	}
+	debugf("%s:%s resolves to %s:%s", rev, file, shortSHA(commit), resolved)
+	content, err := runGitBytes(nil, nil, "cat-file", "blob", commit+":"+resolved)
+	if err != nil {
+		return fmt.Errorf("failed to read %s at %s: %w", resolved, shortSHA(commit), err)
+	}
+	_, err = os.Stdout.Write(content)
+	return err
+}
+
+// resolveExtraction returns the extraction commit rev names: the tip of a
+// prompt branch, here or on the remote, an extraction commit, or the latest
+// extraction of a source commit.
+func resolveExtraction(rev string) (string, error) {
+	remote := getRemote()
+	prefix := getBranchPrefix()
+	for _, ref := range []string{
+		"refs/heads/" + rev,
+		"refs/heads/" + prefix + "/" + rev,
+		"refs/remotes/" + remote + "/" + rev,
+		"refs/remotes/" + remote + "/" + prefix + "/" + rev,
+	} {
+		if commit, err := runGit("rev-parse", "--verify", "--quiet", ref+"^{commit}"); err == nil {
+			return commit, nil
+		}
+	}
+	commit, err := runGit("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return "", fmt.Errorf("no prompt branch or commit %s", rev)
+	}
+	if sources, _ := runGit("log", "-1", "--format=%(trailers:key="+trailerSourceCommit+",valueonly,separator=%x20)", commit); sources != "" {
+		return commit, nil
+	}
+	// A commit amended by prrompt.removeFromSource was extracted as the
+	// commit it was rewritten from
+	args := []string{"log", "-1", "--format=%H", "--fixed-strings"}
+	for _, source := range sourceCommits(commit) {
+		args = append(args, "--grep="+trailerSourceCommit+": "+source)
+	}
+	extraction, err := runGit(append(args, "--branches="+prefix+"/*", "--remotes="+remote+"/"+prefix+"/*")...)
+	if err != nil || extraction == "" {
+		return "", fmt.Errorf("%s was not extracted to a prompt branch", shortSHA(commit))
+	}
+	return extraction, nil
+}
+
+// resolveShowPath maps file to the path it has in the extraction commit.
+// Besides a path as it is there, it takes a path as written to the mirror
+// (under prrompt.mirror.pathPrefix), a skill directory or name for its
+// SKILL.md, and the end of a path the extraction changed.
+func resolveShowPath(commit, file string) (string, error) {
+	file = strings.Trim(file, "/")
+	if rest, ok := strings.CutPrefix(file, getMirrorPathPrefix()+"/"); ok && objectType(commit, file) == "" {
+		file = rest
+	}
+	candidates := []string{file}
+	if !strings.Contains(file, "/") {
+		candidates = append(candidates, ".claude/skills/"+file)
+	}
+	for _, candidate := range candidates {
+		switch objectType(commit, candidate) {
+		case "blob":
+			return candidate, nil
+		case "tree":
+			if objectType(commit, candidate+"/SKILL.md") == "blob" {
+				return candidate + "/SKILL.md", nil
+			}
+			return "", fmt.Errorf("%s is a directory without a SKILL.md at %s", candidate, shortSHA(commit))
+		}
+	}
+
+	// A file the extraction changed (other than an archived copy) ending
+	// with file, or in a skill named so
+	changed, _ := runGit("diff-tree", "--no-commit-id", "--name-only", "-r", "--root", commit)
+	var matches []string
+	for _, candidate := range strings.Split(changed, "\n") {
+		if candidate == "" || strings.HasPrefix(candidate, getArchiveDir()+"/") {
+			continue
+		}
+		if strings.HasSuffix("/"+candidate, "/"+file) || (path.Base(candidate) == "SKILL.md" && strings.HasSuffix("/"+skillPath(candidate), "/"+file)) {
+			matches = append(matches, candidate)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no %s at %s", file, shortSHA(commit))
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("%s is ambiguous at %s: %s", file, shortSHA(commit), strings.Join(matches, ", "))
+}
+
+// objectType returns the type of path at commit, "" when there is none.
+func objectType(commit, file string) string {
+	kind, err := runGit("cat-file", "-t", commit+":"+file)
+	if err != nil {
+		return ""
+	}
+	return kind
+}
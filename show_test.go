@@ -0,0 +1,53 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func Test_ShowResolvesExtractions(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.promptPatterns", ".claude/skills/")
+	first := commitFiles(t, repo.Dir, "Add review skill", map[string]string{".claude/skills/review/SKILL.md": "# Review v1"})
+	second := commitFiles(t, repo.Dir, "Tighten review skill", map[string]string{".claude/skills/review/SKILL.md": "# Review v2"})
+	code := commitFiles(t, repo.Dir, "Change code", map[string]string{"main.go": "package main"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	for _, sha := range []string{first, second} {
+		if result, err := processCommit(sha); err != nil || result.Status != statusExtracted {
+			t.Fatalf("Expected %s extracted, got %+v (err %v)", sha[:7], result, err)
+		}
+	}
+
+	// A source commit shows as its extraction captured it
+	commit, err := resolveExtraction(first)
+	if err != nil {
+		t.Fatalf("resolveExtraction failed: %v", err)
+	}
+	for _, file := range []string{".claude/skills/review/SKILL.md", "review", ".claude/skills/review", "review/SKILL.md"} {
+		resolved, err := resolveShowPath(commit, file)
+		if err != nil || resolved != ".claude/skills/review/SKILL.md" {
+			t.Errorf("resolveShowPath(%q) = %q, %v", file, resolved, err)
+		}
+	}
+	if got := showFile(commit, ".claude/skills/review/SKILL.md"); got != "# Review v1" {
+		t.Errorf("Expected the first extraction's content, got %q", got)
+	}
+
+	// A prompt branch, also without its prefix
+	for _, rev := range []string{getBranchPrefix() + "/" + second[:7], second[:7]} {
+		if commit, err := resolveExtraction(rev); err != nil || showFile(commit, ".claude/skills/review/SKILL.md") != "# Review v2" {
+			t.Errorf("Expected %s to resolve to the second extraction (err %v)", rev, err)
+		}
+	}
+
+	if err := runShow([]string{code + ":main.go"}); err == nil {
+		t.Error("Expected a commit without an extraction to fail")
+	}
+	if err := runShow([]string{second + ":missing.md"}); err == nil {
+		t.Error("Expected a missing path to fail")
+	}
+}
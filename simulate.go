@@ -0,0 +1,189 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// withConfigOverrides makes git (and so gitConfig) see the given settings
+// in the command scope, above every config file, through GIT_CONFIG_COUNT,
+// and sets their PRROMPT_* environment overrides to match. The returned
+// function restores the environment.
+func withConfigOverrides(pairs ...string) func() {
+	saved := make(map[string]*string)
+	save := func(name string) {
+		if _, done := saved[name]; done {
+			return
+		}
+		if value, ok := os.LookupEnv(name); ok {
+			saved[name] = &value
+		} else {
+			saved[name] = nil
+		}
+	}
+
+	count, _ := strconv.Atoi(os.Getenv("GIT_CONFIG_COUNT"))
+	save("GIT_CONFIG_COUNT")
+	for i := 0; i+1 < len(pairs); i += 2 {
+		key, value := fmt.Sprintf("GIT_CONFIG_KEY_%d", count), fmt.Sprintf("GIT_CONFIG_VALUE_%d", count)
+		save(key)
+		save(value)
+		os.Setenv(key, pairs[i])
+		os.Setenv(value, pairs[i+1])
+		count++
+		if strings.HasPrefix(pairs[i], "prrompt.") {
+			for j, name := range configEnvNames(pairs[i]) {
+				save(name)
+				if j == 0 {
+					os.Setenv(name, pairs[i+1])
+				} else {
+					os.Unsetenv(name)
+				}
+			}
+		}
+	}
+	os.Setenv("GIT_CONFIG_COUNT", strconv.Itoa(count))
+
+	return func() {
+		for name, value := range saved {
+			if value == nil {
+				os.Unsetenv(name)
+			} else {
+				os.Setenv(name, *value)
+			}
+		}
+	}
+}
+
+// sandboxSettings keep a simulation to the scratch worktree: nothing is
+// pushed, mirrored, announced, published, summarized by a model or opened
+// as a PR, the scratch commit is not rewritten, a recent prompt branch is
+// not appended to, and the run ends back on the scratch branch.
+var sandboxSettings = []string{
+	"prrompt.push", "false",
+	"prrompt.mirror.url", "",
+	"prrompt.prTool", defaultPRTool,
+	"prrompt.notifyURL", "",
+	"prrompt.registry.url", "",
+	"prrompt.ai.summary", "false",
+	"prrompt.notes", "false",
+	"prrompt.protectBranches", "false",
+	"prrompt.removeFromSource", "false",
+	"prrompt.stayOnBranch", "false",
+	"prrompt.squashWindow", "",
+}
+
+// runSimulate implements `prrompt simulate`: it commits a synthetic prompt
+// file on a scratch branch in a temporary worktree, runs the full pipeline
+// there with sandboxSettings, checks each phase and removes everything it
+// created, including the scratch commit's processed record and note.
+func runSimulate() error {
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+
+	var synthetic string
+	for _, pattern := range getPromptPatterns() {
+		candidate := path.Join(pattern, fmt.Sprintf("prrompt-simulate-%d.md", time.Now().UnixNano()))
+		if !strings.ContainsAny(pattern, "*?[") && isPromptFile(candidate) {
+			synthetic = candidate
+			break
+		}
+	}
+	if synthetic == "" {
+		return fmt.Errorf("no prompt pattern accepts a synthetic prompt file: %s", strings.Join(getPromptPatterns(), ","))
+	}
+
+	failed := false
+	check := func(ok bool, format string, args ...any) bool {
+		mark := "✓"
+		if !ok {
+			mark = "✗"
+			failed = true
+		}
+		fmt.Printf("%s %s\n", mark, fmt.Sprintf(format, args...))
+		return ok
+	}
+
+	scratchBranch := fmt.Sprintf("prrompt-simulate-%d", os.Getpid())
+	worktree, err := makeTempDir("prrompt-simulate-")
+	if err != nil {
+		return fmt.Errorf("failed to create temporary directory: %w", err)
+	}
+	os.Remove(worktree)
+	if _, err := runGit("worktree", "add", "-q", "-b", scratchBranch, worktree, "HEAD"); !check(err == nil, "Created scratch worktree on %s", scratchBranch) {
+		return fmt.Errorf("failed to create worktree: %v", err)
+	}
+
+	var promptBranch, scratchCommit string
+	defer func() {
+		if scratchCommit != "" {
+			forgetProcessed(scratchCommit)
+			runGitInDir(toplevel, "notes", "--ref="+notesRef, "remove", "--ignore-missing", scratchCommit)
+		}
+		runGit("worktree", "remove", "--force", worktree)
+		removeTemp(worktree)
+		runGitInDir(toplevel, "branch", "-D", scratchBranch)
+		if promptBranch != "" {
+			runGitInDir(toplevel, "branch", "-D", promptBranch)
+		}
+		fmt.Println("✓ Cleaned up")
+	}()
+
+	target := filepath.Join(worktree, synthetic)
+	os.MkdirAll(filepath.Dir(target), 0755)
+	content := fmt.Sprintf("# prrompt simulate\n\nSynthetic prompt written at %s.\n", time.Now().Format(time.RFC3339Nano))
+	if err := os.WriteFile(target, []byte(content), 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", synthetic, err)
+	}
+	runGitInDir(worktree, "add", "--", synthetic)
+	if _, err := runGitInDir(worktree, "-c", "core.hooksPath=/dev/null", "commit", "-q", "-m", "prrompt simulate"); !check(err == nil, "Committed synthetic prompt %s", synthetic) {
+		return fmt.Errorf("failed to commit: %v", err)
+	}
+	scratchCommit, _ = runGitInDir(worktree, "rev-parse", "HEAD")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	if err := os.Chdir(worktree); err != nil {
+		return err
+	}
+	restore := withConfigOverrides(sandboxSettings...)
+	defer restore()
+	skip, hadSkip := os.LookupEnv("PRROMPT_SKIP")
+	os.Unsetenv("PRROMPT_SKIP")
+	if hadSkip {
+		defer os.Setenv("PRROMPT_SKIP", skip)
+	}
+
+	result, err := processCommit("HEAD")
+	status := result.Status
+	if result.Reason != "" {
+		status += ", " + result.Reason
+	}
+	if !check(err == nil && result.Status == statusExtracted, "Ran the pipeline (%s)", status) {
+		if err != nil {
+			return fmt.Errorf("pipeline failed: %w", err)
+		}
+		return fmt.Errorf("synthetic commit was not extracted")
+	}
+	promptBranch = result.Branch
+
+	check(len(result.PromptFiles) == 1 && result.PromptFiles[0] == synthetic, "Detected the synthetic prompt file")
+	changed, _ := runGit("diff", "--name-only", baseStartPoint(), promptBranch)
+	check(changed == synthetic, "Prompt branch %s only contains the synthetic prompt", promptBranch)
+	trailers, _ := runGit("log", "--format=%(trailers:only,unfold)", "-n", "1", promptBranch)
+	check(strings.Contains(trailers, trailerSourceCommit), "Provenance trailers present")
+	current, _ := runGit("rev-parse", "--abbrev-ref", "HEAD")
+	check(current == scratchBranch, "Returned to the source branch")
+
+	if failed {
+		return fmt.Errorf("simulation failed")
+	}
+	return nil
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_Simulate(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	// Pushing must stay off even with a remote configured
+	originDir := t.TempDir()
+	runGitInDir(originDir, "init", "--bare", "-b", "main")
+	runGitInDir(repo.Dir, "remote", "add", "origin", originDir)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if err := runSimulate(); err != nil {
+		t.Fatalf("simulate failed: %v", err)
+	}
+
+	branches, _ := runGitInDir(repo.Dir, "branch", "--list")
+	if strings.Contains(branches, "simulate") || strings.Contains(branches, defaultBranchPrefix) {
+		t.Errorf("Expected simulate to clean up its branches, got: %s", branches)
+	}
+	worktrees, _ := runGitInDir(repo.Dir, "worktree", "list")
+	if strings.Count(worktrees, "\n") != 0 {
+		t.Errorf("Expected the scratch worktree to be removed, got: %s", worktrees)
+	}
+	if remote, _ := runGitInDir(originDir, "branch", "--list"); remote != "" {
+		t.Errorf("Expected nothing to be pushed, got: %s", remote)
+	}
+	if _, ok := os.LookupEnv("GIT_CONFIG_COUNT"); ok {
+		t.Error("Expected config overrides to be restored")
+	}
+	current, _ := runGitInDir(repo.Dir, "rev-parse", "--abbrev-ref", "HEAD")
+	if current != repo.BranchName {
+		t.Errorf("Expected to stay on %s, got %s", repo.BranchName, current)
+	}
+}
+
+func Test_SimulateIsSandboxed(t *testing.T) {
+	repo := setupTestRepo(t)
+	originDir := t.TempDir()
+	runGitInDir(originDir, "init", "--bare", "-b", "main")
+	runGitInDir(repo.Dir, "remote", "add", "origin", originDir)
+
+	notified := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) { notified = true }))
+	defer server.Close()
+	for key, value := range map[string]string{
+		"prrompt.notifyURL":        server.URL,
+		"prrompt.notes":            "true",
+		"prrompt.removeFromSource": "true",
+		"prrompt.stayOnBranch":     "true",
+	} {
+		runGitInDir(repo.Dir, "config", key, value)
+	}
+	// As CI usually sets it
+	t.Setenv("PRROMPT_PUSH", "true")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if err := runSimulate(); err != nil {
+		t.Fatalf("simulate failed: %v", err)
+	}
+	if refs, _ := runGitInDir(originDir, "for-each-ref"); refs != "" || notified {
+		t.Errorf("Expected nothing pushed or announced, got refs %q, notified %v", refs, notified)
+	}
+	if state, _ := loadProcessed(); len(state) != 0 {
+		t.Errorf("Expected no processed records left, got %v", state)
+	}
+	if notes, _ := runGitInDir(repo.Dir, "notes", "--ref="+notesRef, "list"); notes != "" {
+		t.Errorf("Expected no notes left, got %q", notes)
+	}
+	if os.Getenv("PRROMPT_PUSH") != "true" {
+		t.Error("Expected PRROMPT_PUSH to be restored")
+	}
+}
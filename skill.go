@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+)
+
+var (
+	skillKeyPattern  = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+	skillNamePattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	skillToolPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*(\(.+\))?$`)
+)
+
+const (
+	maxSkillNameLength        = 64
+	maxSkillDescriptionLength = 1024
+)
+
+// isSkillFile reports whether file is a Claude skill definition:
+// .claude/skills/<skill>/SKILL.md or a Markdown file directly in
+// .claude/skills/.
+func isSkillFile(file string) bool {
+	dir := path.Dir(file)
+	if path.Base(file) == "SKILL.md" && strings.Contains("/"+dir+"/", "/.claude/skills/") {
+		return true
+	}
+	return strings.HasSuffix(file, ".md") && (dir == ".claude/skills" || strings.HasSuffix(dir, "/.claude/skills"))
+}
+
+// validateSkills checks the frontmatter of the skill files the commit adds
+// or changes and returns the number of files checked and their problems.
+func validateSkills(info *CommitInfo) (int, []lintViolation) {
+	if !getBoolConfig("prrompt.validateSkills", true) {
+		return 0, nil
+	}
+	checked := 0
+	var issues []lintViolation
+	for _, file := range info.PromptFiles {
+		if !isSkillFile(file) || blobAt(info.SHA, file) == "" {
+			continue
+		}
+		checked++
+		for _, problem := range validateSkillFrontmatter(showFile(info.SHA, file)) {
+			issues = append(issues, lintViolation{file, problem})
+		}
+	}
+	return checked, issues
+}
+
+// validateSkillFrontmatter checks a skill's frontmatter: that it is
+// well-formed YAML of the shape skills use, and its name, description and
+// allowed-tools fields.
+func validateSkillFrontmatter(content string) []string {
+	content = strings.TrimPrefix(content, "\ufeff")
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+	if strings.TrimSpace(lines[0]) != "---" {
+		return []string{"missing frontmatter"}
+	}
+
+	var problems []string
+	fields := make(map[string]string)
+	items := make(map[string][]string)
+	currentKey := ""
+	closed := false
+	for i, line := range lines[1:] {
+		lineNo := i + 2
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "---" {
+			closed = true
+			break
+		}
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		if strings.HasPrefix(line, "\t") {
+			problems = append(problems, fmt.Sprintf("line %d: tabs are not allowed for indentation", lineNo))
+			continue
+		}
+		if strings.HasPrefix(line, " ") {
+			if currentKey == "" {
+				problems = append(problems, fmt.Sprintf("line %d: indented line without a key", lineNo))
+			} else if item, found := strings.CutPrefix(trimmed, "- "); found {
+				items[currentKey] = append(items[currentKey], unquote(strings.TrimSpace(item)))
+			}
+			continue
+		}
+
+		key, value, found := strings.Cut(line, ":")
+		key = strings.TrimSpace(key)
+		if !found || !skillKeyPattern.MatchString(key) {
+			problems = append(problems, fmt.Sprintf("line %d: expected \"key: value\"", lineNo))
+			currentKey = ""
+			continue
+		}
+		if _, duplicate := fields[key]; duplicate {
+			problems = append(problems, fmt.Sprintf("line %d: duplicate key %q", lineNo, key))
+		}
+		value = strings.TrimSpace(value)
+		if value != "" && (value[0] == '"' || value[0] == '\'') && (len(value) < 2 || value[len(value)-1] != value[0]) {
+			problems = append(problems, fmt.Sprintf("line %d: unterminated quoted value", lineNo))
+		}
+		fields[key] = unquote(value)
+		currentKey = key
+	}
+	if !closed {
+		return append(problems, "frontmatter is not closed with ---")
+	}
+
+	switch name, ok := fields["name"]; {
+	case !ok || name == "":
+		problems = append(problems, "missing required field \"name\"")
+	case len(name) > maxSkillNameLength || !skillNamePattern.MatchString(name):
+		problems = append(problems, fmt.Sprintf("name %q must be lowercase letters, digits and hyphens, at most %d characters", name, maxSkillNameLength))
+	}
+	switch description, ok := fields["description"]; {
+	case !ok || description == "":
+		problems = append(problems, "missing required field \"description\"")
+	case len(description) > maxSkillDescriptionLength:
+		problems = append(problems, fmt.Sprintf("description is longer than %d characters", maxSkillDescriptionLength))
+	}
+	if value, ok := fields["allowed-tools"]; ok {
+		tools := items["allowed-tools"]
+		if value != "" {
+			tools = strings.Split(strings.Trim(value, "[]"), ",")
+		}
+		if len(tools) == 0 {
+			problems = append(problems, "allowed-tools is empty")
+		}
+		for _, tool := range tools {
+			if tool = unquote(strings.TrimSpace(tool)); !skillToolPattern.MatchString(tool) {
+				problems = append(problems, fmt.Sprintf("allowed-tools entry %q is not a tool name", tool))
+			}
+		}
+	}
+	return problems
+}
+
+// formatSkillValidation summarizes skill validation for the commit (and PR)
+// body.
+func formatSkillValidation(checked int, issues []lintViolation) string {
+	if len(issues) == 0 {
+		return fmt.Sprintf("Skill validation: passed (%d checked)", checked)
+	}
+	noun := "problems"
+	if len(issues) == 1 {
+		noun = "problem"
+	}
+	lines := []string{fmt.Sprintf("Skill validation: %d %s", len(issues), noun)}
+	for _, issue := range issues {
+		lines = append(lines, "- "+issue.String())
+	}
+	return strings.Join(lines, "\n")
+}
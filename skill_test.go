@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_isSkillFile(t *testing.T) {
+	tests := map[string]bool{
+		".claude/skills/review/SKILL.md":     true,
+		".claude/skills/review.md":           true,
+		"pkg/.claude/skills/x/SKILL.md":      true,
+		".claude/skills/review/reference.md": false,
+		"prompts/SKILL.md":                   false,
+	}
+	for file, want := range tests {
+		if got := isSkillFile(file); got != want {
+			t.Errorf("isSkillFile(%q) = %v, want %v", file, got, want)
+		}
+	}
+}
+
+func Test_validateSkillFrontmatter(t *testing.T) {
+	tests := []struct {
+		content string
+		want    []string
+	}{
+		{"---\nname: code-review\ndescription: Reviews code\nallowed-tools: Read, Grep, Bash(git:*)\n---\n", nil},
+		{"---\nname: code-review\ndescription: Reviews code\nallowed-tools:\n  - Read\n  - Grep\n---\n", nil},
+		{"# No frontmatter\n", []string{"missing frontmatter"}},
+		{"---\nname: x\ndescription: y\n", []string{"frontmatter is not closed with ---"}},
+		{"---\nname: Code Review\n---\n", []string{
+			`name "Code Review" must be lowercase letters, digits and hyphens, at most 64 characters`,
+			`missing required field "description"`,
+		}},
+		{"---\nname: a\nname: b\ndescription: \"open\n\tbad: indent\n---\n", []string{
+			`line 3: duplicate key "name"`,
+			`line 4: unterminated quoted value`,
+			`line 5: tabs are not allowed for indentation`,
+		}},
+		{"---\nname: a\ndescription: b\nallowed-tools: Read, rm -rf\n---\n", []string{
+			`allowed-tools entry "rm -rf" is not a tool name`,
+		}},
+	}
+	for _, tt := range tests {
+		got := validateSkillFrontmatter(tt.content)
+		if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+			t.Errorf("validateSkillFrontmatter(%q) = %q, want %q", tt.content, got, tt.want)
+		}
+	}
+}
+
+func Test_SkillValidationInCommitBody(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	skillFile := filepath.Join(repo.Dir, ".claude/skills/review/SKILL.md")
+	os.MkdirAll(filepath.Dir(skillFile), 0755)
+	os.WriteFile(skillFile, []byte("---\nname: review\n---\n\nBody\n"), 0644)
+	runGitInDir(repo.Dir, "add", skillFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add review skill")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	body, _ := runGitInDir(repo.Dir, "log", "--format=%B", "-n", "1", defaultBranchPrefix+"/"+commitSHA[:7])
+	if !strings.Contains(body, "Skill validation: 1 problem\n- .claude/skills/review/SKILL.md: missing required field \"description\"") {
+		t.Errorf("Expected skill validation in the commit body, got: %s", body)
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Branch naming, set with prrompt.branchName.
+const (
+	branchNameSHA   = "sha"   // <prefix>/<short-sha>
+	branchNameSkill = "skill" // <prefix>/<skill-slug>-<short-sha>
+)
+
+// Slug styles, set with prrompt.slugStyle.
+const (
+	slugStyleASCII   = "ascii"   // transliterate to a-z0-9
+	slugStyleUnicode = "unicode" // keep letters of any script
+)
+
+const (
+	defaultBranchName = branchNameSHA
+	defaultSlugStyle  = slugStyleASCII
+	maxSlugLength     = 40
+	defaultSHALength  = 7
+	minSHALength      = 4
+)
+
+func getBranchName() string {
+	value, err := gitConfig("--get", "prrompt.branchName")
+	if err == nil && strings.ToLower(strings.TrimSpace(value)) == branchNameSkill {
+		return branchNameSkill
+	}
+	return defaultBranchName
+}
+
+func getSlugStyle() string {
+	value, err := gitConfig("--get", "prrompt.slugStyle")
+	if err == nil && strings.ToLower(strings.TrimSpace(value)) == slugStyleUnicode {
+		return slugStyleUnicode
+	}
+	return defaultSlugStyle
+}
+
+// getSHALength returns prrompt.shaLength, how many characters of a SHA
+// prompt branch names use.
+func getSHALength() int {
+	value, err := gitConfig("--get", "prrompt.shaLength")
+	if n, convErr := strconv.Atoi(strings.TrimSpace(value)); err == nil && convErr == nil && n >= minSHALength {
+		return min(n, 40)
+	}
+	return defaultSHALength
+}
+
+// abbrevSHA abbreviates sha for a branch name.
+func abbrevSHA(sha string) string {
+	if n := getSHALength(); len(sha) > n {
+		return sha[:n]
+	}
+	return sha
+}
+
+// promptBranchName returns the branch a commit's prompts are extracted to.
+// When a branch of that name holds the extraction of another commit whose
+// SHA starts the same, e.g. in a big repository or after a rebase, the SHA
+// is lengthened until the name is free, and the full one is suffixed -2,
+// -3 and so on.
+func promptBranchName(info *CommitInfo) string {
+	name := fmt.Sprintf("%s/", getBranchPrefix())
+	if getBranchName() == branchNameSkill {
+		if skill := skillName(info); skill != "" {
+			name += slugify(skill, getSlugStyle()) + "-"
+		}
+	}
+	for n := len(abbrevSHA(info.SHA)); n < len(info.SHA); n++ {
+		if branch := name + info.SHA[:n]; !branchTaken(branch, info.SHA) {
+			return branch
+		}
+	}
+	branch := name + info.SHA
+	for i := 2; branchTaken(branch, info.SHA); i++ {
+		branch = fmt.Sprintf("%s%s-%d", name, info.SHA, i)
+	}
+	return branch
+}
+
+// branchTaken reports whether branch exists, here or on the remote, without
+// carrying the extraction of sha.
+func branchTaken(branch, sha string) bool {
+	for _, ref := range []string{"refs/heads/" + branch, "refs/remotes/" + getRemote() + "/" + branch} {
+		if _, err := runGit("rev-parse", "--verify", "--quiet", ref); err == nil && !branchCarries(ref, sha) {
+			return true
+		}
+	}
+	return false
+}
+
+// skillName returns the frontmatter name of the first changed prompt file
+// that has one.
+func skillName(info *CommitInfo) string {
+	for _, file := range info.PromptFiles {
+		if fields, ok := parseFrontmatter(showFile(info.SHA, file)); ok && fields["name"] != "" {
+			return fields["name"]
+		}
+	}
+	return ""
+}
+
+// slugify turns name into a lowercase, dash-separated ref component. In
+// ASCII style, Latin diacritics, Cyrillic and Greek are transliterated.
+// Names that leave nothing (e.g. CJK in ASCII style) or are cut to
+// maxSlugLength get a short hash of the full name so distinct names keep
+// distinct slugs.
+func slugify(name, style string) string {
+	var b strings.Builder
+	dash := false
+	lossy := false
+	for _, r := range strings.ToLower(name) {
+		alnum := unicode.IsLetter(r) || unicode.IsDigit(r)
+		translit, known := transliterations[r]
+		var part string
+		switch {
+		case alnum && (r < unicode.MaxASCII || style == slugStyleUnicode):
+			part = string(r)
+		case known && translit == "":
+			// Letters without a sound of their own, like the soft sign
+			continue
+		case known:
+			part = translit
+		case alnum:
+			lossy = true
+		}
+		if part == "" {
+			dash = b.Len() > 0
+			continue
+		}
+		if dash {
+			b.WriteByte('-')
+			dash = false
+		}
+		b.WriteString(part)
+	}
+
+	slug := b.String()
+	if runes := []rune(slug); len(runes) > maxSlugLength {
+		slug = strings.TrimRight(string(runes[:maxSlugLength]), "-")
+		lossy = true
+	}
+	if slug == "" || lossy {
+		sum := sha1.Sum([]byte(name))
+		hash := hex.EncodeToString(sum[:])[:6]
+		if slug == "" {
+			return "skill-" + hash
+		}
+		return slug + "-" + hash
+	}
+	return slug
+}
+
+var transliterations = map[rune]string{
+	// Latin
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'ā': "a", 'ă': "a", 'ą': "a",
+	'æ': "ae", 'ç': "c", 'ć': "c", 'č': "c", 'ď': "d", 'đ': "d", 'ð': "d",
+	'è': "e", 'é': "e", 'ê': "e", 'ë': "e", 'ē': "e", 'ę': "e", 'ě': "e",
+	'ğ': "g", 'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ī': "i", 'ı': "i",
+	'ł': "l", 'ñ': "n", 'ń': "n", 'ň': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o", 'ō': "o", 'ő': "o", 'œ': "oe",
+	'ř': "r", 'ß': "ss", 'ś': "s", 'š': "s", 'ş': "s", 'ť': "t", 'ţ': "t", 'þ': "th",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ū': "u", 'ů': "u", 'ű': "u",
+	'ý': "y", 'ÿ': "y", 'ź': "z", 'ż': "z", 'ž': "z",
+	// Cyrillic
+	'а': "a", 'б': "b", 'в': "v", 'г': "g", 'ґ': "g", 'д': "d", 'е': "e", 'ё': "yo", 'є': "ye",
+	'ж': "zh", 'з': "z", 'и': "i", 'і': "i", 'ї': "yi", 'й': "y", 'к': "k", 'л': "l", 'м': "m",
+	'н': "n", 'о': "o", 'п': "p", 'р': "r", 'с': "s", 'т': "t", 'у': "u", 'ф': "f", 'х': "kh",
+	'ц': "ts", 'ч': "ch", 'ш': "sh", 'щ': "shch", 'ъ': "", 'ы': "y", 'ь': "", 'э': "e", 'ю': "yu", 'я': "ya",
+	// Greek
+	'α': "a", 'ά': "a", 'β': "v", 'γ': "g", 'δ': "d", 'ε': "e", 'έ': "e", 'ζ': "z", 'η': "i", 'ή': "i",
+	'θ': "th", 'ι': "i", 'ί': "i", 'ϊ': "i", 'κ': "k", 'λ': "l", 'μ': "m", 'ν': "n", 'ξ': "x",
+	'ο': "o", 'ό': "o", 'π': "p", 'ρ': "r", 'σ': "s", 'ς': "s", 'τ': "t", 'υ': "y", 'ύ': "y", 'ϋ': "y",
+	'φ': "f", 'χ': "ch", 'ψ': "ps", 'ω': "o", 'ώ': "o",
+}
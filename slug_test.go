@@ -0,0 +1,101 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_slugify(t *testing.T) {
+	tests := []struct {
+		name  string
+		style string
+		want  string
+	}{
+		{"Code Review", slugStyleASCII, "code-review"},
+		{"Überprüfung der Änderungen", slugStyleASCII, "uberprufung-der-anderungen"},
+		{"Проверка кода", slugStyleASCII, "proverka-koda"},
+		{"Объявление", slugStyleASCII, "obyavlenie"},
+		{"Έλεγχος", slugStyleASCII, "elegchos"},
+		{"Проверка кода", slugStyleUnicode, "проверка-кода"},
+		{"  --Weird__name!! ", slugStyleASCII, "weird-name"},
+	}
+	for _, tt := range tests {
+		if got := slugify(tt.name, tt.style); got != tt.want {
+			t.Errorf("slugify(%q, %s) = %q, want %q", tt.name, tt.style, got, tt.want)
+		}
+	}
+
+	// Untransliterable names get distinct hashes
+	a, b := slugify("代码审查", slugStyleASCII), slugify("代码生成", slugStyleASCII)
+	if !strings.HasPrefix(a, "skill-") || a == b {
+		t.Errorf("Expected distinct hashed slugs, got %q and %q", a, b)
+	}
+	if got := slugify("代码审查", slugStyleUnicode); got != "代码审查" {
+		t.Errorf("Expected unicode style to keep CJK, got %q", got)
+	}
+	long := slugify(strings.Repeat("review ", 10), slugStyleASCII)
+	if len(long) > maxSlugLength+7 || long == slugify(strings.Repeat("review ", 11), slugStyleASCII) {
+		t.Errorf("Expected long names to be cut and hashed, got %q", long)
+	}
+}
+
+func Test_SkillBranchName(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.branchName", "skill")
+
+	skillFile := filepath.Join(repo.Dir, ".claude/skills/review/SKILL.md")
+	os.MkdirAll(filepath.Dir(skillFile), 0755)
+	os.WriteFile(skillFile, []byte("---\nname: Überprüfung\ndescription: Reviews code\n---\n\nBody\n"), 0644)
+	runGitInDir(repo.Dir, "add", skillFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add review skill")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	want := defaultBranchPrefix + "/uberprufung-" + commitSHA[:7]
+	if branches, _ := runGitInDir(repo.Dir, "branch", "--list", want); !strings.Contains(branches, want) {
+		t.Errorf("Expected branch %s", want)
+	}
+}
+
+func Test_ShortSHACollision(t *testing.T) {
+	repo := setupTestRepo(t)
+	commitSHA := commitFiles(t, repo.Dir, "Add prompt file", map[string]string{"prompts/test.md": "# Test prompt"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	// Another commit's prompt branch with the same short SHA
+	prefix := defaultBranchPrefix + "/"
+	runGit("branch", prefix+commitSHA[:7], "main")
+	result, err := processCommit(commitSHA)
+	if err != nil || result.Branch != prefix+commitSHA[:8] {
+		t.Fatalf("Expected the longer abbreviation %s, got %+v (err %v)", prefix+commitSHA[:8], result, err)
+	}
+	info := &CommitInfo{SHA: commitSHA}
+	if got := promptBranchName(info); got != result.Branch {
+		t.Errorf("Expected the commit's own branch to keep its name, got %s", got)
+	}
+
+	// Every abbreviation taken
+	runGit("branch", "-D", result.Branch)
+	for n := 8; n <= len(commitSHA); n++ {
+		runGit("branch", prefix+commitSHA[:n], "main")
+	}
+	if got := promptBranchName(info); got != prefix+commitSHA+"-2" {
+		t.Errorf("Expected a suffixed full SHA, got %s", got)
+	}
+
+	runGitInDir(repo.Dir, "config", "prrompt.shaLength", "12")
+	if got := abbrevSHA(commitSHA); got != commitSHA[:12] {
+		t.Errorf("Expected 12 characters, got %s", got)
+	}
+	if err := validSHALength("3"); err == nil {
+		t.Error("Expected a 3-character abbreviation to be invalid")
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// runGitWithEnv is runGit with extra environment variables.
+func runGitWithEnv(env []string, args ...string) (string, error) {
+	debugf("%s git %s", strings.Join(env, " "), strings.Join(args, " "))
+	cmd := exec.Command("git", args...)
+	cmd.Env = append(os.Environ(), env...)
+	output, err := cmd.CombinedOutput()
+	return strings.TrimSpace(string(output)), err
+}
+
+// sourcePaths returns the paths whose changes went to the prompt branch,
+// including the old side of renames.
+func sourcePaths(info *CommitInfo) []string {
+	var paths []string
+	for _, file := range info.PromptFiles {
+		paths = append(paths, file)
+		if old, ok := info.Renames[file]; ok {
+			paths = append(paths, old)
+		}
+	}
+	return paths
+}
+
+// removeFromSource (prrompt.removeFromSource) rewrites the just extracted
+// commit so that its prompt changes only reach the base branch through the
+// prompt PR: a mixed commit is amended to leave the prompt files as they
+// were in its parent, and a prompt-only commit is dropped. The working tree
+// and index follow. It only rewrites the unpushed tip of the source branch
+// and returns the new tip, or "" when it left the commit alone. The amended
+// commit names the original in a Prrompt-Rewritten-From trailer and takes
+// over its processed record and note.
+func removeFromSource(info *CommitInfo) (string, error) {
+	if info.Parents != 1 {
+		return "", fmt.Errorf("only commits with a single parent are rewritten")
+	}
+	if head, _ := runGit("rev-parse", "HEAD"); head != info.SHA {
+		return "", fmt.Errorf("%s is no longer HEAD", shortSHA(info.SHA))
+	}
+	if ref, _ := runGit("symbolic-ref", "-q", "HEAD"); ref != "refs/heads/"+info.SourceBranch {
+		return "", fmt.Errorf("%s is not checked out", info.SourceBranch)
+	}
+	if remotes, _ := runGitHistory("for-each-ref", "--contains", info.SHA, "refs/remotes/"); remotes != "" {
+		return "", fmt.Errorf("%s is already pushed", shortSHA(info.SHA))
+	}
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	paths := sourcePaths(info)
+	pathspec := append([]string{"--"}, paths...)
+	if _, err := runGitInDir(toplevel, append([]string{"diff", "--quiet", "HEAD"}, pathspec...)...); err != nil {
+		return "", fmt.Errorf("prompt files have uncommitted changes")
+	}
+
+	// Build the tree without the prompt changes in a scratch index
+	index, err := makeTempFile("prrompt-index-")
+	if err != nil {
+		return "", err
+	}
+	index.Close()
+	defer removeTemp(index.Name())
+	env := []string{"GIT_INDEX_FILE=" + index.Name()}
+	parent := info.SHA + "^"
+	if output, err := runGitWithEnv(env, "read-tree", info.SHA); err != nil {
+		return "", fmt.Errorf("failed to read tree: %s", output)
+	}
+	for _, path := range paths {
+		entry, _ := runGitInDir(toplevel, "ls-tree", parent, "--", path)
+		args := []string{"update-index", "--force-remove", "--", path}
+		if meta, _, found := strings.Cut(entry, "\t"); found {
+			fields := strings.Fields(meta)
+			args = []string{"update-index", "--add", "--cacheinfo", fields[0] + "," + fields[2] + "," + path}
+		}
+		if output, err := runGitWithEnv(env, args...); err != nil {
+			return "", fmt.Errorf("failed to update %s: %s", path, output)
+		}
+	}
+	tree, err := runGitWithEnv(env, "write-tree")
+	if err != nil {
+		return "", fmt.Errorf("failed to write tree: %s", tree)
+	}
+
+	newHead, _ := runGit("rev-parse", parent)
+	amended := false
+	if parentTree, _ := runGit("rev-parse", parent+"^{tree}"); tree != parentTree {
+		authorEnv, err := commitAuthorEnv(info.SHA)
+		if err != nil {
+			return "", err
+		}
+		// The extraction's Prrompt-Source-Commit names the original commit
+		msg := appendTrailers(info.Message, []trailer{{trailerRewrittenFrom, info.SHA}})
+		args := append([]string{"commit-tree", tree, "-p", parent, "-m", msg}, commitSigningArgs()...)
+		if newHead, err = runGitWithEnv(authorEnv, args...); err != nil {
+			return "", fmt.Errorf("failed to rewrite %s: %s", shortSHA(info.SHA), newHead)
+		}
+		amended = true
+	}
+	if output, err := runGit("update-ref", "-m", "prrompt: remove prompt changes extracted to "+info.PromptBranch,
+		"refs/heads/"+info.SourceBranch, newHead, info.SHA); err != nil {
+		return "", fmt.Errorf("failed to update %s: %s", info.SourceBranch, output)
+	}
+	if amended {
+		recordRewrite(info.SHA, newHead)
+	}
+
+	// Bring the index and working tree of those paths to the new tip
+	runGitInDir(toplevel, append([]string{"reset", "-q"}, pathspec...)...)
+	for _, path := range paths {
+		if _, err := runGitInDir(toplevel, "cat-file", "-e", newHead+":"+path); err == nil {
+			runGitInDir(toplevel, "checkout", "--", path)
+		} else {
+			os.Remove(filepath.Join(toplevel, path))
+		}
+	}
+	return newHead, nil
+}
+
+// sourceCommits returns sha and the commits it was rewritten from, the ones
+// an extraction's Prrompt-Source-Commit trailer may name instead.
+func sourceCommits(sha string) []string {
+	origins, _ := runGit("log", "-1", "--format=%(trailers:key="+trailerRewrittenFrom+",valueonly,separator=%x20)", sha)
+	return append([]string{sha}, strings.Fields(origins)...)
+}
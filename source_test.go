@@ -0,0 +1,92 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_RemoveFromSource(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.removeFromSource", "true")
+	commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/a.md": "# A"})
+	mixed := commitFiles(t, repo.Dir, "Tune the prompt with the code", map[string]string{
+		"prompts/a.md": "# A, tuned",
+		"prompts/b.md": "# B",
+		"src/main.go":  "package main",
+	})
+	parent, _ := runGitInDir(repo.Dir, "rev-parse", mixed+"^")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(mixed)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	head, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+	if result.NewSourceTip == "" || head != result.NewSourceTip || head == mixed {
+		t.Fatalf("Expected the commit to be rewritten, HEAD %s, result %+v", head, result)
+	}
+	files, _ := runGitInDir(repo.Dir, "diff", "--name-only", parent, "HEAD")
+	if files != "src/main.go" {
+		t.Errorf("Expected only the code change to stay on the source branch, got %q", files)
+	}
+	if subject, _ := runGitInDir(repo.Dir, "log", "-1", "--format=%s %an"); subject != "Tune the prompt with the code Test User" {
+		t.Errorf("Expected message and author to be kept, got %q", subject)
+	}
+	// The amended commit still leads to its extraction
+	if origin, _ := runGitInDir(repo.Dir, "log", "-1", "--format=%(trailers:key="+trailerRewrittenFrom+",valueonly)"); origin != mixed {
+		t.Errorf("Expected a trailer naming %s, got %q", mixed, origin)
+	}
+	if branch := alreadyProcessed(head, configHash()); branch != result.Branch {
+		t.Errorf("Expected the amended commit recorded as extracted to %s, got %q", result.Branch, branch)
+	}
+	tip, _ := runGitInDir(repo.Dir, "rev-parse", result.Branch)
+	if extraction, err := resolveExtraction(head); err != nil || extraction != tip {
+		t.Errorf("Expected the amended commit to resolve to %s, got %s (%v)", tip, extraction, err)
+	}
+	if !branchCarries("refs/heads/"+result.Branch, head) {
+		t.Errorf("Expected %s to carry the amended commit", result.Branch)
+	}
+	content, _ := os.ReadFile(filepath.Join(repo.Dir, "prompts/a.md"))
+	if _, err := os.Stat(filepath.Join(repo.Dir, "prompts/b.md")); string(content) != "# A" || !os.IsNotExist(err) {
+		t.Errorf("Expected the working tree to follow, got %q, %v", content, err)
+	}
+	if status, _ := runGitInDir(repo.Dir, "status", "--porcelain"); status != "" {
+		t.Errorf("Expected a clean working tree, got %q", status)
+	}
+	files, _ = runGitInDir(repo.Dir, "diff", "--name-only", "main", result.Branch)
+	if files != "prompts/a.md\nprompts/b.md" {
+		t.Errorf("Expected the prompt changes on %s, got %q", result.Branch, files)
+	}
+
+	// A prompt-only commit is dropped
+	tip = head
+	promptOnly := commitFiles(t, repo.Dir, "Add prompt C", map[string]string{"prompts/c.md": "# C"})
+	if result, err = processCommit(promptOnly); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if head, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD"); head != tip || result.NewSourceTip != tip {
+		t.Errorf("Expected the prompt-only commit to be dropped, HEAD %s", head)
+	}
+}
+
+func Test_RemoveFromSourceKeepsPushedCommits(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.removeFromSource", "true")
+	runGitInDir(repo.Dir, "push", "-q", "origin", repo.BranchName)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if head, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD"); head != commitSHA || result.NewSourceTip != "" {
+		t.Errorf("Expected a pushed commit to be left alone, HEAD %s", head)
+	}
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+)
+
+// How a commit's prompt files are split across branches, set with
+// prrompt.splitBy.
+const (
+	splitByNone      = "none"      // one branch for the commit
+	splitByPattern   = "pattern"   // a branch per matching prompt pattern
+	splitByDirectory = "directory" // a branch per top-level directory
+)
+
+func getSplitBy() string {
+	value, _ := gitConfig("--get", "prrompt.splitBy")
+	switch mode := strings.ToLower(strings.TrimSpace(value)); mode {
+	case splitByPattern, splitByDirectory:
+		return mode
+	}
+	return splitByNone
+}
+
+// splitKey returns the group of a prompt file for mode: the prompt pattern
+// it matches or its top-level directory. Files no pattern matches, included
+// by a control file or the commit message, go by their directory.
+func splitKey(file, mode string) string {
+	if mode == splitByPattern {
+		for _, pattern := range getPromptPatterns() {
+			if strings.HasPrefix(file, pattern) {
+				return pattern
+			}
+		}
+	}
+	if dir, _, found := strings.Cut(file, "/"); found {
+		return dir + "/"
+	}
+	return "."
+}
+
+// fileGroup is the part of the prompt changes of one or more commits that
+// falls into one group, e.g. one skill.
+type fileGroup struct {
+	path  string
+	infos []*CommitInfo
+}
+
+// groupFiles splits infos, oldest first, by the key of their prompt files,
+// in the order the groups first changed. Each group holds a copy of every
+// commit that touched it, limited to the group's files; the commit's other
+// prompt files are left out like its other files.
+func groupFiles(infos []*CommitInfo, key func(file string) string) []*fileGroup {
+	var groups []*fileGroup
+	byPath := make(map[string]*fileGroup)
+	for _, info := range infos {
+		files := make(map[string][]string)
+		var order []string
+		for _, file := range info.PromptFiles {
+			k := key(file)
+			if files[k] == nil {
+				order = append(order, k)
+			}
+			files[k] = append(files[k], file)
+		}
+		for _, k := range order {
+			part := *info
+			part.Group = k
+			part.PromptFiles = files[k]
+			part.OtherFiles = append([]string(nil), info.OtherFiles...)
+			for _, other := range order {
+				if other != k {
+					part.OtherFiles = append(part.OtherFiles, files[other]...)
+				}
+			}
+			part.IsMixed = len(part.OtherFiles) > 0
+			part.VariantFiles = nil
+			for _, file := range info.VariantFiles {
+				if key(file) == k {
+					part.VariantFiles = append(part.VariantFiles, file)
+				}
+			}
+			group := byPath[k]
+			if group == nil {
+				group = &fileGroup{path: k}
+				byPath[k] = group
+				groups = append(groups, group)
+			}
+			group.infos = append(group.infos, &part)
+		}
+	}
+	return groups
+}
+
+// extractGroups extracts each group to the branch branchName gives it. A
+// commit in several groups contributes to each of their branches; its
+// result names them all, and infos point at the first.
+func extractGroups(infos []*CommitInfo, results []*Result, groups []*fileGroup, branchName func(*fileGroup) string) error {
+	if len(groups) > 1 {
+		disableStay("as there are several prompt branches")
+	}
+	branches := make(map[string][]*CommitInfo)
+	for _, group := range groups {
+		promptBranch := branchName(group)
+		if err := extractAndMirror(promptBranch, group.infos); err != nil {
+			return fmt.Errorf("%s: %w", group.path, err)
+		}
+		for _, part := range group.infos {
+			branches[part.SHA] = append(branches[part.SHA], part)
+		}
+	}
+	for i, info := range infos {
+		parts := branches[info.SHA]
+		results[i].setExtracted(parts[0])
+		for _, part := range parts {
+			results[i].Branches = append(results[i].Branches, part.PromptBranch)
+			results[i].Pushed = results[i].Pushed || part.Pushed
+			if pushFailed(part) {
+				results[i].ExitCode = exitPushFailed
+			}
+		}
+		info.PromptBranch = parts[0].PromptBranch
+	}
+	return nil
+}
+
+// splitCommit returns the groups a commit's prompt files split into with
+// prrompt.splitBy, or nil when they all go to one branch.
+func splitCommit(info *CommitInfo) []*fileGroup {
+	mode := getSplitBy()
+	if mode == splitByNone {
+		return nil
+	}
+	groups := groupFiles([]*CommitInfo{info}, func(file string) string { return splitKey(file, mode) })
+	if len(groups) < 2 {
+		return nil
+	}
+	return groups
+}
+
+// extractSplit extracts a commit to a branch per group, named
+// <branch>-<group>, so that different owners review their part on its own.
+func extractSplit(info *CommitInfo, groups []*fileGroup, result *Result) error {
+	infof("Splitting %s into %d prompt branches (prrompt.splitBy=%s)", shortSHA(info.SHA), len(groups), getSplitBy())
+	base := promptBranchName(info)
+	return extractGroups([]*CommitInfo{info}, []*Result{result}, groups, func(group *fileGroup) string {
+		return splitBranchName(base, group)
+	})
+}
+
+// splitBranchName returns the prompt branch of group when a commit that
+// would go to base is split.
+func splitBranchName(base string, group *fileGroup) string {
+	return base + "-" + slugify(group.path, getSlugStyle())
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_SplitByPattern(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.splitBy", "pattern")
+	sha := commitFiles(t, repo.Dir, "Add greeter and triage agent", map[string]string{
+		".claude/skills/greeter/SKILL.md": "# Greeter",
+		"prompts/agents/triage.md":        "# Triage",
+		"prompts/agents/review.md":        "# Review",
+		"main.go":                         "package main",
+	})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(sha)
+	if err != nil {
+		t.Fatalf("processCommit failed: %v", err)
+	}
+	skills := getBranchPrefix() + "/" + sha[:7] + "-claude-skills"
+	prompts := getBranchPrefix() + "/" + sha[:7] + "-prompts"
+	if result.Status != statusExtracted || strings.Join(result.Branches, " ") != skills+" "+prompts || result.Branch != skills {
+		t.Fatalf("Expected a branch per pattern, got %+v", result)
+	}
+	for branch, want := range map[string]string{
+		skills:  ".claude/skills/greeter/SKILL.md",
+		prompts: "prompts/agents/review.md\nprompts/agents/triage.md",
+	} {
+		if files, _ := runGit("ls-tree", "-r", "--name-only", branch); files != want {
+			t.Errorf("Expected %s on %s, got %s", want, branch, files)
+		}
+	}
+	if current, _ := runGit("rev-parse", "--abbrev-ref", "HEAD"); current != repo.BranchName {
+		t.Errorf("Expected to be back on %s, got %s", repo.BranchName, current)
+	}
+}
+
+func Test_SplitKey(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.promptPatterns", ".claude/skills/,.claude/commands/,docs/prompts/")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	tests := []struct {
+		file, mode, want string
+	}{
+		{".claude/skills/greeter/SKILL.md", splitByPattern, ".claude/skills/"},
+		{".claude/commands/deploy.md", splitByPattern, ".claude/commands/"},
+		{".claude/commands/deploy.md", splitByDirectory, ".claude/"},
+		{"docs/prompts/intro.md", splitByDirectory, "docs/"},
+		{"notes/extra.txt", splitByPattern, "notes/"},
+		{"SYSTEM.md", splitByDirectory, "."},
+	}
+	for _, tt := range tests {
+		if got := splitKey(tt.file, tt.mode); got != tt.want {
+			t.Errorf("splitKey(%q, %s) = %q, want %q", tt.file, tt.mode, got, tt.want)
+		}
+	}
+	info := &CommitInfo{PromptFiles: []string{".claude/skills/a/SKILL.md", ".claude/skills/b/SKILL.md"}}
+	runGit("config", "prrompt.splitBy", "pattern")
+	if groups := splitCommit(info); groups != nil {
+		t.Errorf("Expected files under one pattern not to be split, got %d groups", len(groups))
+	}
+}
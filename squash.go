@@ -0,0 +1,65 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// squashUntilPushed keeps appending to a session's prompt branch for as
+// long as it hasn't been pushed.
+const squashUntilPushed = "until-pushed"
+
+// getSquashWindow returns how long after its last extraction a prompt
+// branch keeps collecting new prompt commits from the same source branch.
+// untilPushed is set for "until-pushed"; 0 and false turn sessions off.
+func getSquashWindow() (window time.Duration, untilPushed bool) {
+	value, err := gitConfig("--get", "prrompt.squashWindow")
+	if err != nil {
+		return 0, false
+	}
+	value = strings.ToLower(strings.TrimSpace(value))
+	if value == squashUntilPushed || value == "until pushed" {
+		return 0, true
+	}
+	window, err = time.ParseDuration(value)
+	if err != nil || window < 0 {
+		return 0, false
+	}
+	return window, false
+}
+
+// sessionBranch returns the prompt branch that the commit's extraction
+// should be appended to under prrompt.squashWindow: the most recently
+// extracted-to prompt branch from the same source branch, if it is still
+// within the window (or not yet pushed). It returns "" otherwise.
+func sessionBranch(info *CommitInfo) string {
+	window, untilPushed := getSquashWindow()
+	if window == 0 && !untilPushed {
+		return ""
+	}
+	output, err := runGit("for-each-ref", "--sort=-committerdate", "--count=20",
+		"--format=%(refname:short)\t%(committerdate:unix)\t%(trailers:key="+trailerSourceBranch+",valueonly,separator=)",
+		"refs/heads/"+getBranchPrefix()+"/")
+	if err != nil || output == "" {
+		return ""
+	}
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 || strings.TrimSpace(fields[2]) != info.SourceBranch {
+			continue
+		}
+		branch := fields[0]
+		if untilPushed {
+			if _, err := runGit("rev-parse", "--verify", "--quiet", "refs/remotes/"+getRemote()+"/"+branch); err == nil {
+				return ""
+			}
+			return branch
+		}
+		if at, err := strconv.ParseInt(fields[1], 10, 64); err == nil && time.Since(time.Unix(at, 0)) <= window {
+			return branch
+		}
+		return ""
+	}
+	return ""
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func Test_SquashWindow(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.squashWindow", "1h")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	commitPrompt := func(content string) string {
+		promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+		os.MkdirAll(filepath.Dir(promptFile), 0755)
+		os.WriteFile(promptFile, []byte(content), 0644)
+		runGitInDir(repo.Dir, "add", promptFile)
+		runGitInDir(repo.Dir, "commit", "-m", "Iterate on prompt")
+		sha, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+		return sha
+	}
+
+	first, err := processCommit(commitPrompt("# v1"))
+	if err != nil || first.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", first, err)
+	}
+	second, err := processCommit(commitPrompt("# v2"))
+	if err != nil || second.Status != statusExtracted {
+		t.Fatalf("Expected extraction, got %+v (err %v)", second, err)
+	}
+	if second.Branch != first.Branch {
+		t.Errorf("Expected the second commit to be appended to %s, got %s", first.Branch, second.Branch)
+	}
+	if count, _ := runGitInDir(repo.Dir, "rev-list", "--count", "main.."+first.Branch); count != "2" {
+		t.Errorf("Expected 2 extraction commits on %s, got %s", first.Branch, count)
+	}
+	if content, _ := runGitInDir(repo.Dir, "show", first.Branch+":prompts/test.md"); content != "# v2" {
+		t.Errorf("Expected the latest prompt on %s, got %q", first.Branch, content)
+	}
+
+	// Outside a session, commits get their own branch again
+	runGitInDir(repo.Dir, "config", "prrompt.squashWindow", "until-pushed")
+	runGitInDir(repo.Dir, "update-ref", "refs/remotes/origin/"+first.Branch, first.Branch)
+	third, err := processCommit(commitPrompt("# v3"))
+	if err != nil || third.Branch == first.Branch {
+		t.Errorf("Expected a new branch once the session's branch was pushed, got %+v (err %v)", third, err)
+	}
+}
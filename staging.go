@@ -0,0 +1,180 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// getStagingBranch returns prrompt.stagingBranch: set, extraction PRs
+// target it instead of the base branch, and `prrompt promote` rolls what
+// accumulates there up into one PR to the base.
+func getStagingBranch() string {
+	value, _ := gitConfig("--get", "prrompt.stagingBranch")
+	return strings.TrimSpace(value)
+}
+
+// getTargetBranch returns the branch prompt branches start from and their
+// PRs target: the staging branch if there is one and --base doesn't say
+// otherwise, else the base branch.
+func getTargetBranch() string {
+	if staging := getStagingBranch(); staging != "" && os.Getenv(baseEnv) == "" {
+		return staging
+	}
+	return getBaseBranch()
+}
+
+// ensureTargetBranch creates the staging branch on remote from the base
+// branch, so the first extraction PR has something to target. Other
+// targets are left alone.
+func ensureTargetBranch(remote, target string) {
+	if target == "" || target != getStagingBranch() {
+		return
+	}
+	if _, err := runGit("ls-remote", "--exit-code", "--heads", remote, "refs/heads/"+target); err == nil {
+		return
+	}
+	from := startPointOf(getBaseBranch())
+	if err := timedPush(remote, from+":refs/heads/"+target); err != nil {
+		warnf("failed to create %s on %s: %v", target, remote, err)
+		return
+	}
+	infof("Created %s on %s from %s", target, remote, from)
+}
+
+// promotion is what `prrompt promote` rolls up: the commits on the staging
+// branch that are not on the base yet, and the skills they change.
+type promotion struct {
+	Base, Staging string
+	Commits       []string // "<short sha> <subject>"
+	Skills        map[string]int
+}
+
+func collectPromotion(base, staging string) (*promotion, error) {
+	from, to := startPointOf(base), startPointOf(staging)
+	if _, err := runGit("rev-parse", "--verify", "-q", to+"^{commit}"); err != nil {
+		return nil, fmt.Errorf("%s does not exist", staging)
+	}
+	p := &promotion{Base: base, Staging: staging, Skills: make(map[string]int)}
+	log, err := runGit("log", "--no-merges", "--reverse", "--format=%h %s", from+".."+to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the commits of %s: %s", staging, log)
+	}
+	if log != "" {
+		p.Commits = strings.Split(log, "\n")
+	}
+	files, _ := runGit("diff", "--name-only", from+"..."+to)
+	for _, file := range strings.Split(files, "\n") {
+		if file != "" {
+			p.Skills[skillPath(file)]++
+		}
+	}
+	return p, nil
+}
+
+// titleAndBody describes the roll-up PR, redacted since it leaves git.
+func (p *promotion) titleAndBody() (string, string) {
+	skills := make([]string, 0, len(p.Skills))
+	for skill := range p.Skills {
+		skills = append(skills, skill)
+	}
+	sort.Strings(skills)
+	title := prefixSubject(fmt.Sprintf("Promote %s from %s", countOf(len(skills), "skill"), p.Staging))
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Rolls up the prompt changes reviewed on %s since the last promotion to %s.\n\n", p.Staging, p.Base)
+	b.WriteString("Skills:\n\n")
+	for _, skill := range skills {
+		fmt.Fprintf(&b, "- `%s` (%s)\n", skill, countOf(p.Skills[skill], "file"))
+	}
+	b.WriteString("\nCommits:\n\n")
+	for _, commit := range p.Commits {
+		fmt.Fprintf(&b, "- %s\n", commit)
+	}
+	return redact(title), redact(strings.TrimSpace(b.String()))
+}
+
+// countOf returns "1 noun" or "n nouns".
+func countOf(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// updateOpenPromotion refreshes the title and body of a roll-up PR that is
+// already open, with the API tool, and returns its URL; "" if there is
+// none to update.
+func updateOpenPromotion(base, staging, title, body string) string {
+	repoPath := getGitHubRepoPath()
+	if getPRTool() != prToolAPI || repoPath == "" {
+		return ""
+	}
+	owner, _, _ := strings.Cut(repoPath, "/")
+	var open []struct {
+		Number  int    `json:"number"`
+		HTMLURL string `json:"html_url"`
+	}
+	query := url.Values{"head": {owner + ":" + staging}, "base": {base}, "state": {"open"}}
+	if err := githubGet(fmt.Sprintf("/repos/%s/pulls?%s", repoPath, query.Encode()), &open); err != nil || len(open) == 0 {
+		return ""
+	}
+	path := fmt.Sprintf("/repos/%s/pulls/%d", repoPath, open[0].Number)
+	if err := githubDo(http.MethodPatch, path, map[string]string{"title": title, "body": body}, nil); err != nil {
+		warnf("failed to update PR #%d: %v", open[0].Number, err)
+	}
+	return open[0].HTMLURL
+}
+
+// runPromote implements `prrompt promote [--dry-run]`: it opens the roll-up
+// PR from prrompt.stagingBranch to the base branch, listing the skills and
+// commits it brings, or updates the one already open.
+func runPromote(args []string) error {
+	dryRun := false
+	for _, arg := range args {
+		if arg != "--dry-run" {
+			return fmt.Errorf("usage: %s promote [--dry-run]", toolName)
+		}
+		dryRun = true
+	}
+	staging := getStagingBranch()
+	if staging == "" {
+		return fmt.Errorf("prrompt.stagingBranch is not set, extraction PRs go straight to %s", getBaseBranch())
+	}
+	base, remote := getBaseBranch(), getRemote()
+	p, err := collectPromotion(base, staging)
+	if err != nil {
+		return err
+	}
+	if len(p.Commits) == 0 {
+		fmt.Printf("Nothing to promote, %s has no changes that are not on %s\n", staging, base)
+		return nil
+	}
+	title, body := p.titleAndBody()
+	if dryRun {
+		fmt.Printf("%s\n\n%s\n", title, body)
+		return nil
+	}
+	if !hasRemote(remote) || !branchExists(remote+"/"+staging) {
+		return fmt.Errorf("%s is not on %s, push it first", staging, remote)
+	}
+
+	prURL := updateOpenPromotion(base, staging, title, body)
+	if prURL != "" {
+		fmt.Printf("Updated the promotion of %s: %s\n", countOf(len(p.Commits), "commit"), prURL)
+		return nil
+	}
+	meta := prMetadata{
+		Labels:    getListConfig("prrompt.prLabels"),
+		Reviewers: getListConfig("prrompt.prReviewers"),
+		Assignees: getListConfig("prrompt.prAssignees"),
+	}
+	if prURL = createPR(base, staging, title, body, meta); prURL == "" {
+		return fmt.Errorf("open a PR from %s to %s on %s", staging, base, remote)
+	}
+	fmt.Printf("Promoting %s: %s\n", countOf(len(p.Commits), "commit"), prURL)
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_StagingBranch(t *testing.T) {
+	repo, commitSHA := setupPushableRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.stagingBranch", "prompts-staging")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if err := runPromote(nil); err == nil || !strings.Contains(err.Error(), "prompts-staging does not exist") {
+		t.Errorf("Expected no promotion before the staging branch exists, got %v", err)
+	}
+
+	result, err := processCommit(commitSHA)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	main, _ := runGit("rev-parse", "main")
+	if staging, _ := runGit("ls-remote", "origin", "refs/heads/prompts-staging"); !strings.HasPrefix(staging, main) {
+		t.Errorf("Expected prompts-staging created from main on the remote, got %q", staging)
+	}
+	if !strings.Contains(result.PRURL, "/compare/prompts-staging..."+result.Branch) {
+		t.Errorf("Expected the PR to target prompts-staging, got %s", result.PRURL)
+	}
+	baseStartPoints = map[string]string{}
+	if p, err := collectPromotion("main", "prompts-staging"); err != nil || len(p.Commits) != 0 {
+		t.Errorf("Expected nothing to promote yet, got %+v (err %v)", p, err)
+	}
+
+	// The extraction PR is merged into staging
+	runGit("push", "-q", "origin", result.Branch+":prompts-staging")
+	baseStartPoints = map[string]string{}
+	p, err := collectPromotion("main", "prompts-staging")
+	if err != nil {
+		t.Fatalf("collectPromotion failed: %v", err)
+	}
+	if len(p.Commits) != 1 || p.Skills["prompts/test.md"] != 1 {
+		t.Errorf("Expected one commit changing prompts/test.md, got %+v", p)
+	}
+	title, body := p.titleAndBody()
+	if title != "[prompt] Promote 1 skill from prompts-staging" || !strings.Contains(body, "- `prompts/test.md` (1 file)") || !strings.Contains(body, "Add prompt file") {
+		t.Errorf("Unexpected roll-up PR %q:\n%s", title, body)
+	}
+
+	var created map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/widgets/pulls":
+			if r.URL.Query().Get("head") != "acme:prompts-staging" {
+				t.Errorf("Unexpected query %s", r.URL.RawQuery)
+			}
+			w.Write([]byte(`[]`))
+		case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/widgets/pulls":
+			json.NewDecoder(r.Body).Decode(&created)
+			w.Write([]byte(`{"number": 9, "html_url": "https://github.com/acme/widgets/pull/9"}`))
+		default:
+			t.Errorf("Unexpected request %s %s", r.Method, r.URL)
+		}
+	}))
+	defer server.Close()
+	defer func(url string) { githubAPIURL = url }(githubAPIURL)
+	githubAPIURL = server.URL
+	runGitInDir(repo.Dir, "config", "prrompt.prTool", "api")
+	runGitInDir(repo.Dir, "config", "prrompt.changeType", "false")
+
+	if err := runPromote(nil); err != nil {
+		t.Fatalf("promote failed: %v", err)
+	}
+	if created["head"] != "prompts-staging" || created["base"] != "main" || created["title"] != title {
+		t.Errorf("Expected a roll-up PR from prompts-staging to main, got %v", created)
+	}
+
+	// --base still goes straight to the given branch
+	t.Setenv(baseEnv, "main")
+	if target := getTargetBranch(); target != "main" {
+		t.Errorf("Expected --base to override the staging branch, got %s", target)
+	}
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// preCommitMarker identifies the pre-commit hook prrompt installs, so that
+// someone else's hook is never overwritten.
+const preCommitMarker = "# prrompt pre-commit hook"
+
+// stagedFiles returns the staged prompt and other files, with renames
+// split into their deleted and added halves.
+func stagedFiles() (prompt, other []string, err error) {
+	output, err := runGit("diff", "--cached", "--name-only", "--no-renames")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to list staged files: %s", output)
+	}
+	for _, file := range strings.Split(output, "\n") {
+		if file == "" {
+			continue
+		}
+		if isPromptFile(file) {
+			prompt = append(prompt, file)
+		} else {
+			other = append(other, file)
+		}
+	}
+	return prompt, other, nil
+}
+
+// runPreCommit implements `prrompt pre-commit`, run by the pre-commit hook:
+// with prrompt.strict it fails commits whose staged changes mix prompt and
+// other files, so prompts always land in commits of their own.
+func runPreCommit() error {
+	if !getBoolConfig("prrompt.strict", false) || !getBoolConfig("prrompt.enabled", true) || os.Getenv("PRROMPT_SKIP") == "1" {
+		return nil
+	}
+	// Merges are mixed by nature, and prrompt's own commits are prompt-only
+	if _, err := runGit("rev-parse", "-q", "--verify", "MERGE_HEAD"); err == nil {
+		return nil
+	}
+	if branch, err := currentBranch(); err == nil && strings.HasPrefix(branch, getBranchPrefix()+"/") {
+		return nil
+	}
+	prompt, other, err := stagedFiles()
+	if err != nil || len(prompt) == 0 || len(other) == 0 {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("This commit mixes prompt and other changes (prrompt.strict):\n")
+	for _, file := range prompt {
+		fmt.Fprintf(&b, "  prompt  %s\n", file)
+	}
+	for _, file := range other {
+		fmt.Fprintf(&b, "  other   %s\n", file)
+	}
+	fmt.Fprintf(&b, "Commit the prompt files separately, or run '%s split --staged' to do it for you.\n", toolName)
+	b.WriteString("To commit anyway, use 'git commit --no-verify' or PRROMPT_SKIP=1.")
+	return errors.New(b.String())
+}
+
+// runGitAttached runs git with the terminal attached, for commands that may
+// open an editor.
+func runGitAttached(args ...string) error {
+	debugf("git %s", strings.Join(args, " "))
+	cmd := exec.Command("git", args...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	return cmd.Run()
+}
+
+// runSplit implements `prrompt split --staged [-m <message>]`: it commits
+// the staged changes as two commits, first the other files and then the
+// prompt files, restoring the index if either commit fails. Without a
+// message, git asks for each.
+func runSplit(args []string) error {
+	staged, message := false, ""
+	for i := 0; i < len(args); i++ {
+		switch arg := args[i]; {
+		case arg == "--staged":
+			staged = true
+		case (arg == "-m" || arg == "--message") && i+1 < len(args):
+			i++
+			message = args[i]
+		case strings.HasPrefix(arg, "--message="):
+			message = strings.TrimPrefix(arg, "--message=")
+		default:
+			return fmt.Errorf("usage: %s split --staged [-m <message>]", toolName)
+		}
+	}
+	if !staged {
+		return fmt.Errorf("usage: %s split --staged [-m <message>]", toolName)
+	}
+	prompt, other, err := stagedFiles()
+	if err != nil {
+		return err
+	}
+	if len(prompt) == 0 || len(other) == 0 {
+		return fmt.Errorf("the staged changes do not mix prompt and other files, nothing to split")
+	}
+
+	// The whole staged state, to restage from and to restore on failure
+	index, err := runGit("write-tree")
+	if err != nil {
+		return fmt.Errorf("failed to save the index: %s", index)
+	}
+	restore := func(cause error) error {
+		runGit("read-tree", index)
+		return cause
+	}
+	commit := func(subject string, paths []string) error {
+		if output, err := runGit(append([]string{"reset", "-q", index, "--"}, paths...)...); err != nil {
+			return fmt.Errorf("failed to stage: %s", output)
+		}
+		args := []string{"commit"}
+		if subject != "" {
+			args = append(args, "-m", subject)
+		}
+		return runGitAttached(args...)
+	}
+
+	if output, err := runGit(append([]string{"reset", "-q", "HEAD", "--"}, prompt...)...); err != nil {
+		return restore(fmt.Errorf("failed to unstage prompt files: %s", output))
+	}
+	if err := commit(message, other); err != nil {
+		return restore(fmt.Errorf("failed to commit the other files: %w", err))
+	}
+	promptMessage := message
+	if message != "" {
+		subject, body, _ := strings.Cut(message, "\n")
+		promptMessage = subject + " (prompts)" + "\n" + body
+	}
+	if err := commit(promptMessage, prompt); err != nil {
+		return restore(fmt.Errorf("failed to commit the prompt files, the other files are committed: %w", err))
+	}
+	infof("Split the staged changes into a commit of %d other files and one of %d prompt files", len(other), len(prompt))
+	return nil
+}
+
+// installPreCommitHook installs the hook running `prrompt pre-commit`,
+// unless a pre-commit hook of another tool is in the way.
+func installPreCommitHook(hooksDir, exePath string) error {
+	hookPath := filepath.Join(hooksDir, "pre-commit")
+	if data, err := os.ReadFile(hookPath); err == nil && !strings.Contains(string(data), preCommitMarker) {
+		warnf("%s already exists, add '%s pre-commit' to it to enforce prrompt.strict", hookPath, exePath)
+		return nil
+	}
+	if err := os.WriteFile(hookPath, []byte(preCommitHookScript(exePath)), 0755); err != nil {
+		return fmt.Errorf("failed to write hook: %w", err)
+	}
+	fmt.Printf("✓ Installed pre-commit hook at %s\n", hookPath)
+	return nil
+}
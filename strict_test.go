@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func stageFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+		if output, err := runGitInDir(dir, "add", name); err != nil {
+			t.Fatalf("failed to stage %s: %s", name, output)
+		}
+	}
+}
+
+func Test_PreCommitStrict(t *testing.T) {
+	repo := setupTestRepo(t)
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	stageFiles(t, repo.Dir, map[string]string{"prompts/a.md": "# A", "src/main.go": "package main"})
+	if err := runPreCommit(); err != nil {
+		t.Errorf("Expected mixed commits to pass without prrompt.strict, got %v", err)
+	}
+	runGitInDir(repo.Dir, "config", "prrompt.strict", "true")
+	err := runPreCommit()
+	if err == nil || !strings.Contains(err.Error(), "prompt  prompts/a.md") || !strings.Contains(err.Error(), "split --staged") {
+		t.Fatalf("Expected the mixed commit to be refused with guidance, got %v", err)
+	}
+	t.Setenv("PRROMPT_SKIP", "1")
+	if err := runPreCommit(); err != nil {
+		t.Errorf("Expected PRROMPT_SKIP to let the commit through, got %v", err)
+	}
+	t.Setenv("PRROMPT_SKIP", "")
+
+	runGitInDir(repo.Dir, "reset", "-q", "--", "src/main.go")
+	if err := runPreCommit(); err != nil {
+		t.Errorf("Expected a prompt-only commit to pass, got %v", err)
+	}
+}
+
+func Test_SplitStaged(t *testing.T) {
+	repo := setupTestRepo(t)
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	stageFiles(t, repo.Dir, map[string]string{"prompts/a.md": "# A", "src/main.go": "package main"})
+	// Unstaged edits stay in the working tree
+	os.WriteFile(filepath.Join(repo.Dir, "prompts/a.md"), []byte("# A, unstaged"), 0644)
+	before, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runSplit([]string{"--staged", "-m", "Add main"}); err != nil {
+		t.Fatalf("split failed: %v", err)
+	}
+	log, _ := runGitInDir(repo.Dir, "log", "--format=%s", before+"..HEAD")
+	if log != "Add main (prompts)\nAdd main" {
+		t.Errorf("Expected two commits, got %q", log)
+	}
+	if files, _ := runGitInDir(repo.Dir, "show", "--format=", "--name-only", "HEAD~1"); files != "src/main.go" {
+		t.Errorf("Expected the other files first, got %q", files)
+	}
+	if content, _ := runGitInDir(repo.Dir, "show", "HEAD:prompts/a.md"); content != "# A" {
+		t.Errorf("Expected the staged prompt content, got %q", content)
+	}
+	if status, _ := runGitInDir(repo.Dir, "status", "--porcelain"); status != "M prompts/a.md" {
+		t.Errorf("Expected the unstaged edit to be kept, got %q", status)
+	}
+
+	if err := runSplit([]string{"--staged"}); err == nil {
+		t.Errorf("Expected nothing to split")
+	}
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// subjectNoise is what the subject rewriter strips by default: ticket
+// prefixes ("ABC-123: ", "[ABC-123]"), trailing PR references ("(#42)") and
+// gitmoji shortcodes (":sparkles:").
+var subjectNoise = []*regexp.Regexp{
+	regexp.MustCompile(`^\s*\[?[A-Z][A-Z0-9]+-\d+\]?\s*[:\-]?\s*`),
+	regexp.MustCompile(`\s*\(#\d+\)\s*$`),
+	regexp.MustCompile(`:[a-z0-9_+\-]+:`),
+}
+
+// getSubjectStripPatterns compiles every prrompt.subjectStrip value, removed
+// from subjects on top of the defaults. Invalid patterns are reported and
+// ignored.
+func getSubjectStripPatterns() []*regexp.Regexp {
+	value, err := gitConfig("--get-all", "prrompt.subjectStrip")
+	if err != nil || value == "" {
+		return nil
+	}
+	var patterns []*regexp.Regexp
+	for _, expr := range strings.Split(value, "\n") {
+		expr = strings.TrimSpace(expr)
+		if expr == "" {
+			continue
+		}
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			warnf("ignoring invalid prrompt.subjectStrip %q: %v", expr, err)
+			continue
+		}
+		patterns = append(patterns, re)
+	}
+	return patterns
+}
+
+// rewriteSubject turns a commit subject into a concise prompt-focused one,
+// without ticket prefixes, emojis, PR references, non-prompt conventional
+// commit headers and whatever prrompt.subjectStrip matches. A prompt header
+// like "chore(prompts): " is kept. It returns the subject unchanged when
+// nothing would be left.
+func rewriteSubject(subject string) string {
+	header := ""
+	rest := subject
+	if match := conventionalHeader.FindString(subject); match != "" {
+		if hasPromptHeader(subject) {
+			header = match
+		}
+		rest = subject[len(match):]
+	}
+
+	for _, re := range append(append([]*regexp.Regexp{}, subjectNoise...), getSubjectStripPatterns()...) {
+		rest = re.ReplaceAllString(rest, " ")
+	}
+	rest = strings.Map(func(r rune) rune {
+		if unicode.Is(unicode.So, r) || unicode.Is(unicode.Sk, r) || r == '\u200d' || r == '\ufe0f' {
+			return -1
+		}
+		return r
+	}, rest)
+	rest = strings.Trim(strings.Join(strings.Fields(rest), " "), " -:")
+	if rest == "" {
+		return subject
+	}
+	if header == "" {
+		first := []rune(rest)
+		first[0] = unicode.ToUpper(first[0])
+		rest = string(first)
+	}
+	return header + rest
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_rewriteSubject(t *testing.T) {
+	tests := map[string]string{
+		"ABC-123: tighten the review prompt":        "Tighten the review prompt",
+		"[ABC-123] ✨ tighten the review prompt":     "Tighten the review prompt",
+		"feat(api): add endpoint and triage prompt": "Add endpoint and triage prompt",
+		"chore(prompts): :memo: clarify tone (#42)": "chore(prompts): clarify tone",
+		"Tighten the review prompt":                 "Tighten the review prompt",
+		"🚀":                                         "🚀",
+	}
+	for subject, want := range tests {
+		if got := rewriteSubject(subject); got != want {
+			t.Errorf("rewriteSubject(%q) = %q, want %q", subject, got, want)
+		}
+	}
+}
+
+func Test_SubjectRewriteMessage(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.subjectRewrite", "true")
+	runGitInDir(repo.Dir, "config", "prrompt.subjectStrip", " and update the client$")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	info := &CommitInfo{SHA: "abc1234", SourceBranch: "feature", Message: "WEB-7: 🐛 fix the triage prompt and update the client\n\nDetails."}
+	msg := buildCommitMessage(info)
+	subject, body, _ := strings.Cut(msg, "\n")
+	if subject != "["+defaultCommitPrefix+"] Fix the triage prompt" {
+		t.Errorf("Unexpected subject %q", subject)
+	}
+	if !strings.Contains(body, info.Message) {
+		t.Errorf("Expected the original message in the body, got %q", body)
+	}
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"errors"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// temps are the temporary files and directories prrompt created and has
+// not removed yet, removed on an interrupt too.
+var temps = struct {
+	sync.Mutex
+	paths map[string]bool
+}{paths: make(map[string]bool)}
+
+// getTmpDir returns prrompt.tmpDir, else the system's (TMPDIR). A
+// directory inside the work tree is refused, since files left there could
+// be committed and extracted; one under .git is fine.
+func getTmpDir() string {
+	value, _ := gitConfig("--get", "prrompt.tmpDir")
+	if strings.TrimSpace(value) == "" {
+		return os.TempDir()
+	}
+	dir := tmpDirPath(value)
+	if insideWorkTree(dir) {
+		warnf("prrompt.tmpDir %s is inside the work tree, using %s", dir, os.TempDir())
+		return os.TempDir()
+	}
+	return dir
+}
+
+// tmpDirPath returns the absolute path of a prrompt.tmpDir value, which may
+// start with ~/.
+func tmpDirPath(value string) string {
+	dir := strings.TrimSpace(value)
+	if dir == "~" || strings.HasPrefix(dir, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			dir = filepath.Join(home, strings.TrimPrefix(dir, "~"))
+		}
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return dir
+	}
+	return abs
+}
+
+// insideWorkTree reports whether path is in the work tree but not in the
+// git directory.
+func insideWorkTree(path string) bool {
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil || !within(toplevel, path) {
+		return false
+	}
+	for _, flag := range []string{"--git-dir", "--git-common-dir"} {
+		if dir, err := runGit("rev-parse", "--path-format=absolute", flag); err == nil && within(dir, path) {
+			return false
+		}
+	}
+	return true
+}
+
+func within(dir, path string) bool {
+	rel, err := filepath.Rel(resolvePath(dir), resolvePath(path))
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// resolvePath resolves the symlinks of path's longest existing prefix, so
+// /tmp and /private/tmp compare equal on macOS.
+func resolvePath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	if parent := filepath.Dir(path); parent != path {
+		return filepath.Join(resolvePath(parent), filepath.Base(path))
+	}
+	return path
+}
+
+// makeTempDir creates a temporary directory in getTmpDir, removed by
+// removeTemp or on an interrupt.
+func makeTempDir(pattern string) (string, error) {
+	base := getTmpDir()
+	if err := os.MkdirAll(base, 0700); err != nil {
+		return "", err
+	}
+	dir, err := os.MkdirTemp(base, pattern)
+	if err != nil {
+		return "", err
+	}
+	trackTemp(dir)
+	return dir, nil
+}
+
+// makeTempFile is makeTempDir for a file.
+func makeTempFile(pattern string) (*os.File, error) {
+	base := getTmpDir()
+	if err := os.MkdirAll(base, 0700); err != nil {
+		return nil, err
+	}
+	file, err := os.CreateTemp(base, pattern)
+	if err != nil {
+		return nil, err
+	}
+	trackTemp(file.Name())
+	return file, nil
+}
+
+func trackTemp(path string) {
+	temps.Lock()
+	defer temps.Unlock()
+	temps.paths[path] = true
+}
+
+// removeTemp removes a temporary file or directory.
+func removeTemp(path string) {
+	temps.Lock()
+	defer temps.Unlock()
+	delete(temps.paths, path)
+	os.RemoveAll(path)
+}
+
+// keepTemp leaves a temporary file behind on purpose, e.g. the output of a
+// push left running in the background.
+func keepTemp(path string) {
+	temps.Lock()
+	defer temps.Unlock()
+	delete(temps.paths, path)
+}
+
+// cleanupTemps removes every temporary file and directory still around.
+func cleanupTemps() {
+	temps.Lock()
+	defer temps.Unlock()
+	for path := range temps.paths {
+		os.RemoveAll(path)
+		delete(temps.paths, path)
+	}
+}
+
+// interruptCode is the exit code of the signal that interrupted a run
+// holding the lock, or 0.
+var interruptCode atomic.Int32
+
+// interrupted returns an error once prrompt has been interrupted, for the
+// run to stop at its next step.
+func interrupted() error {
+	if code := interruptCode.Load(); code != 0 {
+		return withExitCode(int(code), errors.New("interrupted"))
+	}
+	return nil
+}
+
+// cleanupTempsOnSignal handles an interrupt or termination. Without the
+// lock prrompt removes the temporary files and exits as the signal would
+// have. A run holding it stops at its next step instead, so that the
+// journal and the lock are cleaned up on the way out, and exits with the
+// signal's code from main; a second signal exits right away.
+func cleanupTempsOnSignal() {
+	signals := make(chan os.Signal, 2)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		for sig := range signals {
+			code := int32(143)
+			if sig == os.Interrupt {
+				code = 130
+			}
+			if locksHeld.Load() > 0 && interruptCode.CompareAndSwap(0, code) {
+				warnf("interrupted, stopping after the current step (again to quit now)")
+				continue
+			}
+			cleanupTemps()
+			os.Exit(int(code))
+		}
+	}()
+}
+
+// exit ends prrompt with code, or with that of the signal that interrupted
+// it.
+func exit(code int) {
+	if interrupt := interruptCode.Load(); interrupt != 0 {
+		cleanupTemps()
+		code = int(interrupt)
+	}
+	os.Exit(code)
+}
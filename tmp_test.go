@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func Test_TmpDir(t *testing.T) {
+	repo := setupTestRepo(t)
+	tmpDir := t.TempDir()
+	runGitInDir(repo.Dir, "config", "prrompt.tmpDir", tmpDir)
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	dir, err := makeTempDir("prrompt-test-")
+	if err != nil || !strings.HasPrefix(dir, tmpDir+string(filepath.Separator)) {
+		t.Fatalf("Expected a directory in %s, got %s (err %v)", tmpDir, dir, err)
+	}
+	file, err := makeTempFile("prrompt-test-")
+	if err != nil {
+		t.Fatalf("makeTempFile failed: %v", err)
+	}
+	file.Close()
+	cleanupTemps()
+	for _, path := range []string{dir, file.Name()} {
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Expected %s removed", path)
+		}
+	}
+
+	// Never in the work tree, where it could be committed
+	if err := validTmpDir("tmp"); err == nil {
+		t.Error("Expected a directory in the work tree to be invalid")
+	}
+	runGitInDir(repo.Dir, "config", "prrompt.tmpDir", "tmp")
+	if got := getTmpDir(); got != os.TempDir() {
+		t.Errorf("Expected the system directory instead of one in the work tree, got %s", got)
+	}
+	if err := validTmpDir(".git/prrompt/tmp"); err != nil {
+		t.Errorf("Expected a directory under .git to be valid, got %v", err)
+	}
+}
+
+func Test_InterruptWhileLocked(t *testing.T) {
+	repo := setupTestRepo(t)
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	sha := commitFiles(t, repo.Dir, "Add a prompt", map[string]string{"prompts/a.md": "# A\n"})
+	cleanupTempsOnSignal()
+	defer signal.Reset(os.Interrupt, syscall.SIGTERM)
+	defer interruptCode.Store(0)
+	release, err := acquireLock()
+	if err != nil {
+		t.Fatalf("acquireLock failed: %v", err)
+	}
+	defer release()
+
+	// Holding the lock, the run is told to stop rather than killed
+	syscall.Kill(os.Getpid(), syscall.SIGTERM)
+	for i := 0; i < 100 && interrupted() == nil; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err := interrupted(); exitCodeOf(err) != 143 {
+		t.Fatalf("Expected the run to be interrupted with 143, got %v", err)
+	}
+	if _, err := checkoutPromptBranch("prompts/interrupted", []*CommitInfo{{SHA: sha, SourceBranch: repo.BranchName}}, false); exitCodeOf(err) != 143 {
+		t.Errorf("Expected the extraction to stop, got %v", err)
+	}
+	if j, _ := loadJournal(); j != nil {
+		t.Errorf("Expected the journal removed on the way out, got %+v", j)
+	}
+	if current, _ := runGit("symbolic-ref", "--short", "HEAD"); current != repo.BranchName {
+		t.Errorf("Expected to be back on %s, got %s", repo.BranchName, current)
+	}
+}
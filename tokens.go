@@ -0,0 +1,86 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	estimateChars = "chars"
+	estimateWords = "words"
+)
+
+type TokenCount struct {
+	File   string
+	Before int
+	After  int
+}
+
+func (c TokenCount) Delta() int {
+	return c.After - c.Before
+}
+
+// estimateTokens approximates the token count of content. No tokenizer is
+// run: both estimates follow the usual rules of thumb for BPE tokenizers on
+// English text, "chars" ~4 characters per token, "words" ~0.75 words per
+// token, and can be off for code, other languages or a given model.
+func estimateTokens(content, estimate string) int {
+	if content == "" {
+		return 0
+	}
+	if estimate == estimateWords {
+		words := len(strings.FieldsFunc(content, unicode.IsSpace))
+		return (words*4 + 2) / 3
+	}
+	return (utf8.RuneCountInString(content) + 3) / 4
+}
+
+// countPromptTokens estimates each prompt file's tokens before (first
+// parent) and after the commit.
+func countPromptTokens(info *CommitInfo) []TokenCount {
+	estimate := getTokenEstimate()
+	counts := make([]TokenCount, 0, len(info.PromptFiles))
+	for _, file := range info.PromptFiles {
+		counts = append(counts, TokenCount{
+			File:   file,
+			Before: estimateTokens(showFile(info.parent(), file), estimate),
+			After:  estimateTokens(showFile(info.SHA, file), estimate),
+		})
+	}
+	return counts
+}
+
+// showFile returns the content of path at ref, or "" when it does not exist.
+// The output is not trimmed, unlike runGit.
+func showFile(ref, path string) string {
+	output, err := runGitBytes(nil, nil, "show", ref+":"+path)
+	if err != nil {
+		return ""
+	}
+	return string(output)
+}
+
+func warnTokenBudget(counts []TokenCount, budget int) {
+	if budget <= 0 {
+		return
+	}
+	for _, c := range counts {
+		if c.After > budget {
+			warnf("%s is ~%d tokens, over the budget of %d", c.File, c.After, budget)
+		}
+	}
+}
+
+func formatTokenCounts(counts []TokenCount) string {
+	var b strings.Builder
+	b.WriteString("Prompt tokens (estimated):\n")
+	total := 0
+	for _, c := range counts {
+		fmt.Fprintf(&b, "- %s: %d -> %d (%+d)\n", c.File, c.Before, c.After, c.Delta())
+		total += c.Delta()
+	}
+	fmt.Fprintf(&b, "Total change: %+d", total)
+	return b.String()
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_estimateTokens(t *testing.T) {
+	tests := []struct {
+		content  string
+		estimate string
+		want     int
+	}{
+		{"", estimateChars, 0},
+		{"abcdefgh", estimateChars, 2},
+		{"abcdefghi", estimateChars, 3},
+		{"one two three", estimateWords, 4},
+	}
+	for _, tt := range tests {
+		if got := estimateTokens(tt.content, tt.estimate); got != tt.want {
+			t.Errorf("estimateTokens(%q, %s) = %d, want %d", tt.content, tt.estimate, got, tt.want)
+		}
+	}
+}
+
+func Test_TokenBudgetInCommitBody(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.tokenBudget", "5")
+
+	promptFile := filepath.Join(repo.Dir, "prompts/test.md")
+	os.MkdirAll(filepath.Dir(promptFile), 0755)
+	os.WriteFile(promptFile, []byte(strings.Repeat("x", 40)), 0644)
+	runGitInDir(repo.Dir, "add", promptFile)
+	runGitInDir(repo.Dir, "commit", "-m", "Add prompt file")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	commitMsg, _ := runGitInDir(repo.Dir, "log", "--format=%B", "-n", "1", defaultBranchPrefix+"/"+commitSHA[:7])
+	if !strings.Contains(commitMsg, "- prompts/test.md: 0 -> 10 (+10)") {
+		t.Errorf("Expected token counts in commit body, got: %s", commitMsg)
+	}
+	trailers, _ := runGitInDir(repo.Dir, "log", "--format=%(trailers:only)", "-n", "1", defaultBranchPrefix+"/"+commitSHA[:7])
+	if !strings.Contains(trailers, trailerSourceCommit) {
+		t.Errorf("Expected trailers to follow token counts, got: %s", trailers)
+	}
+}
+
+func Test_showFile(t *testing.T) {
+	repo := setupTestRepo(t)
+	sha := commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/a.md": "# A\n\n"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if got := showFile(sha, "prompts/a.md"); got != "# A\n\n" {
+		t.Errorf("Expected the content as committed, got %q", got)
+	}
+	if got := showFile(sha, "prompts/missing.md"); got != "" {
+		t.Errorf("Expected nothing for a missing file, got %q", got)
+	}
+}
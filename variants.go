@@ -0,0 +1,96 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// controlVariants are the `variant:` values that mark an experiment's
+// control arm.
+var controlVariants = []string{"a", "control"}
+
+type promptVariant struct {
+	File       string
+	Experiment string
+	Variant    string
+}
+
+func (v promptVariant) IsControl() bool {
+	for _, control := range controlVariants {
+		if strings.EqualFold(v.Variant, control) {
+			return true
+		}
+	}
+	return false
+}
+
+func readVariant(ref, file string) (promptVariant, bool) {
+	fields, ok := parseFrontmatter(showFile(ref, file))
+	if !ok || fields["experiment"] == "" {
+		return promptVariant{}, false
+	}
+	return promptVariant{File: file, Experiment: fields["experiment"], Variant: fields["variant"]}, true
+}
+
+// groupVariants pulls every variant of the experiments a commit touches into
+// the extraction, so an experiment is always reviewed as a whole, and warns
+// when variants changed without their control. Prompt files are grouped by
+// the `experiment:` frontmatter key; `variant:` names the arm.
+func groupVariants(info *CommitInfo) error {
+	changed := make(map[string][]promptVariant)
+	for _, file := range info.PromptFiles {
+		if variant, ok := readVariant(info.SHA, file); ok {
+			changed[variant.Experiment] = append(changed[variant.Experiment], variant)
+		}
+	}
+	if len(changed) == 0 {
+		return nil
+	}
+
+	tree, err := runGit("ls-tree", "-r", "--name-only", info.SHA)
+	if err != nil {
+		return fmt.Errorf("failed to list files: %w", err)
+	}
+
+	inCommit := make(map[string]bool, len(info.PromptFiles))
+	for _, file := range info.PromptFiles {
+		inCommit[file] = true
+	}
+
+	for _, file := range strings.Split(tree, "\n") {
+		if file == "" || inCommit[file] || !isPromptFile(file) {
+			continue
+		}
+		variant, ok := readVariant(info.SHA, file)
+		if !ok || changed[variant.Experiment] == nil {
+			continue
+		}
+		info.VariantFiles = append(info.VariantFiles, file)
+	}
+
+	for experiment, variants := range changed {
+		info.Experiments = append(info.Experiments, experiment)
+
+		controlChanged := false
+		for _, variant := range variants {
+			if variant.IsControl() {
+				controlChanged = true
+			}
+		}
+		if !controlChanged {
+			warnf("experiment %s: variant changed without its control (%s)", experiment, variants[0].File)
+		}
+	}
+	sort.Strings(info.Experiments)
+	return nil
+}
+
+// experimentLabels returns the PR labels for the given experiment IDs.
+func experimentLabels(experiments []string) []string {
+	labels := make([]string, 0, len(experiments))
+	for _, experiment := range experiments {
+		labels = append(labels, "experiment:"+experiment)
+	}
+	return labels
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_parseFrontmatter(t *testing.T) {
+	fields, ok := parseFrontmatter("---\nname: review\nexperiment: \"exp-42\"\ntools:\n  - Read\n---\n# Body\n")
+	if !ok {
+		t.Fatal("Expected frontmatter to parse")
+	}
+	if fields["name"] != "review" || fields["experiment"] != "exp-42" {
+		t.Errorf("Unexpected fields: %v", fields)
+	}
+	if _, ok := parseFrontmatter("# No frontmatter\n"); ok {
+		t.Error("Expected no frontmatter")
+	}
+	if _, ok := parseFrontmatter("---\nname: unclosed\n"); ok {
+		t.Error("Expected unclosed frontmatter to be rejected")
+	}
+}
+
+func Test_VariantGrouping(t *testing.T) {
+	repo := setupTestRepo(t)
+
+	write := func(name, content string) string {
+		file := filepath.Join(repo.Dir, name)
+		os.MkdirAll(filepath.Dir(file), 0755)
+		os.WriteFile(file, []byte(content), 0644)
+		return file
+	}
+
+	control := write("prompts/review.md", "---\nexperiment: exp-42\nvariant: a\n---\nBe thorough.\n")
+	write("prompts/other.md", "# Unrelated\n")
+	runGitInDir(repo.Dir, "add", ".")
+	runGitInDir(repo.Dir, "commit", "-m", "Start experiment [skip prrompt]")
+
+	variant := write("prompts/review-b.md", "---\nexperiment: exp-42\nvariant: b\n---\nBe brief.\n")
+	runGitInDir(repo.Dir, "add", variant)
+	runGitInDir(repo.Dir, "commit", "-m", "Add variant b")
+	commitSHA, _ := runGitInDir(repo.Dir, "rev-parse", "HEAD")
+
+	if err := runPrrompt(t, repo.Dir, commitSHA); err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+
+	promptBranch := defaultBranchPrefix + "/" + commitSHA[:7]
+	files, _ := runGitInDir(repo.Dir, "ls-tree", "-r", "--name-only", promptBranch)
+	if !strings.Contains(files, "prompts/review.md") || !strings.Contains(files, "prompts/review-b.md") {
+		t.Errorf("Expected both variants on %s. Files: %s", promptBranch, files)
+	}
+	if strings.Contains(files, "prompts/other.md") {
+		t.Errorf("Unrelated prompts should not be grouped. Files: %s", files)
+	}
+	if _, err := os.Stat(control); err != nil {
+		t.Errorf("Control file should remain in the working tree: %v", err)
+	}
+
+	trailers, _ := runGitInDir(repo.Dir, "log", "--format=%(trailers:only)", "-n", "1", promptBranch)
+	if !strings.Contains(trailers, trailerExperiment+": exp-42") {
+		t.Errorf("Expected experiment trailer, got: %s", trailers)
+	}
+}
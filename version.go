@@ -0,0 +1,323 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strconv"
+	"strings"
+)
+
+// Set at build time, as goreleaser does:
+//
+//	go build -ldflags "-X main.version=1.2.3 -X main.commit=abc1234 -X main.date=2026-01-02"
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// releaseRepo is where `prrompt self-update` looks for releases.
+const releaseRepo = "Ilnicki010/prrompt"
+
+// checksumsSuffix ends the name of the release asset listing the SHA-256
+// of every archive, e.g. prrompt_1.2.3_checksums.txt.
+const checksumsSuffix = "checksums.txt"
+
+// getVersion returns the version prrompt was built as: the one set with
+// ldflags, else the module version of `go install`, else "unknown".
+func getVersion() string {
+	if version != "" {
+		return strings.TrimPrefix(version, "v")
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return strings.TrimPrefix(info.Main.Version, "v")
+	}
+	return "unknown"
+}
+
+// toolVersion is getVersion as releases are tagged, vX.Y.Z, for the
+// Prrompt-Tool-Version trailer.
+func toolVersion() string {
+	if v := getVersion(); v != "unknown" {
+		return "v" + v
+	}
+	return "unknown"
+}
+
+// versionString is the `prrompt version` line, with the commit and build
+// date when they were embedded.
+func versionString() string {
+	s := fmt.Sprintf("%s version %s", toolName, getVersion())
+	var details []string
+	if commit != "" {
+		details = append(details, "commit "+shortSHA(commit))
+	}
+	if date != "" {
+		details = append(details, "built "+date)
+	}
+	if len(details) > 0 {
+		s += " (" + strings.Join(details, ", ") + ")"
+	}
+	return s
+}
+
+type release struct {
+	TagName string         `json:"tag_name"`
+	Assets  []releaseAsset `json:"assets"`
+}
+
+type releaseAsset struct {
+	Name string `json:"name"`
+	URL  string `json:"browser_download_url"`
+}
+
+// releaseArchiveName returns the name of the release archive for a
+// platform, following the archive name template of .goreleaser.yaml.
+func releaseArchiveName(goos, goarch string) string {
+	arch := goarch
+	switch goarch {
+	case "amd64":
+		arch = "x86_64"
+	case "386":
+		arch = "i386"
+	}
+	return fmt.Sprintf("%s_%s_%s.tar.gz", toolName, strings.ToUpper(goos[:1])+goos[1:], arch)
+}
+
+// asset returns the release asset whose name is name or, with suffix set,
+// ends with it.
+func (r *release) asset(name string, suffix bool) *releaseAsset {
+	for i := range r.Assets {
+		if r.Assets[i].Name == name || suffix && strings.HasSuffix(r.Assets[i].Name, name) {
+			return &r.Assets[i]
+		}
+	}
+	return nil
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := httpClient.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// expectedChecksum finds the SHA-256 of name in a checksums file.
+func expectedChecksum(checksums, name string) string {
+	for _, line := range strings.Split(checksums, "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 && fields[1] == name {
+			return strings.ToLower(fields[0])
+		}
+	}
+	return ""
+}
+
+// extractBinary returns the prrompt executable from a release archive.
+func extractBinary(archive []byte) ([]byte, error) {
+	gz, err := gzip.NewReader(bytes.NewReader(archive))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil, fmt.Errorf("no %s executable in the archive", toolName)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeReg && filepath.Base(header.Name) == toolName {
+			return io.ReadAll(tr)
+		}
+	}
+}
+
+// compareVersions compares two semantic versions without a leading "v",
+// as -1, 0 or 1, with pre-releases before their release. ok is false when
+// either is not MAJOR.MINOR.PATCH[-PRERELEASE].
+func compareVersions(a, b string) (cmp int, ok bool) {
+	parse := func(v string) (core [3]int, pre []string, ok bool) {
+		v, _, _ = strings.Cut(v, "+")
+		v, prerelease, hasPre := strings.Cut(v, "-")
+		parts := strings.Split(v, ".")
+		if len(parts) != 3 || (hasPre && prerelease == "") {
+			return core, nil, false
+		}
+		for i, part := range parts {
+			n, err := strconv.Atoi(part)
+			if err != nil || n < 0 {
+				return core, nil, false
+			}
+			core[i] = n
+		}
+		if hasPre {
+			pre = strings.Split(prerelease, ".")
+		}
+		return core, pre, true
+	}
+	coreA, preA, okA := parse(a)
+	coreB, preB, okB := parse(b)
+	if !okA || !okB {
+		return 0, false
+	}
+	for i := range coreA {
+		if coreA[i] != coreB[i] {
+			return signum(coreA[i] - coreB[i]), true
+		}
+	}
+	switch {
+	case len(preA) == 0 && len(preB) == 0:
+		return 0, true
+	case len(preA) == 0:
+		return 1, true
+	case len(preB) == 0:
+		return -1, true
+	}
+	for i := 0; i < len(preA) && i < len(preB); i++ {
+		if preA[i] == preB[i] {
+			continue
+		}
+		numA, errA := strconv.Atoi(preA[i])
+		numB, errB := strconv.Atoi(preB[i])
+		switch {
+		case errA == nil && errB == nil:
+			return signum(numA - numB), true
+		case errA == nil:
+			return -1, true
+		case errB == nil:
+			return 1, true
+		}
+		return strings.Compare(preA[i], preB[i]), true
+	}
+	return signum(len(preA) - len(preB)), true
+}
+
+func signum(n int) int {
+	switch {
+	case n < 0:
+		return -1
+	case n > 0:
+		return 1
+	}
+	return 0
+}
+
+// selfUpdate replaces the executable at target with the latest release for
+// this platform, when that is newer, after checking it against the
+// release's checksums. The checksums come from the same release, so they
+// catch a corrupted download, not a tampered release. The new binary is
+// written next to target and renamed over it, so the path the hook runs
+// stays valid and is never half-written.
+func selfUpdate(target string, checkOnly, force bool) error {
+	var latest release
+	// Releases are on github.com, whatever prrompt.forgeBaseURL says
+	if err := githubDoAt(githubAPIURL, http.MethodGet, "/repos/"+releaseRepo+"/releases/latest", nil, &latest); err != nil {
+		return fmt.Errorf("failed to look up the latest release: %w", err)
+	}
+	latestVersion := strings.TrimPrefix(latest.TagName, "v")
+	current := getVersion()
+	cmp, comparable := compareVersions(current, latestVersion)
+	if comparable && cmp >= 0 && !force {
+		if cmp == 0 {
+			fmt.Printf("%s %s is the latest version\n", toolName, current)
+		} else {
+			fmt.Printf("%s %s is newer than the latest release %s, use --force to replace it\n", toolName, current, latestVersion)
+		}
+		return nil
+	}
+	if checkOnly {
+		fmt.Printf("%s %s is available (this is %s)\n", toolName, latestVersion, current)
+		return nil
+	}
+	if !comparable && !force {
+		return fmt.Errorf("this is a development build (%s), use --force to replace it with %s", current, latestVersion)
+	}
+
+	name := releaseArchiveName(runtime.GOOS, runtime.GOARCH)
+	archiveAsset, checksumsFile := latest.asset(name, false), latest.asset(checksumsSuffix, true)
+	if archiveAsset == nil {
+		return fmt.Errorf("release %s has no %s for this platform", latest.TagName, name)
+	}
+	if checksumsFile == nil {
+		return fmt.Errorf("release %s has no %s to verify the download with", latest.TagName, checksumsSuffix)
+	}
+	checksums, err := download(checksumsFile.URL)
+	if err != nil {
+		return err
+	}
+	archive, err := download(archiveAsset.URL)
+	if err != nil {
+		return err
+	}
+	sum := sha256.Sum256(archive)
+	if want := expectedChecksum(string(checksums), name); want == "" || want != hex.EncodeToString(sum[:]) {
+		return fmt.Errorf("checksum mismatch for %s, not updating", name)
+	}
+	binary, err := extractBinary(archive)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(target), "."+toolName+"-update-")
+	if err != nil {
+		return fmt.Errorf("cannot write next to %s: %w", target, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(binary); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	fmt.Printf("✓ Updated %s from %s to %s\n", target, current, latestVersion)
+	return nil
+}
+
+// runSelfUpdate implements `prrompt self-update [--check] [--force]`.
+func runSelfUpdate(args []string) error {
+	checkOnly, force := false, false
+	for _, arg := range args {
+		switch arg {
+		case "--check":
+			checkOnly = true
+		case "--force":
+			force = true
+		default:
+			return fmt.Errorf("usage: %s self-update [--check] [--force]", toolName)
+		}
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to get executable path: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(exe); err == nil {
+		exe = resolved
+	}
+	if strings.Contains(exe, "/Cellar/") && !checkOnly {
+		return fmt.Errorf("%s was installed with Homebrew, run 'brew upgrade %s' instead", toolName, toolName)
+	}
+	return selfUpdate(exe, checkOnly, force)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func Test_VersionString(t *testing.T) {
+	oldVersion, oldCommit, oldDate := version, commit, date
+	defer func() { version, commit, date = oldVersion, oldCommit, oldDate }()
+
+	version, commit, date = "v1.4.0", "0123456789abcdef", "2026-03-01"
+	if got := versionString(); got != "prrompt version 1.4.0 (commit 0123456, built 2026-03-01)" {
+		t.Errorf("Unexpected version string %q", got)
+	}
+	version, commit, date = "1.4.0", "", ""
+	if got := versionString(); got != "prrompt version 1.4.0" {
+		t.Errorf("Unexpected version string %q", got)
+	}
+
+	// The trailer keeps the tag's v, whichever way the version was set
+	for _, v := range []string{"1.4.0", "v1.4.0"} {
+		version = v
+		if got := toolVersion(); got != "v1.4.0" {
+			t.Errorf("Expected v1.4.0 for the trailer from %q, got %q", v, got)
+		}
+	}
+}
+
+func Test_ReleaseArchiveName(t *testing.T) {
+	tests := map[[2]string]string{
+		{"darwin", "arm64"}: "prrompt_Darwin_arm64.tar.gz",
+		{"linux", "amd64"}:  "prrompt_Linux_x86_64.tar.gz",
+		{"linux", "386"}:    "prrompt_Linux_i386.tar.gz",
+	}
+	for platform, want := range tests {
+		if got := releaseArchiveName(platform[0], platform[1]); got != want {
+			t.Errorf("releaseArchiveName(%v) = %q, expected %q", platform, got, want)
+		}
+	}
+}
+
+func releaseArchive(t *testing.T, binary string) []byte {
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range map[string]string{"README.md": "# prrompt", toolName: binary} {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0755, Size: int64(len(content)), Typeflag: tar.TypeReg})
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func Test_SelfUpdate(t *testing.T) {
+	oldVersion := version
+	defer func() { version = oldVersion }()
+	version = "1.0.0"
+
+	name := releaseArchiveName(runtime.GOOS, runtime.GOARCH)
+	archive := releaseArchive(t, "#!/bin/sh\necho new\n")
+	sum := sha256.Sum256(archive)
+	checksums := hex.EncodeToString(sum[:]) + "  " + name + "\n"
+
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/" + releaseRepo + "/releases/latest":
+			json.NewEncoder(w).Encode(release{TagName: "v1.1.0", Assets: []releaseAsset{
+				{name, server.URL + "/download/" + name},
+				{"prrompt_1.1.0_checksums.txt", server.URL + "/download/checksums"},
+			}})
+		case "/download/" + name:
+			w.Write(archive)
+		case "/download/checksums":
+			w.Write([]byte(checksums))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+	oldURL := githubAPIURL
+	githubAPIURL = server.URL
+	defer func() { githubAPIURL = oldURL }()
+
+	target := filepath.Join(t.TempDir(), toolName)
+	os.WriteFile(target, []byte("old"), 0755)
+
+	if err := selfUpdate(target, true, false); err != nil {
+		t.Fatalf("self-update --check failed: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "old" {
+		t.Fatalf("Expected --check to leave the executable alone")
+	}
+
+	checksums = strings.Repeat("0", 64) + "  " + name + "\n"
+	if err := selfUpdate(target, false, false); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Fatalf("Expected a checksum mismatch, got %v", err)
+	}
+	checksums = hex.EncodeToString(sum[:]) + "  " + name + "\n"
+
+	if err := selfUpdate(target, false, false); err != nil {
+		t.Fatalf("self-update failed: %v", err)
+	}
+	data, _ := os.ReadFile(target)
+	stat, _ := os.Stat(target)
+	if string(data) != "#!/bin/sh\necho new\n" || stat.Mode()&0111 == 0 {
+		t.Errorf("Expected the new executable in place, got %q (%v)", data, stat.Mode())
+	}
+
+	version = "1.1.0"
+	os.WriteFile(target, []byte("current"), 0755)
+	if err := selfUpdate(target, false, false); err != nil {
+		t.Fatalf("self-update failed: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "current" {
+		t.Errorf("Expected the latest version to be left alone")
+	}
+	// A newer build is not downgraded without --force
+	version = "1.2.0-rc.1"
+	if err := selfUpdate(target, false, false); err != nil {
+		t.Fatalf("self-update failed: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "current" {
+		t.Errorf("Expected a newer pre-release to be left alone")
+	}
+	if err := selfUpdate(target, false, true); err != nil {
+		t.Fatalf("self-update --force failed: %v", err)
+	}
+	if data, _ := os.ReadFile(target); string(data) != "#!/bin/sh\necho new\n" {
+		t.Errorf("Expected --force to replace a newer build")
+	}
+}
+
+func Test_compareVersions(t *testing.T) {
+	tests := []struct {
+		a, b string
+		cmp  int
+		ok   bool
+	}{
+		{"1.2.3", "1.2.3", 0, true},
+		{"1.2.3", "1.10.0", -1, true},
+		{"2.0.0", "1.9.9", 1, true},
+		{"1.2.0-rc.1", "1.2.0", -1, true},
+		{"1.2.0-rc.2", "1.2.0-rc.10", -1, true},
+		{"1.2.0-beta", "1.2.0-alpha", 1, true},
+		{"1.2.0+build.5", "1.2.0", 0, true},
+		{"unknown", "1.2.0", 0, false},
+		{"1.2", "1.2.0", 0, false},
+	}
+	for _, tt := range tests {
+		if cmp, ok := compareVersions(tt.a, tt.b); cmp != tt.cmp || ok != tt.ok {
+			t.Errorf("compareVersions(%q, %q) = %d, %v, want %d, %v", tt.a, tt.b, cmp, ok, tt.cmp, tt.ok)
+		}
+	}
+}
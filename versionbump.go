@@ -0,0 +1,241 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version bump levels, picked by markers in the commit message.
+const (
+	bumpPatch = "patch"
+	bumpMinor = "minor"
+	bumpMajor = "major"
+)
+
+var (
+	bumpMarker = regexp.MustCompile(`(?i)(?:^|[\s(\[])(major|minor)!(?:$|[\s)\]:,.])`)
+	semver     = regexp.MustCompile(`^(v?)(\d+)\.(\d+)\.(\d+)$`)
+)
+
+// versionBump is the frontmatter version of a prompt file before and after
+// an extraction.
+type versionBump struct {
+	File string
+	Old  string
+	New  string
+}
+
+// bumpLevel returns the bump the commit message asks for: major with
+// "major!", minor with "minor!", patch otherwise.
+func bumpLevel(message string) string {
+	level := bumpPatch
+	for _, match := range bumpMarker.FindAllStringSubmatch(message, -1) {
+		switch strings.ToLower(match[1]) {
+		case bumpMajor:
+			return bumpMajor
+		case bumpMinor:
+			level = bumpMinor
+		}
+	}
+	return level
+}
+
+// bumpVersion returns the semantic version after version bumped by level,
+// keeping a leading "v". ok is false when version is not MAJOR.MINOR.PATCH.
+func bumpVersion(version, level string) (string, bool) {
+	match := semver.FindStringSubmatch(version)
+	if match == nil {
+		return "", false
+	}
+	var parts [3]int
+	for i := range parts {
+		parts[i], _ = strconv.Atoi(match[i+2])
+	}
+	switch level {
+	case bumpMajor:
+		parts = [3]int{parts[0] + 1, 0, 0}
+	case bumpMinor:
+		parts = [3]int{parts[0], parts[1] + 1, 0}
+	default:
+		parts[2]++
+	}
+	return fmt.Sprintf("%s%d.%d.%d", match[1], parts[0], parts[1], parts[2]), true
+}
+
+// setFrontmatterVersion returns content with the value of its frontmatter
+// version field replaced by version, quoted as it was. ok is false when
+// there is no such field.
+func setFrontmatterVersion(content, version string) (string, bool) {
+	lines := strings.SplitAfter(content, "\n")
+	if len(lines) == 0 || strings.TrimSpace(strings.TrimPrefix(lines[0], "\ufeff")) != "---" {
+		return content, false
+	}
+	for i, line := range lines[1:] {
+		text := strings.TrimRight(line, "\r\n")
+		if strings.TrimSpace(text) == "---" {
+			break
+		}
+		key, value, found := strings.Cut(text, ":")
+		// Nested keys are indented
+		if !found || strings.TrimRight(key, " \t") != "version" {
+			continue
+		}
+		value = strings.TrimSpace(value)
+		quote := ""
+		if len(value) >= 2 && (value[0] == '"' || value[0] == '\'') && value[len(value)-1] == value[0] {
+			quote = value[:1]
+		}
+		lines[i+1] = key + ": " + quote + version + quote + line[len(text):]
+		return strings.Join(lines, ""), true
+	}
+	return content, false
+}
+
+// frontmatterVersion returns the version field of file at rev, "" when it
+// has none.
+func frontmatterVersion(rev, file string) string {
+	if rev == "" || blobAt(rev, file) == "" {
+		return ""
+	}
+	fields, _ := parseFrontmatter(showFile(rev, file))
+	return fields["version"]
+}
+
+// planVersionBumps sets the version bumps of info with prrompt.versionBump:
+// each modified Markdown prompt with a version in its frontmatter gets the
+// version it has on onto (the commit extracted onto, else the source
+// commit's parent) bumped by the level the message asks for. Prompts whose
+// version the commit changes itself keep it.
+func planVersionBumps(info *CommitInfo, onto string) {
+	info.VersionBumps = nil
+	if !getBoolConfig("prrompt.versionBump", false) {
+		return
+	}
+	level := bumpLevel(extractionMessage(info))
+	for _, file := range info.PromptFiles {
+		if info.FileStatus[file] != "M" || path.Ext(file) != ".md" {
+			continue
+		}
+		before, after := frontmatterVersion(info.parent(), file), frontmatterVersion(info.SHA, file)
+		if before == "" || after != before {
+			continue
+		}
+		old := before
+		if current := frontmatterVersion(onto, file); current != "" {
+			old = current
+		}
+		bumped, ok := bumpVersion(old, level)
+		if !ok {
+			verbosef("Not bumping %s: version %q is not MAJOR.MINOR.PATCH", file, old)
+			continue
+		}
+		info.VersionBumps = append(info.VersionBumps, versionBump{File: file, Old: old, New: bumped})
+	}
+}
+
+// writeVersionBumps applies the version bumps of info to the work tree and
+// stages them.
+func writeVersionBumps(info *CommitInfo) error {
+	if len(info.VersionBumps) == 0 {
+		return nil
+	}
+	toplevel, err := runGit("rev-parse", "--show-toplevel")
+	if err != nil {
+		return fmt.Errorf("not in a git repository: %w", err)
+	}
+	var files []string
+	for _, bump := range info.VersionBumps {
+		file := filepath.Join(toplevel, bump.File)
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %w", bump.File, err)
+		}
+		if bumped, ok := setFrontmatterVersion(string(content), bump.New); ok {
+			if err := os.WriteFile(file, []byte(bumped), 0644); err != nil {
+				return fmt.Errorf("failed to write %s: %w", bump.File, err)
+			}
+			files = append(files, bump.File)
+		}
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	if _, err := runGit(append([]string{"add", "--"}, files...)...); err != nil {
+		return fmt.Errorf("failed to stage version bumps: %w", err)
+	}
+	verbosef("Bumped the version of %d prompt files", len(files))
+	return nil
+}
+
+// bumpedBlob returns blob with the version bump of file applied, or blob
+// itself when it has none.
+func bumpedBlob(info *CommitInfo, file, blob string) (string, error) {
+	for _, bump := range info.VersionBumps {
+		if bump.File != file || blob == "" {
+			continue
+		}
+		content, err := runGitBytes(nil, nil, "cat-file", "blob", blob)
+		if err != nil {
+			return "", err
+		}
+		bumped, ok := setFrontmatterVersion(string(content), bump.New)
+		if !ok {
+			return blob, nil
+		}
+		written, err := runGitBytes(nil, []byte(bumped), "hash-object", "-w", "--stdin")
+		return strings.TrimSpace(string(written)), err
+	}
+	return blob, nil
+}
+
+// netVersionBumps returns the version bumps of infos per file, from the
+// first version to the last.
+func netVersionBumps(infos []*CommitInfo) []versionBump {
+	var bumps []versionBump
+	index := make(map[string]int)
+	for _, info := range infos {
+		for _, bump := range info.VersionBumps {
+			if i, ok := index[bump.File]; ok {
+				bumps[i].New = bump.New
+				continue
+			}
+			index[bump.File] = len(bumps)
+			bumps = append(bumps, bump)
+		}
+	}
+	return bumps
+}
+
+// versionTitle returns the version bumps as a PR title suffix, e.g.
+// " (1.2.3 → 1.2.4)", naming the prompts when there are several.
+func versionTitle(bumps []versionBump) string {
+	if len(bumps) == 0 {
+		return ""
+	}
+	if len(bumps) == 1 {
+		return fmt.Sprintf(" (%s → %s)", bumps[0].Old, bumps[0].New)
+	}
+	var parts []string
+	for _, bump := range bumps {
+		name := strings.TrimSuffix(path.Base(bump.File), path.Ext(bump.File))
+		if path.Base(bump.File) == "SKILL.md" {
+			name = path.Base(skillPath(bump.File))
+		}
+		parts = append(parts, fmt.Sprintf("%s %s → %s", name, bump.Old, bump.New))
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+// formatVersionBumps lists the version bumps for the commit body.
+func formatVersionBumps(bumps []versionBump) string {
+	var lines []string
+	for _, bump := range bumps {
+		lines = append(lines, fmt.Sprintf("Version of %s: %s → %s", bump.File, bump.Old, bump.New))
+	}
+	return strings.Join(lines, "\n")
+}
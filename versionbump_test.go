@@ -0,0 +1,145 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func Test_bumpLevel(t *testing.T) {
+	tests := map[string]string{
+		"Tighten the tone":                       bumpPatch,
+		"Add a checklist minor!":                 bumpMinor,
+		"major! Rewrite the skill":               bumpMajor,
+		"Add a step (minor!)\n\nAnd major!":      bumpMajor,
+		"Mention the minor! and major!gotcha":    bumpMinor,
+		"Fix a typo in minor!important sections": bumpPatch,
+	}
+	for message, want := range tests {
+		if got := bumpLevel(message); got != want {
+			t.Errorf("bumpLevel(%q) = %s, want %s", message, got, want)
+		}
+	}
+}
+
+func Test_bumpVersion(t *testing.T) {
+	tests := []struct {
+		version, level, want string
+		ok                   bool
+	}{
+		{"1.2.3", bumpPatch, "1.2.4", true},
+		{"1.2.3", bumpMinor, "1.3.0", true},
+		{"1.2.3", bumpMajor, "2.0.0", true},
+		{"v0.9.9", bumpPatch, "v0.9.10", true},
+		{"1.2", bumpPatch, "", false},
+		{"1.2.3-beta", bumpPatch, "", false},
+	}
+	for _, tt := range tests {
+		got, ok := bumpVersion(tt.version, tt.level)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("bumpVersion(%q, %s) = %q, %v, want %q, %v", tt.version, tt.level, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func Test_setFrontmatterVersion(t *testing.T) {
+	tests := []struct {
+		content, want string
+		ok            bool
+	}{
+		{"---\nname: review\nversion: 1.2.3\n---\n# Review\n", "---\nname: review\nversion: 1.2.4\n---\n# Review\n", true},
+		{"---\r\nversion: \"1.2.3\"\r\n---\r\n", "---\r\nversion: \"1.2.4\"\r\n---\r\n", true},
+		{"---\nmetadata:\n  version: 1.2.3\n---\n", "---\nmetadata:\n  version: 1.2.3\n---\n", false},
+		{"---\nname: review\n---\nversion: 1.2.3\n", "---\nname: review\n---\nversion: 1.2.3\n", false},
+		{"# No frontmatter\n", "# No frontmatter\n", false},
+	}
+	for _, tt := range tests {
+		got, ok := setFrontmatterVersion(tt.content, "1.2.4")
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("setFrontmatterVersion(%q) = %q, %v, want %q, %v", tt.content, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func Test_versionTitle(t *testing.T) {
+	if got := versionTitle(nil); got != "" {
+		t.Errorf("Expected no suffix without bumps, got %q", got)
+	}
+	one := []versionBump{{File: "prompts/review.md", Old: "1.2.3", New: "1.2.4"}}
+	if got := versionTitle(one); got != " (1.2.3 → 1.2.4)" {
+		t.Errorf("Unexpected suffix %q", got)
+	}
+	two := append(one, versionBump{File: ".claude/skills/lint/SKILL.md", Old: "0.1.0", New: "0.2.0"})
+	if got := versionTitle(two); got != " (review 1.2.3 → 1.2.4, lint 0.1.0 → 0.2.0)" {
+		t.Errorf("Unexpected suffix %q", got)
+	}
+}
+
+func Test_VersionBump(t *testing.T) {
+	repo := setupTestRepo(t)
+	skill := ".claude/skills/review/SKILL.md"
+	runGitInDir(repo.Dir, "checkout", "-q", "main")
+	commitFiles(t, repo.Dir, "Add the review skill", map[string]string{
+		skill:            "---\nname: review\nversion: 1.2.3\n---\n# Review\n",
+		"prompts/raw.md": "# No version\n",
+	})
+	runGitInDir(repo.Dir, "checkout", "-q", repo.BranchName)
+	runGitInDir(repo.Dir, "reset", "-q", "--hard", "main")
+	runGitInDir(repo.Dir, "config", "prrompt.versionBump", "true")
+	sha := commitFiles(t, repo.Dir, "Add a security checklist minor!", map[string]string{
+		skill:            "---\nname: review\nversion: 1.2.3\n---\n# Review\n\nCheck for secrets.\n",
+		"prompts/raw.md": "# Still no version\n",
+	})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(sha)
+	if err != nil || result.Status != statusExtracted {
+		t.Fatalf("processCommit failed: %v %+v", err, result)
+	}
+	want := "---\nname: review\nversion: 1.3.0\n---\n# Review\n\nCheck for secrets."
+	if got, _ := runGit("show", result.Branch+":"+skill); got != want {
+		t.Errorf("Expected the version bumped on the prompt branch, got %q", got)
+	}
+	if got, _ := runGit("show", sha+":"+skill); !strings.Contains(got, "version: 1.2.3") {
+		t.Errorf("Expected the source commit untouched, got %q", got)
+	}
+	if got, _ := runGit("show", result.Branch+":prompts/raw.md"); got != "# Still no version" {
+		t.Errorf("Expected a prompt without a version as committed, got %q", got)
+	}
+	message, _ := runGit("log", "-1", "--format=%B", result.Branch)
+	if !strings.Contains(message, "Version of "+skill+": 1.2.3 → 1.3.0") {
+		t.Errorf("Expected the bump in the commit body, got:\n%s", message)
+	}
+
+	info, err := analyzeCommit(sha)
+	if err != nil {
+		t.Fatal(err)
+	}
+	planVersionBumps(info, result.Branch)
+	if len(info.VersionBumps) != 1 || info.VersionBumps[0].Old != "1.3.0" || info.VersionBumps[0].New != "1.4.0" {
+		t.Fatalf("Expected a bump from the version on the branch built on, got %+v", info.VersionBumps)
+	}
+	commit, err := plumbingCommit(info, "main")
+	if err != nil {
+		t.Fatalf("plumbingCommit failed: %v", err)
+	}
+	if got, _ := runGit("show", commit+":"+skill); !strings.Contains(got, "version: 1.3.0\n") {
+		t.Errorf("Expected the plumbing commit bumped from main, got %q", got)
+	}
+	if title, _ := prTitleAndBody([]*CommitInfo{info}); !strings.HasSuffix(title, "(1.2.3 → 1.3.0)") {
+		t.Errorf("Expected the versions in the PR title, got %q", title)
+	}
+
+	// A commit bumping the version itself keeps it
+	sha = commitFiles(t, repo.Dir, "Release the review skill", map[string]string{
+		skill: "---\nname: review\nversion: 2.0.0\n---\n# Review\n\nCheck for secrets.\n",
+	})
+	info, _ = analyzeCommit(sha)
+	planVersionBumps(info, "main")
+	if len(info.VersionBumps) != 0 {
+		t.Errorf("Expected no bump of a version the commit sets, got %+v", info.VersionBumps)
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// What prrompt.wipCommits does with work-in-progress commits.
+const (
+	wipExtract = "extract"
+	wipSkip    = "skip"
+)
+
+const defaultWIPCommits = wipExtract
+
+// wipMarker matches the WIP markers a commit subject can start with:
+// "WIP", "WIP:", "wip!" or "[WIP]".
+var wipMarker = regexp.MustCompile(`(?i)^(\[wip\]|wip\b[:!]?)\s*`)
+
+// stashSubject matches the subjects `git stash` gives its entries:
+// "WIP on <branch>: <sha> <subject>", or "On <branch>: <message>" for a
+// stash saved with a message.
+var stashSubject = regexp.MustCompile(`^(?:WIP on|On) ([^:]+): (.*)$`)
+
+func getWIPCommits() string {
+	value, _ := gitConfig("--get", "prrompt.wipCommits")
+	if strings.ToLower(strings.TrimSpace(value)) == wipSkip {
+		return wipSkip
+	}
+	return defaultWIPCommits
+}
+
+// isWIPMessage reports whether the subject of message starts with a WIP
+// marker.
+func isWIPMessage(message string) bool {
+	return wipMarker.MatchString(message)
+}
+
+// isStash reports whether sha is a stash entry: a merge of the commit it
+// was made on with an "index on" commit, with a stash subject.
+func isStash(sha, message string) bool {
+	subject, _, _ := strings.Cut(message, "\n")
+	if !stashSubject.MatchString(subject) {
+		return false
+	}
+	index, err := runGit("log", "-1", "--format=%s", sha+"^2")
+	return err == nil && strings.HasPrefix(index, "index on ")
+}
+
+// extractionMessage returns the message an extraction of info starts from:
+// a stash entry's own message, or the message of a WIP commit without its
+// marker, so the prompt branch reads like a finished change. A message
+// edited in `prrompt review` is taken as it is.
+func extractionMessage(info *CommitInfo) string {
+	if info.EditedMessage != "" {
+		return info.EditedMessage
+	}
+	subject, body, _ := strings.Cut(info.Message, "\n")
+	switch {
+	case info.Stash:
+		match := stashSubject.FindStringSubmatch(subject)
+		subject = strings.TrimSpace(match[2])
+		if strings.HasPrefix(match[0], "WIP on ") {
+			// An unnamed stash is only described by the commit it was made on
+			subject = "Prompt changes stashed on " + match[1]
+		}
+	case info.WIP:
+		if subject = wipMarker.ReplaceAllString(subject, ""); subject == "" {
+			subject = "Prompt changes from a work-in-progress commit"
+		}
+	default:
+		return info.Message
+	}
+	if body = strings.TrimSpace(body); body != "" {
+		return subject + "\n\n" + body
+	}
+	return subject
+}
+
+// runFromStash implements `prrompt from-stash [<stash>]`: it extracts the
+// prompt changes of a stash entry, stash@{0} by default, to a prompt branch
+// as if they had been committed. The stash itself is left alone, and files
+// that were untracked when stashing are not included.
+func runFromStash(args []string) error {
+	if len(args) > 1 {
+		return fmt.Errorf("usage: %s from-stash [<stash>]", toolName)
+	}
+	ref := "stash@{0}"
+	if len(args) == 1 {
+		ref = args[0]
+	}
+	sha, err := runGit("rev-parse", "--verify", "-q", ref+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("no stash entry %s", ref)
+	}
+	message, _ := runGit("log", "-1", "--format=%B", sha)
+	if !isStash(sha, message) {
+		return fmt.Errorf("%s is not a stash entry", ref)
+	}
+	result, err := processCommit(sha)
+	if err != nil {
+		return err
+	}
+	if result.Status != statusExtracted {
+		return fmt.Errorf("nothing extracted from %s (%s)", ref, result.Reason)
+	}
+	infof("Extracted the prompt changes of %s to %s", ref, result.Branch)
+	return nil
+}
+
+// stashBranch returns the branch a stash entry was made on, or "" when it
+// was made on a detached HEAD.
+func stashBranch(message string) string {
+	subject, _, _ := strings.Cut(message, "\n")
+	match := stashSubject.FindStringSubmatch(subject)
+	if match == nil || match[1] == "(no branch)" {
+		return ""
+	}
+	return match[1]
+}
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func Test_ExtractionMessage(t *testing.T) {
+	tests := []struct {
+		info *CommitInfo
+		want string
+	}{
+		{&CommitInfo{Message: "Tune prompt"}, "Tune prompt"},
+		{&CommitInfo{Message: "WIP: tune prompt\n\nMore later", WIP: true}, "tune prompt\n\nMore later"},
+		{&CommitInfo{Message: "[WIP] tune prompt", WIP: true}, "tune prompt"},
+		{&CommitInfo{Message: "wip", WIP: true}, "Prompt changes from a work-in-progress commit"},
+		{&CommitInfo{Message: "On feature: better greeting", Stash: true}, "better greeting"},
+		{&CommitInfo{Message: "WIP on feature: 1a2b3c4 Add code", Stash: true}, "Prompt changes stashed on feature"},
+	}
+	for _, tt := range tests {
+		if got := extractionMessage(tt.info); got != tt.want {
+			t.Errorf("extractionMessage(%q) = %q, expected %q", tt.info.Message, got, tt.want)
+		}
+	}
+	for message, want := range map[string]bool{"WIP": true, "wip! later": true, "[wip] x": true, "Wipe the cache": false, "Fix WIP handling": false} {
+		if got := isWIPMessage(message); got != want {
+			t.Errorf("isWIPMessage(%q) = %v, expected %v", message, got, want)
+		}
+	}
+}
+
+func Test_FromStash(t *testing.T) {
+	repo := setupTestRepo(t)
+	commitFiles(t, repo.Dir, "Add prompt", map[string]string{"prompts/a.md": "# A", "src/main.go": "package main"})
+	os.WriteFile(filepath.Join(repo.Dir, "prompts/a.md"), []byte("# A, better"), 0644)
+	os.WriteFile(filepath.Join(repo.Dir, "src/main.go"), []byte("package main\n"), 0644)
+	if output, err := runGitInDir(repo.Dir, "stash", "push", "-m", "Better prompt A"); err != nil {
+		t.Fatalf("failed to stash: %s", output)
+	}
+	stash, _ := runGitInDir(repo.Dir, "rev-parse", "stash@{0}")
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	if err := runFromStash(nil); err != nil {
+		t.Fatalf("from-stash failed: %v", err)
+	}
+	branch := getBranchPrefix() + "/" + stash[:7]
+	if files, _ := runGitInDir(repo.Dir, "diff", "--name-only", "main", branch); files != "prompts/a.md" {
+		t.Errorf("Expected only the prompt change on %s, got %q", branch, files)
+	}
+	body, _ := runGitInDir(repo.Dir, "log", "-1", "--format=%B", branch)
+	if !strings.HasPrefix(body, "[prompt] Better prompt A") || !strings.Contains(body, trailerSourceBranch+": feature-branch") {
+		t.Errorf("Expected the stash message and branch, got %q", body)
+	}
+	if after, _ := runGitInDir(repo.Dir, "rev-parse", "stash@{0}"); after != stash {
+		t.Errorf("Expected the stash to be left alone")
+	}
+
+	if err := runFromStash([]string{"HEAD"}); err == nil || !strings.Contains(err.Error(), "not a stash entry") {
+		t.Errorf("Expected HEAD to be rejected, got %v", err)
+	}
+}
+
+func Test_WIPCommitsSkip(t *testing.T) {
+	repo := setupTestRepo(t)
+	runGitInDir(repo.Dir, "config", "prrompt.wipCommits", "skip")
+	sha := commitFiles(t, repo.Dir, "WIP tune prompt", map[string]string{"prompts/a.md": "# A"})
+
+	oldDir, _ := os.Getwd()
+	defer os.Chdir(oldDir)
+	os.Chdir(repo.Dir)
+
+	result, err := processCommit(sha)
+	if err != nil {
+		t.Fatalf("prrompt failed: %v", err)
+	}
+	if result.Status != statusSkipped || result.Reason != reasonWIP {
+		t.Errorf("Expected the WIP commit to be skipped, got %+v", result)
+	}
+}